@@ -17,6 +17,12 @@ func NewRouter(
 	parserHandler handlers.ParserHandler,
 	downloaderHandler handlers.DownloaderHandler,
 	crawlerHandler handlers.CrawlerHandler,
+	classifierHandler handlers.ClassifierHandler,
+	templateHandler handlers.TemplateHandler,
+	brandKitHandler handlers.BrandKitHandler,
+	archiveHandler handlers.ArchiveHandler,
+	exportTemplateHandler handlers.ExportTemplateHandler,
+	diffHandler handlers.DiffHandler,
 ) *mux.Router {
 	router := mux.NewRouter()
 
@@ -25,16 +31,53 @@ func NewRouter(
 
 	// Регистрируем маршруты парсера
 	apiRouter.HandleFunc("/parse", parserHandler.ParseURL).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/operations", parserHandler.ListOperations).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/export", parserHandler.ExportOperations).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/operations/{id}", parserHandler.GetOperationResult).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/diff/{otherId}", diffHandler.DiffOperations).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/reparse", parserHandler.ReparseOperation).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/operations/{id}/export", parserHandler.ExportOperation).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/exports", parserHandler.ExportSite).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/design-tokens", parserHandler.ExportDesignTokens).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/screenshot", parserHandler.GetPageScreenshot).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/blocks/{blockId}/screenshot", parserHandler.GetBlockScreenshot).Methods(http.MethodGet)
 
 	// Регистрируем маршруты загрузчика
 	apiRouter.HandleFunc("/download/{id}", downloaderHandler.DownloadByID).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/static-site", downloaderHandler.DownloadStaticSite).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/formats", downloaderHandler.GetFormats).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/assets", downloaderHandler.DownloadAssets).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/operations/{id}/assets", downloaderHandler.GetAssetManifest).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/blocks/{blockId}/standalone", downloaderHandler.ExportStandaloneBlock).Methods(http.MethodGet)
 
 	// Регистрируем маршруты краулера
 	apiRouter.HandleFunc("/crawl", crawlerHandler.CrawlURL).Methods(http.MethodPost)
 
+	// Регистрируем маршруты классификатора блоков
+	apiRouter.HandleFunc("/blocks/{id}/label", classifierHandler.LabelBlock).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/classifier/train", classifierHandler.TrainModel).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/classifier/models", classifierHandler.ListModels).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/classifier/models/{version}/activate", classifierHandler.ActivateModel).Methods(http.MethodPost)
+
+	// Регистрируем маршруты шаблонов блоков
+	apiRouter.HandleFunc("/templates/generate", templateHandler.GenerateTemplate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/templates", templateHandler.SaveTemplate).Methods(http.MethodPost)
+
+	// Регистрируем маршруты фирменного набора
+	apiRouter.HandleFunc("/operations/{id}/brand-kit", brandKitHandler.GetBrandKit).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/assets/{assetId}", brandKitHandler.GetAsset).Methods(http.MethodGet)
+
+	// Регистрируем маршруты архивов страниц
+	apiRouter.HandleFunc("/operations/{id}/archives", archiveHandler.GetArchives).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/operations/{id}/archives/{format}", archiveHandler.GetArchive).Methods(http.MethodGet)
+
+	// Регистрируем маршруты пользовательских шаблонов экспорта
+	apiRouter.HandleFunc("/export-templates", exportTemplateHandler.ListTemplates).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/export-templates", exportTemplateHandler.SaveTemplate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/export-templates/preview", exportTemplateHandler.PreviewTemplate).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/export-templates/{name}", exportTemplateHandler.GetTemplate).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/export-templates/{name}", exportTemplateHandler.DeleteTemplate).Methods(http.MethodDelete)
+
 	// Swagger UI
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"scrapper/config"
+	"scrapper/internal/repos"
+	"scrapper/internal/services"
+)
+
+// Обучает новую версию классификатора блоков по размеченным примерам и завершает работу
+func main() {
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	exitCode := 0
+
+	application := fx.New(
+		fx.Supply(logger),
+		fx.Provide(
+			config.NewConfig,
+		),
+		repos.Module,
+		services.Module,
+		fx.NopLogger,
+		fx.Invoke(func(trainer services.ClassifierTrainer) {
+			run, err := trainer.Train(context.Background())
+			if err != nil {
+				logger.Error("Training failed", zap.Error(err))
+				exitCode = 1
+				return
+			}
+
+			logger.Info("Training completed",
+				zap.String("run_id", run.ID.String()),
+				zap.Int("samples", run.Samples),
+				zap.Float64("accuracy", run.Accuracy),
+			)
+		}),
+	)
+
+	if err := application.Err(); err != nil {
+		logger.Error("Failed to initialize application", zap.Error(err))
+		exitCode = 1
+	}
+
+	if exitCode != 0 {
+		logger.Sync()
+		os.Exit(exitCode)
+	}
+}
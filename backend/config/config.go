@@ -3,13 +3,18 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Scraper  ScraperConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Scraper    ScraperConfig
+	Storage    StorageConfig
+	Downloader DownloaderConfig
+	Blocks     BlockPostProcessConfig
+	Reports    ReportsConfig
 }
 
 type ServerConfig struct {
@@ -34,6 +39,31 @@ type ScraperConfig struct {
 	AllowedDomains []string
 }
 
+type StorageConfig struct {
+	Path string
+}
+
+type DownloaderConfig struct {
+	Concurrency  int
+	MaxAssetSize int64
+	MaxAssets    int
+}
+
+// BlockPostProcessConfig настройки обработки HTML блоков перед сохранением
+type BlockPostProcessConfig struct {
+	Sanitize        bool
+	Absolutize      bool
+	Format          string // "", "minify" или "pretty"
+	TrackingDomains []string
+}
+
+// ReportsConfig настройки отчетов, печатаемых в PDF
+type ReportsConfig struct {
+	TemplateDir string // каталог шаблонов команд: <TemplateDir>/<team>.html
+	BrandName   string
+	Timeout     time.Duration
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -61,6 +91,29 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolValue, err := strconv.ParseBool(value)
+		if err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	if value, exists := os.LookupEnv(key); exists {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return defaultValue
+}
+
 func NewConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -88,6 +141,38 @@ func NewConfig() *Config {
 				"skillfactory.ru",
 			},
 		},
+		Storage: StorageConfig{
+			Path: getEnv("STORAGE_PATH", "./data/blobs"),
+		},
+		Downloader: DownloaderConfig{
+			Concurrency:  getEnvInt("DOWNLOADER_CONCURRENCY", 8),
+			MaxAssetSize: int64(getEnvInt("DOWNLOADER_MAX_ASSET_SIZE", 10<<20)),
+			MaxAssets:    getEnvInt("DOWNLOADER_MAX_ASSETS", 500),
+		},
+		Blocks: BlockPostProcessConfig{
+			Sanitize:   getEnvBool("BLOCKS_SANITIZE", true),
+			Absolutize: getEnvBool("BLOCKS_ABSOLUTIZE", true),
+			Format:     getEnv("BLOCKS_FORMAT", ""),
+			TrackingDomains: getEnvList("BLOCKS_TRACKING_DOMAINS", []string{
+				"google-analytics.com",
+				"googletagmanager.com",
+				"googleadservices.com",
+				"googlesyndication.com",
+				"doubleclick.net",
+				"connect.facebook.net",
+				"mc.yandex.ru",
+				"mc.yandex.com",
+				"top-fwz1.mail.ru",
+				"counter.yadro.ru",
+				"hotjar.com",
+				"clarity.ms",
+			}),
+		},
+		Reports: ReportsConfig{
+			TemplateDir: getEnv("REPORTS_TEMPLATE_DIR", "./templates/reports"),
+			BrandName:   getEnv("REPORTS_BRAND_NAME", "Scrapper"),
+			Timeout:     getEnvDuration("REPORTS_TIMEOUT", 60*time.Second),
+		},
 	}
 }
 
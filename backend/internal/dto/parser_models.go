@@ -36,6 +36,54 @@ const (
 	PlatformUnknown   Platform = "unknown"
 )
 
+// SegmentationMode представляет способ деления страницы на блоки
+type SegmentationMode string
+
+const (
+	// SegmentationDOM делит страницу парсерами платформ по DOM-дереву
+	SegmentationDOM SegmentationMode = "dom"
+	// SegmentationLayout делит страницу на визуальные полосы по координатам из браузера
+	SegmentationLayout SegmentationMode = "layout"
+)
+
+// DeviceProfile представляет профиль устройства, под которым рендерится страница
+type DeviceProfile string
+
+const (
+	DeviceDesktop DeviceProfile = "desktop"
+	DeviceTablet  DeviceProfile = "tablet"
+	DeviceMobile  DeviceProfile = "mobile"
+)
+
+// DeviceSettings представляет параметры эмуляции устройства в браузере
+type DeviceSettings struct {
+	Profile          DeviceProfile `json:"profile"`
+	UserAgent        string        `json:"user_agent"`
+	Width            int64         `json:"width"`
+	Height           int64         `json:"height"`
+	DevicePixelRatio float64       `json:"device_pixel_ratio"`
+	Mobile           bool          `json:"mobile"`
+	Touch            bool          `json:"touch"`
+}
+
+// BlockCategory представляет семантический тип контентного блока
+type BlockCategory string
+
+const (
+	BlockCategoryHero         BlockCategory = "hero"
+	BlockCategoryFeatures     BlockCategory = "features"
+	BlockCategoryPricing      BlockCategory = "pricing"
+	BlockCategoryTestimonials BlockCategory = "testimonials"
+	BlockCategoryFAQ          BlockCategory = "faq"
+	BlockCategoryTeam         BlockCategory = "team"
+	BlockCategoryGallery      BlockCategory = "gallery"
+	BlockCategoryContactForm  BlockCategory = "contact_form"
+	BlockCategoryCTA          BlockCategory = "cta"
+	BlockCategoryPartners     BlockCategory = "partners"
+	BlockCategoryBlogList     BlockCategory = "blog_list"
+	BlockCategoryUnknown      BlockCategory = "unknown"
+)
+
 // Operation представляет операцию парсинга
 type Operation struct {
 	ID        uuid.UUID       `json:"id"`
@@ -43,6 +91,31 @@ type Operation struct {
 	Status    OperationStatus `json:"status"`
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
+
+	ScreenshotKey string        `json:"screenshot_key,omitempty"`
+	DesignTokens  *DesignTokens `json:"design_tokens,omitempty"`
+	Brand         *BrandInfo    `json:"brand,omitempty"`
+	ParserVersion string        `json:"parser_version,omitempty"`
+	ReparsedFrom  *uuid.UUID    `json:"reparsed_from,omitempty"`
+	SourceType    SourceType    `json:"source_type"`
+}
+
+// SourceType представляет источник страницы операции
+type SourceType string
+
+const (
+	SourceTypeURL   SourceType = "url"
+	SourceTypeHTML  SourceType = "html"
+	SourceTypeMHTML SourceType = "mhtml"
+	SourceTypeHAR   SourceType = "har"
+	SourceTypeWARC  SourceType = "warc"
+)
+
+// OperationOrigin представляет происхождение результатов операции
+type OperationOrigin struct {
+	ParserVersion string
+	SourceType    SourceType
+	ReparsedFrom  *uuid.UUID
 }
 
 // Block представляет блок, найденный при парсинге
@@ -54,6 +127,127 @@ type Block struct {
 	Content     interface{} `json:"content"`
 	HTML        string      `json:"html"`
 	CreatedAt   time.Time   `json:"created_at"`
+
+	// OriginalHTML HTML блока до очистки и форматирования, пустой если обработка его не изменила
+	OriginalHTML   string               `json:"original_html,omitempty"`
+	Classification *BlockClassification `json:"classification,omitempty"`
+	Bounds         *BlockBounds         `json:"bounds,omitempty"`
+	ScreenshotKey  string               `json:"screenshot_key,omitempty"`
+	Device         DeviceProfile        `json:"device"`
+	DesignTokens   *DesignTokens        `json:"design_tokens,omitempty"`
+}
+
+// TokenUsage представляет значение токена дизайна и частоту его использования
+type TokenUsage struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ColorTokens представляет палитру страницы или блока
+type ColorTokens struct {
+	Background []TokenUsage `json:"background"`
+	Text       []TokenUsage `json:"text"`
+	Accent     []TokenUsage `json:"accent"`
+}
+
+// TypographyTokens представляет шрифты, кегли и начертания
+type TypographyTokens struct {
+	Families []TokenUsage `json:"families"`
+	Sizes    []TokenUsage `json:"sizes"`
+	Weights  []TokenUsage `json:"weights"`
+}
+
+// ButtonToken представляет стиль кнопки
+type ButtonToken struct {
+	Background   string `json:"background"`
+	Color        string `json:"color"`
+	FontWeight   string `json:"font_weight"`
+	BorderRadius string `json:"border_radius"`
+	Padding      string `json:"padding"`
+	Count        int    `json:"count"`
+}
+
+// DesignTokens представляет токены дизайна, собранные из вычисленных стилей
+type DesignTokens struct {
+	Colors     ColorTokens      `json:"colors"`
+	Typography TypographyTokens `json:"typography"`
+	Buttons    []ButtonToken    `json:"buttons"`
+	Radii      []TokenUsage     `json:"radii"`
+	Spacing    []TokenUsage     `json:"spacing"`
+}
+
+// BlockBounds представляет координаты блока на отрендеренной странице
+type BlockBounds struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// BlockClassification представляет результат семантической классификации блока
+type BlockClassification struct {
+	Category BlockCategory `json:"category"`
+	Score    float64       `json:"score"`
+	Signals  []string      `json:"signals"`
+}
+
+// BlockLabel представляет ручную разметку типа блока
+type BlockLabel struct {
+	BlockID   uuid.UUID     `json:"block_id"`
+	Category  BlockCategory `json:"category"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// LabelledBlock представляет размеченный блок для обучения классификатора
+type LabelledBlock struct {
+	BlockID  uuid.UUID     `json:"block_id"`
+	Category BlockCategory `json:"category"`
+	HTML     string        `json:"html"`
+}
+
+// TrainingStatus представляет статус запуска обучения
+type TrainingStatus string
+
+const (
+	TrainingStatusRunning   TrainingStatus = "running"
+	TrainingStatusCompleted TrainingStatus = "completed"
+	TrainingStatusError     TrainingStatus = "error"
+)
+
+// TrainingRun представляет запуск обучения классификатора
+type TrainingRun struct {
+	ID         uuid.UUID      `json:"id"`
+	ModelID    *uuid.UUID     `json:"model_id,omitempty"`
+	Status     TrainingStatus `json:"status"`
+	Samples    int            `json:"samples"`
+	Accuracy   float64        `json:"accuracy"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// ClassifierModel представляет версию обученной модели классификатора блоков
+type ClassifierModel struct {
+	ID         uuid.UUID             `json:"id"`
+	Version    int                   `json:"version"`
+	Algorithm  string                `json:"algorithm"`
+	Samples    int                   `json:"samples"`
+	Accuracy   float64               `json:"accuracy"`
+	Active     bool                  `json:"active"`
+	Parameters *NaiveBayesParameters `json:"parameters,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+// NaiveBayesParameters представляет параметры наивного байесовского классификатора
+type NaiveBayesParameters struct {
+	Priors      map[BlockCategory]float64            `json:"priors"`
+	Likelihoods map[BlockCategory]map[string]float64 `json:"likelihoods"`
+	Unseen      map[BlockCategory]float64            `json:"unseen"`
+}
+
+// LabelBlockRequest представляет запрос на исправление типа блока
+type LabelBlockRequest struct {
+	Category BlockCategory `json:"category"`
 }
 
 type BlockTemplate struct {
@@ -61,9 +255,262 @@ type BlockTemplate struct {
 	HTMLTags  []byte `json:"html"`
 }
 
-// ParseURLRequest представляет запрос на парсинг URL
+// GenerateTemplateRequest представляет запрос на генерацию шаблона по примеру блока
+type GenerateTemplateRequest struct {
+	BlockID   *uuid.UUID `json:"block_id,omitempty"`
+	HTML      string     `json:"html,omitempty"`
+	BlockType string     `json:"block_type"`
+	Platform  Platform   `json:"platform,omitempty"`
+}
+
+// TemplateMatch представляет сохраненный блок, подходящий под шаблон
+type TemplateMatch struct {
+	BlockID        uuid.UUID            `json:"block_id"`
+	OperationID    uuid.UUID            `json:"operation_id"`
+	BlockType      BlockType            `json:"block_type"`
+	Classification *BlockClassification `json:"classification,omitempty"`
+}
+
+// GenerateTemplateResponse представляет кандидат шаблона и блоки, которые он находит
+type GenerateTemplateResponse struct {
+	BlockType  string                 `json:"block_type"`
+	Platform   Platform               `json:"platform"`
+	Template   map[string]interface{} `json:"template"`
+	Features   map[string][]string    `json:"features"`
+	Matches    []TemplateMatch        `json:"matches"`
+	MatchCount int                    `json:"match_count"`
+	Scanned    int                    `json:"scanned"`
+}
+
+// SaveTemplateRequest представляет запрос на сохранение шаблона блока
+type SaveTemplateRequest struct {
+	BlockType string                 `json:"block_type"`
+	Platform  Platform               `json:"platform"`
+	Template  map[string]interface{} `json:"template"`
+}
+
+// AssetKind представляет назначение файла, скачанного для операции
+type AssetKind string
+
+const (
+	AssetKindLogo           AssetKind = "logo"
+	AssetKindFavicon        AssetKind = "favicon"
+	AssetKindAppleTouchIcon AssetKind = "apple_touch_icon"
+	AssetKindManifestIcon   AssetKind = "manifest_icon"
+	AssetKindOGImage        AssetKind = "og_image"
+	AssetKindImage          AssetKind = "image"
+	AssetKindBackground     AssetKind = "background_image"
+	AssetKindFont           AssetKind = "font"
+	AssetKindStylesheet     AssetKind = "stylesheet"
+	AssetKindScript         AssetKind = "script"
+	AssetKindPoster         AssetKind = "video_poster"
+)
+
+// Asset представляет файл, скачанный для операции
+type Asset struct {
+	ID          uuid.UUID `json:"id"`
+	OperationID uuid.UUID `json:"operation_id"`
+	Kind        AssetKind `json:"kind"`
+	URL         string    `json:"url"`
+	StorageKey  string    `json:"storage_key"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BlockAssetLink представляет ссылку блока на скачанный файл
+type BlockAssetLink struct {
+	BlockID uuid.UUID `json:"block_id"`
+	AssetID uuid.UUID `json:"asset_id"`
+	URL     string    `json:"url"`
+}
+
+// FailedAsset представляет файл, который не удалось скачать
+type FailedAsset struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// AssetManifest представляет файлы операции и их связь с блоками
+type AssetManifest struct {
+	OperationID uuid.UUID        `json:"operation_id"`
+	Assets      []Asset          `json:"assets"`
+	Links       []BlockAssetLink `json:"links"`
+	Failed      []FailedAsset    `json:"failed,omitempty"`
+}
+
+// BundleManifest представляет manifest.json в ZIP-архиве операции
+type BundleManifest struct {
+	Operation   Operation     `json:"operation"`
+	Screenshot  string        `json:"screenshot,omitempty"`
+	Blocks      []BundleBlock `json:"blocks"`
+	Assets      []BundleAsset `json:"assets"`
+	Exports     []string      `json:"exports"`
+	Missing     []string      `json:"missing,omitempty"`
+	GeneratedAt time.Time     `json:"generated_at"`
+}
+
+// BundleBlock представляет блок в архиве операции
+type BundleBlock struct {
+	ID             uuid.UUID            `json:"id"`
+	BlockType      BlockType            `json:"block_type"`
+	Platform       Platform             `json:"platform"`
+	Device         DeviceProfile        `json:"device"`
+	Classification *BlockClassification `json:"classification,omitempty"`
+	File           string               `json:"file"`
+	OriginalFile   string               `json:"original_file,omitempty"`
+	StandaloneFile string               `json:"standalone_file,omitempty"`
+	Screenshot     string               `json:"screenshot,omitempty"`
+	Assets         []uuid.UUID          `json:"assets"`
+}
+
+// BundleAsset представляет файл в архиве операции
+type BundleAsset struct {
+	ID          uuid.UUID `json:"id"`
+	Kind        AssetKind `json:"kind"`
+	URL         string    `json:"url"`
+	File        string    `json:"file"`
+	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256"`
+}
+
+// StaticSiteManifest представляет manifest.json статического сайта
+type StaticSiteManifest struct {
+	Host        string    `json:"host"`
+	Pages       []string  `json:"pages"`
+	Missing     []string  `json:"missing,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ExportOptions представляет параметры экспорта операции
+type ExportOptions struct {
+	// Team команда, чей шаблон отчета используется; пустая строка - шаблон по умолчанию
+	Team string
+}
+
+// ExportTemplateEngine представляет движок пользовательского шаблона экспорта
+type ExportTemplateEngine string
+
+const (
+	ExportTemplateEngineText ExportTemplateEngine = "text"
+	ExportTemplateEngineHTML ExportTemplateEngine = "html"
+)
+
+// ExportTemplate представляет пользовательский шаблон экспорта; Name используется как формат экспорта
+type ExportTemplate struct {
+	ID          uuid.UUID            `json:"id"`
+	Name        string               `json:"name"`
+	Engine      ExportTemplateEngine `json:"engine"`
+	ContentType string               `json:"content_type"`
+	Extension   string               `json:"extension"`
+	Description string               `json:"description,omitempty"`
+	Body        string               `json:"body"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// SaveExportTemplateRequest представляет запрос на сохранение шаблона экспорта; шаблон с тем же именем заменяется
+type SaveExportTemplateRequest struct {
+	Name        string               `json:"name"`
+	Engine      ExportTemplateEngine `json:"engine"`
+	ContentType string               `json:"content_type,omitempty"` // по умолчанию по движку
+	Extension   string               `json:"extension,omitempty"`    // по умолчанию по движку
+	Description string               `json:"description,omitempty"`
+	Body        string               `json:"body"`
+}
+
+// PreviewExportTemplateRequest представляет запрос на проверку шаблона на существующей операции:
+// сохраненного по имени или переданного в Body без сохранения
+type PreviewExportTemplateRequest struct {
+	OperationID uuid.UUID            `json:"operation_id"`
+	Name        string               `json:"name,omitempty"`
+	Engine      ExportTemplateEngine `json:"engine,omitempty"`
+	Body        string               `json:"body,omitempty"`
+}
+
+// BrandInfo представляет фирменные параметры сайта из манифеста и мета-тегов
+type BrandInfo struct {
+	Name            string `json:"name,omitempty"`
+	ThemeColor      string `json:"theme_color,omitempty"`
+	BackgroundColor string `json:"background_color,omitempty"`
+	ManifestURL     string `json:"manifest_url,omitempty"`
+}
+
+// BrandKit представляет фирменный набор сайта: логотип, иконки, изображение для соцсетей и цвета
+type BrandKit struct {
+	OperationID uuid.UUID  `json:"operation_id"`
+	Brand       *BrandInfo `json:"brand,omitempty"`
+	Assets      []Asset    `json:"assets"`
+}
+
+// ArchiveFormat представляет формат архива страницы
+type ArchiveFormat string
+
+const (
+	ArchiveFormatMHTML ArchiveFormat = "mhtml"
+	ArchiveFormatWARC  ArchiveFormat = "warc"
+)
+
+// Archive представляет архив страницы, снятый при рендеринге под профилем устройства
+type Archive struct {
+	ID          uuid.UUID     `json:"id"`
+	OperationID uuid.UUID     `json:"operation_id"`
+	Device      DeviceProfile `json:"device"`
+	Format      ArchiveFormat `json:"format"`
+	StorageKey  string        `json:"storage_key"`
+	Size        int64         `json:"size"`
+	SHA256      string        `json:"sha256"`
+	CapturedAt  time.Time     `json:"captured_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Snapshot представляет сохраненный HTML страницы после рендеринга и ответ сервера на запрос документа
+type Snapshot struct {
+	ID             uuid.UUID         `json:"id"`
+	OperationID    uuid.UUID         `json:"operation_id"`
+	Device         DeviceProfile     `json:"device"`
+	StorageKey     string            `json:"storage_key"`
+	Size           int64             `json:"size"`
+	CompressedSize int64             `json:"compressed_size"`
+	SHA256         string            `json:"sha256"`
+	URL            string            `json:"url"`
+	StatusCode     int               `json:"status_code"`
+	ContentType    string            `json:"content_type"`
+	Headers        map[string]string `json:"headers"`
+	CapturedAt     time.Time         `json:"captured_at"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// ReparseRequest представляет запрос на повторный парсинг сохраненных снимков HTML операции
+type ReparseRequest struct {
+	Segmentation SegmentationMode `json:"segmentation,omitempty"`
+}
+
+// ParseURLRequest представляет запрос на парсинг URL.
+// Если задан ArchiveOperationID, страница открывается из WARC-архивов указанной операции, а не с живого сайта.
 type ParseURLRequest struct {
-	URL string `json:"url"`
+	URL                string           `json:"url"`
+	Segmentation       SegmentationMode `json:"segmentation,omitempty"`
+	Devices            []DeviceProfile  `json:"devices,omitempty"`
+	ArchiveOperationID *uuid.UUID       `json:"archive_operation_id,omitempty"`
+
+	// HTML и BaseURL позволяют передать разметку страницы вместо URL
+	HTML    string `json:"html,omitempty"`
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// ParseContentRequest представляет запрос на парсинг загруженной страницы: HTML, MHTML, HAR или WARC.
+// BaseURL задает адрес страницы для относительных ссылок, если его нельзя взять из самого файла.
+type ParseContentRequest struct {
+	SourceType   SourceType       `json:"source_type"`
+	Filename     string           `json:"filename,omitempty"`
+	Content      []byte           `json:"-"`
+	BaseURL      string           `json:"base_url,omitempty"`
+	Segmentation SegmentationMode `json:"segmentation,omitempty"`
+	Devices      []DeviceProfile  `json:"devices,omitempty"`
 }
 
 // ParseURLResponse представляет ответ на запрос парсинга URL
@@ -80,12 +527,117 @@ type GetOperationResultRequest struct {
 type GetOperationResultResponse struct {
 	Operation Operation `json:"operation"`
 	Blocks    []Block   `json:"blocks"`
+
+	// Devices ID блоков по профилям устройств в порядке страницы, для сравнения структуры страниц;
+	// сами блоки есть в Blocks
+	Devices map[DeviceProfile][]uuid.UUID `json:"devices,omitempty"`
+}
+
+// OperationFilter представляет фильтр списка операций; пустые поля не ограничивают выборку
+type OperationFilter struct {
+	IDs    []uuid.UUID     `json:"ids,omitempty"`
+	Status OperationStatus `json:"status,omitempty"`
+	Host   string          `json:"host,omitempty"`  // домен URL без учета регистра
+	Query  string          `json:"query,omitempty"` // подстрока URL без учета регистра
+	From   *time.Time      `json:"from,omitempty"`
+	To     *time.Time      `json:"to,omitempty"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+// ListOperationsResponse представляет страницу списка операций, новые первыми
+type ListOperationsResponse struct {
+	Operations []Operation `json:"operations"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+}
+
+// BlockDiffStatus представляет результат сравнения блока двух операций
+type BlockDiffStatus string
+
+const (
+	BlockDiffUnchanged BlockDiffStatus = "unchanged"
+	BlockDiffChanged   BlockDiffStatus = "changed"
+	BlockDiffAdded     BlockDiffStatus = "added"
+	BlockDiffRemoved   BlockDiffStatus = "removed"
+)
+
+// DiffOp представляет операцию строки диффа
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+	// DiffSkip заменяет длинный неизмененный участок, текст строки описывает пропуск
+	DiffSkip DiffOp = "skip"
+)
+
+// ComponentChangeType представляет вид изменения компонента блока
+type ComponentChangeType string
+
+const (
+	ComponentAdded   ComponentChangeType = "added"
+	ComponentRemoved ComponentChangeType = "removed"
+	ComponentChanged ComponentChangeType = "changed"
+)
+
+// ComponentChange представляет изменение компонента блока: пункта меню, телефона, ссылки
+type ComponentChange struct {
+	Key    string              `json:"key"` // путь компонента в содержимом, например nav_links.2
+	Change ComponentChangeType `json:"change"`
+	Before string              `json:"before,omitempty"`
+	After  string              `json:"after,omitempty"`
+}
+
+// DiffLine представляет строку текстового диффа HTML блока: тег или текстовый узел
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// BlockDiff представляет сопоставление блока двух операций и его изменения
+type BlockDiff struct {
+	Status    BlockDiffStatus `json:"status"`
+	Moved     bool            `json:"moved"` // блок сменил порядок относительно остальных сопоставленных блоков
+	Device    DeviceProfile   `json:"device"`
+	BlockType BlockType       `json:"block_type"`
+	Category  BlockCategory   `json:"category,omitempty"`
+
+	FromBlockID  *uuid.UUID `json:"from_block_id,omitempty"`
+	ToBlockID    *uuid.UUID `json:"to_block_id,omitempty"`
+	FromPosition *int       `json:"from_position,omitempty"` // позиция среди блоков профиля, с 1
+	ToPosition   *int       `json:"to_position,omitempty"`
+
+	// Similarity сходство содержимого сопоставленных блоков от 0 до 1
+	Similarity float64           `json:"similarity"`
+	Text       string            `json:"text"` // текст блока для просмотра: новый, у удаленных блоков старый
+	Components []ComponentChange `json:"components,omitempty"`
+	HTMLDiff   []DiffLine        `json:"html_diff,omitempty"`
+}
+
+// OperationDiffSummary представляет число блоков по результатам сравнения
+type OperationDiffSummary struct {
+	Added     int `json:"added"`
+	Removed   int `json:"removed"`
+	Moved     int `json:"moved"`
+	Changed   int `json:"changed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// OperationDiff представляет поблочное сравнение двух операций одного сайта
+type OperationDiff struct {
+	From        Operation            `json:"from"`
+	To          Operation            `json:"to"`
+	Summary     OperationDiffSummary `json:"summary"`
+	Blocks      []BlockDiff          `json:"blocks"`
+	GeneratedAt time.Time            `json:"generated_at"`
 }
 
 // ExportOperationRequest представляет запрос на экспорт результатов операции
 type ExportOperationRequest struct {
 	OperationID uuid.UUID `json:"operation_id"`
-	Format      string    `json:"format"` // "excel" или "text"
+	Format      string    `json:"format"` // имя формата из ExporterRegistry
 }
 
 // ExportOperationResponse представляет ответ на запрос экспорта
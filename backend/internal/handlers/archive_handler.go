@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/services"
+)
+
+// archiveHandler реализация ArchiveHandler
+type archiveHandler struct {
+	logger  *zap.Logger
+	service services.ArchiveService
+}
+
+// NewArchiveHandler создает новый экземпляр ArchiveHandler
+func NewArchiveHandler(logger *zap.Logger, service services.ArchiveService) ArchiveHandler {
+	return &archiveHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetArchives обрабатывает запрос на получение списка архивов операции
+func (h *archiveHandler) GetArchives(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	archives, err := h.service.GetArchives(r.Context(), operationID)
+	if err != nil {
+		h.logger.Error("Failed to get archives", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get archives")
+		return
+	}
+
+	response := struct {
+		Archives []dto.Archive `json:"archives"`
+	}{
+		Archives: archives,
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// GetArchive обрабатывает запрос на скачивание архива операции.
+// Профиль устройства задается параметром device, по умолчанию desktop.
+func (h *archiveHandler) GetArchive(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	format := dto.ArchiveFormat(vars["format"])
+	if format != dto.ArchiveFormatMHTML && format != dto.ArchiveFormatWARC {
+		RespondWithError(w, http.StatusBadRequest, "Invalid format. Supported formats: mhtml, warc")
+		return
+	}
+
+	device := dto.DeviceProfile(r.URL.Query().Get("device"))
+	switch device {
+	case "":
+		device = dto.DeviceDesktop
+	case dto.DeviceDesktop, dto.DeviceTablet, dto.DeviceMobile:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid device. Supported devices: desktop, tablet, mobile")
+		return
+	}
+
+	archive, reader, err := h.service.OpenArchive(r.Context(), operationID, device, format)
+	if err != nil {
+		if errors.Is(err, services.ErrArchiveNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Archive not found")
+			return
+		}
+		h.logger.Error("Failed to open archive", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to open archive")
+		return
+	}
+	defer reader.Close()
+
+	filename := fmt.Sprintf("operation_%s_%s.%s", operationID.String(), device, format)
+
+	w.Header().Set("Content-Type", services.ArchiveContentType(format))
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Length", strconv.FormatInt(archive.Size, 10))
+	w.Header().Set("ETag", `"`+archive.SHA256+`"`)
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		h.logger.Error("Failed to send archive", zap.Error(err))
+	}
+}
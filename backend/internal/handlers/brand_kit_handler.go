@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"scrapper/internal/services"
+)
+
+// brandKitHandler реализация BrandKitHandler
+type brandKitHandler struct {
+	logger  *zap.Logger
+	service services.BrandKitService
+}
+
+// NewBrandKitHandler создает новый экземпляр BrandKitHandler
+func NewBrandKitHandler(logger *zap.Logger, service services.BrandKitService) BrandKitHandler {
+	return &brandKitHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GetBrandKit обрабатывает запрос на получение фирменного набора операции
+func (h *brandKitHandler) GetBrandKit(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	kit, err := h.service.GetBrandKit(r.Context(), operationID)
+	if err != nil {
+		h.logger.Error("Failed to get brand kit", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get brand kit")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, kit)
+}
+
+// GetAsset обрабатывает запрос на скачивание файла операции
+func (h *brandKitHandler) GetAsset(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	assetID, err := uuid.Parse(vars["assetId"])
+	if err != nil {
+		h.logger.Error("Invalid asset ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid asset ID")
+		return
+	}
+
+	asset, reader, err := h.service.OpenAsset(r.Context(), operationID, assetID)
+	if err != nil {
+		if errors.Is(err, services.ErrAssetNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Asset not found")
+			return
+		}
+		h.logger.Error("Failed to open asset", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to open asset")
+		return
+	}
+	defer reader.Close()
+
+	// Файлы сторонних сайтов не должны выполнять скрипты в origin API: SVG, CSS и JS отдаются вложением
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !isInlineAsset(asset.ContentType) {
+		w.Header().Set("Content-Disposition", "attachment; filename="+asset.ID.String())
+	}
+	w.Header().Set("Content-Type", asset.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(asset.Size, 10))
+	w.Header().Set("ETag", `"`+asset.SHA256+`"`)
+
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		h.logger.Error("Failed to send asset", zap.Error(err))
+	}
+}
+
+// isInlineAsset проверяет, что файл можно показать в браузере: растровые картинки и шрифты не содержат скриптов
+func isInlineAsset(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "font/"):
+		return true
+	}
+	return false
+}
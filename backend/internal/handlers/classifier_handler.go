@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/services"
+)
+
+// classifierHandler реализация ClassifierHandler
+type classifierHandler struct {
+	logger  *zap.Logger
+	service services.ClassifierTrainer
+}
+
+// NewClassifierHandler создает новый экземпляр ClassifierHandler
+func NewClassifierHandler(logger *zap.Logger, service services.ClassifierTrainer) ClassifierHandler {
+	return &classifierHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// LabelBlock обрабатывает запрос на исправление типа блока
+func (h *classifierHandler) LabelBlock(w http.ResponseWriter, r *http.Request) {
+	// Получаем ID блока из URL
+	vars := mux.Vars(r)
+	blockID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid block ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid block ID")
+		return
+	}
+
+	var req dto.LabelBlockRequest
+
+	// Декодируем тело запроса
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Category == "" {
+		RespondWithError(w, http.StatusBadRequest, "Category is required")
+		return
+	}
+
+	label, err := h.service.LabelBlock(r.Context(), blockID, req.Category)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownCategory):
+			RespondWithError(w, http.StatusBadRequest, "Unknown block category")
+		case errors.Is(err, services.ErrBlockNotFound):
+			RespondWithError(w, http.StatusNotFound, "Block not found")
+		default:
+			h.logger.Error("Failed to label block", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to label block")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, label)
+}
+
+// TrainModel обрабатывает запрос на обучение новой версии модели
+func (h *classifierHandler) TrainModel(w http.ResponseWriter, r *http.Request) {
+	run, err := h.service.Train(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to train classifier", zap.Error(err))
+		if run != nil {
+			RespondWithJSON(w, http.StatusUnprocessableEntity, run)
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to train classifier")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, run)
+}
+
+// ListModels обрабатывает запрос на получение версий модели
+func (h *classifierHandler) ListModels(w http.ResponseWriter, r *http.Request) {
+	models, err := h.service.ListModels(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list classifier models", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list classifier models")
+		return
+	}
+
+	response := struct {
+		Models []dto.ClassifierModel `json:"models"`
+	}{
+		Models: models,
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// ActivateModel обрабатывает запрос на откат к указанной версии модели
+func (h *classifierHandler) ActivateModel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil || version <= 0 {
+		RespondWithError(w, http.StatusBadRequest, "Invalid model version")
+		return
+	}
+
+	if err := h.service.ActivateModel(r.Context(), version); err != nil {
+		if errors.Is(err, services.ErrModelNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Classifier model not found")
+			return
+		}
+		h.logger.Error("Failed to activate classifier model", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to activate classifier model")
+		return
+	}
+
+	response := struct {
+		Version int `json:"version"`
+	}{
+		Version: version,
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"scrapper/internal/services"
+)
+
+// diffHandler реализация DiffHandler
+type diffHandler struct {
+	logger  *zap.Logger
+	service services.DiffService
+}
+
+// NewDiffHandler создает новый экземпляр DiffHandler
+func NewDiffHandler(logger *zap.Logger, service services.DiffService) DiffHandler {
+	return &diffHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// DiffOperations обрабатывает запрос на поблочное сравнение операции id с операцией otherId.
+// Без параметра format сравнение возвращается как ответ API, с format=json или format=html скачивается файлом.
+func (h *diffHandler) DiffOperations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	fromID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+	toID, err := uuid.Parse(vars["otherId"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" {
+		RespondWithError(w, http.StatusBadRequest, "Invalid format. Supported formats: json, html")
+		return
+	}
+
+	diff, err := h.service.DiffOperations(r.Context(), fromID, toID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDiff) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to diff operations", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to diff operations")
+		return
+	}
+
+	if format == "" {
+		RespondWithJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	// Отчет собирается в памяти, чтобы ошибку рендера можно было вернуть клиенту
+	var buf bytes.Buffer
+	contentType := "application/json"
+	if format == "html" {
+		contentType = "text/html; charset=utf-8"
+		err = h.service.RenderDiffHTML(diff, &buf)
+	} else {
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	}
+	if err != nil {
+		h.logger.Error("Failed to render diff", zap.String("format", format), zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to render diff")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=diff_%s_%s.%s", fromID, toID, format))
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		h.logger.Error("Failed to write diff", zap.Error(err))
+	}
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
-	"go.uber.org/zap"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
 	"scrapper/internal/services"
 )
 
@@ -20,14 +27,103 @@ func NewDownloaderHandler(logger *zap.Logger, service services.DownloaderService
 	}
 }
 
-// DownloadByID обрабатывает запрос на загрузку файлов по ID операции
+// DownloadByID обрабатывает запрос на загрузку файлов по ID операции.
+// Отдает ZIP-архив потоком; состав экспортов задается параметром formats через запятую.
 func (h *downloaderHandler) DownloadByID(w http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	formats := []string{"json"}
+	if value := r.URL.Query().Get("formats"); value != "" {
+		formats = nil
+		for _, format := range strings.Split(value, ",") {
+			if format = strings.TrimSpace(format); format != "" {
+				formats = append(formats, format)
+			}
+		}
+	}
+
+	availableFormats, err := h.service.GetAvailableFormats(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get formats", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to prepare bundle")
+		return
+	}
+
+	available := make(map[string]bool)
+	for _, format := range availableFormats {
+		available[format] = true
+	}
+	for _, format := range formats {
+		if !available[format] {
+			RespondWithError(w, http.StatusBadRequest, "Unsupported format: "+format)
+			return
+		}
+	}
+
+	bundle, err := h.service.PrepareBundle(r.Context(), operationID, formats)
+	if err != nil {
+		h.logger.Error("Failed to prepare bundle", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to prepare bundle")
+		return
+	}
+
+	// Размер архива заранее неизвестен, Content-Length не выставляется
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+bundle.Filename())
+	w.WriteHeader(http.StatusOK)
+
+	if err := bundle.Write(r.Context(), w); err != nil {
+		h.logger.Error("Failed to write bundle", zap.String("operation_id", operationID.String()), zap.Error(err))
+	}
+}
+
+// DownloadStaticSite обрабатывает запрос на статический сайт из операций одного домена.
+// ID операций передаются через запятую в operation_ids, архив отдается потоком.
+func (h *downloaderHandler) DownloadStaticSite(w http.ResponseWriter, r *http.Request) {
+	operationIDs, err := parseOperationIDs(r.URL.Query().Get("operation_ids"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(operationIDs) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "Operation IDs are required")
+		return
+	}
+
+	site, err := h.service.PrepareStaticSite(r.Context(), operationIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSiteExport) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to prepare static site", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to prepare static site")
+		return
+	}
+
+	// Размер архива заранее неизвестен, Content-Length не выставляется
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename="+site.Filename())
+	w.WriteHeader(http.StatusOK)
+
+	if err := site.Write(r.Context(), w); err != nil {
+		h.logger.Error("Failed to write static site", zap.Int("operations", len(operationIDs)), zap.Error(err))
+	}
 }
 
 // GetFormats обрабатывает запрос на получение доступных форматов
 func (h *downloaderHandler) GetFormats(w http.ResponseWriter, r *http.Request) {
-	formats := h.service.GetAvailableFormats()
+	formats, err := h.service.GetAvailableFormats(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get formats", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get formats")
+		return
+	}
 
 	response := struct {
 		Formats []string `json:"formats"`
@@ -37,3 +133,90 @@ func (h *downloaderHandler) GetFormats(w http.ResponseWriter, r *http.Request) {
 
 	RespondWithJSON(w, http.StatusOK, response)
 }
+
+// DownloadAssets обрабатывает запрос на скачивание файлов, на которые ссылаются блоки операции
+func (h *downloaderHandler) DownloadAssets(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	manifest, err := h.service.DownloadAssets(r.Context(), operationID)
+	if err != nil {
+		h.logger.Error("Failed to download assets", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to download assets")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, manifest)
+}
+
+// GetAssetManifest обрабатывает запрос на получение манифеста файлов операции
+func (h *downloaderHandler) GetAssetManifest(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	manifest, err := h.service.GetAssetManifest(r.Context(), operationID)
+	if err != nil {
+		h.logger.Error("Failed to get asset manifest", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get asset manifest")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, manifest)
+}
+
+// ExportStandaloneBlock обрабатывает запрос на автономный HTML блока.
+// С параметром inline=true картинки и шрифты встраиваются в файл как data: URI.
+func (h *downloaderHandler) ExportStandaloneBlock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	blockID, err := uuid.Parse(vars["blockId"])
+	if err != nil {
+		h.logger.Error("Invalid block ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid block ID")
+		return
+	}
+
+	inline := false
+	if value := r.URL.Query().Get("inline"); value != "" {
+		inline, err = strconv.ParseBool(value)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, "Invalid inline flag")
+			return
+		}
+	}
+
+	content, filename, err := h.service.ExportStandaloneBlock(r.Context(), operationID, blockID, inline)
+	if err != nil {
+		if errors.Is(err, services.ErrBlockNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Block not found")
+			return
+		}
+		h.logger.Error("Failed to export standalone block", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to export standalone block")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(content); err != nil {
+		h.logger.Error("Failed to write standalone block", zap.Error(err))
+	}
+}
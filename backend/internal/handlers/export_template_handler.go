@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/services"
+)
+
+// exportTemplateHandler реализация ExportTemplateHandler
+type exportTemplateHandler struct {
+	logger  *zap.Logger
+	service services.ExportTemplateService
+}
+
+// NewExportTemplateHandler создает новый экземпляр ExportTemplateHandler
+func NewExportTemplateHandler(logger *zap.Logger, service services.ExportTemplateService) ExportTemplateHandler {
+	return &exportTemplateHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// ListTemplates обрабатывает запрос на получение шаблонов экспорта
+func (h *exportTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.service.ListTemplates(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list export templates", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list export templates")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, templates)
+}
+
+// SaveTemplate обрабатывает запрос на сохранение шаблона экспорта
+func (h *exportTemplateHandler) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.SaveExportTemplateRequest
+
+	// Декодируем тело запроса
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	template, err := h.service.SaveTemplate(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidExportTemplate) {
+			RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		h.logger.Error("Failed to save export template", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to save export template")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplate обрабатывает запрос на получение шаблона экспорта по имени
+func (h *exportTemplateHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.service.GetTemplate(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Is(err, services.ErrExportTemplateNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Export template not found")
+			return
+		}
+		h.logger.Error("Failed to get export template", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get export template")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, template)
+}
+
+// DeleteTemplate обрабатывает запрос на удаление шаблона экспорта
+func (h *exportTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteTemplate(r.Context(), mux.Vars(r)["name"]); err != nil {
+		if errors.Is(err, services.ErrExportTemplateNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Export template not found")
+			return
+		}
+		h.logger.Error("Failed to delete export template", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to delete export template")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PreviewTemplate обрабатывает запрос на проверку шаблона на существующей операции.
+// Результат отдается с MIME-типом шаблона без Content-Disposition, чтобы его можно было открыть в браузере.
+func (h *exportTemplateHandler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.PreviewExportTemplateRequest
+
+	// Декодируем тело запроса
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.OperationID == uuid.Nil {
+		RespondWithError(w, http.StatusBadRequest, "Operation ID is required")
+		return
+	}
+
+	content, contentType, err := h.service.PreviewTemplate(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExportTemplate):
+			RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, services.ErrExportTemplateNotFound):
+			RespondWithError(w, http.StatusNotFound, "Export template not found")
+		default:
+			h.logger.Error("Failed to preview export template", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to preview export template")
+		}
+		return
+	}
+
+	// Пользовательский HTML не должен выполнять скрипты в origin API
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(content); err != nil {
+		h.logger.Error("Failed to write template preview", zap.Error(err))
+	}
+}
@@ -9,11 +9,32 @@ type ParserHandler interface {
 	// ParseURL обрабатывает запрос на парсинг URL
 	ParseURL(w http.ResponseWriter, r *http.Request)
 
+	// ReparseOperation обрабатывает запрос на повторный парсинг сохраненных снимков операции
+	ReparseOperation(w http.ResponseWriter, r *http.Request)
+
 	// GetOperationResult обрабатывает запрос на получение результатов операции
 	GetOperationResult(w http.ResponseWriter, r *http.Request)
 
 	// ExportOperation обрабатывает запрос на экспорт результатов операции
 	ExportOperation(w http.ResponseWriter, r *http.Request)
+
+	// ListOperations обрабатывает запрос на список операций по фильтру
+	ListOperations(w http.ResponseWriter, r *http.Request)
+
+	// ExportOperations обрабатывает запрос на экспорт набора операций для аналитики
+	ExportOperations(w http.ResponseWriter, r *http.Request)
+
+	// ExportSite обрабатывает запрос на экспорт нескольких операций одного сайта в один файл
+	ExportSite(w http.ResponseWriter, r *http.Request)
+
+	// ExportDesignTokens обрабатывает запрос на экспорт токенов дизайна операции
+	ExportDesignTokens(w http.ResponseWriter, r *http.Request)
+
+	// GetBlockScreenshot обрабатывает запрос на получение скриншота блока
+	GetBlockScreenshot(w http.ResponseWriter, r *http.Request)
+
+	// GetPageScreenshot обрабатывает запрос на получение скриншота всей страницы
+	GetPageScreenshot(w http.ResponseWriter, r *http.Request)
 }
 
 // DownloaderHandler представляет интерфейс для обработчика загрузчика
@@ -21,8 +42,20 @@ type DownloaderHandler interface {
 	// DownloadByID обрабатывает запрос на загрузку файлов по ID операции
 	DownloadByID(w http.ResponseWriter, r *http.Request)
 
+	// DownloadStaticSite обрабатывает запрос на статический сайт из операций одного домена
+	DownloadStaticSite(w http.ResponseWriter, r *http.Request)
+
 	// GetFormats обрабатывает запрос на получение доступных форматов
 	GetFormats(w http.ResponseWriter, r *http.Request)
+
+	// DownloadAssets обрабатывает запрос на скачивание файлов, на которые ссылаются блоки операции
+	DownloadAssets(w http.ResponseWriter, r *http.Request)
+
+	// GetAssetManifest обрабатывает запрос на получение манифеста файлов операции
+	GetAssetManifest(w http.ResponseWriter, r *http.Request)
+
+	// ExportStandaloneBlock обрабатывает запрос на автономный HTML блока
+	ExportStandaloneBlock(w http.ResponseWriter, r *http.Request)
 }
 
 // CrawlerHandler представляет интерфейс для обработчика краулера
@@ -30,3 +63,69 @@ type CrawlerHandler interface {
 	// CrawlURL обрабатывает запрос на обход URL и сбор ссылок
 	CrawlURL(w http.ResponseWriter, r *http.Request)
 }
+
+// ClassifierHandler представляет интерфейс для обработчика обучаемого классификатора блоков
+type ClassifierHandler interface {
+	// LabelBlock обрабатывает запрос на исправление типа блока
+	LabelBlock(w http.ResponseWriter, r *http.Request)
+
+	// TrainModel обрабатывает запрос на обучение новой версии модели
+	TrainModel(w http.ResponseWriter, r *http.Request)
+
+	// ListModels обрабатывает запрос на получение версий модели
+	ListModels(w http.ResponseWriter, r *http.Request)
+
+	// ActivateModel обрабатывает запрос на откат к указанной версии модели
+	ActivateModel(w http.ResponseWriter, r *http.Request)
+}
+
+// TemplateHandler представляет интерфейс для обработчика шаблонов блоков
+type TemplateHandler interface {
+	// GenerateTemplate обрабатывает запрос на генерацию шаблона по примеру блока
+	GenerateTemplate(w http.ResponseWriter, r *http.Request)
+
+	// SaveTemplate обрабатывает запрос на сохранение шаблона
+	SaveTemplate(w http.ResponseWriter, r *http.Request)
+}
+
+// BrandKitHandler представляет интерфейс обработчика фирменного набора
+type BrandKitHandler interface {
+	// GetBrandKit обрабатывает запрос на получение фирменного набора операции
+	GetBrandKit(w http.ResponseWriter, r *http.Request)
+
+	// GetAsset обрабатывает запрос на скачивание файла операции
+	GetAsset(w http.ResponseWriter, r *http.Request)
+}
+
+// ArchiveHandler представляет интерфейс обработчика архивов страниц
+type ArchiveHandler interface {
+	// GetArchives обрабатывает запрос на получение списка архивов операции
+	GetArchives(w http.ResponseWriter, r *http.Request)
+
+	// GetArchive обрабатывает запрос на скачивание архива операции
+	GetArchive(w http.ResponseWriter, r *http.Request)
+}
+
+// ExportTemplateHandler представляет интерфейс обработчика пользовательских шаблонов экспорта
+type ExportTemplateHandler interface {
+	// ListTemplates обрабатывает запрос на получение шаблонов экспорта
+	ListTemplates(w http.ResponseWriter, r *http.Request)
+
+	// SaveTemplate обрабатывает запрос на сохранение шаблона экспорта
+	SaveTemplate(w http.ResponseWriter, r *http.Request)
+
+	// GetTemplate обрабатывает запрос на получение шаблона экспорта по имени
+	GetTemplate(w http.ResponseWriter, r *http.Request)
+
+	// DeleteTemplate обрабатывает запрос на удаление шаблона экспорта
+	DeleteTemplate(w http.ResponseWriter, r *http.Request)
+
+	// PreviewTemplate обрабатывает запрос на проверку шаблона на существующей операции
+	PreviewTemplate(w http.ResponseWriter, r *http.Request)
+}
+
+// DiffHandler представляет интерфейс обработчика сравнения операций
+type DiffHandler interface {
+	// DiffOperations обрабатывает запрос на поблочное сравнение двух операций
+	DiffOperations(w http.ResponseWriter, r *http.Request)
+}
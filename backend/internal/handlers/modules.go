@@ -10,5 +10,11 @@ var Module = fx.Module("handlers",
 		NewParserHandler,
 		NewDownloaderHandler,
 		NewCrawlerHandler,
+		NewClassifierHandler,
+		NewTemplateHandler,
+		NewBrandKitHandler,
+		NewArchiveHandler,
+		NewExportTemplateHandler,
+		NewDiffHandler,
 	),
 )
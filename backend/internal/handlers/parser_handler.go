@@ -2,7 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -14,20 +22,37 @@ import (
 
 // parserHandler реализация ParserHandler
 type parserHandler struct {
-	logger  *zap.Logger
-	service services.ParserService
+	logger    *zap.Logger
+	service   services.ParserService
+	exporters services.ExporterRegistry
 }
 
 // NewParserHandler создает новый экземпляр ParserHandler
-func NewParserHandler(logger *zap.Logger, service services.ParserService) ParserHandler {
+func NewParserHandler(logger *zap.Logger, service services.ParserService, exporters services.ExporterRegistry) ParserHandler {
 	return &parserHandler{
-		logger:  logger,
-		service: service,
+		logger:    logger,
+		service:   service,
+		exporters: exporters,
 	}
 }
 
-// ParseURL обрабатывает запрос на парсинг URL
+// maxUploadSize максимальный размер загружаемой страницы или архива
+const maxUploadSize = 64 << 20
+
+// ParseURL обрабатывает запрос на парсинг URL.
+// Вместо URL можно передать HTML в поле html, загрузить файл формой multipart/form-data
+// (HTML, MHTML, HAR или WARC) или отправить HTML телом запроса с Content-Type text/html.
 func (h *parserHandler) ParseURL(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		h.parseUploadedFile(w, r)
+		return
+	case "text/html":
+		h.parseRawHTML(w, r)
+		return
+	}
+
 	var req dto.ParseURLRequest
 
 	// Декодируем тело запроса
@@ -37,15 +62,37 @@ func (h *parserHandler) ParseURL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем URL
-	if req.URL == "" {
+	// HTML страницы, переданный в теле JSON, парсится как загруженный файл
+	if req.HTML != "" {
+		h.parseContent(w, r, dto.ParseContentRequest{
+			SourceType:   dto.SourceTypeHTML,
+			Content:      []byte(req.HTML),
+			BaseURL:      req.BaseURL,
+			Segmentation: req.Segmentation,
+			Devices:      req.Devices,
+		})
+		return
+	}
+
+	// Проверяем URL; при парсинге из архива он берется из исходной операции
+	if req.URL == "" && req.ArchiveOperationID == nil {
 		RespondWithError(w, http.StatusBadRequest, "URL is required")
 		return
 	}
 
+	// Проверяем режим сегментации и профили устройств
+	if message := validateParseOptions(req.Segmentation, req.Devices); message != "" {
+		RespondWithError(w, http.StatusBadRequest, message)
+		return
+	}
+
 	// Вызываем сервис для парсинга URL
-	operationID, err := h.service.ParseURL(r.Context(), req.URL)
+	operationID, err := h.service.ParseURL(r.Context(), req)
 	if err != nil {
+		if errors.Is(err, services.ErrArchiveNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Archive not found")
+			return
+		}
 		h.logger.Error("Failed to parse URL", zap.Error(err))
 		RespondWithError(w, http.StatusInternalServerError, "Failed to parse URL")
 		return
@@ -59,6 +106,231 @@ func (h *parserHandler) ParseURL(w http.ResponseWriter, r *http.Request) {
 	RespondWithJSON(w, http.StatusOK, response)
 }
 
+// parseUploadedFile разбирает форму с файлом страницы: file, type, base_url, segmentation, devices
+func (h *parserHandler) parseUploadedFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.logger.Error("Failed to read uploaded file", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		h.logger.Error("Failed to read uploaded file", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Failed to read uploaded file")
+		return
+	}
+
+	sourceType := dto.SourceType(r.FormValue("type"))
+	switch sourceType {
+	case "", dto.SourceTypeHTML, dto.SourceTypeMHTML, dto.SourceTypeHAR, dto.SourceTypeWARC:
+	default:
+		RespondWithError(w, http.StatusBadRequest, "Invalid type. Supported types: html, mhtml, har, warc")
+		return
+	}
+
+	h.parseContent(w, r, dto.ParseContentRequest{
+		SourceType:   sourceType,
+		Filename:     header.Filename,
+		Content:      content,
+		BaseURL:      r.FormValue("base_url"),
+		Segmentation: dto.SegmentationMode(r.FormValue("segmentation")),
+		Devices:      parseDeviceList(r.FormValue("devices")),
+	})
+}
+
+// parseRawHTML разбирает HTML из тела запроса; base_url, segmentation и devices передаются в query
+func (h *parserHandler) parseRawHTML(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadSize))
+	if err != nil {
+		h.logger.Error("Failed to read request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(content) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "HTML is required")
+		return
+	}
+
+	query := r.URL.Query()
+	h.parseContent(w, r, dto.ParseContentRequest{
+		SourceType:   dto.SourceTypeHTML,
+		Content:      content,
+		BaseURL:      query.Get("base_url"),
+		Segmentation: dto.SegmentationMode(query.Get("segmentation")),
+		Devices:      parseDeviceList(query.Get("devices")),
+	})
+}
+
+// parseContent проверяет параметры и запускает парсинг загруженной страницы
+func (h *parserHandler) parseContent(w http.ResponseWriter, r *http.Request, req dto.ParseContentRequest) {
+	if message := validateParseOptions(req.Segmentation, req.Devices); message != "" {
+		RespondWithError(w, http.StatusBadRequest, message)
+		return
+	}
+
+	operationID, err := h.service.ParseContent(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidContent) {
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.logger.Error("Failed to parse uploaded page", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to parse uploaded page")
+		return
+	}
+
+	response := dto.ParseURLResponse{
+		OperationID: operationID,
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// validateParseOptions проверяет режим сегментации и профили устройств, возвращает текст ошибки
+func validateParseOptions(segmentation dto.SegmentationMode, devices []dto.DeviceProfile) string {
+	switch segmentation {
+	case "", dto.SegmentationDOM, dto.SegmentationLayout:
+	default:
+		return "Invalid segmentation. Supported modes: dom, layout"
+	}
+
+	for _, device := range devices {
+		switch device {
+		case dto.DeviceDesktop, dto.DeviceTablet, dto.DeviceMobile:
+		default:
+			return "Invalid device. Supported devices: desktop, tablet, mobile"
+		}
+	}
+
+	return ""
+}
+
+// parseDeviceList разбирает список профилей устройств через запятую
+func parseDeviceList(value string) []dto.DeviceProfile {
+	var devices []dto.DeviceProfile
+	for _, device := range strings.Split(value, ",") {
+		if device = strings.TrimSpace(device); device != "" {
+			devices = append(devices, dto.DeviceProfile(device))
+		}
+	}
+	return devices
+}
+
+// parseOperationIDs разбирает список ID операций через запятую
+func parseOperationIDs(value string) ([]uuid.UUID, error) {
+	var operationIDs []uuid.UUID
+	for _, raw := range strings.Split(value, ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		operationID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid operation ID %q", raw)
+		}
+		operationIDs = append(operationIDs, operationID)
+	}
+	return operationIDs, nil
+}
+
+// parseOperationFilter разбирает фильтр списка операций из query параметров:
+// ids, status, host, q, from и to (RFC 3339 или дата), limit и offset
+func parseOperationFilter(query url.Values) (dto.OperationFilter, error) {
+	var filter dto.OperationFilter
+
+	ids, err := parseOperationIDs(query.Get("ids"))
+	if err != nil {
+		return filter, err
+	}
+	filter.IDs = ids
+
+	if status := dto.OperationStatus(query.Get("status")); status != "" {
+		switch status {
+		case dto.StatusPending, dto.StatusProcessing, dto.StatusCompleted, dto.StatusError:
+			filter.Status = status
+		default:
+			return filter, fmt.Errorf("invalid status %q", status)
+		}
+	}
+
+	filter.Host = strings.TrimSpace(query.Get("host"))
+	filter.Query = strings.TrimSpace(query.Get("q"))
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if parsed, err = time.Parse(time.DateOnly, value); err != nil {
+				return filter, fmt.Errorf("invalid %s: expected RFC 3339 time or YYYY-MM-DD date", name)
+			}
+		}
+		*target = &parsed
+	}
+
+	for name, target := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 0 {
+			return filter, fmt.Errorf("invalid %s: expected non-negative integer", name)
+		}
+		*target = number
+	}
+
+	return filter, nil
+}
+
+// ReparseOperation обрабатывает запрос на повторный парсинг сохраненных снимков операции.
+// Тело запроса необязательно; в нем можно задать режим сегментации.
+func (h *parserHandler) ReparseOperation(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	var req dto.ReparseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Проверяем режим сегментации
+	if message := validateParseOptions(req.Segmentation, nil); message != "" {
+		RespondWithError(w, http.StatusBadRequest, message)
+		return
+	}
+
+	reparsedID, err := h.service.ReparseOperation(r.Context(), operationID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrSnapshotNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Operation has no stored snapshots")
+			return
+		}
+		h.logger.Error("Failed to reparse operation", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to reparse operation")
+		return
+	}
+
+	response := dto.ParseURLResponse{
+		OperationID: reparsedID,
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
 // GetOperationResult обрабатывает запрос на получение результатов операции
 func (h *parserHandler) GetOperationResult(w http.ResponseWriter, r *http.Request) {
 	// Получаем ID операции из URL
@@ -104,39 +376,252 @@ func (h *parserHandler) ExportOperation(w http.ResponseWriter, r *http.Request)
 		format = "excel" // По умолчанию Excel
 	}
 
-	// Проверяем формат
-	if format != "excel" && format != "text" {
-		RespondWithError(w, http.StatusBadRequest, "Invalid format. Supported formats: excel, text")
+	// Проверяем формат по реестру экспортеров и пользовательских шаблонов
+	exporter, err := h.exporters.Get(r.Context(), format)
+	if err != nil {
+		if !errors.Is(err, services.ErrUnsupportedFormat) {
+			h.logger.Error("Failed to get exporter", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to export operation")
+			return
+		}
+		formats, _ := h.exporters.Names(r.Context())
+		RespondWithError(w, http.StatusBadRequest, "Invalid format. Supported formats: "+strings.Join(formats, ", "))
 		return
 	}
 
-	// Вызываем сервис для экспорта операции
-	content, filename, err := h.service.ExportOperation(r.Context(), operationID, format)
+	// Команда выбирает шаблон отчета, например PDF
+	options := dto.ExportOptions{Team: r.URL.Query().Get("team")}
+
+	// Готовим экспорт; ошибки до начала записи еще можно вернуть клиенту
+	export, err := h.service.PrepareExport(r.Context(), operationID, format, options)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTeam) {
+			RespondWithError(w, http.StatusBadRequest, "Invalid team name")
+			return
+		}
 		h.logger.Error("Failed to export operation", zap.Error(err))
 		RespondWithError(w, http.StatusInternalServerError, "Failed to export operation")
 		return
 	}
 
-	// Устанавливаем заголовки для скачивания файла
-	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
-	w.Header().Set("Content-Type", getContentType(format))
-	w.Header().Set("Content-Length", string(len(content)))
+	// Пользовательский шаблон может выдать HTML со скриптами, он не должен выполняться в origin API
+	if _, ok := exporter.(services.UserDefinedExporter); ok {
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+	}
 
+	// Размер экспорта заранее неизвестен, Content-Length не выставляется и ответ уходит chunked
+	w.Header().Set("Content-Disposition", "attachment; filename="+export.Filename())
+	w.Header().Set("Content-Type", export.ContentType())
 	w.WriteHeader(http.StatusOK)
-	w.Write(content)
+
+	if err := export.Write(r.Context(), w); err != nil {
+		h.logger.Error("Failed to write export", zap.String("operation_id", operationID.String()), zap.Error(err))
+	}
+}
+
+// ListOperations обрабатывает запрос на список операций по фильтру
+func (h *parserHandler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOperationFilter(r.URL.Query())
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.service.ListOperations(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list operations", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list operations")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// ExportOperations обрабатывает запрос на экспорт набора операций для аналитики, например в Parquet или SQLite.
+// Набор задается теми же параметрами, что и список операций; без limit выгружаются все подходящие операции.
+func (h *parserHandler) ExportOperations(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOperationFilter(r.URL.Query())
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "parquet"
+	}
+
+	// Готовим экспорт; ошибки до начала записи еще можно вернуть клиенту
+	export, err := h.service.PrepareBatchExport(r.Context(), filter, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnsupportedFormat):
+			RespondWithError(w, http.StatusBadRequest, "Format does not support operation sets: "+format)
+		case errors.Is(err, services.ErrBatchTooLarge):
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.logger.Error("Failed to export operations", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to export operations")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+export.Filename())
+	w.Header().Set("Content-Type", export.ContentType())
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.Write(r.Context(), w); err != nil {
+		h.logger.Error("Failed to write operations export", zap.Error(err))
+	}
+}
+
+// ExportSite обрабатывает запрос на экспорт нескольких операций одного сайта, например в WXR.
+// ID операций передаются через запятую в operation_ids; первая операция с самым коротким путем считается главной страницей.
+func (h *parserHandler) ExportSite(w http.ResponseWriter, r *http.Request) {
+	operationIDs, err := parseOperationIDs(r.URL.Query().Get("operation_ids"))
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(operationIDs) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "Operation IDs are required")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "wxr"
+	}
+
+	options := dto.ExportOptions{Team: r.URL.Query().Get("team")}
+
+	// Готовим экспорт; ошибки до начала записи еще можно вернуть клиенту
+	export, err := h.service.PrepareSiteExport(r.Context(), operationIDs, format, options)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnsupportedFormat):
+			RespondWithError(w, http.StatusBadRequest, "Format does not support site export: "+format)
+		case errors.Is(err, services.ErrInvalidSiteExport):
+			RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrInvalidTeam):
+			RespondWithError(w, http.StatusBadRequest, "Invalid team name")
+		default:
+			h.logger.Error("Failed to export site", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to export site")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+export.Filename())
+	w.Header().Set("Content-Type", export.ContentType())
+	w.WriteHeader(http.StatusOK)
+
+	if err := export.Write(r.Context(), w); err != nil {
+		h.logger.Error("Failed to write site export", zap.Int("operations", len(operationIDs)), zap.Error(err))
+	}
 }
 
-// getContentType возвращает Content-Type в зависимости от формата
-func getContentType(format string) string {
+// ExportDesignTokens обрабатывает запрос на экспорт токенов дизайна операции
+func (h *parserHandler) ExportDesignTokens(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	// Получаем формат экспорта из query параметров
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	var contentType string
 	switch format {
-	case "excel":
-		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
-	case "text":
-		return "text/plain"
+	case "json":
+		contentType = "application/json"
+	case "css":
+		contentType = "text/css; charset=utf-8"
 	default:
-		return "application/octet-stream"
+		RespondWithError(w, http.StatusBadRequest, "Invalid format. Supported formats: json, css")
+		return
+	}
+
+	content, filename, err := h.service.ExportDesignTokens(r.Context(), operationID, format)
+	if err != nil {
+		if errors.Is(err, services.ErrDesignTokensNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Design tokens not found")
+			return
+		}
+		h.logger.Error("Failed to export design tokens", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to export design tokens")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// GetBlockScreenshot обрабатывает запрос на получение скриншота блока
+func (h *parserHandler) GetBlockScreenshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
+	}
+
+	blockID, err := uuid.Parse(vars["blockId"])
+	if err != nil {
+		h.logger.Error("Invalid block ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid block ID")
+		return
+	}
+
+	screenshot, err := h.service.GetBlockScreenshot(r.Context(), operationID, blockID)
+	h.respondWithScreenshot(w, screenshot, err)
+}
+
+// GetPageScreenshot обрабатывает запрос на получение скриншота всей страницы
+func (h *parserHandler) GetPageScreenshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	operationID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		h.logger.Error("Invalid operation ID", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid operation ID")
+		return
 	}
+
+	screenshot, err := h.service.GetPageScreenshot(r.Context(), operationID)
+	h.respondWithScreenshot(w, screenshot, err)
+}
+
+// respondWithScreenshot отправляет клиенту PNG-скриншот или ошибку
+func (h *parserHandler) respondWithScreenshot(w http.ResponseWriter, screenshot []byte, err error) {
+	if err != nil {
+		if errors.Is(err, services.ErrScreenshotNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Screenshot not found")
+			return
+		}
+		h.logger.Error("Failed to get screenshot", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to get screenshot")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(screenshot)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(screenshot)
 }
 
 // RespondWithError отправляет клиенту ошибку в формате JSON
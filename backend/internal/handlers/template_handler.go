@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/services"
+)
+
+// templateHandler реализация TemplateHandler
+type templateHandler struct {
+	logger  *zap.Logger
+	service services.TemplateService
+}
+
+// NewTemplateHandler создает новый экземпляр TemplateHandler
+func NewTemplateHandler(logger *zap.Logger, service services.TemplateService) TemplateHandler {
+	return &templateHandler{
+		logger:  logger,
+		service: service,
+	}
+}
+
+// GenerateTemplate обрабатывает запрос на генерацию шаблона по примеру блока
+func (h *templateHandler) GenerateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.GenerateTemplateRequest
+
+	// Декодируем тело запроса
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.BlockType == "" {
+		RespondWithError(w, http.StatusBadRequest, "Block type is required")
+		return
+	}
+
+	if req.BlockID == nil && req.HTML == "" {
+		RespondWithError(w, http.StatusBadRequest, "Either block_id or html is required")
+		return
+	}
+
+	response, err := h.service.GenerateTemplate(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBlockNotFound):
+			RespondWithError(w, http.StatusNotFound, "Block not found")
+		case errors.Is(err, services.ErrTemplateNotDerived):
+			RespondWithError(w, http.StatusUnprocessableEntity, "No stable structural features found in example block")
+		default:
+			h.logger.Error("Failed to generate template", zap.Error(err))
+			RespondWithError(w, http.StatusInternalServerError, "Failed to generate template")
+		}
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, response)
+}
+
+// SaveTemplate обрабатывает запрос на сохранение шаблона
+func (h *templateHandler) SaveTemplate(w http.ResponseWriter, r *http.Request) {
+	var req dto.SaveTemplateRequest
+
+	// Декодируем тело запроса
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.SaveTemplate(r.Context(), req); err != nil {
+		if errors.Is(err, services.ErrInvalidTemplate) {
+			RespondWithError(w, http.StatusBadRequest, "Block type and template are required")
+			return
+		}
+		h.logger.Error("Failed to save template", zap.Error(err))
+		RespondWithError(w, http.StatusInternalServerError, "Failed to save template")
+		return
+	}
+
+	RespondWithJSON(w, http.StatusCreated, req)
+}
@@ -0,0 +1,99 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+)
+
+// NewArchiveRepo создает новый экземпляр ArchiveRepo
+func NewArchiveRepo(db *sql.DB, logger *zap.Logger) ArchiveRepo {
+	return &PostgresRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveArchive сохраняет запись об архиве страницы, заменяя архив того же формата и устройства
+func (r *PostgresRepo) SaveArchive(ctx context.Context, archive *dto.Archive) error {
+	query := `
+	INSERT INTO operation_archives (operation_id, device, format, storage_key, size, sha256, captured_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (operation_id, device, format) DO UPDATE
+	SET storage_key = EXCLUDED.storage_key,
+	    size = EXCLUDED.size,
+	    sha256 = EXCLUDED.sha256,
+	    captured_at = EXCLUDED.captured_at
+	RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		archive.OperationID,
+		archive.Device,
+		archive.Format,
+		archive.StorageKey,
+		archive.Size,
+		archive.SHA256,
+		archive.CapturedAt,
+	).Scan(&archive.ID, &archive.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+
+	return nil
+}
+
+// GetArchivesByOperationID получает все архивы операции
+func (r *PostgresRepo) GetArchivesByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Archive, error) {
+	query := `
+	SELECT id, operation_id, device, format, storage_key, size, sha256, captured_at, created_at
+	FROM operation_archives
+	WHERE operation_id = $1
+	ORDER BY device, format
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := []dto.Archive{}
+	for rows.Next() {
+		var archive dto.Archive
+		var device, format string
+
+		err := rows.Scan(
+			&archive.ID,
+			&archive.OperationID,
+			&device,
+			&format,
+			&archive.StorageKey,
+			&archive.Size,
+			&archive.SHA256,
+			&archive.CapturedAt,
+			&archive.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan archive: %w", err)
+		}
+
+		archive.Device = dto.DeviceProfile(device)
+		archive.Format = dto.ArchiveFormat(format)
+		archives = append(archives, archive)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating archives: %w", err)
+	}
+
+	return archives, nil
+}
@@ -0,0 +1,199 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+)
+
+// NewAssetRepo создает новый экземпляр AssetRepo
+func NewAssetRepo(db *sql.DB, logger *zap.Logger) AssetRepo {
+	return &PostgresRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveAsset сохраняет запись о скачанном файле
+func (r *PostgresRepo) SaveAsset(ctx context.Context, asset *dto.Asset) error {
+	query := `
+	INSERT INTO operation_assets (operation_id, kind, url, storage_key, content_type, size, width, height, sha256)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		asset.OperationID,
+		asset.Kind,
+		asset.URL,
+		asset.StorageKey,
+		asset.ContentType,
+		asset.Size,
+		asset.Width,
+		asset.Height,
+		asset.SHA256,
+	).Scan(&asset.ID, &asset.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save asset: %w", err)
+	}
+
+	return nil
+}
+
+// GetAssetByID получает файл по ID
+func (r *PostgresRepo) GetAssetByID(ctx context.Context, assetID uuid.UUID) (*dto.Asset, error) {
+	query := `
+	SELECT id, operation_id, kind, url, storage_key, content_type, size, width, height, sha256, created_at
+	FROM operation_assets
+	WHERE id = $1
+	`
+
+	asset, err := scanAsset(r.db.QueryRowContext(ctx, query, assetID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("asset not found: %s", assetID)
+		}
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// GetAssetsByOperationID получает все файлы операции
+func (r *PostgresRepo) GetAssetsByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Asset, error) {
+	query := `
+	SELECT id, operation_id, kind, url, storage_key, content_type, size, width, height, sha256, created_at
+	FROM operation_assets
+	WHERE operation_id = $1
+	ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assets: %w", err)
+	}
+	defer rows.Close()
+
+	assets := []dto.Asset{}
+	for rows.Next() {
+		asset, err := scanAsset(rows)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *asset)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating assets: %w", err)
+	}
+
+	return assets, nil
+}
+
+// LinkBlockAsset связывает блок со скачанным файлом
+func (r *PostgresRepo) LinkBlockAsset(ctx context.Context, link dto.BlockAssetLink) error {
+	query := `
+	INSERT INTO block_assets (block_id, asset_id, url)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (block_id, asset_id) DO NOTHING
+	`
+
+	_, err := r.db.ExecContext(ctx, query, link.BlockID, link.AssetID, link.URL)
+	if err != nil {
+		return fmt.Errorf("failed to link block asset: %w", err)
+	}
+
+	return nil
+}
+
+// GetBlockAssetLinks получает связи блоков операции с файлами
+func (r *PostgresRepo) GetBlockAssetLinks(ctx context.Context, operationID uuid.UUID) ([]dto.BlockAssetLink, error) {
+	query := `
+	SELECT ba.block_id, ba.asset_id, ba.url
+	FROM block_assets ba
+	JOIN blocks b ON b.id = ba.block_id
+	WHERE b.operation_id = $1
+	ORDER BY b.created_at, ba.url
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block assets: %w", err)
+	}
+	defer rows.Close()
+
+	links := []dto.BlockAssetLink{}
+	for rows.Next() {
+		var link dto.BlockAssetLink
+		if err := rows.Scan(&link.BlockID, &link.AssetID, &link.URL); err != nil {
+			return nil, fmt.Errorf("failed to scan block asset: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating block assets: %w", err)
+	}
+
+	return links, nil
+}
+
+// UpdateOperationBrand сохраняет фирменные параметры сайта в операции
+func (r *PostgresRepo) UpdateOperationBrand(ctx context.Context, operationID uuid.UUID, brand *dto.BrandInfo) error {
+	brandJSON, err := json.Marshal(brand)
+	if err != nil {
+		return fmt.Errorf("failed to marshal brand: %w", err)
+	}
+
+	query := `
+	UPDATE operations
+	SET brand = $1, updated_at = NOW()
+	WHERE id = $2
+	`
+
+	_, err = r.db.ExecContext(ctx, query, brandJSON, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation brand: %w", err)
+	}
+
+	return nil
+}
+
+// scanAsset читает файл операции из строки результата
+func scanAsset(row rowScanner) (*dto.Asset, error) {
+	var asset dto.Asset
+	var kind string
+
+	err := row.Scan(
+		&asset.ID,
+		&asset.OperationID,
+		&kind,
+		&asset.URL,
+		&asset.StorageKey,
+		&asset.ContentType,
+		&asset.Size,
+		&asset.Width,
+		&asset.Height,
+		&asset.SHA256,
+		&asset.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan asset: %w", err)
+	}
+
+	asset.Kind = dto.AssetKind(kind)
+
+	return &asset, nil
+}
@@ -0,0 +1,286 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+)
+
+// ErrModelNotFound возвращается, если версии модели классификатора нет
+var ErrModelNotFound = errors.New("classifier model not found")
+
+// NewClassifierRepo создает новый экземпляр ClassifierRepo
+func NewClassifierRepo(db *sql.DB, logger *zap.Logger) ClassifierRepo {
+	return &PostgresRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveBlockLabel сохраняет ручную разметку типа блока
+func (r *PostgresRepo) SaveBlockLabel(ctx context.Context, label *dto.BlockLabel) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO block_labels (block_id, category)
+	VALUES ($1, $2)
+	ON CONFLICT (block_id) DO UPDATE SET category = EXCLUDED.category, created_at = NOW()
+	RETURNING created_at
+	`
+
+	if err := tx.QueryRowContext(ctx, query, label.BlockID, label.Category).Scan(&label.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save block label: %w", err)
+	}
+
+	// Ручная разметка заменяет автоматическую классификацию блока
+	classificationJSON, err := json.Marshal(dto.BlockClassification{
+		Category: label.Category,
+		Score:    1,
+		Signals:  []string{"label:manual"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal block classification: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE blocks SET classification = $1 WHERE id = $2`, classificationJSON, label.BlockID); err != nil {
+		return fmt.Errorf("failed to update block classification: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit block label: %w", err)
+	}
+
+	return nil
+}
+
+// GetLabelledBlocks получает все размеченные блоки для обучения
+func (r *PostgresRepo) GetLabelledBlocks(ctx context.Context) ([]dto.LabelledBlock, error) {
+	query := `
+	SELECT l.block_id, l.category, b.html
+	FROM block_labels l
+	JOIN blocks b ON b.id = l.block_id
+	ORDER BY l.created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labelled blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []dto.LabelledBlock
+
+	for rows.Next() {
+		var sample dto.LabelledBlock
+		var category string
+
+		if err := rows.Scan(&sample.BlockID, &category, &sample.HTML); err != nil {
+			return nil, fmt.Errorf("failed to scan labelled block: %w", err)
+		}
+
+		sample.Category = dto.BlockCategory(category)
+		samples = append(samples, sample)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating labelled blocks: %w", err)
+	}
+
+	return samples, nil
+}
+
+// CreateTrainingRun создает запись о запуске обучения
+func (r *PostgresRepo) CreateTrainingRun(ctx context.Context) (*dto.TrainingRun, error) {
+	run := &dto.TrainingRun{Status: dto.TrainingStatusRunning}
+
+	query := `
+	INSERT INTO classifier_training_runs (status)
+	VALUES ($1)
+	RETURNING id, started_at
+	`
+
+	if err := r.db.QueryRowContext(ctx, query, run.Status).Scan(&run.ID, &run.StartedAt); err != nil {
+		return nil, fmt.Errorf("failed to create training run: %w", err)
+	}
+
+	return run, nil
+}
+
+// FinishTrainingRun сохраняет результат запуска обучения
+func (r *PostgresRepo) FinishTrainingRun(ctx context.Context, run *dto.TrainingRun) error {
+	query := `
+	UPDATE classifier_training_runs
+	SET model_id = $1, status = $2, samples = $3, accuracy = $4, error = $5, finished_at = NOW()
+	WHERE id = $6
+	RETURNING finished_at
+	`
+
+	var finishedAt sql.NullTime
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		run.ModelID,
+		run.Status,
+		run.Samples,
+		run.Accuracy,
+		run.Error,
+		run.ID,
+	).Scan(&finishedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to finish training run: %w", err)
+	}
+
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+
+	return nil
+}
+
+// SaveModel сохраняет новую версию модели
+func (r *PostgresRepo) SaveModel(ctx context.Context, model *dto.ClassifierModel) error {
+	parametersJSON, err := json.Marshal(model.Parameters)
+	if err != nil {
+		return fmt.Errorf("failed to marshal model parameters: %w", err)
+	}
+
+	query := `
+	INSERT INTO classifier_models (algorithm, samples, accuracy, parameters)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, version, active, created_at
+	`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		model.Algorithm,
+		model.Samples,
+		model.Accuracy,
+		parametersJSON,
+	).Scan(&model.ID, &model.Version, &model.Active, &model.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save classifier model: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveModel получает активную версию модели, nil если моделей нет
+func (r *PostgresRepo) GetActiveModel(ctx context.Context) (*dto.ClassifierModel, error) {
+	query := `
+	SELECT id, version, algorithm, samples, accuracy, active, parameters, created_at
+	FROM classifier_models
+	WHERE active
+	`
+
+	var model dto.ClassifierModel
+	var parametersJSON []byte
+
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&model.ID,
+		&model.Version,
+		&model.Algorithm,
+		&model.Samples,
+		&model.Accuracy,
+		&model.Active,
+		&parametersJSON,
+		&model.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get active classifier model: %w", err)
+	}
+
+	var parameters dto.NaiveBayesParameters
+	if err := json.Unmarshal(parametersJSON, &parameters); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal model parameters: %w", err)
+	}
+	model.Parameters = &parameters
+
+	return &model, nil
+}
+
+// ListModels получает все версии моделей без параметров
+func (r *PostgresRepo) ListModels(ctx context.Context) ([]dto.ClassifierModel, error) {
+	query := `
+	SELECT id, version, algorithm, samples, accuracy, active, created_at
+	FROM classifier_models
+	ORDER BY version DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list classifier models: %w", err)
+	}
+	defer rows.Close()
+
+	var models []dto.ClassifierModel
+
+	for rows.Next() {
+		var model dto.ClassifierModel
+
+		err := rows.Scan(
+			&model.ID,
+			&model.Version,
+			&model.Algorithm,
+			&model.Samples,
+			&model.Accuracy,
+			&model.Active,
+			&model.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan classifier model: %w", err)
+		}
+
+		models = append(models, model)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating classifier models: %w", err)
+	}
+
+	return models, nil
+}
+
+// ActivateModel делает указанную версию модели активной
+func (r *PostgresRepo) ActivateModel(ctx context.Context, version int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE classifier_models SET active = FALSE WHERE active`); err != nil {
+		return fmt.Errorf("failed to deactivate classifier models: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE classifier_models SET active = TRUE WHERE version = $1`, version)
+	if err != nil {
+		return fmt.Errorf("failed to activate classifier model: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("%w: version %d", ErrModelNotFound, version)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit model activation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+)
+
+// NewExportTemplateRepo создает новый экземпляр ExportTemplateRepo
+func NewExportTemplateRepo(db *sql.DB, logger *zap.Logger) ExportTemplateRepo {
+	return &PostgresRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveExportTemplate сохраняет шаблон, заменяя шаблон с тем же именем
+func (r *PostgresRepo) SaveExportTemplate(ctx context.Context, template *dto.ExportTemplate) error {
+	query := `
+	INSERT INTO export_templates (name, engine, content_type, extension, description, body)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (name) DO UPDATE
+	SET engine = EXCLUDED.engine,
+	    content_type = EXCLUDED.content_type,
+	    extension = EXCLUDED.extension,
+	    description = EXCLUDED.description,
+	    body = EXCLUDED.body,
+	    updated_at = NOW()
+	RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		template.Name,
+		template.Engine,
+		template.ContentType,
+		template.Extension,
+		template.Description,
+		template.Body,
+	).Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save export template: %w", err)
+	}
+
+	return nil
+}
+
+// GetExportTemplateByName получает шаблон по имени, nil если шаблона нет
+func (r *PostgresRepo) GetExportTemplateByName(ctx context.Context, name string) (*dto.ExportTemplate, error) {
+	query := `
+	SELECT id, name, engine, content_type, extension, description, body, created_at, updated_at
+	FROM export_templates
+	WHERE name = $1
+	`
+
+	template, err := scanExportTemplate(r.db.QueryRowContext(ctx, query, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get export template: %w", err)
+	}
+
+	return template, nil
+}
+
+// ListExportTemplates получает все шаблоны по имени
+func (r *PostgresRepo) ListExportTemplates(ctx context.Context) ([]dto.ExportTemplate, error) {
+	query := `
+	SELECT id, name, engine, content_type, extension, description, body, created_at, updated_at
+	FROM export_templates
+	ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []dto.ExportTemplate{}
+	for rows.Next() {
+		template, err := scanExportTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan export template: %w", err)
+		}
+		templates = append(templates, *template)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating export templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// DeleteExportTemplate удаляет шаблон по имени, false если шаблона не было
+func (r *PostgresRepo) DeleteExportTemplate(ctx context.Context, name string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM export_templates WHERE name = $1`, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete export template: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete export template: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// scanExportTemplate читает шаблон экспорта из строки результата
+func scanExportTemplate(row rowScanner) (*dto.ExportTemplate, error) {
+	var template dto.ExportTemplate
+	var engine string
+
+	err := row.Scan(
+		&template.ID,
+		&template.Name,
+		&engine,
+		&template.ContentType,
+		&template.Extension,
+		&template.Description,
+		&template.Body,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Engine = dto.ExportTemplateEngine(engine)
+	return &template, nil
+}
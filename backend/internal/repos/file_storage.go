@@ -0,0 +1,127 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"scrapper/config"
+)
+
+// ErrBlobNotFound возвращается, если по ключу ничего не сохранено
+var ErrBlobNotFound = errors.New("blob not found")
+
+// FileBlobStorage реализация BlobStorage на локальной файловой системе
+type FileBlobStorage struct {
+	root   string
+	logger *zap.Logger
+}
+
+// NewFileBlobStorage создает новый экземпляр BlobStorage
+func NewFileBlobStorage(cfg *config.Config, logger *zap.Logger) (BlobStorage, error) {
+	root, err := filepath.Abs(cfg.Storage.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage path: %w", err)
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	logger.Info("Using file blob storage", zap.String("path", root))
+
+	return &FileBlobStorage{
+		root:   root,
+		logger: logger,
+	}, nil
+}
+
+// Put сохраняет содержимое по ключу, перезаписывая существующее
+func (s *FileBlobStorage) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы читатели не видели частичную запись
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close blob file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return nil
+}
+
+// Open открывает содержимое по ключу
+func (s *FileBlobStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+		}
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+
+	return file, nil
+}
+
+// Exists проверяет, есть ли содержимое по ключу
+func (s *FileBlobStorage) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	return true, nil
+}
+
+// path преобразует ключ в путь внутри корня хранилища
+func (s *FileBlobStorage) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid blob key: %q", key)
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid blob key: %q", key)
+		}
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -2,6 +2,7 @@ package repos
 
 import (
 	"context"
+	"io"
 
 	"scrapper/internal/dto"
 
@@ -19,12 +20,130 @@ type ParserRepo interface {
 	// GetOperationByID получает операцию по ID
 	GetOperationByID(ctx context.Context, operationID uuid.UUID) (*dto.Operation, error)
 
+	// ListOperations получает операции по фильтру, новые первыми
+	ListOperations(ctx context.Context, filter dto.OperationFilter) ([]dto.Operation, error)
+
 	// SaveBlock сохраняет блок, найденный при парсинге
 	SaveBlock(ctx context.Context, block *dto.Block) error
 
+	// UpdateOperationScreenshot сохраняет ключ полного скриншота страницы операции
+	UpdateOperationScreenshot(ctx context.Context, operationID uuid.UUID, key string) error
+
+	// UpdateOperationDesignTokens сохраняет токены дизайна страницы операции
+	UpdateOperationDesignTokens(ctx context.Context, operationID uuid.UUID, tokens *dto.DesignTokens) error
+
+	// UpdateOperationOrigin сохраняет версию парсера, источник страницы и операцию, из снимков которой она перепарсена
+	UpdateOperationOrigin(ctx context.Context, operationID uuid.UUID, origin dto.OperationOrigin) error
+
+	// GetBlockByID получает блок по ID
+	GetBlockByID(ctx context.Context, blockID uuid.UUID) (*dto.Block, error)
+
 	// GetBlocksByOperationID получает все блоки по ID операции
 	GetBlocksByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Block, error)
 
+	// IterateBlocksByOperationID передает блоки операции в fn по одному по мере чтения из базы,
+	// не загружая их в память целиком; ошибка fn прерывает обход
+	IterateBlocksByOperationID(ctx context.Context, operationID uuid.UUID, fn func(block *dto.Block) error) error
+
 	//GetAllTemplates получает все HTML теги для парсера блоков страницы
 	GetAllTemplates(platform dto.Platform) ([]dto.BlockTemplate, error)
+
+	// SaveTemplate сохраняет шаблон блока для платформы
+	SaveTemplate(ctx context.Context, platform dto.Platform, template dto.BlockTemplate) error
+
+	// GetRecentBlocksByPlatform получает последние сохраненные блоки платформы
+	GetRecentBlocksByPlatform(ctx context.Context, platform dto.Platform, limit int) ([]dto.Block, error)
+}
+
+// ClassifierRepo представляет интерфейс для репозитория обучаемого классификатора блоков
+type ClassifierRepo interface {
+	// SaveBlockLabel сохраняет ручную разметку типа блока
+	SaveBlockLabel(ctx context.Context, label *dto.BlockLabel) error
+
+	// GetLabelledBlocks получает все размеченные блоки для обучения
+	GetLabelledBlocks(ctx context.Context) ([]dto.LabelledBlock, error)
+
+	// CreateTrainingRun создает запись о запуске обучения
+	CreateTrainingRun(ctx context.Context) (*dto.TrainingRun, error)
+
+	// FinishTrainingRun сохраняет результат запуска обучения
+	FinishTrainingRun(ctx context.Context, run *dto.TrainingRun) error
+
+	// SaveModel сохраняет новую версию модели
+	SaveModel(ctx context.Context, model *dto.ClassifierModel) error
+
+	// GetActiveModel получает активную версию модели, nil если моделей нет
+	GetActiveModel(ctx context.Context) (*dto.ClassifierModel, error)
+
+	// ListModels получает все версии моделей без параметров
+	ListModels(ctx context.Context) ([]dto.ClassifierModel, error)
+
+	// ActivateModel делает указанную версию модели активной
+	ActivateModel(ctx context.Context, version int) error
+}
+
+// AssetRepo представляет интерфейс репозитория файлов операций
+type AssetRepo interface {
+	// SaveAsset сохраняет запись о скачанном файле
+	SaveAsset(ctx context.Context, asset *dto.Asset) error
+
+	// GetAssetByID получает файл по ID
+	GetAssetByID(ctx context.Context, assetID uuid.UUID) (*dto.Asset, error)
+
+	// GetAssetsByOperationID получает все файлы операции
+	GetAssetsByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Asset, error)
+
+	// LinkBlockAsset связывает блок со скачанным файлом
+	LinkBlockAsset(ctx context.Context, link dto.BlockAssetLink) error
+
+	// GetBlockAssetLinks получает связи блоков операции с файлами
+	GetBlockAssetLinks(ctx context.Context, operationID uuid.UUID) ([]dto.BlockAssetLink, error)
+
+	// UpdateOperationBrand сохраняет фирменные параметры сайта в операции
+	UpdateOperationBrand(ctx context.Context, operationID uuid.UUID, brand *dto.BrandInfo) error
+}
+
+// ArchiveRepo представляет интерфейс репозитория архивов страниц
+type ArchiveRepo interface {
+	// SaveArchive сохраняет запись об архиве страницы, заменяя архив того же формата и устройства
+	SaveArchive(ctx context.Context, archive *dto.Archive) error
+
+	// GetArchivesByOperationID получает все архивы операции
+	GetArchivesByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Archive, error)
+}
+
+// SnapshotRepo представляет интерфейс репозитория снимков HTML страниц
+type SnapshotRepo interface {
+	// SaveSnapshot сохраняет запись о снимке HTML, заменяя снимок того же устройства
+	SaveSnapshot(ctx context.Context, snapshot *dto.Snapshot) error
+
+	// GetSnapshotsByOperationID получает все снимки операции в порядке рендеринга
+	GetSnapshotsByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Snapshot, error)
+}
+
+// BlobStorage представляет интерфейс хранилища бинарных файлов (скриншоты, ассеты, архивы, снимки HTML)
+type BlobStorage interface {
+	// Put сохраняет содержимое по ключу, перезаписывая существующее
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Open открывает содержимое по ключу
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Exists проверяет, есть ли содержимое по ключу
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// ExportTemplateRepo представляет интерфейс репозитория пользовательских шаблонов экспорта
+type ExportTemplateRepo interface {
+	// SaveExportTemplate сохраняет шаблон, заменяя шаблон с тем же именем
+	SaveExportTemplate(ctx context.Context, template *dto.ExportTemplate) error
+
+	// GetExportTemplateByName получает шаблон по имени, nil если шаблона нет
+	GetExportTemplateByName(ctx context.Context, name string) (*dto.ExportTemplate, error)
+
+	// ListExportTemplates получает все шаблоны по имени
+	ListExportTemplates(ctx context.Context) ([]dto.ExportTemplate, error)
+
+	// DeleteExportTemplate удаляет шаблон по имени, false если шаблона не было
+	DeleteExportTemplate(ctx context.Context, name string) (bool, error)
 }
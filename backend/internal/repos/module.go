@@ -14,6 +14,12 @@ var Module = fx.Module("repos",
 	fx.Provide(
 		NewPostgresConnection,
 		NewParserRepo,
+		NewClassifierRepo,
+		NewAssetRepo,
+		NewArchiveRepo,
+		NewSnapshotRepo,
+		NewExportTemplateRepo,
+		NewFileBlobStorage,
 	),
 )
 
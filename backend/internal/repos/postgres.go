@@ -4,15 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 
 	"scrapper/internal/dto"
 )
 
+// ErrBlockNotFound возвращается, если блока с таким ID нет
+var ErrBlockNotFound = errors.New("block not found")
+
 // PostgresRepo реализация ParserRepo для PostgreSQL
 type PostgresRepo struct {
 	db     *sql.DB
@@ -61,33 +66,198 @@ func (r *PostgresRepo) UpdateOperationStatus(ctx context.Context, operationID uu
 	return nil
 }
 
+// UpdateOperationScreenshot сохраняет ключ полного скриншота страницы операции
+func (r *PostgresRepo) UpdateOperationScreenshot(ctx context.Context, operationID uuid.UUID, key string) error {
+	query := `
+	UPDATE operations
+	SET screenshot_key = $1, updated_at = NOW()
+	WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, key, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation screenshot: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOperationDesignTokens сохраняет токены дизайна страницы операции
+func (r *PostgresRepo) UpdateOperationDesignTokens(ctx context.Context, operationID uuid.UUID, tokens *dto.DesignTokens) error {
+	tokensJSON, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to marshal design tokens: %w", err)
+	}
+
+	query := `
+	UPDATE operations
+	SET design_tokens = $1, updated_at = NOW()
+	WHERE id = $2
+	`
+
+	_, err = r.db.ExecContext(ctx, query, tokensJSON, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation design tokens: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOperationOrigin сохраняет версию парсера, источник страницы и операцию, из снимков которой она перепарсена
+func (r *PostgresRepo) UpdateOperationOrigin(ctx context.Context, operationID uuid.UUID, origin dto.OperationOrigin) error {
+	query := `
+	UPDATE operations
+	SET parser_version = $1, source_type = $2, reparsed_from = $3, updated_at = NOW()
+	WHERE id = $4
+	`
+
+	_, err := r.db.ExecContext(ctx, query, origin.ParserVersion, origin.SourceType, origin.ReparsedFrom, operationID)
+	if err != nil {
+		return fmt.Errorf("failed to update operation origin: %w", err)
+	}
+
+	return nil
+}
+
+// operationColumns колонки операции в порядке scanOperation
+const operationColumns = `id, url, status, created_at, updated_at, COALESCE(screenshot_key, ''), design_tokens, brand, parser_version, reparsed_from, source_type`
+
 // GetOperationByID получает операцию по ID
 func (r *PostgresRepo) GetOperationByID(ctx context.Context, operationID uuid.UUID) (*dto.Operation, error) {
 	query := `
-	SELECT id, url, status, created_at, updated_at
+	SELECT ` + operationColumns + `
 	FROM operations
 	WHERE id = $1
 	`
 
+	operation, err := scanOperation(r.db.QueryRowContext(ctx, query, operationID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("operation not found: %s", operationID)
+		}
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return operation, nil
+}
+
+// ListOperations получает операции по фильтру, новые первыми
+func (r *PostgresRepo) ListOperations(ctx context.Context, filter dto.OperationFilter) ([]dto.Operation, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.IDs) > 0 {
+		ids := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			ids[i] = id.String()
+		}
+		conditions = append(conditions, "id = ANY("+arg(pq.Array(ids))+"::uuid[])")
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+arg(filter.Status))
+	}
+	if filter.Host != "" {
+		// Домен берется из URL без схемы, учетных данных и порта
+		conditions = append(conditions, "lower(substring(url from '^[A-Za-z][A-Za-z0-9+.-]*://(?:[^/@]*@)?([^/:?#]+)')) = lower("+arg(filter.Host)+")")
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "url ILIKE '%' || "+arg(escapeLike(filter.Query))+" || '%'")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*filter.To))
+	}
+
+	query := `SELECT ` + operationColumns + ` FROM operations`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list operations: %w", err)
+	}
+	defer rows.Close()
+
+	operations := []dto.Operation{}
+	for rows.Next() {
+		operation, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		operations = append(operations, *operation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating operations: %w", err)
+	}
+
+	return operations, nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// scanOperation считывает операцию из строки результата запроса с колонками operationColumns
+func scanOperation(row rowScanner) (*dto.Operation, error) {
 	var operation dto.Operation
-	var status string
+	var status, sourceType string
+	var tokensJSON, brandJSON []byte
+	var reparsedFrom uuid.NullUUID
 
-	err := r.db.QueryRowContext(ctx, query, operationID).Scan(
+	err := row.Scan(
 		&operation.ID,
 		&operation.URL,
 		&status,
 		&operation.CreatedAt,
 		&operation.UpdatedAt,
+		&operation.ScreenshotKey,
+		&tokensJSON,
+		&brandJSON,
+		&operation.ParserVersion,
+		&reparsedFrom,
+		&sourceType,
 	)
-
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("operation not found: %s", operationID)
-		}
-		return nil, fmt.Errorf("failed to get operation: %w", err)
+		return nil, err
 	}
 
 	operation.Status = dto.OperationStatus(status)
+	operation.SourceType = dto.SourceType(sourceType)
+	if reparsedFrom.Valid {
+		operation.ReparsedFrom = &reparsedFrom.UUID
+	}
+
+	if tokensJSON != nil {
+		var tokens dto.DesignTokens
+		if err := json.Unmarshal(tokensJSON, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal operation design tokens: %w", err)
+		}
+		operation.DesignTokens = &tokens
+	}
+
+	if brandJSON != nil {
+		var brand dto.BrandInfo
+		if err := json.Unmarshal(brandJSON, &brand); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal operation brand: %w", err)
+		}
+		operation.Brand = &brand
+	}
 
 	return &operation, nil
 }
@@ -99,9 +269,33 @@ func (r *PostgresRepo) SaveBlock(ctx context.Context, block *dto.Block) error {
 		return fmt.Errorf("failed to marshal block content: %w", err)
 	}
 
+	var classificationJSON []byte
+	if block.Classification != nil {
+		classificationJSON, err = json.Marshal(block.Classification)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block classification: %w", err)
+		}
+	}
+
+	var boundsJSON []byte
+	if block.Bounds != nil {
+		boundsJSON, err = json.Marshal(block.Bounds)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block bounds: %w", err)
+		}
+	}
+
+	var tokensJSON []byte
+	if block.DesignTokens != nil {
+		tokensJSON, err = json.Marshal(block.DesignTokens)
+		if err != nil {
+			return fmt.Errorf("failed to marshal block design tokens: %w", err)
+		}
+	}
+
 	query := `
-	INSERT INTO blocks (operation_id, block_type, platform, content, html)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO blocks (operation_id, block_type, platform, content, html, original_html, classification, bounds, screenshot_key, device, design_tokens)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, NULLIF($9, ''), $10, $11)
 	RETURNING id, created_at
 	`
 
@@ -113,6 +307,12 @@ func (r *PostgresRepo) SaveBlock(ctx context.Context, block *dto.Block) error {
 		block.Platform,
 		contentJSON,
 		block.HTML,
+		block.OriginalHTML,
+		classificationJSON,
+		boundsJSON,
+		block.ScreenshotKey,
+		block.Device,
+		tokensJSON,
 	).Scan(&block.ID, &block.CreatedAt)
 
 	if err != nil {
@@ -122,10 +322,44 @@ func (r *PostgresRepo) SaveBlock(ctx context.Context, block *dto.Block) error {
 	return nil
 }
 
+// GetBlockByID получает блок по ID
+func (r *PostgresRepo) GetBlockByID(ctx context.Context, blockID uuid.UUID) (*dto.Block, error) {
+	query := `
+	SELECT id, operation_id, block_type, platform, content, html, COALESCE(original_html, ''), classification, bounds, COALESCE(screenshot_key, ''), device, design_tokens, created_at
+	FROM blocks
+	WHERE id = $1
+	`
+
+	block, err := scanBlock(r.db.QueryRowContext(ctx, query, blockID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%w: %s", ErrBlockNotFound, blockID)
+		}
+		return nil, err
+	}
+
+	return block, nil
+}
+
 // GetBlocksByOperationID получает все блоки по ID операции
 func (r *PostgresRepo) GetBlocksByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Block, error) {
+	var blocks []dto.Block
+
+	err := r.IterateBlocksByOperationID(ctx, operationID, func(block *dto.Block) error {
+		blocks = append(blocks, *block)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// IterateBlocksByOperationID передает блоки операции в fn по одному по мере чтения из базы
+func (r *PostgresRepo) IterateBlocksByOperationID(ctx context.Context, operationID uuid.UUID, fn func(block *dto.Block) error) error {
 	query := `
-	SELECT id, operation_id, block_type, platform, content, html, created_at
+	SELECT id, operation_id, block_type, platform, content, html, COALESCE(original_html, ''), classification, bounds, COALESCE(screenshot_key, ''), device, design_tokens, created_at
 	FROM blocks
 	WHERE operation_id = $1
 	ORDER BY created_at
@@ -133,48 +367,97 @@ func (r *PostgresRepo) GetBlocksByOperationID(ctx context.Context, operationID u
 
 	rows, err := r.db.QueryContext(ctx, query, operationID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get blocks: %w", err)
+		return fmt.Errorf("failed to get blocks: %w", err)
 	}
 	defer rows.Close()
 
-	var blocks []dto.Block
-
 	for rows.Next() {
-		var block dto.Block
-		var blockType, platform string
-		var contentJSON []byte
-
-		err := rows.Scan(
-			&block.ID,
-			&block.OperationID,
-			&blockType,
-			&platform,
-			&contentJSON,
-			&block.HTML,
-			&block.CreatedAt,
-		)
-
+		block, err := scanBlock(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan block: %w", err)
+			return err
 		}
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
 
-		block.BlockType = dto.BlockType(blockType)
-		block.Platform = dto.Platform(platform)
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating blocks: %w", err)
+	}
+
+	return nil
+}
+
+// rowScanner общий интерфейс для sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-		var content map[string]interface{}
-		if err := json.Unmarshal(contentJSON, &content); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal block content: %w", err)
+// scanBlock считывает блок из строки результата запроса
+func scanBlock(row rowScanner) (*dto.Block, error) {
+	var block dto.Block
+	var blockType, platform, device string
+	var contentJSON, classificationJSON, boundsJSON, tokensJSON []byte
+
+	err := row.Scan(
+		&block.ID,
+		&block.OperationID,
+		&blockType,
+		&platform,
+		&contentJSON,
+		&block.HTML,
+		&block.OriginalHTML,
+		&classificationJSON,
+		&boundsJSON,
+		&block.ScreenshotKey,
+		&device,
+		&tokensJSON,
+		&block.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to scan block: %w", err)
+	}
 
-		block.Content = content
-		blocks = append(blocks, block)
+	block.BlockType = dto.BlockType(blockType)
+	block.Platform = dto.Platform(platform)
+	block.Device = dto.DeviceProfile(device)
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(contentJSON, &content); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block content: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating blocks: %w", err)
+	block.Content = content
+
+	if classificationJSON != nil {
+		var classification dto.BlockClassification
+		if err := json.Unmarshal(classificationJSON, &classification); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal block classification: %w", err)
+		}
+		block.Classification = &classification
 	}
 
-	return blocks, nil
+	if boundsJSON != nil {
+		var bounds dto.BlockBounds
+		if err := json.Unmarshal(boundsJSON, &bounds); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal block bounds: %w", err)
+		}
+		block.Bounds = &bounds
+	}
+
+	if tokensJSON != nil {
+		var tokens dto.DesignTokens
+		if err := json.Unmarshal(tokensJSON, &tokens); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal block design tokens: %w", err)
+		}
+		block.DesignTokens = &tokens
+	}
+
+	return &block, nil
 }
 
 // GetAllTemplates получает все HTML теги для парсера блоков страницы
@@ -212,3 +495,65 @@ func (r *PostgresRepo) GetAllTemplates(platform dto.Platform) ([]dto.BlockTempla
 
 	return templates, nil
 }
+
+// templateColumns колонки block_templates для каждой платформы
+var templateColumns = map[dto.Platform]string{
+	dto.PlatformWordPress: "wordpress",
+	dto.PlatformTilda:     "tilda",
+	dto.PlatformBitrix:    "bitrix",
+	dto.PlatformHTML5:     "html5",
+}
+
+// SaveTemplate сохраняет шаблон блока для платформы
+func (r *PostgresRepo) SaveTemplate(ctx context.Context, platform dto.Platform, template dto.BlockTemplate) error {
+	column, ok := templateColumns[platform]
+	if !ok {
+		return fmt.Errorf("unsupported platform: %s", platform)
+	}
+
+	// Имя колонки берется только из templateColumns, поэтому подстановка безопасна
+	query := fmt.Sprintf(`
+	INSERT INTO block_templates (block_type, %[1]s)
+	VALUES ($1, $2)
+	ON CONFLICT (block_type) DO UPDATE SET %[1]s = EXCLUDED.%[1]s
+	`, column)
+
+	if _, err := r.db.ExecContext(ctx, query, template.BlockType, template.HTMLTags); err != nil {
+		return fmt.Errorf("failed to save block template: %w", err)
+	}
+
+	return nil
+}
+
+// GetRecentBlocksByPlatform получает последние сохраненные блоки платформы
+func (r *PostgresRepo) GetRecentBlocksByPlatform(ctx context.Context, platform dto.Platform, limit int) ([]dto.Block, error) {
+	query := `
+	SELECT id, operation_id, block_type, platform, content, html, COALESCE(original_html, ''), classification, bounds, COALESCE(screenshot_key, ''), device, design_tokens, created_at
+	FROM blocks
+	WHERE platform = $1 AND html <> ''
+	ORDER BY created_at DESC
+	LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, platform, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+	defer rows.Close()
+
+	var blocks []dto.Block
+
+	for rows.Next() {
+		block, err := scanBlock(rows)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocks: %w", err)
+	}
+
+	return blocks, nil
+}
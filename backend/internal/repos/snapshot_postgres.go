@@ -0,0 +1,124 @@
+package repos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+)
+
+// NewSnapshotRepo создает новый экземпляр SnapshotRepo
+func NewSnapshotRepo(db *sql.DB, logger *zap.Logger) SnapshotRepo {
+	return &PostgresRepo{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveSnapshot сохраняет запись о снимке HTML, заменяя снимок того же устройства
+func (r *PostgresRepo) SaveSnapshot(ctx context.Context, snapshot *dto.Snapshot) error {
+	headersJSON, err := json.Marshal(snapshot.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot headers: %w", err)
+	}
+
+	query := `
+	INSERT INTO operation_snapshots (operation_id, device, storage_key, size, compressed_size, sha256, url, status_code, content_type, headers, captured_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (operation_id, device) DO UPDATE
+	SET storage_key = EXCLUDED.storage_key,
+	    size = EXCLUDED.size,
+	    compressed_size = EXCLUDED.compressed_size,
+	    sha256 = EXCLUDED.sha256,
+	    url = EXCLUDED.url,
+	    status_code = EXCLUDED.status_code,
+	    content_type = EXCLUDED.content_type,
+	    headers = EXCLUDED.headers,
+	    captured_at = EXCLUDED.captured_at
+	RETURNING id, created_at
+	`
+
+	err = r.db.QueryRowContext(
+		ctx,
+		query,
+		snapshot.OperationID,
+		snapshot.Device,
+		snapshot.StorageKey,
+		snapshot.Size,
+		snapshot.CompressedSize,
+		snapshot.SHA256,
+		snapshot.URL,
+		snapshot.StatusCode,
+		snapshot.ContentType,
+		headersJSON,
+		snapshot.CapturedAt,
+	).Scan(&snapshot.ID, &snapshot.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetSnapshotsByOperationID получает все снимки операции в порядке рендеринга
+func (r *PostgresRepo) GetSnapshotsByOperationID(ctx context.Context, operationID uuid.UUID) ([]dto.Snapshot, error) {
+	query := `
+	SELECT id, operation_id, device, storage_key, size, compressed_size, sha256, url, status_code, content_type, headers, captured_at, created_at
+	FROM operation_snapshots
+	WHERE operation_id = $1
+	ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []dto.Snapshot{}
+	for rows.Next() {
+		var snapshot dto.Snapshot
+		var device string
+		var headersJSON []byte
+
+		err := rows.Scan(
+			&snapshot.ID,
+			&snapshot.OperationID,
+			&device,
+			&snapshot.StorageKey,
+			&snapshot.Size,
+			&snapshot.CompressedSize,
+			&snapshot.SHA256,
+			&snapshot.URL,
+			&snapshot.StatusCode,
+			&snapshot.ContentType,
+			&headersJSON,
+			&snapshot.CapturedAt,
+			&snapshot.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+
+		if headersJSON != nil {
+			if err := json.Unmarshal(headersJSON, &snapshot.Headers); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal snapshot headers: %w", err)
+			}
+		}
+
+		snapshot.Device = dto.DeviceProfile(device)
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
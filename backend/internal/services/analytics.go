@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"scrapper/internal/dto"
+)
+
+// analyticsRow строка таблицы аналитической выгрузки; values возвращает значения в порядке колонок таблицы
+type analyticsRow interface {
+	values() []interface{}
+}
+
+// analyticsTable нормализованная таблица выгрузки: модель строки для Parquet и схема для SQLite
+type analyticsTable struct {
+	name   string
+	model  analyticsRow
+	schema string
+}
+
+// analyticsTables таблицы выгрузки в порядке создания; строки связаны по operation_id и block_id
+var analyticsTables = []analyticsTable{
+	{
+		name:  "operations",
+		model: &analyticsOperation{},
+		schema: `CREATE TABLE operations (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	host TEXT NOT NULL,
+	status TEXT NOT NULL,
+	source_type TEXT NOT NULL,
+	parser_version TEXT NOT NULL,
+	reparsed_from TEXT,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`,
+	},
+	{
+		name:  "pages",
+		model: &analyticsPage{},
+		schema: `CREATE TABLE pages (
+	operation_id TEXT NOT NULL REFERENCES operations (id),
+	device TEXT NOT NULL,
+	url TEXT NOT NULL,
+	path TEXT NOT NULL,
+	title TEXT,
+	blocks INTEGER NOT NULL,
+	components INTEGER NOT NULL,
+	links INTEGER NOT NULL,
+	PRIMARY KEY (operation_id, device)
+)`,
+	},
+	{
+		name:  "blocks",
+		model: &analyticsBlock{},
+		schema: `CREATE TABLE blocks (
+	id TEXT PRIMARY KEY,
+	operation_id TEXT NOT NULL REFERENCES operations (id),
+	device TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	block_type TEXT NOT NULL,
+	platform TEXT NOT NULL,
+	category TEXT,
+	score REAL,
+	x INTEGER,
+	y INTEGER,
+	width INTEGER,
+	height INTEGER,
+	text_length INTEGER NOT NULL,
+	html_length INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL
+)`,
+	},
+	{
+		name:  "components",
+		model: &analyticsComponent{},
+		schema: `CREATE TABLE components (
+	operation_id TEXT NOT NULL REFERENCES operations (id),
+	block_id TEXT NOT NULL REFERENCES blocks (id),
+	key TEXT NOT NULL,
+	text TEXT NOT NULL,
+	link TEXT,
+	PRIMARY KEY (block_id, key)
+)`,
+	},
+	{
+		name:  "links",
+		model: &analyticsLink{},
+		schema: `CREATE TABLE links (
+	operation_id TEXT NOT NULL REFERENCES operations (id),
+	block_id TEXT NOT NULL REFERENCES blocks (id),
+	component TEXT NOT NULL,
+	url TEXT NOT NULL,
+	scheme TEXT NOT NULL,
+	host TEXT,
+	internal INTEGER NOT NULL
+)`,
+	},
+	{
+		name:  "technologies",
+		model: &analyticsTechnology{},
+		schema: `CREATE TABLE technologies (
+	operation_id TEXT NOT NULL REFERENCES operations (id),
+	name TEXT NOT NULL,
+	category TEXT NOT NULL,
+	blocks INTEGER NOT NULL,
+	evidence TEXT NOT NULL,
+	PRIMARY KEY (operation_id, name)
+)`,
+	},
+}
+
+// analyticsIndexes индексы SQLite для соединений по операции и блоку; создаются после загрузки строк
+var analyticsIndexes = []string{
+	`CREATE INDEX blocks_operation_id ON blocks (operation_id)`,
+	`CREATE INDEX components_operation_id ON components (operation_id)`,
+	`CREATE INDEX links_operation_id ON links (operation_id)`,
+	`CREATE INDEX links_block_id ON links (block_id)`,
+}
+
+// analyticsOperation строка таблицы operations
+type analyticsOperation struct {
+	ID            string    `parquet:"id"`
+	URL           string    `parquet:"url"`
+	Host          string    `parquet:"host"`
+	Status        string    `parquet:"status"`
+	SourceType    string    `parquet:"source_type"`
+	ParserVersion string    `parquet:"parser_version"`
+	ReparsedFrom  *string   `parquet:"reparsed_from"`
+	CreatedAt     time.Time `parquet:"created_at,timestamp(millisecond)"`
+	UpdatedAt     time.Time `parquet:"updated_at,timestamp(millisecond)"`
+}
+
+func (r *analyticsOperation) values() []interface{} {
+	return []interface{}{r.ID, r.URL, r.Host, r.Status, r.SourceType, r.ParserVersion, r.ReparsedFrom, r.CreatedAt, r.UpdatedAt}
+}
+
+// analyticsPage строка таблицы pages: страница операции под профилем устройства
+type analyticsPage struct {
+	OperationID string  `parquet:"operation_id"`
+	Device      string  `parquet:"device"`
+	URL         string  `parquet:"url"`
+	Path        string  `parquet:"path"`
+	Title       *string `parquet:"title"`
+	Blocks      int32   `parquet:"blocks"`
+	Components  int32   `parquet:"components"`
+	Links       int32   `parquet:"links"`
+}
+
+func (r *analyticsPage) values() []interface{} {
+	return []interface{}{r.OperationID, r.Device, r.URL, r.Path, r.Title, r.Blocks, r.Components, r.Links}
+}
+
+// analyticsBlock строка таблицы blocks; position - порядок блока на странице устройства
+type analyticsBlock struct {
+	ID          string    `parquet:"id"`
+	OperationID string    `parquet:"operation_id"`
+	Device      string    `parquet:"device"`
+	Position    int32     `parquet:"position"`
+	BlockType   string    `parquet:"block_type"`
+	Platform    string    `parquet:"platform"`
+	Category    *string   `parquet:"category"`
+	Score       *float64  `parquet:"score"`
+	X           *int32    `parquet:"x"`
+	Y           *int32    `parquet:"y"`
+	Width       *int32    `parquet:"width"`
+	Height      *int32    `parquet:"height"`
+	TextLength  int32     `parquet:"text_length"`
+	HTMLLength  int32     `parquet:"html_length"`
+	CreatedAt   time.Time `parquet:"created_at,timestamp(millisecond)"`
+}
+
+func (r *analyticsBlock) values() []interface{} {
+	return []interface{}{r.ID, r.OperationID, r.Device, r.Position, r.BlockType, r.Platform, r.Category, r.Score,
+		r.X, r.Y, r.Width, r.Height, r.TextLength, r.HTMLLength, r.CreatedAt}
+}
+
+// analyticsComponent строка таблицы components: компонент содержимого блока по пути flattenContent
+type analyticsComponent struct {
+	OperationID string  `parquet:"operation_id"`
+	BlockID     string  `parquet:"block_id"`
+	Key         string  `parquet:"key"`
+	Text        string  `parquet:"text"`
+	Link        *string `parquet:"link"`
+}
+
+func (r *analyticsComponent) values() []interface{} {
+	return []interface{}{r.OperationID, r.BlockID, r.Key, r.Text, r.Link}
+}
+
+// analyticsLink строка таблицы links; internal - ссылка на домен операции
+type analyticsLink struct {
+	OperationID string  `parquet:"operation_id"`
+	BlockID     string  `parquet:"block_id"`
+	Component   string  `parquet:"component"`
+	URL         string  `parquet:"url"`
+	Scheme      string  `parquet:"scheme"`
+	Host        *string `parquet:"host"`
+	Internal    bool    `parquet:"internal"`
+}
+
+func (r *analyticsLink) values() []interface{} {
+	return []interface{}{r.OperationID, r.BlockID, r.Component, r.URL, r.Scheme, r.Host, r.Internal}
+}
+
+// analyticsTechnology строка таблицы technologies; blocks - число блоков с признаками технологии
+type analyticsTechnology struct {
+	OperationID string `parquet:"operation_id"`
+	Name        string `parquet:"name"`
+	Category    string `parquet:"category"`
+	Blocks      int32  `parquet:"blocks"`
+	Evidence    string `parquet:"evidence"`
+}
+
+func (r *analyticsTechnology) values() []interface{} {
+	return []interface{}{r.OperationID, r.Name, r.Category, r.Blocks, r.Evidence}
+}
+
+// collectAnalytics обходит операции и передает строки таблиц в write по мере чтения блоков.
+// Строки операции идут раньше строк ее блоков, строки блока раньше его компонентов и ссылок.
+func collectAnalytics(ctx context.Context, sources []ExportSource, write func(table string, row analyticsRow) error) error {
+	for _, source := range sources {
+		if err := collectOperationAnalytics(ctx, source, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectOperationAnalytics пишет строки одной операции
+func collectOperationAnalytics(ctx context.Context, source ExportSource, write func(table string, row analyticsRow) error) error {
+	operation := source.Operation()
+	operationID := operation.ID.String()
+
+	base, err := url.Parse(operation.URL)
+	if err != nil {
+		return fmt.Errorf("invalid operation URL %q: %w", operation.URL, err)
+	}
+
+	row := &analyticsOperation{
+		ID:            operationID,
+		URL:           operation.URL,
+		Host:          base.Hostname(),
+		Status:        string(operation.Status),
+		SourceType:    string(operation.SourceType),
+		ParserVersion: operation.ParserVersion,
+		CreatedAt:     operation.CreatedAt,
+		UpdatedAt:     operation.UpdatedAt,
+	}
+	if operation.ReparsedFrom != nil {
+		reparsedFrom := operation.ReparsedFrom.String()
+		row.ReparsedFrom = &reparsedFrom
+	}
+	if err := write("operations", row); err != nil {
+		return err
+	}
+
+	// Страницы собираются по профилям устройств и пишутся после блоков
+	pages := make(map[dto.DeviceProfile]*analyticsPage)
+	var devices []dto.DeviceProfile
+	findings := newReportFindings()
+
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		page, ok := pages[block.Device]
+		if !ok {
+			page = &analyticsPage{OperationID: operationID, Device: string(block.Device), URL: operation.URL, Path: base.EscapedPath()}
+			if page.Path == "" {
+				page.Path = "/"
+			}
+			pages[block.Device] = page
+			devices = append(devices, block.Device)
+		}
+		page.Blocks++
+
+		raw, components, err := blockComponents(block, base)
+		if err != nil {
+			return fmt.Errorf("failed to parse block %s components: %w", block.ID, err)
+		}
+		findings.collect(block, raw, components)
+
+		blockRow := &analyticsBlock{
+			ID:          block.ID.String(),
+			OperationID: operationID,
+			Device:      string(block.Device),
+			Position:    page.Blocks,
+			BlockType:   string(block.BlockType),
+			Platform:    string(block.Platform),
+			TextLength:  int32(len([]rune(blockText(block.HTML)))),
+			HTMLLength:  int32(len(block.HTML)),
+			CreatedAt:   block.CreatedAt,
+		}
+		if block.Classification != nil {
+			category := string(block.Classification.Category)
+			score := block.Classification.Score
+			blockRow.Category, blockRow.Score = &category, &score
+		}
+		if bounds := block.Bounds; bounds != nil {
+			x, y, width, height := int32(bounds.X), int32(bounds.Y), int32(bounds.Width), int32(bounds.Height)
+			blockRow.X, blockRow.Y, blockRow.Width, blockRow.Height = &x, &y, &width, &height
+		}
+		if err := write("blocks", blockRow); err != nil {
+			return err
+		}
+
+		if page.Title == nil {
+			if title := firstHeading(block.HTML); title != "" {
+				page.Title = &title
+			}
+		}
+
+		keys := make([]string, 0, len(components))
+		for key := range components {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			component := components[key]
+			componentRow := &analyticsComponent{OperationID: operationID, BlockID: blockRow.ID, Key: key, Text: component.text}
+			if component.link != "" {
+				link := component.link
+				componentRow.Link = &link
+			}
+			if err := write("components", componentRow); err != nil {
+				return err
+			}
+			page.Components++
+
+			for _, link := range component.links {
+				if err := write("links", analyticsLinkRow(operationID, blockRow.ID, key, link, base)); err != nil {
+					return err
+				}
+				page.Links++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := write("pages", pages[device]); err != nil {
+			return err
+		}
+	}
+
+	for _, technology := range findings.sortedTechnologies() {
+		err := write("technologies", &analyticsTechnology{
+			OperationID: operationID,
+			Name:        technology.name,
+			Category:    technology.category,
+			Blocks:      int32(technology.blocks),
+			Evidence:    strings.Join(technology.evidence, "; "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// analyticsLinkRow разбирает ссылку компонента на схему и домен
+func analyticsLinkRow(operationID, blockID, component, link string, base *url.URL) *analyticsLink {
+	row := &analyticsLink{OperationID: operationID, BlockID: blockID, Component: component, URL: link}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return row
+	}
+	row.Scheme = strings.ToLower(parsed.Scheme)
+	if host := strings.ToLower(parsed.Hostname()); host != "" {
+		row.Host = &host
+		row.Internal = strings.TrimPrefix(host, "www.") == strings.TrimPrefix(strings.ToLower(base.Hostname()), "www.")
+	}
+
+	return row
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ErrArchiveNotFound возвращается, если у операции нет архива нужного формата и устройства
+var ErrArchiveNotFound = errors.New("archive not found")
+
+// archiveContentTypes типы содержимого архивов
+var archiveContentTypes = map[dto.ArchiveFormat]string{
+	dto.ArchiveFormatMHTML: "multipart/related",
+	dto.ArchiveFormatWARC:  "application/warc",
+}
+
+// archiveKey возвращает ключ архива страницы операции для профиля устройства
+func archiveKey(operationID uuid.UUID, device dto.DeviceProfile, format dto.ArchiveFormat) string {
+	return fmt.Sprintf("archives/%s/%s.%s", operationID, device, format)
+}
+
+// ArchiveContentType возвращает тип содержимого архива
+func ArchiveContentType(format dto.ArchiveFormat) string {
+	return archiveContentTypes[format]
+}
+
+// captureMHTML возвращает действие chromedp, снимающее страницу в MHTML
+func captureMHTML(data *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		snapshot, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to capture mhtml: %w", err)
+		}
+
+		*data = []byte(snapshot)
+		return nil
+	})
+}
+
+// archiveService реализация ArchiveService
+type archiveService struct {
+	logger  *zap.Logger
+	repo    repos.ArchiveRepo
+	storage repos.BlobStorage
+}
+
+// NewArchiveService создает новый экземпляр ArchiveService
+func NewArchiveService(logger *zap.Logger, repo repos.ArchiveRepo, storage repos.BlobStorage) ArchiveService {
+	return &archiveService{
+		logger:  logger,
+		repo:    repo,
+		storage: storage,
+	}
+}
+
+// SaveArchive сохраняет архив страницы, снятый под профилем устройства
+func (s *archiveService) SaveArchive(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, format dto.ArchiveFormat, data []byte) (*dto.Archive, error) {
+	if _, ok := archiveContentTypes[format]; !ok {
+		return nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+
+	key := archiveKey(operationID, device, format)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to store archive: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	archive := &dto.Archive{
+		OperationID: operationID,
+		Device:      device,
+		Format:      format,
+		StorageKey:  key,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		CapturedAt:  time.Now().UTC(),
+	}
+
+	if err := s.repo.SaveArchive(ctx, archive); err != nil {
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// AttachArchives привязывает к операции архивы исходной операции, из которых она была перепарсена
+func (s *archiveService) AttachArchives(ctx context.Context, sourceID, targetID uuid.UUID) error {
+	archives, err := s.repo.GetArchivesByOperationID(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range archives {
+		archive.OperationID = targetID
+		if err := s.repo.SaveArchive(ctx, &archive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetArchives возвращает архивы операции
+func (s *archiveService) GetArchives(ctx context.Context, operationID uuid.UUID) ([]dto.Archive, error) {
+	return s.repo.GetArchivesByOperationID(ctx, operationID)
+}
+
+// OpenArchive открывает архив операции нужного формата для профиля устройства
+func (s *archiveService) OpenArchive(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, format dto.ArchiveFormat) (*dto.Archive, io.ReadCloser, error) {
+	archives, err := s.repo.GetArchivesByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, archive := range archives {
+		if archive.Device != device || archive.Format != format {
+			continue
+		}
+
+		reader, err := s.storage.Open(ctx, archive.StorageKey)
+		if err != nil {
+			if errors.Is(err, repos.ErrBlobNotFound) {
+				return nil, nil, ErrArchiveNotFound
+			}
+			return nil, nil, err
+		}
+
+		return &archive, reader, nil
+	}
+
+	return nil, nil, ErrArchiveNotFound
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ErrAssetNotFound возвращается, если файл не принадлежит операции или отсутствует в хранилище
+var ErrAssetNotFound = errors.New("asset not found")
+
+// ErrForbiddenAssetURL возвращается для URL, которые нельзя скачивать: не http(s) или во внутреннюю сеть
+var ErrForbiddenAssetURL = errors.New("forbidden asset url")
+
+const (
+	// maxBrandAssetSize максимальный размер файла фирменного набора
+	maxBrandAssetSize = 10 << 20
+
+	// assetFetchTimeout таймаут скачивания одного файла
+	assetFetchTimeout = 15 * time.Second
+
+	// maxAssetRedirects число редиректов при скачивании одного файла
+	maxAssetRedirects = 10
+)
+
+// reservedNetworks сети, которые не считаются публичными помимо loopback, частных и link-local:
+// "этот хост", CGNAT, бенчмарки и зарезервированные диапазоны
+var reservedNetworks = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96")
+
+// assetExtensions расширения файлов по типу содержимого
+var assetExtensions = map[string]string{
+	"image/png":                     ".png",
+	"image/jpeg":                    ".jpg",
+	"image/gif":                     ".gif",
+	"image/webp":                    ".webp",
+	"image/svg+xml":                 ".svg",
+	"image/x-icon":                  ".ico",
+	"image/vnd.microsoft.icon":      ".ico",
+	"image/avif":                    ".avif",
+	"font/woff":                     ".woff",
+	"font/woff2":                    ".woff2",
+	"font/ttf":                      ".ttf",
+	"font/otf":                      ".otf",
+	"application/vnd.ms-fontobject": ".eot",
+	"text/css":                      ".css",
+	"text/javascript":               ".js",
+	"application/javascript":        ".js",
+}
+
+// fetchedAsset содержимое скачанного файла
+type fetchedAsset struct {
+	url         string
+	data        []byte
+	contentType string
+}
+
+// newAssetClient создает HTTP-клиент для скачивания файлов сайта. URL файлов приходят со страниц
+// и из загруженных файлов, поэтому клиент соединяется только с публичными адресами: проверка идет
+// на уже разрешенном IP при каждом соединении, в том числе после редиректов. Прокси из окружения
+// не используется, иначе проверялся бы адрес прокси.
+func newAssetClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: assetFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenAssetURL, address)
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s is not a public address", ErrForbiddenAssetURL, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   assetFetchTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxAssetRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxAssetRedirects)
+			}
+			return checkAssetURL(req.URL)
+		},
+	}
+}
+
+// checkAssetURL разрешает скачивание только по http и https
+func checkAssetURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrForbiddenAssetURL, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: url has no host", ErrForbiddenAssetURL)
+	}
+	return nil
+}
+
+// isPublicIP проверяет, что адрес не ведет во внутреннюю сеть, на сам сервер или к метаданным облака
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// mustParseCIDRs разбирает список сетей в нотации CIDR
+func mustParseCIDRs(values ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// fetchAsset скачивает файл не больше maxSize байт по абсолютному URL или декодирует data: URL
+func fetchAsset(ctx context.Context, client *http.Client, rawURL string, maxSize int64) (*fetchedAsset, error) {
+	if strings.HasPrefix(rawURL, "data:") {
+		return decodeDataURL(rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset url: %w", err)
+	}
+	if err := checkAssetURL(req.URL); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", deviceProfiles[dto.DeviceDesktop].UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch asset: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch asset: status %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("asset is larger than %d bytes", maxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read asset: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("asset is larger than %d bytes", maxSize)
+	}
+
+	return &fetchedAsset{
+		url:         resp.Request.URL.String(),
+		data:        data,
+		contentType: detectAssetType(data, resp.Header.Get("Content-Type")),
+	}, nil
+}
+
+// decodeDataURL декодирует встроенный в страницу data: URL
+func decodeDataURL(rawURL string) (*fetchedAsset, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(rawURL, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("invalid data url")
+	}
+
+	var data []byte
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+		if err != nil {
+			return nil, fmt.Errorf("invalid data url: %w", err)
+		}
+		data = decoded
+		header = strings.TrimSuffix(header, ";base64")
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data url: %w", err)
+		}
+		data = []byte(unescaped)
+	}
+
+	return &fetchedAsset{
+		url:         rawURL,
+		data:        data,
+		contentType: detectAssetType(data, header),
+	}, nil
+}
+
+// detectAssetType определяет тип содержимого по заголовку и сигнатуре файла
+func detectAssetType(data []byte, header string) string {
+	contentType, _, _ := mime.ParseMediaType(header)
+
+	if contentType == "" || contentType == "application/octet-stream" || contentType == "text/plain" || contentType == "text/xml" {
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	// SVG приходит как text/xml или text/plain, сигнатуру http.DetectContentType не знает
+	if !strings.HasPrefix(contentType, "image/") && bytes.Contains(bytes.ToLower(data[:min(len(data), 1024)]), []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	// WebP определяется по сигнатуре RIFF....WEBP
+	if len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" {
+		return "image/webp"
+	}
+
+	return contentType
+}
+
+// storeAsset сохраняет содержимое в хранилище по хэшу, одинаковые файлы хранятся один раз
+func storeAsset(ctx context.Context, storage repos.BlobStorage, data []byte, contentType string) (key, sum string, err error) {
+	hash := sha256.Sum256(data)
+	sum = hex.EncodeToString(hash[:])
+	key = fmt.Sprintf("assets/%s/%s%s", sum[:2], sum, assetExtensions[contentType])
+
+	exists, err := storage.Exists(ctx, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !exists {
+		if err := storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+			return "", "", err
+		}
+	}
+
+	return key, sum, nil
+}
+
+// newStoredAsset скачивает файл, сохраняет его в хранилище и возвращает запись для БД
+func newStoredAsset(ctx context.Context, client *http.Client, storage repos.BlobStorage, operationID uuid.UUID, kind dto.AssetKind, rawURL string, maxSize int64) (*dto.Asset, error) {
+	fetched, err := fetchAsset(ctx, client, rawURL, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return storeFetchedAsset(ctx, storage, operationID, kind, rawURL, fetched)
+}
+
+// storeFetchedAsset сохраняет скачанный файл в хранилище и возвращает запись для БД
+func storeFetchedAsset(ctx context.Context, storage repos.BlobStorage, operationID uuid.UUID, kind dto.AssetKind, rawURL string, fetched *fetchedAsset) (*dto.Asset, error) {
+
+	key, sum, err := storeAsset(ctx, storage, fetched.data, fetched.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := imageDimensions(fetched.data, fetched.contentType)
+
+	return &dto.Asset{
+		OperationID: operationID,
+		Kind:        kind,
+		URL:         rawURL,
+		StorageKey:  key,
+		ContentType: fetched.contentType,
+		Size:        int64(len(fetched.data)),
+		Width:       width,
+		Height:      height,
+		SHA256:      sum,
+	}, nil
+}
+
+// resolveURL превращает ссылку со страницы в абсолютный URL
+func resolveURL(base *url.URL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "javascript:") || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+
+	if strings.HasPrefix(ref, "data:") {
+		return ref, true
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+
+	resolved := base.ResolveReference(parsed)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+	resolved.Fragment = ""
+
+	return resolved.String(), true
+}
+
+// imageDimensions возвращает размеры изображения в пикселях, 0 если их не удалось определить
+func imageDimensions(data []byte, contentType string) (int, int) {
+	switch contentType {
+	case "image/svg+xml":
+		return svgDimensions(data)
+	case "image/webp":
+		return webpDimensions(data)
+	case "image/x-icon", "image/vnd.microsoft.icon":
+		return icoDimensions(data)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0
+	}
+
+	return cfg.Width, cfg.Height
+}
+
+var (
+	reSVGRoot    = regexp.MustCompile(`(?is)<svg\b[^>]*>`)
+	reSVGWidth   = regexp.MustCompile(`(?i)\swidth\s*=\s*["']\s*([\d.]+)(?:px)?\s*["']`)
+	reSVGHeight  = regexp.MustCompile(`(?i)\sheight\s*=\s*["']\s*([\d.]+)(?:px)?\s*["']`)
+	reSVGViewBox = regexp.MustCompile(`(?i)\sviewBox\s*=\s*["']\s*[-\d.]+[\s,]+[-\d.]+[\s,]+([\d.]+)[\s,]+([\d.]+)\s*["']`)
+)
+
+// svgDimensions берет размеры из атрибутов width/height корневого svg, иначе из viewBox
+func svgDimensions(data []byte) (int, int) {
+	root := reSVGRoot.Find(data)
+	if root == nil {
+		return 0, 0
+	}
+
+	number := func(value []byte) int {
+		v, _ := strconv.ParseFloat(string(value), 64)
+		return int(v + 0.5)
+	}
+
+	if width, height := reSVGWidth.FindSubmatch(root), reSVGHeight.FindSubmatch(root); width != nil && height != nil {
+		return number(width[1]), number(height[1])
+	}
+
+	if viewBox := reSVGViewBox.FindSubmatch(root); viewBox != nil {
+		return number(viewBox[1]), number(viewBox[2])
+	}
+
+	return 0, 0
+}
+
+// webpDimensions читает размеры из заголовка VP8, VP8L или VP8X
+func webpDimensions(data []byte) (int, int) {
+	if len(data) < 30 {
+		return 0, 0
+	}
+
+	switch string(data[12:16]) {
+	case "VP8 ":
+		return int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff), int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff)
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1
+	case "VP8X":
+		width := int(data[24]) | int(data[25])<<8 | int(data[26])<<16
+		height := int(data[27]) | int(data[28])<<8 | int(data[29])<<16
+		return width + 1, height + 1
+	}
+
+	return 0, 0
+}
+
+// icoDimensions возвращает размеры самой большой картинки в ICO
+func icoDimensions(data []byte) (int, int) {
+	if len(data) < 6 || binary.LittleEndian.Uint16(data[2:4]) != 1 {
+		return 0, 0
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	width, height := 0, 0
+
+	for i := 0; i < count; i++ {
+		offset := 6 + i*16
+		if offset+2 > len(data) {
+			break
+		}
+
+		// Ноль в заголовке ICO означает 256 пикселей
+		w, h := int(data[offset]), int(data[offset+1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+
+		if w*h > width*height {
+			width, height = w, h
+		}
+	}
+
+	return width, height
+}
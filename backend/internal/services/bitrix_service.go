@@ -10,13 +10,6 @@ import (
 	"scrapper/internal/dto"
 )
 
-// BitrixService интерфейс для работы с элементами Bitrix
-type BitrixService interface {
-	DetectPlatform(html string) bool
-	ParseHeader(html string) (*dto.Block, error)
-	ParseFooter(html string) (*dto.Block, error)
-}
-
 // bitrixService реализация BitrixService
 type bitrixService struct {
 	logger *zap.Logger
@@ -106,10 +99,14 @@ func (s *bitrixService) ParseHeader(html string) (*dto.Block, error) {
 		return nil, err
 	}
 
+	// Компоненты шапки собираются в Content блока
+	content := map[string]interface{}{
+		"version": s.detectBitrixVersion(html),
+	}
 	header := &dto.Block{
-		Type:       "bitrix_header",
-		Components: make(map[string]interface{}),
-		Version:    s.detectBitrixVersion(html),
+		BlockType: dto.BlockTypeHeader,
+		Platform:  dto.PlatformBitrix,
+		Content:   content,
 	}
 
 	// Поиск контейнера шапки
@@ -127,25 +124,25 @@ func (s *bitrixService) ParseHeader(html string) (*dto.Block, error) {
 	}
 
 	// Парсинг логотипа
-	s.parseLogo(headerContainer, header)
+	s.parseLogo(headerContainer, content)
 
 	// Парсинг меню
-	s.parseMenu(headerContainer, header)
+	s.parseMenu(headerContainer, content)
 
 	// Парсинг поиска
-	s.parseSearch(headerContainer, header)
+	s.parseSearch(headerContainer, content)
 
 	// Парсинг телефонов
-	s.parsePhones(headerContainer, header)
+	s.parsePhones(headerContainer, content)
 
 	// Парсинг корзины
-	s.parseCart(headerContainer, header)
+	s.parseCart(headerContainer, content)
 
 	// Парсинг авторизации
-	s.parseAuth(headerContainer, header)
+	s.parseAuth(headerContainer, content)
 
 	// Валидация результата
-	if !s.validateHeader(header) {
+	if !s.validateHeader(content) {
 		s.logger.Warn("Header validation failed")
 	}
 
@@ -153,15 +150,15 @@ func (s *bitrixService) ParseHeader(html string) (*dto.Block, error) {
 }
 
 // parseLogo парсит логотип
-func (s *bitrixService) parseLogo(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parseLogo(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.HeaderSelectors.Logo {
 		logo := container.Find(selector).First()
 		if logo.Length() > 0 {
-			if src, exists := logo.Find("img").Attr("src"); exists {
-				header.Components["logo"] = src
+			if src := logoSource(logo); src != "" {
+				content["logo"] = src
 				break
 			} else if href, exists := logo.Attr("href"); exists {
-				header.Components["logo_link"] = href
+				content["logo_link"] = href
 				break
 			}
 		}
@@ -169,7 +166,7 @@ func (s *bitrixService) parseLogo(container *goquery.Selection, header *dto.Bloc
 }
 
 // parseMenu парсит меню
-func (s *bitrixService) parseMenu(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parseMenu(container *goquery.Selection, content map[string]interface{}) {
 	var menuItems []string
 	for _, selector := range s.config.HeaderSelectors.Menu {
 		container.Find(selector).Each(func(i int, s *goquery.Selection) {
@@ -180,54 +177,54 @@ func (s *bitrixService) parseMenu(container *goquery.Selection, header *dto.Bloc
 			})
 		})
 		if len(menuItems) > 0 {
-			header.Components["menu"] = menuItems
+			content["menu"] = menuItems
 			break
 		}
 	}
 }
 
 // parseSearch парсит поиск
-func (s *bitrixService) parseSearch(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parseSearch(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.HeaderSelectors.Search {
 		if container.Find(selector).Length() > 0 {
-			header.Components["search"] = true
+			content["search"] = true
 			break
 		}
 	}
 }
 
 // parsePhones парсит телефоны
-func (s *bitrixService) parsePhones(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parsePhones(container *goquery.Selection, content map[string]interface{}) {
 	var phones []string
 	for _, selector := range s.config.HeaderSelectors.Phones {
-		container.Find(selector).Each(func(i int, s *goquery.Selection) {
-			phone := strings.TrimSpace(s.Text())
+		container.Find(selector).Each(func(i int, item *goquery.Selection) {
+			phone := strings.TrimSpace(item.Text())
 			if s.isValidPhone(phone) {
 				phones = append(phones, phone)
 			}
 		})
 		if len(phones) > 0 {
-			header.Components["phones"] = phones
+			content["phones"] = phones
 			break
 		}
 	}
 }
 
 // parseCart парсит корзину
-func (s *bitrixService) parseCart(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parseCart(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.HeaderSelectors.Cart {
 		if container.Find(selector).Length() > 0 {
-			header.Components["cart"] = true
+			content["cart"] = true
 			break
 		}
 	}
 }
 
 // parseAuth парсит авторизацию
-func (s *bitrixService) parseAuth(container *goquery.Selection, header *dto.Block) {
+func (s *bitrixService) parseAuth(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.HeaderSelectors.Auth {
 		if container.Find(selector).Length() > 0 {
-			header.Components["auth"] = true
+			content["auth"] = true
 			break
 		}
 	}
@@ -241,10 +238,14 @@ func (s *bitrixService) ParseFooter(html string) (*dto.Block, error) {
 		return nil, err
 	}
 
+	// Компоненты подвала собираются в Content блока
+	content := map[string]interface{}{
+		"version": s.detectBitrixVersion(html),
+	}
 	footer := &dto.Block{
-		Type:       "bitrix_footer",
-		Components: make(map[string]interface{}),
-		Version:    s.detectBitrixVersion(html),
+		BlockType: dto.BlockTypeFooter,
+		Platform:  dto.PlatformBitrix,
+		Content:   content,
 	}
 
 	// Поиск контейнера подвала
@@ -262,22 +263,22 @@ func (s *bitrixService) ParseFooter(html string) (*dto.Block, error) {
 	}
 
 	// Парсинг копирайта
-	s.parseCopyright(footerContainer, footer)
+	s.parseCopyright(footerContainer, content)
 
 	// Парсинг меню
-	s.parseFooterMenu(footerContainer, footer)
+	s.parseFooterMenu(footerContainer, content)
 
 	// Парсинг контактов
-	s.parseContacts(footerContainer, footer)
+	s.parseContacts(footerContainer, content)
 
 	// Парсинг соцсетей
-	s.parseSocial(footerContainer, footer)
+	s.parseSocial(footerContainer, content)
 
 	// Парсинг разработчика
-	s.parseDeveloper(footerContainer, footer)
+	s.parseDeveloper(footerContainer, content)
 
 	// Валидация результата
-	if !s.validateFooter(footer) {
+	if !s.validateFooter(content) {
 		s.logger.Warn("Footer validation failed")
 	}
 
@@ -285,17 +286,17 @@ func (s *bitrixService) ParseFooter(html string) (*dto.Block, error) {
 }
 
 // parseCopyright парсит копирайт
-func (s *bitrixService) parseCopyright(container *goquery.Selection, footer *dto.Block) {
+func (s *bitrixService) parseCopyright(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.FooterSelectors.Copyright {
 		if elem := container.Find(selector).First(); elem.Length() > 0 {
-			footer.Components["copyright"] = strings.TrimSpace(elem.Text())
+			content["copyright"] = strings.TrimSpace(elem.Text())
 			break
 		}
 	}
 }
 
 // parseFooterMenu парсит меню в подвале
-func (s *bitrixService) parseFooterMenu(container *goquery.Selection, footer *dto.Block) {
+func (s *bitrixService) parseFooterMenu(container *goquery.Selection, content map[string]interface{}) {
 	var menuItems []string
 	for _, selector := range s.config.FooterSelectors.Menu {
 		container.Find(selector).Each(func(i int, s *goquery.Selection) {
@@ -306,14 +307,14 @@ func (s *bitrixService) parseFooterMenu(container *goquery.Selection, footer *dt
 			})
 		})
 		if len(menuItems) > 0 {
-			footer.Components["menu"] = menuItems
+			content["menu"] = menuItems
 			break
 		}
 	}
 }
 
 // parseContacts парсит контакты
-func (s *bitrixService) parseContacts(container *goquery.Selection, footer *dto.Block) {
+func (s *bitrixService) parseContacts(container *goquery.Selection, content map[string]interface{}) {
 	var contacts []string
 	for _, selector := range s.config.FooterSelectors.Contacts {
 		container.Find(selector).Each(func(i int, s *goquery.Selection) {
@@ -325,14 +326,14 @@ func (s *bitrixService) parseContacts(container *goquery.Selection, footer *dto.
 			})
 		})
 		if len(contacts) > 0 {
-			footer.Components["contacts"] = contacts
+			content["contacts"] = contacts
 			break
 		}
 	}
 }
 
 // parseSocial парсит соцсети
-func (s *bitrixService) parseSocial(container *goquery.Selection, footer *dto.Block) {
+func (s *bitrixService) parseSocial(container *goquery.Selection, content map[string]interface{}) {
 	var socialLinks []string
 	for _, selector := range s.config.FooterSelectors.Social {
 		container.Find(selector).Each(func(i int, s *goquery.Selection) {
@@ -343,18 +344,18 @@ func (s *bitrixService) parseSocial(container *goquery.Selection, footer *dto.Bl
 			})
 		})
 		if len(socialLinks) > 0 {
-			footer.Components["social"] = socialLinks
+			content["social"] = socialLinks
 			break
 		}
 	}
 }
 
 // parseDeveloper парсит ссылку на разработчика
-func (s *bitrixService) parseDeveloper(container *goquery.Selection, footer *dto.Block) {
+func (s *bitrixService) parseDeveloper(container *goquery.Selection, content map[string]interface{}) {
 	for _, selector := range s.config.FooterSelectors.Developer {
 		if elem := container.Find(selector).First(); elem.Length() > 0 {
 			if href, exists := elem.Attr("href"); exists {
-				footer.Components["developer"] = href
+				content["developer"] = href
 				break
 			}
 		}
@@ -362,10 +363,10 @@ func (s *bitrixService) parseDeveloper(container *goquery.Selection, footer *dto
 }
 
 // validateHeader проверяет валидность шапки
-func (s *bitrixService) validateHeader(header *dto.Block) bool {
+func (s *bitrixService) validateHeader(content map[string]interface{}) bool {
 	required := []string{"logo", "menu"}
 	for _, field := range required {
-		if _, exists := header.Components[field]; !exists {
+		if _, exists := content[field]; !exists {
 			s.logger.Warn("Header validation failed - missing field", 
 				zap.String("field", field))
 			return false
@@ -375,8 +376,8 @@ func (s *bitrixService) validateHeader(header *dto.Block) bool {
 }
 
 // validateFooter проверяет валидность подвала
-func (s *bitrixService) validateFooter(footer *dto.Block) bool {
-	if _, exists := footer.Components["copyright"]; !exists {
+func (s *bitrixService) validateFooter(content map[string]interface{}) bool {
+	if _, exists := content["copyright"]; !exists {
 		s.logger.Warn("Footer validation failed - missing copyright")
 		return false
 	}
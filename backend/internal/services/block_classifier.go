@@ -0,0 +1,357 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"scrapper/internal/dto"
+)
+
+// minClassificationScore минимальная оценка, при которой метка считается найденной
+const minClassificationScore = 0.45
+
+// reClassPrice ищет цены в тексте блока
+var reClassPrice = regexp.MustCompile(`(?i)(?:[$€£₽]\s?\d[\d\s.,]*|\d[\d\s.,]*\s?(?:₽|руб|р\.|\$|€|usd|eur|rub))`)
+
+// reClassToken разбивает значения class и id на отдельные токены
+var reClassToken = regexp.MustCompile(`[\s_\-]+`)
+
+// blockFeatures признаки секции, по которым работают правила классификации
+type blockFeatures struct {
+	tags     map[string]int
+	tokens   []string
+	text     string
+	words    int
+	prices   int
+	buttons  int
+	position int
+}
+
+// classificationRule правило классификации: вес и проверка признаков
+type classificationRule struct {
+	weight float64
+	match  func(f *blockFeatures) (string, bool)
+}
+
+// blockCategories фиксированный порядок категорий для детерминированного выбора
+var blockCategories = []dto.BlockCategory{
+	dto.BlockCategoryHero,
+	dto.BlockCategoryFeatures,
+	dto.BlockCategoryPricing,
+	dto.BlockCategoryTestimonials,
+	dto.BlockCategoryFAQ,
+	dto.BlockCategoryTeam,
+	dto.BlockCategoryGallery,
+	dto.BlockCategoryContactForm,
+	dto.BlockCategoryCTA,
+	dto.BlockCategoryPartners,
+	dto.BlockCategoryBlogList,
+}
+
+// classificationRules правила для каждой категории
+var classificationRules = map[dto.BlockCategory][]classificationRule{
+	dto.BlockCategoryHero: {
+		featureRule("position:first", 0.35, func(f *blockFeatures) bool { return f.position == 0 }),
+		tagRule(0.4, "h1", 1),
+		tokenRule(0.5, "hero", "banner", "jumbotron", "intro", "masthead", "promo"),
+		featureRule("structure:short-text-with-button", 0.2, func(f *blockFeatures) bool { return f.buttons > 0 && f.words < 80 }),
+	},
+	dto.BlockCategoryFeatures: {
+		tokenRule(0.5, "feature", "benefit", "advantage", "service", "why", "preimushchestva", "preimushestva"),
+		textRule(0.3, "преимущества", "возможности", "почему мы", "features", "why choose", "benefits"),
+		featureRule("structure:repeated-subheadings", 0.3, func(f *blockFeatures) bool { return f.tags["h3"]+f.tags["h4"] >= 3 }),
+		tagRule(0.15, "svg", 3),
+	},
+	dto.BlockCategoryPricing: {
+		tokenRule(0.5, "price", "pricing", "tariff", "tarif", "tarify", "plan"),
+		textRule(0.3, "тариф", "стоимость", "pricing", "/мес", "в месяц", "per month", "/mo"),
+		featureRule("text:prices", 0.5, func(f *blockFeatures) bool { return f.prices >= 2 }),
+	},
+	dto.BlockCategoryTestimonials: {
+		tokenRule(0.5, "testimonial", "review", "otzyv", "otzyvy", "quote"),
+		textRule(0.35, "отзыв", "testimonial", "what our clients", "говорят клиенты"),
+		tagRule(0.3, "blockquote", 1),
+		featureRule("text:rating", 0.2, func(f *blockFeatures) bool { return strings.Contains(f.text, "★") }),
+	},
+	dto.BlockCategoryFAQ: {
+		tokenRule(0.5, "faq", "accordion", "question", "vopros", "voprosy"),
+		textRule(0.35, "частые вопросы", "вопросы и ответы", "faq", "frequently asked"),
+		tagRule(0.5, "details", 2),
+		featureRule("text:questions", 0.2, func(f *blockFeatures) bool { return strings.Count(f.text, "?") >= 3 }),
+	},
+	dto.BlockCategoryTeam: {
+		tokenRule(0.5, "team", "staff", "member", "person", "employee", "komanda", "sotrudniki"),
+		textRule(0.35, "наша команда", "команда", "сотрудники", "наши специалисты", "our team", "meet the team"),
+		featureRule("structure:portraits", 0.2, func(f *blockFeatures) bool { return f.tags["img"] >= 3 && f.tags["h3"]+f.tags["h4"] >= 3 }),
+	},
+	dto.BlockCategoryGallery: {
+		tokenRule(0.45, "gallery", "carousel", "lightbox", "portfolio", "photo", "galereya"),
+		textRule(0.2, "галерея", "фотографии", "портфолио", "gallery"),
+		featureRule("structure:image-grid", 0.45, func(f *blockFeatures) bool {
+			return f.tags["img"] >= 6 && f.words < f.tags["img"]*15
+		}),
+	},
+	dto.BlockCategoryContactForm: {
+		featureRule("structure:form-fields", 0.5, func(f *blockFeatures) bool {
+			return f.tags["form"] > 0 && (f.tags["input"] >= 2 || f.tags["textarea"] > 0)
+		}),
+		featureRule("structure:contact-inputs", 0.3, func(f *blockFeatures) bool { return f.tags["input:contact"] > 0 }),
+		tokenRule(0.3, "contact", "callback", "order", "request", "zayavka", "zayavki"),
+		textRule(0.3, "свяжитесь", "оставьте заявку", "обратный звонок", "contact us", "get in touch"),
+	},
+	dto.BlockCategoryCTA: {
+		tokenRule(0.5, "cta", "subscribe", "action"),
+		textRule(0.3, "начать", "попробуйте", "заказать", "записаться", "get started", "sign up", "try it"),
+		featureRule("structure:short-text-with-button", 0.35, func(f *blockFeatures) bool { return f.buttons > 0 && f.words < 60 }),
+	},
+	dto.BlockCategoryPartners: {
+		tokenRule(0.5, "partner", "client", "logos", "partnery", "brand", "sponsor"),
+		textRule(0.35, "партнер", "партнёр", "нам доверяют", "наши клиенты", "our clients", "trusted by"),
+		featureRule("structure:logo-strip", 0.3, func(f *blockFeatures) bool { return f.tags["img"] >= 4 && f.words < 40 }),
+	},
+	dto.BlockCategoryBlogList: {
+		tokenRule(0.45, "blog", "news", "post", "article", "novosti"),
+		textRule(0.3, "блог", "новости", "статьи", "читать далее", "подробнее", "read more"),
+		tagRule(0.45, "article", 2),
+		tagRule(0.3, "time", 2),
+	},
+}
+
+// blockClassifier реализация BlockClassifier
+type blockClassifier struct {
+}
+
+// NewBlockClassifier создает новый экземпляр BlockClassifier
+func NewBlockClassifier() BlockClassifier {
+	return &blockClassifier{}
+}
+
+// SplitSections делит страницу на контентные секции между шапкой и подвалом
+func (c *blockClassifier) SplitSections(html string) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Find("script, style, noscript, template").Remove()
+
+	// Основной контейнер страницы: <main>, если он есть, иначе <body>
+	container := doc.Find("main").First()
+	if container.Length() == 0 {
+		container = doc.Find("body").First()
+	}
+	if container.Length() == 0 {
+		return nil, nil
+	}
+
+	// Спускаемся через обертки, у которых единственный дочерний элемент
+	for {
+		children := container.Children().FilterFunction(func(i int, s *goquery.Selection) bool {
+			return !isLayoutChrome(s)
+		})
+		if children.Length() != 1 {
+			break
+		}
+		container = children.First()
+	}
+
+	var sections []string
+	container.Children().Each(func(i int, s *goquery.Selection) {
+		if isLayoutChrome(s) {
+			return
+		}
+
+		// Пропускаем пустые секции без текста, медиа и форм
+		if strings.TrimSpace(s.Text()) == "" && s.Find("img, picture, svg, video, iframe, form").Length() == 0 {
+			return
+		}
+
+		sectionHTML, err := goquery.OuterHtml(s)
+		if err != nil {
+			return
+		}
+		sections = append(sections, sectionHTML)
+	})
+
+	return sections, nil
+}
+
+// Classify определяет семантический тип секции
+func (c *blockClassifier) Classify(html string, position int) *dto.BlockClassification {
+	features, err := extractBlockFeatures(html, position)
+	if err != nil {
+		return &dto.BlockClassification{Category: dto.BlockCategoryUnknown}
+	}
+
+	best := &dto.BlockClassification{Category: dto.BlockCategoryUnknown}
+	for _, category := range blockCategories {
+		score, signals := scoreCategory(features, classificationRules[category])
+		if score > best.Score {
+			best = &dto.BlockClassification{
+				Category: category,
+				Score:    score,
+				Signals:  signals,
+			}
+		}
+	}
+
+	if best.Score < minClassificationScore {
+		return &dto.BlockClassification{
+			Category: dto.BlockCategoryUnknown,
+			Score:    best.Score,
+		}
+	}
+
+	return best
+}
+
+// scoreCategory объединяет сработавшие правила по схеме noisy-or
+func scoreCategory(f *blockFeatures, rules []classificationRule) (float64, []string) {
+	miss := 1.0
+	var signals []string
+
+	for _, rule := range rules {
+		if signal, ok := rule.match(f); ok {
+			miss *= 1 - rule.weight
+			signals = append(signals, signal)
+		}
+	}
+
+	return 1 - miss, signals
+}
+
+// extractBlockFeatures собирает признаки секции для правил классификации
+func extractBlockFeatures(html string, position int) (*blockFeatures, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &blockFeatures{
+		tags:     make(map[string]int),
+		position: position,
+	}
+
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		tag := goquery.NodeName(s)
+		f.tags[tag]++
+
+		if tag == "input" {
+			inputType, _ := s.Attr("type")
+			if inputType == "email" || inputType == "tel" {
+				f.tags["input:contact"]++
+			}
+		}
+
+		for _, attr := range []string{"class", "id"} {
+			if value, ok := s.Attr(attr); ok {
+				for _, token := range reClassToken.Split(strings.ToLower(value), -1) {
+					if token != "" {
+						f.tokens = append(f.tokens, token)
+					}
+				}
+			}
+		}
+
+		if tag == "button" || hasAnyToken(s, "btn", "button") {
+			f.buttons++
+		}
+	})
+
+	f.text = strings.ToLower(strings.Join(strings.Fields(doc.Find("body").Text()), " "))
+	f.words = len(strings.Fields(f.text))
+	f.prices = len(reClassPrice.FindAllString(f.text, -1))
+
+	return f, nil
+}
+
+// tokenRule срабатывает, если один из токенов class/id совпадает с ключевым словом
+// целиком или во множественном числе: "order" не срабатывает на "border"
+func tokenRule(weight float64, keywords ...string) classificationRule {
+	return classificationRule{
+		weight: weight,
+		match: func(f *blockFeatures) (string, bool) {
+			for _, token := range f.tokens {
+				for _, keyword := range keywords {
+					if isKeywordToken(token, keyword) {
+						return "class:" + keyword, true
+					}
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+// isKeywordToken сравнивает токен с ключевым словом с учетом окончаний -s/-es
+func isKeywordToken(token, keyword string) bool {
+	return token == keyword || token == keyword+"s" || token == keyword+"es"
+}
+
+// textRule срабатывает, если в тексте секции встречается одна из фраз
+func textRule(weight float64, phrases ...string) classificationRule {
+	return classificationRule{
+		weight: weight,
+		match: func(f *blockFeatures) (string, bool) {
+			for _, phrase := range phrases {
+				if strings.Contains(f.text, phrase) {
+					return "text:" + phrase, true
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+// tagRule срабатывает, если тег встречается не менее min раз
+func tagRule(weight float64, tag string, min int) classificationRule {
+	return classificationRule{
+		weight: weight,
+		match: func(f *blockFeatures) (string, bool) {
+			if f.tags[tag] >= min {
+				return fmt.Sprintf("tag:%s>=%d", tag, min), true
+			}
+			return "", false
+		},
+	}
+}
+
+// featureRule срабатывает по произвольному предикату над признаками
+func featureRule(signal string, weight float64, predicate func(f *blockFeatures) bool) classificationRule {
+	return classificationRule{
+		weight: weight,
+		match: func(f *blockFeatures) (string, bool) {
+			return signal, predicate(f)
+		},
+	}
+}
+
+// isLayoutChrome проверяет, является ли элемент шапкой, подвалом или навигацией
+func isLayoutChrome(s *goquery.Selection) bool {
+	switch goquery.NodeName(s) {
+	case "header", "footer", "nav", "script", "style", "noscript", "template":
+		return true
+	}
+	return hasAnyToken(s, "header", "footer")
+}
+
+// hasAnyToken проверяет, содержит ли class или id элемента одно из слов
+func hasAnyToken(s *goquery.Selection, keywords ...string) bool {
+	for _, attr := range []string{"class", "id"} {
+		value, ok := s.Attr(attr)
+		if !ok {
+			continue
+		}
+		for _, token := range reClassToken.Split(strings.ToLower(value), -1) {
+			for _, keyword := range keywords {
+				if token == keyword {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
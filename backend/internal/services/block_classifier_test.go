@@ -0,0 +1,195 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"scrapper/internal/dto"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		position int
+		want     dto.BlockCategory
+	}{
+		{
+			name:     "hero",
+			html:     `<section class="hero"><h1>Build sites faster</h1><p>Short intro</p><a class="btn">Start</a></section>`,
+			position: 0,
+			want:     dto.BlockCategoryHero,
+		},
+		{
+			name:     "pricing by prices in text",
+			html:     `<section><h2>Тарифы</h2><div><h3>Base</h3><p>990 руб</p></div><div><h3>Pro</h3><p>1990 руб</p></div></section>`,
+			position: 2,
+			want:     dto.BlockCategoryPricing,
+		},
+		{
+			name: "faq",
+			html: `<section id="faq"><h2>Частые вопросы</h2><details><summary>How?</summary></details>` +
+				`<details><summary>Why?</summary></details><details><summary>When?</summary></details></section>`,
+			position: 3,
+			want:     dto.BlockCategoryFAQ,
+		},
+		{
+			name: "contact form",
+			html: `<section><h2>Contact us</h2><form><input type="text" name="name"><input type="email" name="email">` +
+				`<textarea name="message"></textarea><button>Send</button></form></section>`,
+			position: 4,
+			want:     dto.BlockCategoryContactForm,
+		},
+		{
+			name:     "testimonials",
+			html:     `<section class="reviews-list"><blockquote>Great service</blockquote><blockquote>Loved it</blockquote></section>`,
+			position: 5,
+			want:     dto.BlockCategoryTestimonials,
+		},
+		{
+			name:     "plain text is unknown",
+			html:     `<section><p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.</p></section>`,
+			position: 3,
+			want:     dto.BlockCategoryUnknown,
+		},
+	}
+
+	classifier := NewBlockClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifier.Classify(tt.html, tt.position)
+			if got.Category != tt.want {
+				t.Errorf("Classify() = %s (score %.2f, signals %v), want %s", got.Category, got.Score, got.Signals, tt.want)
+			}
+			if got.Category != dto.BlockCategoryUnknown && got.Score < minClassificationScore {
+				t.Errorf("Classify() score = %.2f, want at least %.2f", got.Score, minClassificationScore)
+			}
+		})
+	}
+}
+
+func TestScoreCategory(t *testing.T) {
+	features := &blockFeatures{tags: map[string]int{"h1": 1}, position: 0}
+
+	tests := []struct {
+		name        string
+		rules       []classificationRule
+		wantScore   float64
+		wantSignals []string
+	}{
+		{
+			name:      "no rules",
+			wantScore: 0,
+		},
+		{
+			name:        "single rule",
+			rules:       []classificationRule{tagRule(0.4, "h1", 1)},
+			wantScore:   0.4,
+			wantSignals: []string{"tag:h1>=1"},
+		},
+		{
+			name: "noisy-or of matched rules",
+			rules: []classificationRule{
+				tagRule(0.4, "h1", 1),
+				featureRule("position:first", 0.5, func(f *blockFeatures) bool { return f.position == 0 }),
+			},
+			wantScore:   0.7,
+			wantSignals: []string{"tag:h1>=1", "position:first"},
+		},
+		{
+			name: "rules that do not match are ignored",
+			rules: []classificationRule{
+				tagRule(0.4, "h2", 1),
+				tokenRule(0.5, "hero"),
+			},
+			wantScore: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, signals := scoreCategory(features, tt.rules)
+			if math.Abs(score-tt.wantScore) > 1e-9 {
+				t.Errorf("scoreCategory() score = %v, want %v", score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(signals, tt.wantSignals) {
+				t.Errorf("scoreCategory() signals = %v, want %v", signals, tt.wantSignals)
+			}
+		})
+	}
+}
+
+func TestTokenRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokens   []string
+		keywords []string
+		want     string
+	}{
+		{name: "whole token", tokens: []string{"section", "order"}, keywords: []string{"order"}, want: "class:order"},
+		{name: "plural token", tokens: []string{"features"}, keywords: []string{"feature"}, want: "class:feature"},
+		{name: "es plural token", tokens: []string{"prices"}, keywords: []string{"price"}, want: "class:price"},
+		{name: "border is not order", tokens: []string{"border"}, keywords: []string{"order"}},
+		{name: "transaction is not action", tokens: []string{"transaction"}, keywords: []string{"action"}},
+		{name: "planet is not plan", tokens: []string{"planet"}, keywords: []string{"plan"}},
+		{name: "poster is not post", tokens: []string{"poster"}, keywords: []string{"post"}},
+		{name: "no tokens", keywords: []string{"hero"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal, ok := tokenRule(0.5, tt.keywords...).match(&blockFeatures{tokens: tt.tokens})
+			if ok != (tt.want != "") || signal != tt.want {
+				t.Errorf("tokenRule() = %q %v, want %q", signal, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "header, nav and footer are skipped",
+			html: `<body><header>Logo</header><nav>Menu</nav><section>One</section><section>Two</section><footer>Contacts</footer></body>`,
+			want: []string{"<section>One</section>", "<section>Two</section>"},
+		},
+		{
+			name: "main is preferred over body",
+			html: `<body><div>Outside</div><main><section>Inside</section><section>Second</section></main></body>`,
+			want: []string{"<section>Inside</section>", "<section>Second</section>"},
+		},
+		{
+			name: "single child wrappers are unwrapped",
+			html: `<body><div class="wrapper"><div class="container"><section>A</section><section>B</section></div></div></body>`,
+			want: []string{"<section>A</section>", "<section>B</section>"},
+		},
+		{
+			name: "empty sections are skipped, media is kept",
+			html: `<body><section> </section><section><img src="a.png"/></section><section>Text</section></body>`,
+			want: []string{`<section><img src="a.png"/></section>`, "<section>Text</section>"},
+		},
+		{
+			name: "header by class is skipped",
+			html: `<body><div class="site-header">Logo</div><section>One</section><section>Two</section><div class="page-footer">End</div></body>`,
+			want: []string{"<section>One</section>", "<section>Two</section>"},
+		},
+	}
+
+	classifier := NewBlockClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := classifier.SplitSections(tt.html)
+			if err != nil {
+				t.Fatalf("SplitSections() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitSections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// naiveBayesAlgorithm название алгоритма обучаемой модели
+const naiveBayesAlgorithm = "naive_bayes"
+
+// maxWordFeatures ограничивает число текстовых признаков одного блока
+const maxWordFeatures = 200
+
+// modelSignalsLimit количество признаков, объясняющих предсказание модели
+const modelSignalsLimit = 5
+
+// ErrBlockNotFound возвращается, если блок не найден
+var ErrBlockNotFound = errors.New("block not found")
+
+// ErrUnknownCategory возвращается при разметке блока категорией, которой нет в классификаторе
+var ErrUnknownCategory = errors.New("unknown block category")
+
+// ErrModelNotFound возвращается, если версии модели классификатора нет
+var ErrModelNotFound = errors.New("classifier model not found")
+
+// reModelWord выделяет слова из текста блока
+var reModelWord = regexp.MustCompile(`[\p{L}]{3,}`)
+
+// classifierTrainer реализация ClassifierTrainer
+type classifierTrainer struct {
+	logger *zap.Logger
+	repo   repos.ParserRepo
+	models repos.ClassifierRepo
+}
+
+// NewClassifierTrainer создает новый экземпляр ClassifierTrainer
+func NewClassifierTrainer(logger *zap.Logger, repo repos.ParserRepo, models repos.ClassifierRepo) ClassifierTrainer {
+	return &classifierTrainer{
+		logger: logger,
+		repo:   repo,
+		models: models,
+	}
+}
+
+// LabelBlock исправляет тип сохраненного блока
+func (t *classifierTrainer) LabelBlock(ctx context.Context, blockID uuid.UUID, category dto.BlockCategory) (*dto.BlockLabel, error) {
+	if !isKnownCategory(category) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCategory, category)
+	}
+
+	if _, err := t.repo.GetBlockByID(ctx, blockID); err != nil {
+		if errors.Is(err, repos.ErrBlockNotFound) {
+			return nil, ErrBlockNotFound
+		}
+		return nil, err
+	}
+
+	label := &dto.BlockLabel{
+		BlockID:  blockID,
+		Category: category,
+	}
+
+	if err := t.models.SaveBlockLabel(ctx, label); err != nil {
+		t.logger.Error("Failed to save block label", zap.Error(err))
+		return nil, err
+	}
+
+	return label, nil
+}
+
+// Train обучает новую версию модели по размеченным блокам и делает ее активной
+func (t *classifierTrainer) Train(ctx context.Context) (*dto.TrainingRun, error) {
+	run, err := t.models.CreateTrainingRun(ctx)
+	if err != nil {
+		t.logger.Error("Failed to create training run", zap.Error(err))
+		return nil, err
+	}
+
+	model, err := t.train(ctx, run)
+	if err != nil {
+		run.Status = dto.TrainingStatusError
+		run.Error = err.Error()
+	} else {
+		run.Status = dto.TrainingStatusCompleted
+		run.ModelID = &model.ID
+	}
+
+	if finishErr := t.models.FinishTrainingRun(ctx, run); finishErr != nil {
+		t.logger.Error("Failed to finish training run", zap.Error(finishErr))
+		return nil, finishErr
+	}
+
+	if err != nil {
+		return run, err
+	}
+
+	t.logger.Info("Classifier model trained",
+		zap.Int("version", model.Version),
+		zap.Int("samples", run.Samples),
+		zap.Float64("accuracy", run.Accuracy),
+	)
+
+	return run, nil
+}
+
+// train выполняет обучение и сохраняет модель
+func (t *classifierTrainer) train(ctx context.Context, run *dto.TrainingRun) (*dto.ClassifierModel, error) {
+	samples, err := t.models.GetLabelledBlocks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no labelled blocks to train on")
+	}
+
+	bags := make([][]string, len(samples))
+	for i, sample := range samples {
+		bags[i] = blockFeatureBag(sample.HTML)
+	}
+
+	run.Samples = len(samples)
+	run.Accuracy = evaluateNaiveBayes(samples, bags)
+
+	model := &dto.ClassifierModel{
+		Algorithm:  naiveBayesAlgorithm,
+		Samples:    len(samples),
+		Accuracy:   run.Accuracy,
+		Parameters: trainNaiveBayes(samples, bags),
+	}
+
+	if err := t.models.SaveModel(ctx, model); err != nil {
+		return nil, err
+	}
+
+	if err := t.models.ActivateModel(ctx, model.Version); err != nil {
+		return nil, err
+	}
+	model.Active = true
+
+	return model, nil
+}
+
+// ListModels возвращает все версии моделей
+func (t *classifierTrainer) ListModels(ctx context.Context) ([]dto.ClassifierModel, error) {
+	return t.models.ListModels(ctx)
+}
+
+// ActivateModel откатывает классификатор на указанную версию модели
+func (t *classifierTrainer) ActivateModel(ctx context.Context, version int) error {
+	if err := t.models.ActivateModel(ctx, version); err != nil {
+		if errors.Is(err, repos.ErrModelNotFound) {
+			return ErrModelNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// blockFeatureBag собирает признаки блока для статистической модели: теги, токены классов и слова
+func blockFeatureBag(html string) []string {
+	f, err := extractBlockFeatures(html, -1)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var bag []string
+	add := func(feature string) {
+		if !seen[feature] {
+			seen[feature] = true
+			bag = append(bag, feature)
+		}
+	}
+
+	for tag := range f.tags {
+		add("tag:" + tag)
+	}
+	for _, token := range f.tokens {
+		add("class:" + token)
+	}
+
+	words := 0
+	for _, word := range reModelWord.FindAllString(f.text, -1) {
+		if words >= maxWordFeatures {
+			break
+		}
+		if !seen["word:"+word] {
+			add("word:" + word)
+			words++
+		}
+	}
+
+	if f.prices >= 2 {
+		add("text:prices")
+	}
+
+	sort.Strings(bag)
+	return bag
+}
+
+// trainNaiveBayes обучает мультиномиальный наивный байесовский классификатор со сглаживанием Лапласа
+func trainNaiveBayes(samples []dto.LabelledBlock, bags [][]string) *dto.NaiveBayesParameters {
+	docs := make(map[dto.BlockCategory]int)
+	counts := make(map[dto.BlockCategory]map[string]int)
+	totals := make(map[dto.BlockCategory]int)
+	vocabulary := make(map[string]bool)
+
+	for i, sample := range samples {
+		docs[sample.Category]++
+		if counts[sample.Category] == nil {
+			counts[sample.Category] = make(map[string]int)
+		}
+		for _, feature := range bags[i] {
+			counts[sample.Category][feature]++
+			totals[sample.Category]++
+			vocabulary[feature] = true
+		}
+	}
+
+	params := &dto.NaiveBayesParameters{
+		Priors:      make(map[dto.BlockCategory]float64),
+		Likelihoods: make(map[dto.BlockCategory]map[string]float64),
+		Unseen:      make(map[dto.BlockCategory]float64),
+	}
+
+	for category, n := range docs {
+		params.Priors[category] = math.Log(float64(n+1) / float64(len(samples)+len(docs)))
+
+		denominator := float64(totals[category] + len(vocabulary))
+		params.Unseen[category] = math.Log(1 / denominator)
+		params.Likelihoods[category] = make(map[string]float64, len(counts[category]))
+		for feature, count := range counts[category] {
+			params.Likelihoods[category][feature] = math.Log(float64(count+1) / denominator)
+		}
+	}
+
+	return params
+}
+
+// predictNaiveBayes предсказывает категорию блока по активной модели
+func predictNaiveBayes(model *dto.ClassifierModel, html string) *dto.BlockClassification {
+	params := model.Parameters
+	if params == nil || len(params.Priors) == 0 {
+		return nil
+	}
+
+	bag := blockFeatureBag(html)
+
+	// Категории в фиксированном порядке, чтобы результат был детерминированным
+	categories := make([]dto.BlockCategory, 0, len(params.Priors))
+	for category := range params.Priors {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+
+	logScores := make([]float64, len(categories))
+	for i, category := range categories {
+		logScores[i] = params.Priors[category]
+		for _, feature := range bag {
+			if ll, ok := params.Likelihoods[category][feature]; ok {
+				logScores[i] += ll
+			} else if knownFeature(params, feature) {
+				logScores[i] += params.Unseen[category]
+			}
+		}
+	}
+
+	// Апостериорная вероятность через softmax
+	best := 0
+	maxScore := math.Inf(-1)
+	for i, score := range logScores {
+		if score > maxScore {
+			maxScore = score
+			best = i
+		}
+	}
+	var sum float64
+	for _, score := range logScores {
+		sum += math.Exp(score - maxScore)
+	}
+
+	category := categories[best]
+
+	return &dto.BlockClassification{
+		Category: category,
+		Score:    1 / sum,
+		Signals:  append([]string{fmt.Sprintf("model:v%d", model.Version)}, topModelSignals(params, category, bag)...),
+	}
+}
+
+// topModelSignals возвращает признаки, сильнее всего повлиявшие на выбор категории
+func topModelSignals(params *dto.NaiveBayesParameters, category dto.BlockCategory, bag []string) []string {
+	type contribution struct {
+		feature string
+		weight  float64
+	}
+
+	var contributions []contribution
+	for _, feature := range bag {
+		ll, ok := params.Likelihoods[category][feature]
+		if !ok {
+			continue
+		}
+
+		// Сравниваем со средним правдоподобием признака в остальных категориях
+		var others float64
+		n := 0
+		for other := range params.Priors {
+			if other == category {
+				continue
+			}
+			if otherLL, ok := params.Likelihoods[other][feature]; ok {
+				others += otherLL
+			} else {
+				others += params.Unseen[other]
+			}
+			n++
+		}
+		if n > 0 {
+			ll -= others / float64(n)
+		}
+
+		if ll > 0 {
+			contributions = append(contributions, contribution{feature: feature, weight: ll})
+		}
+	}
+
+	sort.Slice(contributions, func(i, j int) bool {
+		if contributions[i].weight == contributions[j].weight {
+			return contributions[i].feature < contributions[j].feature
+		}
+		return contributions[i].weight > contributions[j].weight
+	})
+
+	var signals []string
+	for i := 0; i < len(contributions) && i < modelSignalsLimit; i++ {
+		signals = append(signals, contributions[i].feature)
+	}
+
+	return signals
+}
+
+// evaluateNaiveBayes оценивает точность модели на отложенной выборке (каждый пятый пример)
+func evaluateNaiveBayes(samples []dto.LabelledBlock, bags [][]string) float64 {
+	var trainSamples, testSamples []dto.LabelledBlock
+	var trainBags [][]string
+
+	for i, sample := range samples {
+		if len(samples) >= 10 && i%5 == 4 {
+			testSamples = append(testSamples, sample)
+			continue
+		}
+		trainSamples = append(trainSamples, sample)
+		trainBags = append(trainBags, bags[i])
+	}
+
+	// На маленькой выборке оцениваем точность на обучающих данных
+	if len(testSamples) == 0 {
+		testSamples = trainSamples
+	}
+
+	model := &dto.ClassifierModel{Parameters: trainNaiveBayes(trainSamples, trainBags)}
+
+	correct := 0
+	for _, sample := range testSamples {
+		if prediction := predictNaiveBayes(model, sample.HTML); prediction != nil && prediction.Category == sample.Category {
+			correct++
+		}
+	}
+
+	return float64(correct) / float64(len(testSamples))
+}
+
+// knownFeature проверяет, встречался ли признак хотя бы в одной категории при обучении
+func knownFeature(params *dto.NaiveBayesParameters, feature string) bool {
+	for _, likelihoods := range params.Likelihoods {
+		if _, ok := likelihoods[feature]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownCategory проверяет, входит ли категория в фиксированную таксономию
+func isKnownCategory(category dto.BlockCategory) bool {
+	for _, known := range blockCategories {
+		if known == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"scrapper/internal/dto"
+)
+
+func TestBlockFeatureBag(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "tags, class tokens and words",
+			html: `<section class="price-table"><h2>Our plans</h2></section>`,
+			want: []string{"class:price", "class:table", "tag:h2", "tag:section", "word:our", "word:plans"},
+		},
+		{
+			name: "short and repeated words are skipped",
+			html: `<p>to be or not to be, faq faq</p>`,
+			want: []string{"tag:p", "word:faq", "word:not"},
+		},
+		{
+			name: "prices in text",
+			html: `<div>100 руб and 200 руб</div>`,
+			want: []string{"tag:div", "text:prices", "word:and", "word:руб"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockFeatureBag(tt.html); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blockFeatureBag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrainNaiveBayes(t *testing.T) {
+	samples := []dto.LabelledBlock{
+		{Category: dto.BlockCategoryFAQ},
+		{Category: dto.BlockCategoryFAQ},
+		{Category: dto.BlockCategoryTeam},
+	}
+	bags := [][]string{
+		{"word:question", "tag:details"},
+		{"word:question"},
+		{"word:team"},
+	}
+
+	params := trainNaiveBayes(samples, bags)
+
+	// Лаплас по документам: (2+1)/(3+2) и (1+1)/(3+2)
+	wantPriors := map[dto.BlockCategory]float64{
+		dto.BlockCategoryFAQ:  math.Log(3.0 / 5),
+		dto.BlockCategoryTeam: math.Log(2.0 / 5),
+	}
+	for category, want := range wantPriors {
+		if got := params.Priors[category]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("prior[%s] = %v, want %v", category, got, want)
+		}
+	}
+
+	// Словарь из трех признаков: у FAQ три вхождения, у Team одно
+	wantLikelihoods := map[dto.BlockCategory]map[string]float64{
+		dto.BlockCategoryFAQ:  {"word:question": math.Log(3.0 / 6), "tag:details": math.Log(2.0 / 6)},
+		dto.BlockCategoryTeam: {"word:team": math.Log(2.0 / 4)},
+	}
+	for category, features := range wantLikelihoods {
+		if len(params.Likelihoods[category]) != len(features) {
+			t.Errorf("likelihoods[%s] = %v, want %v", category, params.Likelihoods[category], features)
+		}
+		for feature, want := range features {
+			if got := params.Likelihoods[category][feature]; math.Abs(got-want) > 1e-9 {
+				t.Errorf("likelihood[%s][%s] = %v, want %v", category, feature, got, want)
+			}
+		}
+	}
+
+	if got, want := params.Unseen[dto.BlockCategoryTeam], math.Log(1.0/4); math.Abs(got-want) > 1e-9 {
+		t.Errorf("unseen[team] = %v, want %v", got, want)
+	}
+}
+
+func TestPredictNaiveBayes(t *testing.T) {
+	samples := []dto.LabelledBlock{
+		{Category: dto.BlockCategoryFAQ, HTML: `<section class="faq"><h2>Questions</h2><details>Delivery question</details></section>`},
+		{Category: dto.BlockCategoryFAQ, HTML: `<div class="faq-list"><details>Payment question</details></div>`},
+		{Category: dto.BlockCategoryTeam, HTML: `<section class="team"><h3>Anna</h3><img src="a.jpg"><h3>Ivan</h3></section>`},
+		{Category: dto.BlockCategoryTeam, HTML: `<div class="team-grid"><img src="b.jpg"><p>Designer</p></div>`},
+	}
+	bags := make([][]string, len(samples))
+	for i, sample := range samples {
+		bags[i] = blockFeatureBag(sample.HTML)
+	}
+	model := &dto.ClassifierModel{Version: 3, Parameters: trainNaiveBayes(samples, bags)}
+
+	tests := []struct {
+		name string
+		html string
+		want dto.BlockCategory
+	}{
+		{name: "faq", html: `<div class="faq"><details>Return question</details></div>`, want: dto.BlockCategoryFAQ},
+		{name: "team", html: `<section class="team"><img src="c.jpg"><h3>Oleg</h3></section>`, want: dto.BlockCategoryTeam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := predictNaiveBayes(model, tt.html)
+			if got == nil {
+				t.Fatalf("predictNaiveBayes() = nil")
+			}
+			if got.Category != tt.want {
+				t.Errorf("predictNaiveBayes() = %s, want %s", got.Category, tt.want)
+			}
+			if got.Score <= 0.5 || got.Score > 1 {
+				t.Errorf("predictNaiveBayes() score = %v, want (0.5, 1]", got.Score)
+			}
+			if len(got.Signals) == 0 || got.Signals[0] != "model:v3" {
+				t.Errorf("predictNaiveBayes() signals = %v, want model version first", got.Signals)
+			}
+		})
+	}
+
+	if got := predictNaiveBayes(&dto.ClassifierModel{}, "<p>x</p>"); got != nil {
+		t.Errorf("predictNaiveBayes() without parameters = %v, want nil", got)
+	}
+}
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// maxManifestIcons количество иконок из web manifest, которые скачиваются
+const maxManifestIcons = 8
+
+// brandAssetRef ссылка на файл фирменного набора, найденная на странице
+type brandAssetRef struct {
+	kind dto.AssetKind
+	url  string
+}
+
+// webManifest поля web manifest, нужные для фирменного набора
+type webManifest struct {
+	Name            string `json:"name"`
+	ShortName       string `json:"short_name"`
+	ThemeColor      string `json:"theme_color"`
+	BackgroundColor string `json:"background_color"`
+	Icons           []struct {
+		Src   string `json:"src"`
+		Sizes string `json:"sizes"`
+		Type  string `json:"type"`
+	} `json:"icons"`
+}
+
+// brandKitService реализация BrandKitService
+type brandKitService struct {
+	logger  *zap.Logger
+	repo    repos.ParserRepo
+	assets  repos.AssetRepo
+	storage repos.BlobStorage
+	client  *http.Client
+
+	// platforms парсеры платформ, у которых шапка содержит логотип
+	platforms []PlatformService
+}
+
+// NewBrandKitService создает новый экземпляр BrandKitService
+func NewBrandKitService(
+	logger *zap.Logger,
+	repo repos.ParserRepo,
+	assets repos.AssetRepo,
+	storage repos.BlobStorage,
+	wordpressService WordPressService,
+	bitrixService BitrixService,
+) BrandKitService {
+	return &brandKitService{
+		logger:    logger,
+		repo:      repo,
+		assets:    assets,
+		storage:   storage,
+		client:    newAssetClient(),
+		platforms: []PlatformService{wordpressService, bitrixService},
+	}
+}
+
+// CollectBrandKit находит на странице логотип, иконки, web manifest и og:image, скачивает файлы и сохраняет их в операции
+func (s *brandKitService) CollectBrandKit(ctx context.Context, operationID uuid.UUID, pageURL, html string) (*dto.BrandKit, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page url: %w", err)
+	}
+
+	// Относительные ссылки считаются от <base href>, если он задан
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if resolved, ok := resolveURL(base, href); ok {
+			base, _ = url.Parse(resolved)
+		}
+	}
+
+	kit := &dto.BrandKit{
+		OperationID: operationID,
+		Brand:       &dto.BrandInfo{},
+		Assets:      []dto.Asset{},
+	}
+
+	refs := s.findBrandAssets(ctx, doc, html, base, kit.Brand)
+
+	// Скачиваем файлы, одна ссылка скачивается один раз
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.url] {
+			continue
+		}
+		seen[ref.url] = true
+
+		asset, err := newStoredAsset(ctx, s.client, s.storage, operationID, ref.kind, ref.url, maxBrandAssetSize)
+		if err != nil {
+			s.logger.Warn("Failed to download brand asset", zap.Error(err), zap.String("kind", string(ref.kind)), zap.String("url", ref.url))
+			continue
+		}
+
+		if !strings.HasPrefix(asset.ContentType, "image/") {
+			s.logger.Warn("Brand asset is not an image", zap.String("url", ref.url), zap.String("content_type", asset.ContentType))
+			continue
+		}
+
+		if err := s.assets.SaveAsset(ctx, asset); err != nil {
+			s.logger.Error("Failed to save brand asset", zap.Error(err))
+			continue
+		}
+
+		kit.Assets = append(kit.Assets, *asset)
+	}
+
+	if *kit.Brand == (dto.BrandInfo{}) {
+		kit.Brand = nil
+	} else if err := s.assets.UpdateOperationBrand(ctx, operationID, kit.Brand); err != nil {
+		s.logger.Error("Failed to save operation brand", zap.Error(err))
+	}
+
+	return kit, nil
+}
+
+// findBrandAssets собирает ссылки на файлы фирменного набора и заполняет фирменные параметры
+func (s *brandKitService) findBrandAssets(ctx context.Context, doc *goquery.Document, html string, base *url.URL, brand *dto.BrandInfo) []brandAssetRef {
+	var refs []brandAssetRef
+	add := func(kind dto.AssetKind, ref string) {
+		if resolved, ok := resolveURL(base, ref); ok {
+			refs = append(refs, brandAssetRef{kind: kind, url: resolved})
+		}
+	}
+
+	// Логотип: сначала тот, что нашел парсер платформы, иначе по признакам на странице
+	if logo := s.platformLogo(html); logo != "" {
+		add(dto.AssetKindLogo, logo)
+	} else if logo, ok := findLogo(doc); ok {
+		add(dto.AssetKindLogo, logo)
+	}
+
+	// Иконки из <link rel>
+	hasFavicon := false
+	doc.Find("link[rel][href]").Each(func(i int, link *goquery.Selection) {
+		rel := strings.Fields(strings.ToLower(link.AttrOr("rel", "")))
+		href := link.AttrOr("href", "")
+
+		for _, value := range rel {
+			switch value {
+			case "icon":
+				hasFavicon = true
+				add(dto.AssetKindFavicon, href)
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				add(dto.AssetKindAppleTouchIcon, href)
+			case "manifest":
+				if manifestURL, ok := resolveURL(base, href); ok {
+					refs = append(refs, s.manifestAssets(ctx, manifestURL, brand)...)
+				}
+			}
+		}
+	})
+
+	// Браузеры запрашивают /favicon.ico, если иконка не указана явно
+	if !hasFavicon {
+		add(dto.AssetKindFavicon, "/favicon.ico")
+	}
+
+	// Изображение для соцсетей
+	doc.Find(`meta[property="og:image"], meta[property="og:image:url"], meta[name="og:image"]`).EachWithBreak(func(i int, meta *goquery.Selection) bool {
+		add(dto.AssetKindOGImage, meta.AttrOr("content", ""))
+		return false
+	})
+
+	// theme-color из мета-тега, если его не задал манифест
+	if brand.ThemeColor == "" {
+		brand.ThemeColor = strings.TrimSpace(doc.Find(`meta[name="theme-color"]`).First().AttrOr("content", ""))
+	}
+
+	if brand.Name == "" {
+		brand.Name = strings.TrimSpace(doc.Find(`meta[property="og:site_name"], meta[name="application-name"]`).First().AttrOr("content", ""))
+	}
+
+	return refs
+}
+
+// manifestAssets скачивает web manifest, заполняет название и цвета и возвращает ссылки на иконки
+func (s *brandKitService) manifestAssets(ctx context.Context, manifestURL string, brand *dto.BrandInfo) []brandAssetRef {
+	fetched, err := fetchAsset(ctx, s.client, manifestURL, maxBrandAssetSize)
+	if err != nil {
+		s.logger.Warn("Failed to download web manifest", zap.Error(err), zap.String("url", manifestURL))
+		return nil
+	}
+
+	var manifest webManifest
+	if err := json.Unmarshal(fetched.data, &manifest); err != nil {
+		s.logger.Warn("Failed to parse web manifest", zap.Error(err), zap.String("url", manifestURL))
+		return nil
+	}
+
+	brand.ManifestURL = manifestURL
+	brand.Name = manifest.Name
+	if brand.Name == "" {
+		brand.Name = manifest.ShortName
+	}
+	brand.ThemeColor = manifest.ThemeColor
+	brand.BackgroundColor = manifest.BackgroundColor
+
+	// Иконки манифеста указываются относительно самого манифеста
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil
+	}
+
+	var refs []brandAssetRef
+	for _, icon := range manifest.Icons {
+		if len(refs) >= maxManifestIcons {
+			break
+		}
+		if resolved, ok := resolveURL(base, icon.Src); ok {
+			refs = append(refs, brandAssetRef{kind: dto.AssetKindManifestIcon, url: resolved})
+		}
+	}
+
+	return refs
+}
+
+// GetBrandKit возвращает сохраненный фирменный набор операции
+func (s *brandKitService) GetBrandKit(ctx context.Context, operationID uuid.UUID) (*dto.BrandKit, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.assets.GetAssetsByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	kit := &dto.BrandKit{
+		OperationID: operationID,
+		Brand:       operation.Brand,
+		Assets:      []dto.Asset{},
+	}
+
+	for _, asset := range assets {
+		switch asset.Kind {
+		case dto.AssetKindLogo, dto.AssetKindFavicon, dto.AssetKindAppleTouchIcon, dto.AssetKindManifestIcon, dto.AssetKindOGImage:
+			kit.Assets = append(kit.Assets, asset)
+		}
+	}
+
+	return kit, nil
+}
+
+// OpenAsset открывает содержимое файла операции
+func (s *brandKitService) OpenAsset(ctx context.Context, operationID, assetID uuid.UUID) (*dto.Asset, io.ReadCloser, error) {
+	asset, err := s.assets.GetAssetByID(ctx, assetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if asset.OperationID != operationID {
+		return nil, nil, ErrAssetNotFound
+	}
+
+	reader, err := s.storage.Open(ctx, asset.StorageKey)
+	if err != nil {
+		if errors.Is(err, repos.ErrBlobNotFound) {
+			return nil, nil, ErrAssetNotFound
+		}
+		return nil, nil, err
+	}
+
+	return asset, reader, nil
+}
+
+// platformLogo возвращает ссылку на логотип из шапки, разобранной парсером платформы сайта.
+// Ссылка берется как есть из разметки и разрешается относительно страницы вызывающим кодом
+func (s *brandKitService) platformLogo(html string) string {
+	for _, platform := range s.platforms {
+		if !platform.DetectPlatform(html) {
+			continue
+		}
+
+		header, err := platform.ParseHeader(html)
+		if err != nil || header == nil {
+			return ""
+		}
+
+		content, _ := header.Content.(map[string]interface{})
+		logo, _ := content["logo"].(string)
+		return logo
+	}
+
+	return ""
+}
+
+// findLogo находит ссылку на логотип сайта: изображение или встроенный svg с признаками логотипа
+func findLogo(doc *goquery.Document) (string, bool) {
+	best := ""
+	bestScore := 0
+
+	doc.Find("img, svg").Each(func(i int, sel *goquery.Selection) {
+		score := logoScore(sel)
+		if score <= bestScore {
+			return
+		}
+
+		if src := logoSource(sel); src != "" {
+			best = src
+			bestScore = score
+		}
+	})
+
+	return best, best != ""
+}
+
+// logoScore оценивает, насколько элемент похож на логотип сайта
+func logoScore(sel *goquery.Selection) int {
+	score := 0
+
+	// Признак logo у самого элемента и у ближайших предков
+	if containsLogo(sel.AttrOr("class", ""), sel.AttrOr("id", ""), sel.AttrOr("alt", ""), sel.AttrOr("src", "")) {
+		score += 3
+	}
+	parent := sel.Parent()
+	for depth := 0; depth < 3 && parent.Length() > 0; depth++ {
+		if containsLogo(parent.AttrOr("class", ""), parent.AttrOr("id", "")) {
+			score += 2
+			break
+		}
+		parent = parent.Parent()
+	}
+
+	if score == 0 {
+		return 0
+	}
+
+	// Логотип обычно в шапке и ведет на главную
+	if sel.ParentsFiltered("header, [role=banner]").Length() > 0 {
+		score += 2
+	}
+	if link := sel.ParentsFiltered("a").First(); link.Length() > 0 && isHomeLink(link.AttrOr("href", "")) {
+		score++
+	}
+
+	// Логотипы партнеров и клиентов в подвале и в полосах логотипов
+	if sel.ParentsFiltered("footer").Length() > 0 {
+		score -= 2
+	}
+	if sel.Parent().Parent().Find("img").Length() > 3 {
+		score -= 2
+	}
+
+	return score
+}
+
+// isHomeLink проверяет, ведет ли ссылка на главную страницу сайта
+func isHomeLink(href string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || parsed.RawQuery != "" {
+		return false
+	}
+
+	switch parsed.Path {
+	case "", "/", "./", "/index.html", "/index.php":
+		return parsed.Path != "" || parsed.Host != ""
+	}
+
+	return false
+}
+
+// containsLogo проверяет, упоминается ли logo в значениях атрибутов
+func containsLogo(values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), "logo") {
+			return true
+		}
+	}
+	return false
+}
+
+// imageSource возвращает адрес картинки с учетом ленивой загрузки и srcset
+func imageSource(sel *goquery.Selection) string {
+	for _, attr := range []string{"src", "data-src", "data-lazy-src"} {
+		if src := strings.TrimSpace(sel.AttrOr(attr, "")); src != "" && !strings.HasPrefix(src, "data:image/gif") {
+			return src
+		}
+	}
+
+	if candidate := strings.Fields(strings.Split(sel.AttrOr("srcset", ""), ",")[0]); len(candidate) > 0 {
+		return candidate[0]
+	}
+
+	return ""
+}
+
+// logoSource возвращает ссылку на изображение логотипа: сам img или svg либо первое изображение внутри элемента
+func logoSource(sel *goquery.Selection) string {
+	if !sel.Is("img, svg") {
+		sel = sel.Find("img, svg").First()
+	}
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	if goquery.NodeName(sel) == "svg" {
+		return inlineSVGDataURL(sel)
+	}
+	return imageSource(sel)
+}
+
+// inlineSVGDataURL превращает встроенный svg в data: URL, чтобы сохранить его как файл
+func inlineSVGDataURL(sel *goquery.Selection) string {
+	markup, err := goquery.OuterHtml(sel)
+	if err != nil || markup == "" {
+		return ""
+	}
+
+	markup = stripRenderAttributes(markup)
+	if !strings.Contains(markup, "xmlns=") {
+		markup = strings.Replace(markup, "<svg", `<svg xmlns="http://www.w3.org/2000/svg"`, 1)
+	}
+
+	return "data:image/svg+xml," + url.PathEscape(markup)
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// operationBundle реализация OperationBundle
+type operationBundle struct {
+	service   *downloaderService
+	result    *dto.GetOperationResultResponse
+	assets    *dto.AssetManifest
+	exporters []Exporter
+	base      *url.URL
+	manifest  dto.BundleManifest
+
+	// assetFiles путь файла в архиве по исходному URL
+	assetFiles map[string]string
+
+	// pages стили и снимки страницы по профилям устройств для автономных блоков
+	pages map[dto.DeviceProfile]*standalonePage
+}
+
+// PrepareBundle собирает данные операции для ZIP-архива; архив пишется потоково через OperationBundle
+func (s *downloaderService) PrepareBundle(ctx context.Context, operationID uuid.UUID, formats []string) (OperationBundle, error) {
+	exporters := make([]Exporter, 0, len(formats))
+	for _, format := range formats {
+		exporter, err := s.exporters.Get(ctx, format)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, exporter)
+	}
+
+	result, err := s.parserService.GetOperationResult(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.GetAssetManifest(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(result.Operation.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation url: %w", err)
+	}
+
+	bundle := &operationBundle{
+		service:    s,
+		result:     result,
+		assets:     assets,
+		exporters:  exporters,
+		base:       base,
+		assetFiles: make(map[string]string),
+		pages:      make(map[dto.DeviceProfile]*standalonePage),
+	}
+	bundle.plan()
+
+	return bundle, nil
+}
+
+// Filename возвращает имя файла архива
+func (b *operationBundle) Filename() string {
+	return fmt.Sprintf("operation_%s.zip", b.result.Operation.ID.String())
+}
+
+// plan раскладывает блоки, файлы, скриншоты и экспорты по путям внутри архива
+func (b *operationBundle) plan() {
+	operation := b.result.Operation
+
+	b.manifest = dto.BundleManifest{
+		Operation:   operation,
+		Blocks:      make([]dto.BundleBlock, 0, len(b.result.Blocks)),
+		Assets:      make([]dto.BundleAsset, 0, len(b.assets.Assets)),
+		Exports:     []string{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	b.manifest.Operation.ScreenshotKey = ""
+
+	if operation.ScreenshotKey != "" {
+		b.manifest.Screenshot = "screenshots/page.png"
+	}
+
+	for _, asset := range b.assets.Assets {
+		file := "assets/" + path.Base(asset.StorageKey)
+		b.assetFiles[asset.URL] = file
+		b.manifest.Assets = append(b.manifest.Assets, dto.BundleAsset{
+			ID:          asset.ID,
+			Kind:        asset.Kind,
+			URL:         asset.URL,
+			File:        file,
+			ContentType: asset.ContentType,
+			SHA256:      asset.SHA256,
+		})
+	}
+
+	blockAssets := make(map[uuid.UUID][]uuid.UUID)
+	for _, link := range b.assets.Links {
+		blockAssets[link.BlockID] = append(blockAssets[link.BlockID], link.AssetID)
+	}
+
+	// При нескольких профилях устройств блоки раскладываются по папкам устройств
+	positions := make(map[dto.DeviceProfile]int)
+	for _, block := range b.result.Blocks {
+		positions[block.Device]++
+
+		name := fmt.Sprintf("%03d_%s", positions[block.Device], block.BlockType)
+		if len(b.result.Devices) > 0 {
+			name = path.Join(string(block.Device), name)
+		}
+
+		entry := dto.BundleBlock{
+			ID:             block.ID,
+			BlockType:      block.BlockType,
+			Platform:       block.Platform,
+			Device:         block.Device,
+			Classification: block.Classification,
+			File:           path.Join("blocks", name+".html"),
+			StandaloneFile: path.Join("standalone", name+".html"),
+			Assets:         blockAssets[block.ID],
+		}
+		if entry.Assets == nil {
+			entry.Assets = []uuid.UUID{}
+		}
+		if block.OriginalHTML != "" {
+			entry.OriginalFile = path.Join("originals", name+".html")
+		}
+		if block.ScreenshotKey != "" {
+			entry.Screenshot = path.Join("screenshots", name+".png")
+		}
+
+		b.manifest.Blocks = append(b.manifest.Blocks, entry)
+	}
+
+	for _, exporter := range b.exporters {
+		b.manifest.Exports = append(b.manifest.Exports, path.Join("exports", exportFilename(&b.result.Operation, exporter)))
+	}
+}
+
+// Write пишет архив в w: блоки, файлы, скриншоты, экспорты и manifest.json.
+// Манифест пишется последним, чтобы перечислить файлы, которых не оказалось в хранилище.
+func (b *operationBundle) Write(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	if err := b.writeBlocks(ctx, zw); err != nil {
+		return err
+	}
+
+	if err := b.writeAssets(ctx, zw); err != nil {
+		return err
+	}
+
+	if err := b.writeScreenshots(ctx, zw); err != nil {
+		return err
+	}
+
+	if err := b.writeExports(ctx, zw); err != nil {
+		return err
+	}
+
+	if err := b.writeManifest(zw); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+
+	return nil
+}
+
+// writeManifest пишет manifest.json с описанием операции и блоков
+func (b *operationBundle) writeManifest(zw *zip.Writer) error {
+	content, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bundle manifest: %w", err)
+	}
+
+	return writeZipEntry(zw, "manifest.json", true, bytes.NewReader(content))
+}
+
+// writeBlocks пишет HTML каждого блока со ссылками на файлы внутри архива
+func (b *operationBundle) writeBlocks(ctx context.Context, zw *zip.Writer) error {
+	for i, block := range b.result.Blocks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry := b.manifest.Blocks[i]
+		if err := b.writeBlockPage(zw, entry.File, block.BlockType, block.HTML); err != nil {
+			return err
+		}
+
+		// Исходная разметка до очистки лежит рядом в originals/
+		if entry.OriginalFile != "" {
+			if err := b.writeBlockPage(zw, entry.OriginalFile, block.BlockType, block.OriginalHTML); err != nil {
+				return err
+			}
+		}
+
+		if err := b.writeStandaloneBlock(ctx, zw, entry.StandaloneFile, block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeStandaloneBlock пишет автономный HTML блока со стилями страницы; файлы блока остаются внешними ссылками
+func (b *operationBundle) writeStandaloneBlock(ctx context.Context, zw *zip.Writer, file string, block dto.Block) error {
+	page, ok := b.pages[block.Device]
+	if !ok {
+		var err error
+		page, err = b.service.loadStandalonePage(ctx, b.result.Operation.ID, block.Device, b.base)
+		if err != nil {
+			return err
+		}
+		b.pages[block.Device] = page
+	}
+
+	content, err := page.render(block, nil)
+	if err != nil {
+		return err
+	}
+
+	return writeZipEntry(zw, file, true, strings.NewReader(content))
+}
+
+// writeBlockPage пишет HTML блока отдельной страницей со ссылками на файлы внутри архива
+func (b *operationBundle) writeBlockPage(zw *zip.Writer, file string, blockType dto.BlockType, content string) error {
+	prefix := strings.Repeat("../", strings.Count(file, "/"))
+
+	body := rewriteAssetLinks(content, b.base, func(absURL string) (string, bool) {
+		local, ok := b.assetFiles[absURL]
+		return prefix + local, ok
+	})
+
+	page := fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n%s\n</body>\n</html>\n",
+		blockType, body)
+
+	return writeZipEntry(zw, file, true, strings.NewReader(page))
+}
+
+// writeAssets копирует скачанные файлы из хранилища; одинаковые файлы пишутся один раз
+func (b *operationBundle) writeAssets(ctx context.Context, zw *zip.Writer) error {
+	written := make(map[string]bool)
+
+	for _, asset := range b.assets.Assets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		file := b.assetFiles[asset.URL]
+		if written[file] {
+			continue
+		}
+		written[file] = true
+
+		reader, err := b.service.storage.Open(ctx, asset.StorageKey)
+		if err != nil {
+			if errors.Is(err, repos.ErrBlobNotFound) {
+				b.skipMissing(file, err)
+				continue
+			}
+			return fmt.Errorf("failed to open asset %s: %w", asset.ID, err)
+		}
+
+		err = writeZipEntry(zw, file, isCompressible(asset.ContentType), reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeScreenshots пишет скриншот страницы и скриншоты блоков
+func (b *operationBundle) writeScreenshots(ctx context.Context, zw *zip.Writer) error {
+	parser := b.service.parserService
+	operationID := b.result.Operation.ID
+
+	if b.manifest.Screenshot != "" {
+		data, err := parser.GetPageScreenshot(ctx, operationID)
+		switch {
+		case errors.Is(err, ErrScreenshotNotFound):
+			b.skipMissing(b.manifest.Screenshot, err)
+		case err != nil:
+			return fmt.Errorf("failed to read page screenshot: %w", err)
+		default:
+			if err := writeZipEntry(zw, b.manifest.Screenshot, false, bytes.NewReader(data)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, entry := range b.manifest.Blocks {
+		if entry.Screenshot == "" {
+			continue
+		}
+
+		data, err := parser.GetBlockScreenshot(ctx, operationID, entry.ID)
+		if err != nil {
+			if errors.Is(err, ErrScreenshotNotFound) {
+				b.skipMissing(entry.Screenshot, err)
+				continue
+			}
+			return fmt.Errorf("failed to read block screenshot: %w", err)
+		}
+		if err := writeZipEntry(zw, entry.Screenshot, false, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipMissing пропускает файл, которого нет в хранилище: ответ уже начат,
+// поэтому архив дописывается без файла, а его путь попадает в манифест
+func (b *operationBundle) skipMissing(file string, err error) {
+	b.service.logger.Warn("Bundle file is missing in storage",
+		zap.String("operation_id", b.result.Operation.ID.String()),
+		zap.String("file", file),
+		zap.Error(err))
+	b.manifest.Missing = append(b.manifest.Missing, file)
+}
+
+// writeExports пишет выбранные экспорты операции, каждый экспортер пишет прямо в запись архива
+func (b *operationBundle) writeExports(ctx context.Context, zw *zip.Writer) error {
+	for i, exporter := range b.exporters {
+		entry, err := createZipEntry(zw, b.manifest.Exports[i], isCompressible(exporter.ContentType()))
+		if err != nil {
+			return err
+		}
+
+		if err := exporter.Export(ctx, &resultExportSource{result: b.result}, entry); err != nil {
+			return fmt.Errorf("failed to export operation as %s: %w", exporter.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// writeZipEntry добавляет файл в архив; уже сжатые форматы сохраняются без повторного сжатия
+func writeZipEntry(zw *zip.Writer, name string, compress bool, r io.Reader) error {
+	entry, err := createZipEntry(zw, name, compress)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(entry, r); err != nil {
+		return fmt.Errorf("failed to write zip entry %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// createZipEntry создает запись архива для потоковой записи
+func createZipEntry(zw *zip.Writer, name string, compress bool) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	}
+	if compress {
+		header.Method = zip.Deflate
+	}
+
+	entry, err := zw.CreateHeader(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip entry %s: %w", name, err)
+	}
+
+	return entry, nil
+}
+
+// isCompressible проверяет, имеет ли смысл сжимать файл
+func isCompressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "javascript") ||
+		strings.Contains(contentType, "json") ||
+		contentType == "image/svg+xml" ||
+		contentType == "font/ttf" ||
+		contentType == "font/otf"
+}
+
+// rewriteAssetLinks заменяет ссылки на скачанные файлы в HTML блока локальными путями
+func rewriteAssetLinks(content string, base *url.URL, local func(absURL string) (string, bool)) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+
+	replace := func(ref string) string {
+		resolved, ok := resolveURL(base, ref)
+		if !ok {
+			return ref
+		}
+		if file, ok := local(resolved); ok {
+			return file
+		}
+		return ref
+	}
+
+	rewriteCSS := func(css string) string {
+		return reCSSURL.ReplaceAllStringFunc(css, func(match string) string {
+			groups := reCSSURL.FindStringSubmatch(match)
+			ref := firstNonEmpty(groups[1:]...)
+			if rewritten := replace(ref); rewritten != ref {
+				return fmt.Sprintf("url('%s')", rewritten)
+			}
+			return match
+		})
+	}
+
+	for _, attr := range []string{"src", "data-src", "data-lazy-src", "poster"} {
+		doc.Find("[" + attr + "]").Each(func(i int, sel *goquery.Selection) {
+			sel.SetAttr(attr, replace(sel.AttrOr(attr, "")))
+		})
+	}
+
+	doc.Find("link[href]").Each(func(i int, sel *goquery.Selection) {
+		sel.SetAttr("href", replace(sel.AttrOr("href", "")))
+	})
+
+	doc.Find("[srcset]").Each(func(i int, sel *goquery.Selection) {
+		candidates := strings.Split(sel.AttrOr("srcset", ""), ",")
+		for j, candidate := range candidates {
+			fields := strings.Fields(candidate)
+			if len(fields) == 0 {
+				continue
+			}
+			fields[0] = replace(fields[0])
+			candidates[j] = strings.Join(fields, " ")
+		}
+		sel.SetAttr("srcset", strings.Join(candidates, ", "))
+	})
+
+	doc.Find("[style]").Each(func(i int, sel *goquery.Selection) {
+		sel.SetAttr("style", rewriteCSS(sel.AttrOr("style", "")))
+	})
+
+	// Текст style меняется в узле напрямую: SetText экранирует кавычки
+	doc.Find("style").Each(func(i int, sel *goquery.Selection) {
+		for _, node := range sel.Nodes {
+			if child := node.FirstChild; child != nil && child.Type == html.TextNode {
+				child.Data = rewriteCSS(child.Data)
+			}
+		}
+	})
+
+	// Парсер переносит ведущие link/style фрагмента в head, поэтому возвращаются оба раздела
+	head, err := doc.Find("head").Html()
+	if err != nil {
+		return content
+	}
+
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return content
+	}
+
+	return head + body
+}
@@ -0,0 +1,494 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// cssRule правило таблицы стилей: селектор или заголовок @-правила и содержимое фигурных скобок
+type cssRule struct {
+	prelude  string
+	block    string
+	hasBlock bool
+}
+
+// groupingAtRules @-правила, внутри которых лежат обычные правила
+var groupingAtRules = map[string]bool{
+	"media": true, "supports": true, "container": true, "layer": true, "scope": true, "document": true, "-moz-document": true,
+}
+
+// keyframesAtRules варианты @keyframes с префиксами браузеров
+var keyframesAtRules = map[string]bool{"keyframes": true, "-webkit-keyframes": true, "-moz-keyframes": true, "-o-keyframes": true}
+
+// dynamicPseudoClasses псевдоклассы и псевдоэлементы, которые не проверить по статичной разметке
+var dynamicPseudoClasses = map[string]bool{
+	"hover": true, "active": true, "focus": true, "focus-within": true, "focus-visible": true, "visited": true,
+	"target": true, "before": true, "after": true, "first-letter": true, "first-line": true, "placeholder": true,
+	"placeholder-shown": true, "selection": true, "marker": true, "backdrop": true, "autofill": true,
+	"invalid": true, "valid": true, "required": true, "optional": true, "read-only": true, "read-write": true,
+	"defined": true, "focus-ring": true,
+}
+
+// parseCSSRules разбирает таблицу стилей на правила верхнего уровня; комментарии отбрасываются
+func parseCSSRules(text string) []cssRule {
+	text = stripCSSComments(text)
+
+	var rules []cssRule
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '"', '\'':
+			i = skipCSSString(text, i)
+		case ';':
+			if prelude := strings.TrimSpace(text[start:i]); prelude != "" {
+				rules = append(rules, cssRule{prelude: prelude})
+			}
+			start = i + 1
+		case '{':
+			end := matchingBracket(text, i, '{', '}')
+			rules = append(rules, cssRule{
+				prelude:  strings.TrimSpace(text[start:i]),
+				block:    text[i+1 : end],
+				hasBlock: true,
+			})
+			i = end
+			start = end + 1
+		case '}':
+			start = i + 1
+		}
+	}
+
+	return rules
+}
+
+// stripCSSComments удаляет комментарии /* */ вне строк
+func stripCSSComments(text string) string {
+	if !strings.Contains(text, "/*") {
+		return text
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '"' || text[i] == '\'':
+			end := skipCSSString(text, i)
+			buf.WriteString(text[i:min(end+1, len(text))])
+			i = end
+		case text[i] == '/' && i+1 < len(text) && text[i+1] == '*':
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return buf.String()
+			}
+			i += end + 3
+		default:
+			buf.WriteByte(text[i])
+		}
+	}
+
+	return buf.String()
+}
+
+// skipCSSString возвращает индекс закрывающей кавычки строки, начинающейся с start
+func skipCSSString(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(text) - 1
+}
+
+// matchingBracket возвращает индекс закрывающей скобки с учетом вложенности и строк, либо конец текста
+func matchingBracket(text string, start int, open, close byte) int {
+	depth := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"', '\'':
+			i = skipCSSString(text, i)
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(text)
+}
+
+// splitCSSList делит список через запятую верхнего уровня: селекторы правила или значения свойства
+func splitCSSList(text string) []string {
+	var parts []string
+	start, depth := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"', '\'':
+			i = skipCSSString(text, i)
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(text[start:]))
+}
+
+// reClassAttrSelector разбирает селектор атрибута class с оператором и значением: [class^="col-" i]
+var reClassAttrSelector = regexp.MustCompile(`(?i)^\[\s*class\s*([~|^$*]?=)\s*(?:"([^"\\]*)"|'([^'\\]*)'|([^\s"'\\\]]+))\s*([is])?\s*\]$`)
+
+// atRuleName возвращает имя @-правила в нижнем регистре
+func atRuleName(prelude string) string {
+	name := strings.TrimPrefix(prelude, "@")
+	if end := strings.IndexAny(name, " \t\r\n({"); end >= 0 {
+		name = name[:end]
+	}
+	return strings.ToLower(name)
+}
+
+// isCSSIdentChar проверяет, может ли символ входить в идентификатор CSS
+func isCSSIdentChar(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// styleFilter отбирает правила, которые применяются к элементам блока или его предков на странице
+type styleFilter struct {
+	// scope элементы блока и его предков, к которым проверяются селекторы
+	scope []*html.Node
+
+	// present классы и id элементов scope для селекторов, которые не удалось разобрать
+	present map[string]bool
+
+	// prefix префикс классов автономного блока
+	prefix string
+
+	cache      map[string]bool
+	fonts      map[string]bool
+	animations map[string]bool
+}
+
+func newStyleFilter(scope []*html.Node, prefix string) *styleFilter {
+	f := &styleFilter{
+		scope:      scope,
+		present:    make(map[string]bool),
+		prefix:     prefix,
+		cache:      make(map[string]bool),
+		fonts:      make(map[string]bool),
+		animations: make(map[string]bool),
+	}
+
+	for _, node := range scope {
+		for _, attr := range node.Attr {
+			switch attr.Key {
+			case "id":
+				f.present["#"+attr.Val] = true
+			case "class":
+				for _, class := range strings.Fields(attr.Val) {
+					f.present["."+class] = true
+				}
+			}
+		}
+	}
+
+	return f
+}
+
+// filter возвращает CSS только с правилами блока.
+// Первый проход собирает шрифты и анимации отобранных правил, второй добавляет нужные @font-face и @keyframes.
+func (f *styleFilter) filter(rules []cssRule) string {
+	f.write(rules, nil)
+
+	var buf strings.Builder
+	f.write(rules, &buf)
+	return buf.String()
+}
+
+// write пишет отобранные правила в buf; при buf == nil только собирает используемые шрифты и анимации
+func (f *styleFilter) write(rules []cssRule, buf *strings.Builder) {
+	for _, rule := range rules {
+		if !rule.hasBlock {
+			// @import, @charset и объявления порядка @layer автономному блоку не нужны
+			continue
+		}
+
+		if !strings.HasPrefix(rule.prelude, "@") {
+			var selectors []string
+			for _, selector := range splitCSSList(rule.prelude) {
+				if selector != "" && f.matches(selector) {
+					selectors = append(selectors, scopeSelector(selector, f.prefix))
+				}
+			}
+			if len(selectors) == 0 {
+				continue
+			}
+
+			if buf == nil {
+				f.noteUsage(rule.block)
+			} else {
+				buf.WriteString(strings.Join(selectors, ", ") + " {" + rule.block + "}\n")
+			}
+			continue
+		}
+
+		name := atRuleName(rule.prelude)
+		switch {
+		case groupingAtRules[name]:
+			var inner *strings.Builder
+			if buf != nil {
+				inner = &strings.Builder{}
+			}
+			f.write(parseCSSRules(rule.block), inner)
+			if inner != nil && inner.Len() > 0 {
+				buf.WriteString(rule.prelude + " {\n" + inner.String() + "}\n")
+			}
+		case name == "font-face":
+			if buf != nil && f.fontUsed(rule.block) {
+				buf.WriteString(rule.prelude + " {" + rule.block + "}\n")
+			}
+		case keyframesAtRules[name]:
+			fields := strings.Fields(rule.prelude)
+			if buf != nil && len(fields) > 1 && f.animations[strings.Trim(fields[1], `"'`)] {
+				buf.WriteString(rule.prelude + " {" + rule.block + "}\n")
+			}
+		case name == "property" || name == "counter-style":
+			if buf != nil {
+				buf.WriteString(rule.prelude + " {" + rule.block + "}\n")
+			}
+		}
+	}
+}
+
+// matches проверяет, применяется ли селектор хотя бы к одному элементу scope
+func (f *styleFilter) matches(selector string) bool {
+	if matched, ok := f.cache[selector]; ok {
+		return matched
+	}
+
+	matched := false
+	compiled, err := cascadia.Compile(staticSelector(selector))
+	if err != nil {
+		// Селектор, который не разбирает cascadia, оставляем, если в блоке есть все его классы и id
+		tokens := selectorTokens(selector)
+		matched = len(tokens) > 0
+		for _, token := range tokens {
+			matched = matched && f.present[token]
+		}
+	} else {
+		for _, node := range f.scope {
+			if compiled.Match(node) {
+				matched = true
+				break
+			}
+		}
+	}
+
+	f.cache[selector] = matched
+	return matched
+}
+
+// noteUsage запоминает шрифты и анимации из объявлений правила, включая значения CSS-переменных
+func (f *styleFilter) noteUsage(block string) {
+	for _, declaration := range strings.Split(block, ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+
+		isVariable := strings.HasPrefix(name, "--")
+		if name == "font-family" || name == "font" || isVariable {
+			for i, family := range splitCSSList(value) {
+				// В сокращенной записи font семейство идет после кегля
+				if i == 0 && name == "font" {
+					if fields := strings.Fields(family); len(fields) > 0 {
+						family = fields[len(fields)-1]
+					}
+				}
+				f.fonts[normalizeFontFamily(family)] = true
+			}
+		}
+
+		if name == "animation" || name == "animation-name" || isVariable {
+			for _, animation := range splitCSSList(value) {
+				for _, token := range strings.Fields(animation) {
+					f.animations[strings.Trim(token, `"'`)] = true
+				}
+			}
+		}
+	}
+}
+
+// fontUsed проверяет, используется ли шрифт из @font-face в отобранных правилах
+func (f *styleFilter) fontUsed(block string) bool {
+	for _, declaration := range strings.Split(block, ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "font-family") {
+			return f.fonts[normalizeFontFamily(value)]
+		}
+	}
+	return false
+}
+
+// normalizeFontFamily приводит имя семейства шрифтов к виду для сравнения
+func normalizeFontFamily(family string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`))
+}
+
+// staticSelector убирает из селектора псевдоэлементы и динамические псевдоклассы,
+// чтобы проверить его по статичной разметке. Опустевшая часть селектора заменяется на *.
+func staticSelector(selector string) string {
+	var buf strings.Builder
+	for i := 0; i < len(selector); {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			end := min(i+2, len(selector))
+			buf.WriteString(selector[i:end])
+			i = end
+		case c == '"' || c == '\'':
+			end := min(skipCSSString(selector, i)+1, len(selector))
+			buf.WriteString(selector[i:end])
+			i = end
+		case c == ':':
+			nameStart := i + 1
+			if nameStart < len(selector) && selector[nameStart] == ':' {
+				nameStart++
+			}
+			nameEnd := nameStart
+			for nameEnd < len(selector) && isCSSIdentChar(selector[nameEnd]) {
+				nameEnd++
+			}
+			end := nameEnd
+			if end < len(selector) && selector[end] == '(' {
+				end = min(matchingBracket(selector, end, '(', ')')+1, len(selector))
+			}
+
+			name := strings.ToLower(selector[nameStart:nameEnd])
+			isElement := nameStart == i+2
+			isVendor := strings.HasPrefix(name, "-webkit-") || strings.HasPrefix(name, "-moz-") || strings.HasPrefix(name, "-ms-")
+			if isElement || isVendor || dynamicPseudoClasses[name] {
+				if written := buf.String(); written == "" || strings.ContainsAny(written[len(written)-1:], " \t\n>+~(,") {
+					buf.WriteByte('*')
+				}
+			} else {
+				buf.WriteString(selector[i:end])
+			}
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
+
+// selectorTokens возвращает классы и id селектора
+func selectorTokens(selector string) []string {
+	var tokens []string
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			i = skipCSSString(selector, i)
+		case c == '[':
+			i = matchingBracket(selector, i, '[', ']')
+		case c == '.' || c == '#':
+			end := i + 1
+			for end < len(selector) && (isCSSIdentChar(selector[end]) || selector[end] == '\\') {
+				if selector[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end > i+1 {
+				tokens = append(tokens, string(c)+strings.ReplaceAll(selector[i+1:min(end, len(selector))], `\`, ""))
+			}
+			i = end - 1
+		}
+	}
+	return tokens
+}
+
+// scopeSelector добавляет префикс ко всем классам селектора, включая значения в [class...], кроме упомянутых в строках
+func scopeSelector(selector, prefix string) string {
+	var buf strings.Builder
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case c == '\\':
+			end := min(i+2, len(selector))
+			buf.WriteString(selector[i:end])
+			i = end - 1
+		case c == '"' || c == '\'' || c == '[':
+			end := 0
+			if c == '[' {
+				end = matchingBracket(selector, i, '[', ']')
+			} else {
+				end = skipCSSString(selector, i)
+			}
+			end = min(end+1, len(selector))
+			if c == '[' {
+				buf.WriteString(scopeClassAttribute(selector[i:end], prefix))
+			} else {
+				buf.WriteString(selector[i:end])
+			}
+			i = end - 1
+		case c == '.' && i+1 < len(selector) && (isCSSIdentChar(selector[i+1]) || selector[i+1] == '\\') &&
+			(selector[i+1] < '0' || selector[i+1] > '9'):
+			buf.WriteString("." + prefix)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String()
+}
+
+// scopeClassAttribute добавляет префикс к классам в значении селектора [class...].
+// Префикс ставится в начале каждого класса значения: для ~=, =, ^= и |= значение начинается с класса,
+// для $= и *= - только после пробела, иначе часть класса в конце или середине совпадет и с префиксом.
+// Другие атрибуты и значения с экранированием остаются без изменений.
+func scopeClassAttribute(attribute, prefix string) string {
+	groups := reClassAttrSelector.FindStringSubmatch(attribute)
+	if groups == nil {
+		return attribute
+	}
+
+	operator, value := groups[1], firstNonEmpty(groups[2:5]...)
+	atClassStart := operator != "$=" && operator != "*="
+
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != ' ' && c != '\t' && atClassStart {
+			buf.WriteString(prefix)
+		}
+		atClassStart = c == ' ' || c == '\t'
+		buf.WriteByte(c)
+	}
+
+	result := "[class" + operator + `"` + buf.String() + `"`
+	if groups[5] != "" {
+		result += " " + groups[5]
+	}
+	return result + "]"
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseCSSRules(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want []cssRule
+	}{
+		{
+			name: "simple rules",
+			css:  ".a { color: red; } .b{margin:0}",
+			want: []cssRule{
+				{prelude: ".a", block: " color: red; ", hasBlock: true},
+				{prelude: ".b", block: "margin:0", hasBlock: true},
+			},
+		},
+		{
+			name: "statements without block",
+			css:  `@charset "utf-8"; @import url("a.css"); .a{}`,
+			want: []cssRule{
+				{prelude: `@charset "utf-8"`},
+				{prelude: `@import url("a.css")`},
+				{prelude: ".a", hasBlock: true},
+			},
+		},
+		{
+			name: "comments with braces are dropped",
+			css:  "/* .x { } */ .a { /* } */ color: red }",
+			want: []cssRule{
+				{prelude: ".a", block: "  color: red ", hasBlock: true},
+			},
+		},
+		{
+			name: "strings with braces and comment markers",
+			css:  `.a::before { content: "}{ /* not a comment */" } .b { content: '\'}' }`,
+			want: []cssRule{
+				{prelude: ".a::before", block: ` content: "}{ /* not a comment */" `, hasBlock: true},
+				{prelude: ".b", block: ` content: '\'}' `, hasBlock: true},
+			},
+		},
+		{
+			name: "nested grouping rules stay in the block",
+			css:  "@media (min-width: 10px) { @supports (display: grid) { .a { b: c } } } .d { e: f }",
+			want: []cssRule{
+				{prelude: "@media (min-width: 10px)", block: " @supports (display: grid) { .a { b: c } } ", hasBlock: true},
+				{prelude: ".d", block: " e: f ", hasBlock: true},
+			},
+		},
+		{
+			name: "unclosed block runs to the end",
+			css:  ".a { color: red",
+			want: []cssRule{
+				{prelude: ".a", block: " color: red", hasBlock: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCSSRules(tt.css); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCSSRules() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: ".a", want: ".a"},
+		{selector: ".a:hover", want: ".a"},
+		{selector: ".a::before", want: ".a"},
+		{selector: ".a:before", want: ".a"},
+		{selector: "a:focus-visible > .b::after", want: "a > .b"},
+		{selector: ":hover", want: "*"},
+		{selector: ".a :hover", want: ".a *"},
+		{selector: "input::-webkit-input-placeholder", want: "input"},
+		{selector: "li:first-child", want: "li:first-child"},
+		{selector: "li:nth-child(2n+1):hover", want: "li:nth-child(2n+1)"},
+		// Аргументы функциональных псевдоклассов не разбираются
+		{selector: ".a:not(:hover)", want: ".a:not(:hover)"},
+		{selector: `a[href=":hover"]`, want: `a[href=":hover"]`},
+		{selector: `.md\:flex:hover`, want: `.md\:flex`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := staticSelector(tt.selector); got != tt.want {
+				t.Errorf("staticSelector(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: ".a", want: ".p-a"},
+		{selector: ".a .b > .c", want: ".p-a .p-b > .p-c"},
+		{selector: "div.a#main", want: "div.p-a#main"},
+		{selector: `[class~="a.b"] .c`, want: `[class~="p-a.b"] .p-c`},
+		{selector: `[class^="col-"]`, want: `[class^="p-col-"]`},
+		{selector: `div[class|='btn' i]`, want: `div[class|="p-btn" i]`},
+		{selector: `[class="a  b"]`, want: `[class="p-a  p-b"]`},
+		{selector: `[class$="-primary"]`, want: `[class$="-primary"]`},
+		{selector: `[class*=" icon-"]`, want: `[class*=" p-icon-"]`},
+		{selector: `[CLASS ^= col]`, want: `[class^="p-col"]`},
+		{selector: `[class]`, want: `[class]`},
+		{selector: `[data-class^="col-"]`, want: `[data-class^="col-"]`},
+		{selector: `a[href$='.pdf']`, want: `a[href$='.pdf']`},
+		{selector: `.md\:flex`, want: `.p-md\:flex`},
+		{selector: ".a:not(.b)", want: ".p-a:not(.p-b)"},
+		{selector: "p", want: "p"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := scopeSelector(tt.selector, "p-"); got != tt.want {
+				t.Errorf("scopeSelector(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleFilterMatches(t *testing.T) {
+	filter := newStyleFilter(parseScope(t, `<div class="card" id="main"><a class="link" href="/x">x</a></div>`), "p-")
+
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: ".card", want: true},
+		{selector: "#main .link", want: true},
+		{selector: ".card > a", want: true},
+		{selector: ".link:hover", want: true},
+		{selector: ".card::before", want: true},
+		{selector: ".missing", want: false},
+		{selector: ".card .missing:hover", want: false},
+		{selector: "section .link", want: false},
+		// cascadia не разбирает :has, такие селекторы проверяются по классам и id
+		{selector: ".card:has(> img)", want: true},
+		{selector: ".other:has(> img)", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			if got := filter.matches(tt.selector); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStyleFilterFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want []string
+		skip []string
+	}{
+		{
+			name: "unused rules are dropped and classes are prefixed",
+			css:  ".card { color: red } .other { color: blue } .card:hover, .other:hover { color: green }",
+			want: []string{".p-card { color: red }", ".p-card:hover { color: green }"},
+			skip: []string{".other", "blue"},
+		},
+		{
+			name: "nested media and supports keep only matching rules",
+			css:  "@media (min-width: 1px) { @supports (display: grid) { .card { a: b } .other { c: d } } } @media print { .other { e: f } }",
+			want: []string{"@media (min-width: 1px) {\n@supports (display: grid) {\n.p-card { a: b }\n}\n}\n"},
+			skip: []string{"print", ".other"},
+		},
+		{
+			name: "font-face only for used families",
+			css:  `.card { font-family: "Brand", sans-serif } @font-face { font-family: 'Brand'; src: url(a.woff2) } @font-face { font-family: Unused; src: url(b.woff2) }`,
+			want: []string{"@font-face { font-family: 'Brand'; src: url(a.woff2) }"},
+			skip: []string{"Unused"},
+		},
+		{
+			name: "keyframes only for used animations",
+			css:  ".card { animation: spin 1s linear } @keyframes spin { to { a: b } } @-webkit-keyframes spin { to { a: b } } @keyframes fade { to { c: d } }",
+			want: []string{"@keyframes spin {", "@-webkit-keyframes spin {"},
+			skip: []string{"fade"},
+		},
+		{
+			name: "fonts and animations through css variables",
+			css:  ".card { --font: Brand; --anim: pulse } @font-face { font-family: Brand } @keyframes pulse { to { a: b } }",
+			want: []string{"@font-face { font-family: Brand }", "@keyframes pulse {"},
+		},
+		{
+			name: "imports and charset are dropped",
+			css:  `@charset "utf-8"; @import url(a.css); .card { a: b }`,
+			want: []string{".p-card { a: b }"},
+			skip: []string{"@import", "@charset"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := newStyleFilter(parseScope(t, `<div class="card"><span>x</span></div>`), "p-")
+			got := filter.filter(parseCSSRules(tt.css))
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("filter() = %q, want it to contain %q", got, want)
+				}
+			}
+			for _, skip := range tt.skip {
+				if strings.Contains(got, skip) {
+					t.Errorf("filter() = %q, want no %q", got, skip)
+				}
+			}
+		})
+	}
+}
+
+// parseScope возвращает элементы фрагмента разметки в порядке обхода
+func parseScope(t *testing.T, markup string) []*html.Node {
+	t.Helper()
+
+	doc, err := html.Parse(strings.NewReader(markup))
+	if err != nil {
+		t.Fatalf("failed to parse markup: %v", err)
+	}
+
+	var nodes []*html.Node
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			nodes = append(nodes, node)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return nodes
+}
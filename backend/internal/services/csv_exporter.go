@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"scrapper/internal/dto"
+)
+
+// csvExporter выгружает блоки операции таблицей: строка на блок, компоненты содержимого в отдельных колонках
+type csvExporter struct{}
+
+// NewCSVExporter создает экспортер в CSV
+func NewCSVExporter() Exporter {
+	return &csvExporter{}
+}
+
+func (e *csvExporter) Name() string        { return "csv" }
+func (e *csvExporter) ContentType() string { return "text/csv; charset=utf-8" }
+func (e *csvExporter) Extension() string   { return "csv" }
+
+// csvColumns колонки блока, которые идут перед компонентами содержимого
+var csvColumns = []string{
+	"id", "device", "block_type", "platform", "category", "score",
+	"x", "y", "width", "height", "created_at", "html",
+}
+
+// Export пишет заголовок и строку на каждый блок.
+// Компоненты содержимого разворачиваются в колонки content.<путь>, общие для всех блоков,
+// поэтому блоки обходятся дважды: сначала собираются колонки, затем пишутся строки.
+func (e *csvExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	keys := make(map[string]bool)
+	err := source.EachBlock(ctx, func(block *dto.Block) error {
+		flat, err := flattenContent(block.Content)
+		if err != nil {
+			return fmt.Errorf("failed to flatten block %s: %w", block.ID, err)
+		}
+		for key := range flat {
+			keys[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	componentKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		componentKeys = append(componentKeys, key)
+	}
+	sort.Strings(componentKeys)
+
+	cw := csv.NewWriter(w)
+
+	header := append([]string(nil), csvColumns...)
+	for _, key := range componentKeys {
+		header = append(header, "content."+key)
+	}
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		components, err := flattenContent(block.Content)
+		if err != nil {
+			return fmt.Errorf("failed to flatten block %s: %w", block.ID, err)
+		}
+
+		var category, score string
+		if block.Classification != nil {
+			category = string(block.Classification.Category)
+			score = strconv.FormatFloat(block.Classification.Score, 'f', -1, 64)
+		}
+
+		var x, y, width, height string
+		if block.Bounds != nil {
+			x = strconv.Itoa(block.Bounds.X)
+			y = strconv.Itoa(block.Bounds.Y)
+			width = strconv.Itoa(block.Bounds.Width)
+			height = strconv.Itoa(block.Bounds.Height)
+		}
+
+		record := []string{
+			block.ID.String(), string(block.Device), string(block.BlockType), string(block.Platform), category, score,
+			x, y, width, height, block.CreatedAt.Format(time.RFC3339), block.HTML,
+		}
+		for _, key := range componentKeys {
+			record = append(record, components[key])
+		}
+
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+
+	return nil
+}
+
+// flattenContent разворачивает содержимое блока в плоский словарь с путями через точку:
+// {"menu": [{"title": "A"}]} превращается в {"menu.0.title": "A"}
+func flattenContent(content interface{}) (map[string]string, error) {
+	flat := make(map[string]string)
+	if content == nil {
+		return flat, nil
+	}
+
+	// Содержимое приходит и как типизированные структуры парсеров, и как JSON из базы;
+	// после JSON оно одинаково состоит из map, slice и скаляров
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	flattenValue(flat, "", value)
+	return flat, nil
+}
+
+// flattenValue добавляет в flat значение по пути prefix
+func flattenValue(flat map[string]string, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flattenValue(flat, join(key), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flattenValue(flat, join(strconv.Itoa(i)), item)
+		}
+	case nil:
+		if prefix != "" {
+			flat[prefix] = ""
+		}
+	case string:
+		flat[prefix] = v
+	case bool:
+		flat[prefix] = strconv.FormatBool(v)
+	case float64:
+		flat[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		flat[prefix] = fmt.Sprint(v)
+	}
+}
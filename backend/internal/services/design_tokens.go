@@ -0,0 +1,418 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+
+	"scrapper/internal/dto"
+)
+
+// attrRenderStyle атрибут с вычисленными стилями элемента
+const attrRenderStyle = "data-scr-style"
+
+// ErrDesignTokensNotFound возвращается, если для операции не собраны токены дизайна
+var ErrDesignTokensNotFound = errors.New("design tokens not found")
+
+const (
+	// tokensLimit количество самых частых значений в каждой группе токенов
+	tokensLimit = 12
+
+	// buttonTokensLimit количество стилей кнопок в результате
+	buttonTokensLimit = 5
+
+	// accentSaturation минимальная насыщенность цвета, чтобы считать его акцентным
+	accentSaturation = 0.35
+)
+
+// annotateStylesJS записывает вычисленные стили видимых элементов с текстом, фоном или ролью кнопки.
+// Выполняется после annotateVisibilityJS, скрытые элементы пропускаются.
+const annotateStylesJS = `(() => {
+	const body = document.body;
+	if (!body) {
+		return 0;
+	}
+
+	const skip = new Set(['script', 'style', 'noscript', 'template', 'svg', 'path', 'br']);
+	const nonZero = (v) => v && v !== '0px' && v !== 'auto' && v !== 'normal' ? v : '';
+	let annotated = 0;
+
+	for (const el of body.querySelectorAll('*')) {
+		el.removeAttribute('` + attrRenderStyle + `');
+
+		const tag = el.tagName.toLowerCase();
+		if (skip.has(tag) || el.hasAttribute('` + attrRenderHidden + `')) continue;
+
+		const style = window.getComputedStyle(el);
+		const className = typeof el.className === 'string' ? el.className : '';
+		const isButton = tag === 'button' ||
+			el.getAttribute('role') === 'button' ||
+			(tag === 'input' && ['submit', 'button'].includes(el.type)) ||
+			(tag === 'a' && /(^|[\s_-])(btn|button)([\s_-]|$)/i.test(className));
+		const hasText = Array.from(el.childNodes).some((n) => n.nodeType === 3 && n.textContent.trim() !== '');
+		const bg = style.backgroundColor;
+		const hasBg = bg && bg !== 'transparent' && bg !== 'rgba(0, 0, 0, 0)';
+
+		if (!hasText && !isButton && !hasBg) continue;
+
+		const typed = hasText || isButton;
+		const record = {
+			c: typed ? style.color : '',
+			b: hasBg ? bg : '',
+			f: typed ? style.fontFamily : '',
+			s: typed ? style.fontSize : '',
+			w: typed ? style.fontWeight : '',
+			r: nonZero(style.borderTopLeftRadius),
+			p: isButton ? style.paddingTop + ' ' + style.paddingRight : '',
+			sp: [style.paddingTop, style.paddingRight, style.paddingBottom, style.paddingLeft,
+				style.marginTop, style.marginBottom, style.rowGap].map(nonZero).filter(Boolean),
+			k: isButton ? 'button' : (tag === 'a' ? 'link' : ''),
+		};
+
+		el.setAttribute('` + attrRenderStyle + `', JSON.stringify(record));
+		annotated++;
+	}
+
+	return annotated;
+})()`
+
+// annotateStyles возвращает действие chromedp, записывающее вычисленные стили элементов в DOM
+func annotateStyles() chromedp.Action {
+	var annotated int
+	return chromedp.Evaluate(annotateStylesJS, &annotated)
+}
+
+// styleRecord вычисленные стили одного элемента
+type styleRecord struct {
+	Color      string   `json:"c"`
+	Background string   `json:"b"`
+	Family     string   `json:"f"`
+	Size       string   `json:"s"`
+	Weight     string   `json:"w"`
+	Radius     string   `json:"r"`
+	Padding    string   `json:"p"`
+	Spacing    []string `json:"sp"`
+	Kind       string   `json:"k"`
+}
+
+// reCSSColor разбирает цвет в формате rgb()/rgba()
+var reCSSColor = regexp.MustCompile(`^rgba?\(\s*([\d.]+)[,\s]+([\d.]+)[,\s]+([\d.]+)(?:\s*[,/]\s*([\d.]+%?))?\s*\)$`)
+
+// tokenCounter считает частоту значений токена с сохранением порядка первого появления
+type tokenCounter struct {
+	counts map[string]int
+	order  []string
+}
+
+func newTokenCounter() *tokenCounter {
+	return &tokenCounter{counts: make(map[string]int)}
+}
+
+func (c *tokenCounter) add(value string) {
+	if value == "" {
+		return
+	}
+	if c.counts[value] == 0 {
+		c.order = append(c.order, value)
+	}
+	c.counts[value]++
+}
+
+// top возвращает самые частые значения
+func (c *tokenCounter) top(limit int) []dto.TokenUsage {
+	usages := make([]dto.TokenUsage, 0, len(c.order))
+	for _, value := range c.order {
+		usages = append(usages, dto.TokenUsage{Value: value, Count: c.counts[value]})
+	}
+
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].Count > usages[j].Count })
+	if len(usages) > limit {
+		usages = usages[:limit]
+	}
+
+	return usages
+}
+
+// extractDesignTokens собирает токены дизайна по стилям, размеченным браузером.
+// Возвращает nil, если в HTML нет разметки стилей.
+func extractDesignTokens(html string) *dto.DesignTokens {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	backgrounds, texts, accents := newTokenCounter(), newTokenCounter(), newTokenCounter()
+	families, sizes, weights := newTokenCounter(), newTokenCounter(), newTokenCounter()
+	radii, spacing := newTokenCounter(), newTokenCounter()
+
+	buttons := make(map[dto.ButtonToken]int)
+	var buttonOrder []dto.ButtonToken
+
+	found := false
+	doc.Find("[" + attrRenderStyle + "]").Each(func(i int, sel *goquery.Selection) {
+		var record styleRecord
+		if err := json.Unmarshal([]byte(sel.AttrOr(attrRenderStyle, "")), &record); err != nil {
+			return
+		}
+		found = true
+
+		color := normalizeColor(record.Color)
+		background := normalizeColor(record.Background)
+
+		texts.add(color)
+		backgrounds.add(background)
+
+		// Акцентные цвета: насыщенные фоны кнопок, цвета ссылок и любые яркие цвета
+		if isAccentColor(background) {
+			accents.add(background)
+		}
+		if isAccentColor(color) || (record.Kind == "link" && color != "") {
+			accents.add(color)
+		}
+
+		families.add(primaryFontFamily(record.Family))
+		sizes.add(record.Size)
+		weights.add(record.Weight)
+		radii.add(record.Radius)
+		for _, value := range record.Spacing {
+			spacing.add(value)
+		}
+
+		if record.Kind == "button" {
+			button := dto.ButtonToken{
+				Background:   background,
+				Color:        color,
+				FontWeight:   record.Weight,
+				BorderRadius: record.Radius,
+				Padding:      record.Padding,
+			}
+			if buttons[button] == 0 {
+				buttonOrder = append(buttonOrder, button)
+			}
+			buttons[button]++
+		}
+	})
+
+	if !found {
+		return nil
+	}
+
+	tokens := &dto.DesignTokens{
+		Colors: dto.ColorTokens{
+			Background: backgrounds.top(tokensLimit),
+			Text:       texts.top(tokensLimit),
+			Accent:     accents.top(tokensLimit),
+		},
+		Typography: dto.TypographyTokens{
+			Families: families.top(tokensLimit),
+			Sizes:    sizes.top(tokensLimit),
+			Weights:  weights.top(tokensLimit),
+		},
+		Buttons: make([]dto.ButtonToken, 0, len(buttonOrder)),
+		Radii:   radii.top(tokensLimit),
+		Spacing: spacingScale(spacing.top(tokensLimit)),
+	}
+
+	for _, button := range buttonOrder {
+		button.Count = buttons[button]
+		tokens.Buttons = append(tokens.Buttons, button)
+	}
+	sort.SliceStable(tokens.Buttons, func(i, j int) bool { return tokens.Buttons[i].Count > tokens.Buttons[j].Count })
+	if len(tokens.Buttons) > buttonTokensLimit {
+		tokens.Buttons = tokens.Buttons[:buttonTokensLimit]
+	}
+
+	return tokens
+}
+
+// normalizeColor приводит цвет к виду #rrggbb, полупрозрачные цвета к #rrggbbaa; прозрачные отбрасываются
+func normalizeColor(value string) string {
+	match := reCSSColor.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return ""
+	}
+
+	channel := func(s string) int {
+		v, _ := strconv.ParseFloat(s, 64)
+		return int(math.Round(math.Max(0, math.Min(255, v))))
+	}
+
+	hex := fmt.Sprintf("#%02x%02x%02x", channel(match[1]), channel(match[2]), channel(match[3]))
+
+	if match[4] == "" {
+		return hex
+	}
+
+	alpha, _ := strconv.ParseFloat(strings.TrimSuffix(match[4], "%"), 64)
+	if strings.HasSuffix(match[4], "%") {
+		alpha /= 100
+	}
+
+	switch {
+	case alpha <= 0:
+		return ""
+	case alpha >= 1:
+		return hex
+	}
+
+	return fmt.Sprintf("%s%02x", hex, int(math.Round(alpha*255)))
+}
+
+// isAccentColor проверяет, что цвет достаточно насыщенный и не слишком темный или светлый
+func isAccentColor(hex string) bool {
+	if len(hex) < 7 {
+		return false
+	}
+
+	var r, g, b int
+	if _, err := fmt.Sscanf(hex[:7], "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return false
+	}
+
+	maxC := math.Max(float64(r), math.Max(float64(g), float64(b)))
+	minC := math.Min(float64(r), math.Min(float64(g), float64(b)))
+	if maxC < 40 || minC > 235 {
+		return false
+	}
+
+	return (maxC-minC)/maxC >= accentSaturation
+}
+
+// primaryFontFamily возвращает первый шрифт из стека font-family
+func primaryFontFamily(stack string) string {
+	family := strings.TrimSpace(strings.Split(stack, ",")[0])
+	return strings.Trim(family, `"'`)
+}
+
+// spacingScale упорядочивает самые частые отступы по возрастанию
+func spacingScale(usages []dto.TokenUsage) []dto.TokenUsage {
+	sort.SliceStable(usages, func(i, j int) bool {
+		return cssPixels(usages[i].Value) < cssPixels(usages[j].Value)
+	})
+	return usages
+}
+
+// cssPixels возвращает числовое значение длины в пикселях
+func cssPixels(value string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(value, "px"), 64)
+	return v
+}
+
+// Допустимые значения токенов в CSS: значение со страницы попадает в файл только после проверки
+var (
+	reTokenColor  = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|rgba?\([0-9.,%/ ]+\)|hsla?\([0-9.,%/ a-z]+\)|[a-zA-Z]+)$`)
+	reTokenLength = regexp.MustCompile(`^-?[0-9.]+(px|em|rem|%|vw|vh|pt)?( -?[0-9.]+(px|em|rem|%|vw|vh|pt)?){0,3}$`)
+	reTokenWeight = regexp.MustCompile(`^([1-9]00|normal|bold|bolder|lighter)$`)
+	reTokenFamily = regexp.MustCompile(`^[\p{L}\p{N} _.-]+$`)
+)
+
+// genericFontFamilies общие семейства шрифтов, которые пишутся без кавычек
+var genericFontFamilies = map[string]bool{
+	"serif": true, "sans-serif": true, "monospace": true, "cursive": true, "fantasy": true,
+	"system-ui": true, "ui-serif": true, "ui-sans-serif": true, "ui-monospace": true, "ui-rounded": true,
+	"emoji": true, "math": true, "fangsong": true, "inherit": true, "initial": true,
+}
+
+// tokenValueKind определяет, по какому правилу проверяется значение токена
+type tokenValueKind int
+
+const (
+	tokenColor tokenValueKind = iota
+	tokenLength
+	tokenWeight
+	tokenFamily
+)
+
+// cssTokenValue возвращает значение токена, безопасное для вставки в CSS, или false,
+// если значение не подходит под строгий шаблон своего вида
+func cssTokenValue(kind tokenValueKind, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+
+	switch kind {
+	case tokenColor:
+		return value, reTokenColor.MatchString(value)
+	case tokenLength:
+		return value, reTokenLength.MatchString(value)
+	case tokenWeight:
+		return value, reTokenWeight.MatchString(value)
+	case tokenFamily:
+		// Каждое семейство списка проверяется отдельно и заново берется в кавычки
+		var families []string
+		for _, family := range strings.Split(value, ",") {
+			family = strings.Trim(strings.TrimSpace(family), `"'`)
+			if !reTokenFamily.MatchString(family) {
+				return "", false
+			}
+			if genericFontFamilies[strings.ToLower(family)] {
+				families = append(families, family)
+			} else {
+				families = append(families, `"`+family+`"`)
+			}
+		}
+		return strings.Join(families, ", "), len(families) > 0
+	}
+
+	return "", false
+}
+
+// designTokensCSS представляет токены дизайна как пользовательские свойства CSS.
+// Значения, не прошедшие проверку cssTokenValue, пропускаются.
+func designTokensCSS(tokens *dto.DesignTokens) string {
+	var b strings.Builder
+
+	b.WriteString(":root {\n")
+
+	writeGroup := func(comment, prefix string, usages []dto.TokenUsage, kind tokenValueKind) {
+		if len(usages) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "  /* %s */\n", comment)
+		for i, usage := range usages {
+			if value, ok := cssTokenValue(kind, usage.Value); ok {
+				fmt.Fprintf(&b, "  --%s-%d: %s; /* uses: %d */\n", prefix, i+1, value, usage.Count)
+			}
+		}
+	}
+
+	writeGroup("Background colors", "color-bg", tokens.Colors.Background, tokenColor)
+	writeGroup("Text colors", "color-text", tokens.Colors.Text, tokenColor)
+	writeGroup("Accent colors", "color-accent", tokens.Colors.Accent, tokenColor)
+	writeGroup("Font families", "font-family", tokens.Typography.Families, tokenFamily)
+	writeGroup("Font sizes", "font-size", tokens.Typography.Sizes, tokenLength)
+	writeGroup("Font weights", "font-weight", tokens.Typography.Weights, tokenWeight)
+	writeGroup("Border radii", "radius", tokens.Radii, tokenLength)
+	writeGroup("Spacing scale", "space", tokens.Spacing, tokenLength)
+
+	if len(tokens.Buttons) > 0 {
+		b.WriteString("  /* Buttons */\n")
+		for i, button := range tokens.Buttons {
+			prefix := fmt.Sprintf("button-%d", i+1)
+			for _, prop := range []struct {
+				name, value string
+				kind        tokenValueKind
+			}{
+				{"bg", button.Background, tokenColor},
+				{"color", button.Color, tokenColor},
+				{"font-weight", button.FontWeight, tokenWeight},
+				{"radius", button.BorderRadius, tokenLength},
+				{"padding", button.Padding, tokenLength},
+			} {
+				if value, ok := cssTokenValue(prop.kind, prop.value); ok {
+					fmt.Fprintf(&b, "  --%s-%s: %s;\n", prefix, prop.name, value)
+				}
+			}
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
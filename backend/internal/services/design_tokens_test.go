@@ -0,0 +1,69 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"scrapper/internal/dto"
+)
+
+func TestCSSTokenValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   tokenValueKind
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{name: "rgb color", kind: tokenColor, value: "rgb(255, 0, 0)", want: "rgb(255, 0, 0)", wantOK: true},
+		{name: "rgba color", kind: tokenColor, value: "rgba(0, 0, 0, 0.5)", want: "rgba(0, 0, 0, 0.5)", wantOK: true},
+		{name: "hex color", kind: tokenColor, value: "#1a2B3c", want: "#1a2B3c", wantOK: true},
+		{name: "color breaking out of the declaration", kind: tokenColor, value: "red; } body { background: url(x)"},
+		{name: "color with comment", kind: tokenColor, value: "red/* */"},
+		{name: "single length", kind: tokenLength, value: "16px", want: "16px", wantOK: true},
+		{name: "padding shorthand", kind: tokenLength, value: "8px 16px", want: "8px 16px", wantOK: true},
+		{name: "length with expression", kind: tokenLength, value: "calc(1px + 2px)"},
+		{name: "numeric weight", kind: tokenWeight, value: "700", want: "700", wantOK: true},
+		{name: "weight with injection", kind: tokenWeight, value: "700;}"},
+		{
+			name:   "font family list is requoted",
+			kind:   tokenFamily,
+			value:  `"Helvetica Neue", Arial, sans-serif`,
+			want:   `"Helvetica Neue", "Arial", sans-serif`,
+			wantOK: true,
+		},
+		{name: "cyrillic family", kind: tokenFamily, value: "'Шрифт 2'", want: `"Шрифт 2"`, wantOK: true},
+		{name: "family closing the quote", kind: tokenFamily, value: `Arial", x"; } *{color:red}`},
+		{name: "empty family in list", kind: tokenFamily, value: "Arial, , serif"},
+		{name: "empty value", kind: tokenColor, value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cssTokenValue(tt.kind, tt.value)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("cssTokenValue(%q) = %q %v, want %q %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDesignTokensCSS(t *testing.T) {
+	tokens := &dto.DesignTokens{
+		Buttons: []dto.ButtonToken{{Background: "rgb(0, 0, 255)", Padding: "8px}</style><script>"}},
+	}
+	tokens.Colors.Text = []dto.TokenUsage{{Value: "rgb(0, 0, 0)", Count: 3}, {Value: "red;}", Count: 1}}
+
+	css := designTokensCSS(tokens)
+
+	for _, want := range []string{"--color-text-1: rgb(0, 0, 0); /* uses: 3 */", "--button-1-bg: rgb(0, 0, 255);"} {
+		if !strings.Contains(css, want) {
+			t.Errorf("designTokensCSS() has no %q:\n%s", want, css)
+		}
+	}
+	for _, unwanted := range []string{"red;}", "<script>", "--color-text-2", "--button-1-padding"} {
+		if strings.Contains(css, unwanted) {
+			t.Errorf("designTokensCSS() contains %q:\n%s", unwanted, css)
+		}
+	}
+}
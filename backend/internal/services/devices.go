@@ -0,0 +1,81 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+
+	"scrapper/internal/dto"
+)
+
+// deviceProfiles поддерживаемые профили устройств
+var deviceProfiles = map[dto.DeviceProfile]dto.DeviceSettings{
+	dto.DeviceDesktop: {
+		Profile:          dto.DeviceDesktop,
+		UserAgent:        "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+		Width:            1920,
+		Height:           1080,
+		DevicePixelRatio: 1,
+	},
+	dto.DeviceTablet: {
+		Profile:          dto.DeviceTablet,
+		UserAgent:        "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		Width:            820,
+		Height:           1180,
+		DevicePixelRatio: 2,
+		Mobile:           true,
+		Touch:            true,
+	},
+	dto.DeviceMobile: {
+		Profile:          dto.DeviceMobile,
+		UserAgent:        "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+		Width:            390,
+		Height:           844,
+		DevicePixelRatio: 3,
+		Mobile:           true,
+		Touch:            true,
+	},
+}
+
+// resolveDevices возвращает параметры запрошенных профилей без повторов, по умолчанию только desktop
+func resolveDevices(profiles []dto.DeviceProfile) ([]dto.DeviceSettings, error) {
+	if len(profiles) == 0 {
+		return []dto.DeviceSettings{deviceProfiles[dto.DeviceDesktop]}, nil
+	}
+
+	seen := make(map[dto.DeviceProfile]bool, len(profiles))
+	devices := make([]dto.DeviceSettings, 0, len(profiles))
+
+	for _, profile := range profiles {
+		settings, ok := deviceProfiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown device profile: %s", profile)
+		}
+		if seen[profile] {
+			continue
+		}
+		seen[profile] = true
+		devices = append(devices, settings)
+	}
+
+	return devices, nil
+}
+
+// emulateDevice возвращает действия chromedp, включающие эмуляцию устройства до загрузки страницы
+func emulateDevice(device dto.DeviceSettings) chromedp.Tasks {
+	opts := []chromedp.EmulateViewportOption{
+		chromedp.EmulateScale(device.DevicePixelRatio),
+	}
+	if device.Mobile {
+		opts = append(opts, chromedp.EmulateMobile)
+	}
+	if device.Touch {
+		opts = append(opts, chromedp.EmulateTouch)
+	}
+
+	return chromedp.Tasks{
+		emulation.SetUserAgentOverride(device.UserAgent),
+		chromedp.EmulateViewport(device.Width, device.Height, opts...),
+	}
+}
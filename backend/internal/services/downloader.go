@@ -2,166 +2,439 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/config"
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
 )
 
+// maxStylesheetDepth глубина вложенности @import, до которой скачиваются таблицы стилей
+const maxStylesheetDepth = 3
+
+var (
+	// reCSSURL находит ссылки url(...) в CSS
+	reCSSURL = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'")\s]+))\s*\)`)
+
+	// reCSSImport находит @import "..." без url()
+	reCSSImport = regexp.MustCompile(`@import\s+(?:"([^"]+)"|'([^']+)')`)
+)
+
+// fontExtensions расширения файлов шрифтов
+var fontExtensions = map[string]bool{".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true}
+
+// assetRef ссылка на файл, найденная в блоке или в таблице стилей
+type assetRef struct {
+	kind dto.AssetKind
+	url  string
+}
+
 // downloaderService реализация DownloaderService
 type downloaderService struct {
+	logger        *zap.Logger
 	parserService ParserService
+	assets        repos.AssetRepo
+	snapshots     repos.SnapshotRepo
+	exporters     ExporterRegistry
+	storage       repos.BlobStorage
+	cfg           config.DownloaderConfig
+
+	// client скачивает файлы только с публичных адресов по http и https, см. newAssetClient
+	client *http.Client
 }
 
 // NewDownloaderService создает новый экземпляр DownloaderService
-func NewDownloaderService(parserService ParserService) DownloaderService {
+func NewDownloaderService(
+	logger *zap.Logger,
+	cfg *config.Config,
+	parserService ParserService,
+	assets repos.AssetRepo,
+	snapshots repos.SnapshotRepo,
+	exporters ExporterRegistry,
+	storage repos.BlobStorage,
+) DownloaderService {
 	return &downloaderService{
+		logger:        logger,
 		parserService: parserService,
+		assets:        assets,
+		snapshots:     snapshots,
+		exporters:     exporters,
+		storage:       storage,
+		cfg:           cfg.Downloader,
+		client:        newAssetClient(),
+	}
+}
+
+// DownloadAssets скачивает файлы, на которые ссылаются сохраненные блоки операции,
+// и сохраняет их в хранилище по хэшу содержимого
+func (s *downloaderService) DownloadAssets(ctx context.Context, operationID uuid.UUID) (*dto.AssetManifest, error) {
+	result, err := s.parserService.GetOperationResult(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := url.Parse(result.Operation.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation url: %w", err)
+	}
+
+	// Уже скачанные файлы операции не скачиваются повторно
+	existing, err := s.assets.GetAssetsByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	downloaded := make(map[string]*dto.Asset, len(existing))
+	for i := range existing {
+		downloaded[existing[i].URL] = &existing[i]
+	}
+
+	// Собираем ссылки всех блоков: URL -> блоки, которые на него ссылаются
+	owners := make(map[string][]uuid.UUID)
+	var queue []assetRef
+	for _, block := range result.Blocks {
+		for _, ref := range blockAssetRefs(block.HTML, base) {
+			if _, seen := owners[ref.url]; !seen {
+				queue = append(queue, ref)
+			}
+			owners[ref.url] = appendUnique(owners[ref.url], block.ID)
+		}
+	}
+
+	manifest := &dto.AssetManifest{
+		OperationID: operationID,
+		Assets:      []dto.Asset{},
+		Links:       []dto.BlockAssetLink{},
+	}
+
+	// Скачиваем волнами: таблицы стилей из первой волны порождают шрифты и фоны следующей
+	total := 0
+	for depth := 0; len(queue) > 0 && depth <= maxStylesheetDepth; depth++ {
+		// Лимит расходуют только ссылки, которые действительно будут скачаны
+		queue = slices.DeleteFunc(queue, func(ref assetRef) bool {
+			_, done := downloaded[ref.url]
+			return done
+		})
+		if remaining := max(s.cfg.MaxAssets-total, 0); len(queue) > remaining {
+			for _, ref := range queue[remaining:] {
+				manifest.Failed = append(manifest.Failed, dto.FailedAsset{URL: ref.url, Error: "asset limit reached"})
+			}
+			queue = queue[:remaining]
+		}
+		total += len(queue)
+
+		nested := s.downloadWave(ctx, operationID, queue, downloaded, manifest)
+
+		// Файлы из таблицы стилей принадлежат тем же блокам, что и сама таблица
+		queue = nil
+		for _, child := range nested {
+			if _, seen := owners[child.ref.url]; !seen {
+				queue = append(queue, child.ref)
+			}
+			for _, blockID := range owners[child.parent] {
+				owners[child.ref.url] = appendUnique(owners[child.ref.url], blockID)
+			}
+		}
+	}
+
+	// Ссылки из таблиц стилей глубже maxStylesheetDepth не скачиваются
+	for _, ref := range queue {
+		if _, done := downloaded[ref.url]; !done {
+			manifest.Failed = append(manifest.Failed, dto.FailedAsset{URL: ref.url, Error: "stylesheet depth limit reached"})
+		}
+	}
+
+	// Связываем блоки с файлами
+	for assetURL, blockIDs := range owners {
+		asset, ok := downloaded[assetURL]
+		if !ok {
+			continue
+		}
+		for _, blockID := range blockIDs {
+			link := dto.BlockAssetLink{BlockID: blockID, AssetID: asset.ID, URL: assetURL}
+			if err := s.assets.LinkBlockAsset(ctx, link); err != nil {
+				s.logger.Error("Failed to link block asset", zap.Error(err))
+			}
+		}
+	}
+
+	s.logger.Info("Operation assets downloaded",
+		zap.String("operation_id", operationID.String()),
+		zap.Int("assets", len(downloaded)),
+		zap.Int("failed", len(manifest.Failed)),
+	)
+
+	failed := manifest.Failed
+	manifest, err = s.GetAssetManifest(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Failed = failed
+
+	return manifest, nil
+}
+
+// nestedRef ссылка из скачанной таблицы стилей
+type nestedRef struct {
+	parent string
+	ref    assetRef
+}
+
+// downloadWave параллельно скачивает ссылки одной волны, которых еще нет в downloaded,
+// и возвращает ссылки из скачанных таблиц стилей
+func (s *downloaderService) downloadWave(ctx context.Context, operationID uuid.UUID, refs []assetRef, downloaded map[string]*dto.Asset, manifest *dto.AssetManifest) []nestedRef {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		nested []nestedRef
+	)
+
+	concurrency := s.cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, ref := range refs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(ref assetRef) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			asset, children, err := s.downloadAsset(ctx, operationID, ref)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				s.logger.Warn("Failed to download asset", zap.Error(err), zap.String("url", ref.url))
+				manifest.Failed = append(manifest.Failed, dto.FailedAsset{URL: ref.url, Error: err.Error()})
+				return
+			}
+
+			downloaded[ref.url] = asset
+			for _, child := range children {
+				nested = append(nested, nestedRef{parent: ref.url, ref: child})
+			}
+		}(ref)
+	}
+
+	wg.Wait()
+
+	return nested
+}
+
+// downloadAsset скачивает и сохраняет один файл; для таблиц стилей возвращает ссылки из них
+func (s *downloaderService) downloadAsset(ctx context.Context, operationID uuid.UUID, ref assetRef) (*dto.Asset, []assetRef, error) {
+	fetched, err := fetchAsset(ctx, s.client, ref.url, s.cfg.MaxAssetSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	asset, err := storeFetchedAsset(ctx, s.storage, operationID, ref.kind, ref.url, fetched)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	if err := s.assets.SaveAsset(ctx, asset); err != nil {
+		return nil, nil, err
+	}
+
+	var children []assetRef
+	if ref.kind == dto.AssetKindStylesheet {
+		if base, err := url.Parse(fetched.url); err == nil {
+			children = cssAssetRefs(string(fetched.data), base)
+		}
+	}
+
+	return asset, children, nil
 }
 
-// DownloadByOperationID загружает файлы по ID операции и сохраняет их в указанный путь
-func (s *downloaderService) DownloadByOperationID(ctx context.Context, operationID uuid.UUID, path string) error {
-	// TODO: Реализовать загрузку файлов по ID операции.
-	// Алгоритм:
-	// 1. Получить результаты операции через парсер-сервис
-	// 2. Создать каталог для сохранения файлов, если он не существует
-	// 3. Сохранить HTML и данные каждого блока в отдельные файлы
-	// 4. Вернуть ошибку, если что-то пошло не так, или nil в случае успеха
-	//
-	// Пример реализации:
-	// // Получаем результаты операции
-	// result, err := s.parserService.GetOperationResult(ctx, operationID)
-	// if err != nil {
-	//     return err
-	// }
-	//
-	// // Создаем каталог, если он не существует
-	// if _, err := os.Stat(path); os.IsNotExist(err) {
-	//     if err := os.MkdirAll(path, 0755); err != nil {
-	//         return err
-	//     }
-	// }
-	//
-	// // Создаем файл с основной информацией об операции
-	// infoFile := filepath.Join(path, fmt.Sprintf("operation_%s_info.txt", operationID.String()))
-	// infoContent := fmt.Sprintf("Operation ID: %s\n", result.Operation.ID.String())
-	// infoContent += fmt.Sprintf("URL: %s\n", result.Operation.URL)
-	// infoContent += fmt.Sprintf("Status: %s\n", result.Operation.Status)
-	// infoContent += fmt.Sprintf("Created At: %s\n", result.Operation.CreatedAt.Format(time.RFC3339))
-	// infoContent += fmt.Sprintf("Updated At: %s\n", result.Operation.UpdatedAt.Format(time.RFC3339))
-	//
-	// if err := os.WriteFile(infoFile, []byte(infoContent), 0644); err != nil {
-	//     return err
-	// }
-	//
-	// // Сохраняем каждый блок в отдельный файл
-	// for i, block := range result.Blocks {
-	//     // Создаем файл с HTML-кодом блока
-	//     htmlFile := filepath.Join(path, fmt.Sprintf("block_%d_%s_%s.html", i, block.BlockType, block.ID.String()))
-	//     if err := os.WriteFile(htmlFile, []byte(block.HTML), 0644); err != nil {
-	//         return err
-	//     }
-	// }
-	//
-	// return nil
-	//
-	// Важно: Пути к файлам должны быть безопасными и не содержать специальных символов.
-	// Можно использовать filepath.Clean() для нормализации путей.
-
-	panic("implement me: разработайте алгоритм загрузки файлов по ID операции")
+// GetAssetManifest возвращает файлы операции и их связь с блоками
+func (s *downloaderService) GetAssetManifest(ctx context.Context, operationID uuid.UUID) (*dto.AssetManifest, error) {
+	assets, err := s.assets.GetAssetsByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.assets.GetBlockAssetLinks(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.AssetManifest{
+		OperationID: operationID,
+		Assets:      assets,
+		Links:       links,
+	}, nil
+}
+
+// blockAssetRefs находит в HTML блока ссылки на картинки, фоны, шрифты, стили, скрипты и постеры видео
+func blockAssetRefs(html string, base *url.URL) []assetRef {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	var refs []assetRef
+	seen := make(map[string]bool)
+	add := func(kind dto.AssetKind, ref string) {
+		resolved, ok := resolveURL(base, ref)
+		if !ok || seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		refs = append(refs, assetRef{kind: kind, url: resolved})
+	}
+
+	doc.Find("img").Each(func(i int, sel *goquery.Selection) {
+		if src := imageSource(sel); src != "" {
+			add(dto.AssetKindImage, src)
+		}
+		for _, candidate := range srcsetURLs(sel.AttrOr("srcset", "")) {
+			add(dto.AssetKindImage, candidate)
+		}
+	})
+
+	doc.Find("picture source[srcset]").Each(func(i int, sel *goquery.Selection) {
+		for _, candidate := range srcsetURLs(sel.AttrOr("srcset", "")) {
+			add(dto.AssetKindImage, candidate)
+		}
+	})
+
+	doc.Find("video[poster]").Each(func(i int, sel *goquery.Selection) {
+		add(dto.AssetKindPoster, sel.AttrOr("poster", ""))
+	})
+
+	doc.Find("link[href]").Each(func(i int, sel *goquery.Selection) {
+		rel := strings.ToLower(sel.AttrOr("rel", ""))
+		switch {
+		case strings.Contains(rel, "stylesheet"):
+			add(dto.AssetKindStylesheet, sel.AttrOr("href", ""))
+		case strings.Contains(rel, "preload") && sel.AttrOr("as", "") == "font":
+			add(dto.AssetKindFont, sel.AttrOr("href", ""))
+		}
+	})
+
+	doc.Find("script[src]").Each(func(i int, sel *goquery.Selection) {
+		add(dto.AssetKindScript, sel.AttrOr("src", ""))
+	})
+
+	// Фоны и шрифты из инлайновых стилей и блоков <style>
+	doc.Find("[style]").Each(func(i int, sel *goquery.Selection) {
+		for _, ref := range cssAssetRefs(sel.AttrOr("style", ""), base) {
+			add(ref.kind, ref.url)
+		}
+	})
+	doc.Find("style").Each(func(i int, sel *goquery.Selection) {
+		for _, ref := range cssAssetRefs(sel.Text(), base) {
+			add(ref.kind, ref.url)
+		}
+	})
+
+	return refs
 }
 
-// GetAvailableFormats возвращает список доступных форматов для загрузки
-func (s *downloaderService) GetAvailableFormats() []string {
-	// TODO: Реализовать получение списка доступных форматов.
-	// Возвращает список форматов, поддерживаемых для экспорта.
-	//
-	// Пример реализации:
-	// return []string{"excel", "text", "html", "json"}
-	//
-	// Или можно просто вернуть стандартные форматы:
-	// return []string{"excel", "text"}
-	//
-	// Комментарий: При добавлении новых форматов их нужно будет также добавить в метод DownloadByOperationIDWithFormat.
-
-	return []string{"excel", "text"}
+// cssAssetRefs находит в CSS ссылки на шрифты, фоновые картинки и вложенные таблицы стилей
+func cssAssetRefs(css string, base *url.URL) []assetRef {
+	var refs []assetRef
+
+	for _, match := range reCSSImport.FindAllStringSubmatch(css, -1) {
+		if resolved, ok := resolveURL(base, firstNonEmpty(match[1:]...)); ok {
+			refs = append(refs, assetRef{kind: dto.AssetKindStylesheet, url: resolved})
+		}
+	}
+
+	for _, match := range reCSSURL.FindAllStringSubmatchIndex(css, -1) {
+		ref := ""
+		for group := 1; group <= 3; group++ {
+			if match[2*group] >= 0 {
+				ref = css[match[2*group]:match[2*group+1]]
+				break
+			}
+		}
+
+		resolved, ok := resolveURL(base, ref)
+		if !ok || strings.HasPrefix(resolved, "data:") {
+			continue
+		}
+
+		refs = append(refs, assetRef{kind: cssRefKind(css[:match[0]], resolved), url: resolved})
+	}
+
+	return refs
+}
+
+// cssRefKind определяет назначение ссылки из CSS по расширению и по правилу, в котором она стоит
+func cssRefKind(before, ref string) dto.AssetKind {
+	parsed, err := url.Parse(ref)
+	if err == nil {
+		ext := strings.ToLower(path.Ext(parsed.Path))
+		if fontExtensions[ext] {
+			return dto.AssetKindFont
+		}
+		if ext == ".css" {
+			return dto.AssetKindStylesheet
+		}
+	}
+
+	// Ссылка внутри @font-face без расширения шрифта
+	if open := strings.LastIndex(before, "{"); open >= 0 && strings.Contains(strings.ToLower(before[max(0, open-20):open]), "@font-face") {
+		return dto.AssetKindFont
+	}
+
+	// url() сразу после @import
+	if strings.HasSuffix(strings.TrimSpace(before), "@import") {
+		return dto.AssetKindStylesheet
+	}
+
+	return dto.AssetKindBackground
+}
+
+// srcsetURLs возвращает адреса всех кандидатов из srcset
+func srcsetURLs(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// firstNonEmpty возвращает первую непустую строку
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// appendUnique добавляет ID, если его еще нет в списке
+func appendUnique(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
 }
 
-// DownloadByOperationIDWithFormat загружает файлы в указанном формате
-func (s *downloaderService) DownloadByOperationIDWithFormat(ctx context.Context, operationID uuid.UUID, format string, path string) error {
-	// TODO: Реализовать загрузку файлов в указанном формате.
-	// Алгоритм:
-	// 1. Проверить, поддерживается ли указанный формат
-	// 2. Получить результаты операции через парсер-сервис
-	// 3. Создать каталог для сохранения файлов, если он не существует
-	// 4. В зависимости от формата, создать соответствующий файл:
-	//    - Excel: таблица с данными блоков
-	//    - Text: текстовый файл с информацией
-	//    - HTML: HTML-файлы с блоками
-	//    - JSON: JSON-файл с данными
-	// 5. Вернуть ошибку, если что-то пошло не так, или nil в случае успеха
-	//
-	// Пример реализации для Excel-формата:
-	// if format == "excel" {
-	//     result, err := s.parserService.GetOperationResult(ctx, operationID)
-	//     if err != nil {
-	//         return err
-	//     }
-	//
-	//     // Создаем каталог, если он не существует
-	//     if _, err := os.Stat(path); os.IsNotExist(err) {
-	//         if err := os.MkdirAll(path, 0755); err != nil {
-	//             return err
-	//         }
-	//     }
-	//
-	//     // Создаем Excel-файл
-	//     f := excelize.NewFile()
-	//
-	//     // Устанавливаем заголовки для первого листа (Информация об операции)
-	//     f.SetCellValue("Sheet1", "A1", "ID")
-	//     f.SetCellValue("Sheet1", "B1", "URL")
-	//     f.SetCellValue("Sheet1", "C1", "Status")
-	//     f.SetCellValue("Sheet1", "D1", "Created At")
-	//     f.SetCellValue("Sheet1", "E1", "Updated At")
-	//
-	//     // Заполняем данные операции
-	//     f.SetCellValue("Sheet1", "A2", result.Operation.ID.String())
-	//     f.SetCellValue("Sheet1", "B2", result.Operation.URL)
-	//     f.SetCellValue("Sheet1", "C2", string(result.Operation.Status))
-	//     f.SetCellValue("Sheet1", "D2", result.Operation.CreatedAt.Format(time.RFC3339))
-	//     f.SetCellValue("Sheet1", "E2", result.Operation.UpdatedAt.Format(time.RFC3339))
-	//
-	//     // Создаем новый лист для блоков
-	//     f.NewSheet("Blocks")
-	//
-	//     // Устанавливаем заголовки для листа блоков
-	//     f.SetCellValue("Blocks", "A1", "ID")
-	//     f.SetCellValue("Blocks", "B1", "Type")
-	//     f.SetCellValue("Blocks", "C1", "Platform")
-	//     f.SetCellValue("Blocks", "D1", "Created At")
-	//
-	//     // Заполняем данные блоков
-	//     for i, block := range result.Blocks {
-	//         row := i + 2
-	//         f.SetCellValue("Blocks", fmt.Sprintf("A%d", row), block.ID.String())
-	//         f.SetCellValue("Blocks", fmt.Sprintf("B%d", row), string(block.BlockType))
-	//         f.SetCellValue("Blocks", fmt.Sprintf("C%d", row), string(block.Platform))
-	//         f.SetCellValue("Blocks", fmt.Sprintf("D%d", row), block.CreatedAt.Format(time.RFC3339))
-	//     }
-	//
-	//     // Сохраняем Excel-файл
-	//     excelFile := filepath.Join(path, fmt.Sprintf("operation_%s.xlsx", operationID.String()))
-	//     if err := f.SaveAs(excelFile); err != nil {
-	//         return err
-	//     }
-	//
-	//     return nil
-	// }
-	//
-	// // Обработка других форматов...
-	//
-	// Важно: Проверяйте поддерживаемые форматы перед обработкой.
-	// Если формат не поддерживается, возвращайте соответствующую ошибку.
-
-	panic("implement me: разработайте алгоритм загрузки файлов в указанном формате")
+// GetAvailableFormats возвращает список форматов экспорта, включая пользовательские шаблоны
+func (s *downloaderService) GetAvailableFormats(ctx context.Context) ([]string, error) {
+	return s.exporters.Names(ctx)
 }
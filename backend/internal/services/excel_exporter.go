@@ -0,0 +1,542 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// Листы отчета Excel в порядке следования
+const (
+	summarySheet      = "Summary"
+	headerSheet       = "Header"
+	footerSheet       = "Footer"
+	contentSheet      = "Content Blocks"
+	contactsSheet     = "Contacts"
+	socialsSheet      = "Socials"
+	technologiesSheet = "Technologies"
+	devicesSheet      = "Devices"
+)
+
+// excelExporter формирует отчет Excel: сводку, листы шапок, подвалов и контентных блоков
+// с колонкой на каждый компонент содержимого, контакты, соцсети и технологии сайта
+type excelExporter struct {
+	logger  *zap.Logger
+	storage repos.BlobStorage
+	assets  repos.AssetRepo
+}
+
+// NewExcelExporter создает экспортер в Excel
+func NewExcelExporter(logger *zap.Logger, storage repos.BlobStorage, assets repos.AssetRepo) Exporter {
+	return &excelExporter{
+		logger:  logger,
+		storage: storage,
+		assets:  assets,
+	}
+}
+
+func (e *excelExporter) Name() string { return "excel" }
+func (e *excelExporter) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+func (e *excelExporter) Extension() string { return "xlsx" }
+
+// reportSummary счетчики операции для листа сводки
+type reportSummary struct {
+	blocks  int
+	sheets  map[string]int
+	devices map[dto.DeviceProfile]int
+}
+
+// Export пишет отчет по операции. Блоки обходятся дважды: первый проход собирает компоненты
+// для колонок листов, второй потоково пишет строки через StreamWriter.
+func (e *excelExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	operation := source.Operation()
+	base, _ := url.Parse(operation.URL)
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newReportStyles(f)
+	if err != nil {
+		return err
+	}
+	if err := f.SetSheetName("Sheet1", summarySheet); err != nil {
+		return fmt.Errorf("failed to create summary sheet: %w", err)
+	}
+
+	// Первый проход: пути компонентов содержимого для каждого листа блоков
+	keys := make(map[string]map[string]bool)
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		flat, err := flattenContent(block.Content)
+		if err != nil {
+			return fmt.Errorf("failed to flatten block %s: %w", block.ID, err)
+		}
+
+		sheet := blockSheetName(block)
+		if keys[sheet] == nil {
+			keys[sheet] = make(map[string]bool)
+		}
+		for key := range flat {
+			keys[sheet][key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sheets := make(map[string]*blockSheet)
+	for _, name := range []string{headerSheet, footerSheet, contentSheet} {
+		sheet, err := newBlockSheet(f, styles, name, sortedKeys(keys[name]))
+		if err != nil {
+			return err
+		}
+		sheets[name] = sheet
+	}
+
+	// Второй проход: строки блоков, контакты, соцсети и технологии
+	findings := newReportFindings()
+	summary := &reportSummary{sheets: make(map[string]int), devices: make(map[dto.DeviceProfile]int)}
+	devices := make(map[dto.DeviceProfile][]string)
+	thumbnails := 0
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		raw, components, err := blockComponents(block, base)
+		if err != nil {
+			return fmt.Errorf("failed to parse block %s components: %w", block.ID, err)
+		}
+		findings.collect(block, raw, components)
+
+		// Картинки держатся в памяти до записи файла, поэтому миниатюр не больше maxExcelThumbnails
+		name := blockSheetName(block)
+		embed := thumbnails < maxExcelThumbnails
+		if err := e.writeBlockRow(ctx, sheets[name], block, components, embed); err != nil {
+			return err
+		}
+		if embed && block.ScreenshotKey != "" {
+			thumbnails++
+		}
+
+		summary.blocks++
+		summary.sheets[name]++
+		summary.devices[block.Device]++
+
+		label := string(block.BlockType)
+		if block.Classification != nil {
+			label += ": " + string(block.Classification.Category)
+		}
+		devices[block.Device] = append(devices[block.Device], label)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{headerSheet, footerSheet, contentSheet} {
+		if err := sheets[name].close(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFindingSheets(f, styles, findings); err != nil {
+		return err
+	}
+
+	// Сравнение структуры страницы на разных устройствах
+	if len(devices) > 1 {
+		writeDeviceComparison(f, styles, devices)
+	}
+
+	e.writeSummary(ctx, f, styles, operation, summary, findings)
+	f.SetActiveSheet(0)
+
+	if _, err := f.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write Excel file: %w", err)
+	}
+
+	return nil
+}
+
+// blockSheetName возвращает лист отчета для блока
+func blockSheetName(block *dto.Block) string {
+	switch block.BlockType {
+	case dto.BlockTypeHeader:
+		return headerSheet
+	case dto.BlockTypeFooter:
+		return footerSheet
+	default:
+		return contentSheet
+	}
+}
+
+// blockSheet лист блоков одного типа с колонкой на каждый компонент содержимого
+type blockSheet struct {
+	*reportSheet
+	components []string
+	content    bool
+}
+
+// newBlockSheet создает лист блоков; у контентных блоков есть колонки категории классификатора
+func newBlockSheet(f *excelize.File, styles *reportStyles, name string, components []string) (*blockSheet, error) {
+	content := name == contentSheet
+
+	columns := []reportColumn{
+		{title: "#", width: 6},
+		{title: "Screenshot", width: blockThumbnailWidth / 7},
+		{title: "Device", width: 10},
+		{title: "Platform", width: 12},
+	}
+	if content {
+		columns = append(columns, reportColumn{title: "Category", width: 16}, reportColumn{title: "Score", width: 8})
+	}
+	for _, key := range components {
+		columns = append(columns, reportColumn{title: componentTitle(key), width: 30})
+	}
+	columns = append(columns, reportColumn{title: "Text", width: 60}, reportColumn{title: "Block ID", width: 38})
+
+	sheet, err := newReportSheet(f, styles, name, columns, blockRowHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockSheet{reportSheet: sheet, components: components, content: content}, nil
+}
+
+// writeBlockRow пишет строку блока и, если embed, вставляет миниатюру скриншота;
+// иначе в ячейке миниатюры остается пометка, что скриншот не вставлен
+func (e *excelExporter) writeBlockRow(ctx context.Context, sheet *blockSheet, block *dto.Block, components map[string]blockComponent, embed bool) error {
+	var screenshot reportCell
+	if block.ScreenshotKey != "" && !embed {
+		screenshot.value = "Screenshot not embedded"
+	}
+
+	cells := []reportCell{
+		{value: sheet.row},
+		screenshot,
+		{value: string(block.Device)},
+		{value: string(block.Platform)},
+	}
+	if sheet.content {
+		if block.Classification != nil {
+			cells = append(cells, reportCell{value: string(block.Classification.Category)}, reportCell{value: block.Classification.Score})
+		} else {
+			cells = append(cells, reportCell{}, reportCell{})
+		}
+	}
+	for _, key := range sheet.components {
+		component := components[key]
+		cells = append(cells, reportCell{value: component.text, link: component.link})
+	}
+	cells = append(cells, reportCell{value: blockText(block.HTML)}, reportCell{value: block.ID.String()})
+
+	row, err := sheet.addRow(cells)
+	if err != nil {
+		return err
+	}
+
+	if block.ScreenshotKey != "" && embed {
+		e.embedImage(ctx, sheet.f, sheet.name, fmt.Sprintf("B%d", row), block.ScreenshotKey, ".png", blockThumbnailWidth, blockThumbnailHeight)
+	}
+
+	return nil
+}
+
+// writeFindingSheets пишет листы контактов, соцсетей и технологий
+func writeFindingSheets(f *excelize.File, styles *reportStyles, findings *reportFindings) error {
+	contacts, err := newReportSheet(f, styles, contactsSheet, []reportColumn{
+		{title: "Type", width: 12},
+		{title: "Value", width: 40},
+		{title: "Block Type", width: 12},
+		{title: "Block ID", width: 38},
+	}, 0)
+	if err != nil {
+		return err
+	}
+	for _, contact := range findings.contacts {
+		cells := []reportCell{
+			{value: contact.kind},
+			{value: contact.value, link: contact.link},
+			{value: string(contact.blockType)},
+			{value: contact.blockID},
+		}
+		if _, err := contacts.addRow(cells); err != nil {
+			return err
+		}
+	}
+	if err := contacts.close(); err != nil {
+		return err
+	}
+
+	socials, err := newReportSheet(f, styles, socialsSheet, []reportColumn{
+		{title: "Network", width: 16},
+		{title: "URL", width: 50},
+		{title: "Block Type", width: 12},
+		{title: "Block ID", width: 38},
+	}, 0)
+	if err != nil {
+		return err
+	}
+	for _, social := range findings.socials {
+		cells := []reportCell{
+			{value: social.network},
+			{value: social.url, link: social.url},
+			{value: string(social.blockType)},
+			{value: social.blockID},
+		}
+		if _, err := socials.addRow(cells); err != nil {
+			return err
+		}
+	}
+	if err := socials.close(); err != nil {
+		return err
+	}
+
+	technologies, err := newReportSheet(f, styles, technologiesSheet, []reportColumn{
+		{title: "Technology", width: 20},
+		{title: "Category", width: 20},
+		{title: "Blocks", width: 8},
+		{title: "Evidence", width: 60},
+	}, 0)
+	if err != nil {
+		return err
+	}
+	for _, technology := range findings.sortedTechnologies() {
+		cells := []reportCell{
+			{value: technology.name},
+			{value: technology.category},
+			{value: technology.blocks},
+			{value: strings.Join(technology.evidence, "; ")},
+		}
+		if _, err := technologies.addRow(cells); err != nil {
+			return err
+		}
+	}
+	return technologies.close()
+}
+
+// writeSummary заполняет лист сводки: данные операции, счетчики, логотип и скриншот страницы
+func (e *excelExporter) writeSummary(ctx context.Context, f *excelize.File, styles *reportStyles, operation *dto.Operation, summary *reportSummary, findings *reportFindings) {
+	const sheet = summarySheet
+
+	var deviceCounts []string
+	for _, device := range []dto.DeviceProfile{dto.DeviceDesktop, dto.DeviceTablet, dto.DeviceMobile} {
+		if count, ok := summary.devices[device]; ok {
+			deviceCounts = append(deviceCounts, fmt.Sprintf("%s: %d", device, count))
+		}
+	}
+
+	var technologies []string
+	for _, technology := range findings.sortedTechnologies() {
+		technologies = append(technologies, technology.name)
+	}
+
+	var siteName, themeColor string
+	if operation.Brand != nil {
+		siteName, themeColor = operation.Brand.Name, operation.Brand.ThemeColor
+	}
+
+	rows := []struct {
+		label string
+		value interface{}
+	}{
+		{"URL", operation.URL},
+		{"Site Name", siteName},
+		{"Operation ID", operation.ID.String()},
+		{"Status", string(operation.Status)},
+		{"Source", string(operation.SourceType)},
+		{"Parser Version", operation.ParserVersion},
+		{"Created At", operation.CreatedAt.Format(time.RFC3339)},
+		{"Updated At", operation.UpdatedAt.Format(time.RFC3339)},
+		{"Theme Color", themeColor},
+		{"Blocks", summary.blocks},
+		{"Header Blocks", summary.sheets[headerSheet]},
+		{"Footer Blocks", summary.sheets[footerSheet]},
+		{"Content Blocks", summary.sheets[contentSheet]},
+		{"Devices", strings.Join(deviceCounts, ", ")},
+		{"Contacts", len(findings.contacts)},
+		{"Social Profiles", len(findings.socials)},
+		{"Technologies", strings.Join(technologies, ", ")},
+	}
+
+	f.SetColWidth(sheet, "A", "A", 18)
+	f.SetColWidth(sheet, "B", "B", 60)
+	f.SetColWidth(sheet, "D", "D", 30)
+	f.SetColWidth(sheet, "F", "F", pageThumbnailWidth/7)
+
+	f.SetCellStyle(sheet, "A1", "B1", styles.header)
+	f.SetCellValue(sheet, "A1", "Field")
+	f.SetCellValue(sheet, "B1", "Value")
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	for i, row := range rows {
+		label, value := fmt.Sprintf("A%d", i+2), fmt.Sprintf("B%d", i+2)
+		f.SetCellValue(sheet, label, row.label)
+		f.SetCellValue(sheet, value, row.value)
+		f.SetCellStyle(sheet, label, label, styles.label)
+		f.SetCellStyle(sheet, value, value, styles.text)
+	}
+	f.SetCellHyperLink(sheet, "B2", operation.URL, "External")
+	f.SetCellStyle(sheet, "B2", "B2", styles.link)
+
+	// Логотип из фирменного набора и скриншот всей страницы
+	f.SetCellValue(sheet, "D1", "Logo")
+	f.SetCellStyle(sheet, "D1", "D1", styles.header)
+	e.embedLogo(ctx, f, sheet, "D2", operation.ID)
+
+	if operation.ScreenshotKey != "" {
+		f.SetCellValue(sheet, "F1", "Screenshot")
+		f.SetCellStyle(sheet, "F1", "F1", styles.header)
+		e.embedImage(ctx, f, sheet, "F2", operation.ScreenshotKey, ".png", pageThumbnailWidth, 0)
+	}
+}
+
+// embedLogo вставляет растровый логотип из фирменного набора операции, если он скачан
+func (e *excelExporter) embedLogo(ctx context.Context, f *excelize.File, sheet, cell string, operationID uuid.UUID) {
+	assets, err := e.assets.GetAssetsByOperationID(ctx, operationID)
+	if err != nil {
+		e.logger.Warn("Failed to get assets for export", zap.Error(err), zap.String("operation_id", operationID.String()))
+		return
+	}
+
+	for _, asset := range assets {
+		if asset.Kind != dto.AssetKindLogo {
+			continue
+		}
+		switch ext := assetExtensions[asset.ContentType]; ext {
+		case ".png", ".jpg", ".gif":
+			e.embedImage(ctx, f, sheet, cell, asset.StorageKey, ext, logoMaxWidth, logoMaxHeight)
+			return
+		}
+	}
+}
+
+// sortedKeys возвращает ключи множества по алфавиту
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeDeviceComparison добавляет лист, где блоки разных устройств стоят в соседних колонках по порядку
+func writeDeviceComparison(f *excelize.File, styles *reportStyles, devices map[dto.DeviceProfile][]string) {
+	const sheet = devicesSheet
+	f.NewSheet(sheet)
+
+	order := []dto.DeviceProfile{dto.DeviceDesktop, dto.DeviceTablet, dto.DeviceMobile}
+
+	f.SetCellValue(sheet, "A1", "#")
+	column := 2
+	for _, device := range order {
+		labels, ok := devices[device]
+		if !ok {
+			continue
+		}
+
+		cell, _ := excelize.CoordinatesToCellName(column, 1)
+		f.SetCellValue(sheet, cell, device)
+		f.SetColWidth(sheet, cell[:1], cell[:1], 30)
+
+		for i, label := range labels {
+			cell, _ = excelize.CoordinatesToCellName(1, i+2)
+			f.SetCellValue(sheet, cell, i+1)
+
+			cell, _ = excelize.CoordinatesToCellName(column, i+2)
+			f.SetCellValue(sheet, cell, label)
+		}
+		column++
+	}
+
+	last, _ := excelize.CoordinatesToCellName(column-1, 1)
+	f.SetCellStyle(sheet, "A1", last, styles.header)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+// Размеры миниатюр скриншотов в Excel
+const (
+	pageThumbnailWidth   = 480
+	blockThumbnailWidth  = 320
+	blockThumbnailHeight = 240
+	logoMaxWidth         = 200
+	logoMaxHeight        = 100
+
+	// maxExcelThumbnails число блоков с миниатюрами в отчете; у остальных скриншот не вставляется
+	maxExcelThumbnails = 200
+
+	// blockRowHeight высота строк листа блоков в пунктах. excelize переводит пункты в пиксели
+	// с коэффициентом 4/3.4, а не 4/3 как Excel; при 210 пунктах миниатюра в 240 px остается в пределах строки для обоих.
+	blockRowHeight  = 210
+	headerRowHeight = 15
+)
+
+// embedImage вставляет картинку из хранилища в ячейку, уменьшая до заданной ширины
+// и, если maxHeight больше нуля, высоты.
+// Возвращает высоту вставленной миниатюры в пикселях или 0, если вставить не удалось.
+func (e *excelExporter) embedImage(ctx context.Context, f *excelize.File, sheet, cell, key, ext string, maxWidth, maxHeight int) int {
+	data, err := e.readImage(ctx, key)
+	if err != nil {
+		e.logger.Warn("Failed to read image for export", zap.Error(err), zap.String("key", key))
+		return 0
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 {
+		e.logger.Warn("Failed to decode image for export", zap.Error(err), zap.String("key", key))
+		return 0
+	}
+
+	scale := 1.0
+	if cfg.Width > maxWidth {
+		scale = float64(maxWidth) / float64(cfg.Width)
+	}
+	if maxHeight > 0 && float64(cfg.Height)*scale > float64(maxHeight) {
+		scale = float64(maxHeight) / float64(cfg.Height)
+	}
+
+	err = f.AddPictureFromBytes(sheet, cell, &excelize.Picture{
+		Extension: ext,
+		File:      data,
+		Format: &excelize.GraphicOptions{
+			ScaleX:          scale,
+			ScaleY:          scale,
+			LockAspectRatio: true,
+		},
+	})
+	if err != nil {
+		e.logger.Warn("Failed to embed image", zap.Error(err), zap.String("key", key))
+		return 0
+	}
+
+	return int(float64(cfg.Height) * scale)
+}
+
+// readImage читает картинку из хранилища целиком
+func (e *excelExporter) readImage(ctx context.Context, key string) ([]byte, error) {
+	reader, err := e.storage.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, repos.ErrBlobNotFound) {
+			return nil, ErrScreenshotNotFound
+		}
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
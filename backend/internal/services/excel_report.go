@@ -0,0 +1,574 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/net/html"
+
+	"scrapper/internal/dto"
+)
+
+// maxSheetHyperlinks ограничение ссылок на лист: excelize проверяет повторы перебором,
+// а Excel не открывает листы больше чем с 65530 ссылками
+const maxSheetHyperlinks = 10000
+
+// maxCellText длина текста блока в ячейке отчета
+const maxCellText = 500
+
+var (
+	// reEmail находит адреса электронной почты в тексте
+	reEmail = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// rePhone находит телефонные номера в тексте; число цифр проверяется отдельно
+	rePhone = regexp.MustCompile(`\+?\d[\d\s().-]{8,18}\d`)
+)
+
+// reportColumn колонка листа отчета
+type reportColumn struct {
+	title string
+	width float64
+}
+
+// reportCell значение ячейки отчета; link делает ячейку гиперссылкой
+type reportCell struct {
+	value interface{}
+	link  string
+}
+
+// reportStyles стили ячеек отчета
+type reportStyles struct {
+	header int
+	text   int
+	link   int
+	label  int
+}
+
+// newReportStyles регистрирует стили отчета в книге
+func newReportStyles(f *excelize.File) (*reportStyles, error) {
+	header, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#305496"}},
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create header style: %w", err)
+	}
+
+	text, err := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create text style: %w", err)
+	}
+
+	link, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Color: "#0563C1", Underline: "single"},
+		Alignment: &excelize.Alignment{Vertical: "top", WrapText: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link style: %w", err)
+	}
+
+	label, err := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Alignment: &excelize.Alignment{Vertical: "top"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create label style: %w", err)
+	}
+
+	return &reportStyles{header: header, text: text, link: link, label: label}, nil
+}
+
+// reportSheet лист отчета, который пишется потоково: заголовок закреплен, на колонках автофильтр
+type reportSheet struct {
+	f       *excelize.File
+	styles  *reportStyles
+	name    string
+	sw      *excelize.StreamWriter
+	columns int
+	row     int
+	links   int
+}
+
+// newReportSheet создает лист с колонками и строкой заголовка.
+// rowHeight задает высоту строк листа в пунктах, 0 оставляет высоту по умолчанию.
+func newReportSheet(f *excelize.File, styles *reportStyles, name string, columns []reportColumn, rowHeight float64) (*reportSheet, error) {
+	if _, err := f.NewSheet(name); err != nil {
+		return nil, fmt.Errorf("failed to create sheet %s: %w", name, err)
+	}
+
+	// StreamWriter не сохраняет высоты строк в модели листа, а excelize привязывает картинки по ним,
+	// поэтому высота задается для всего листа
+	if rowHeight > 0 {
+		customHeight := true
+		if err := f.SetSheetProps(name, &excelize.SheetPropsOptions{CustomHeight: &customHeight, DefaultRowHeight: &rowHeight}); err != nil {
+			return nil, fmt.Errorf("failed to set sheet %s props: %w", name, err)
+		}
+	}
+
+	sw, err := f.NewStreamWriter(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sheet %s writer: %w", name, err)
+	}
+
+	sheet := &reportSheet{f: f, styles: styles, name: name, sw: sw, columns: len(columns), row: 1}
+
+	for i, column := range columns {
+		if err := sw.SetColWidth(i+1, i+1, column.width); err != nil {
+			return nil, fmt.Errorf("failed to set column width: %w", err)
+		}
+	}
+
+	if err := sw.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return nil, fmt.Errorf("failed to freeze header row: %w", err)
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = excelize.Cell{StyleID: styles.header, Value: column.title}
+	}
+	if err := sw.SetRow("A1", header, excelize.RowOpts{Height: headerRowHeight}); err != nil {
+		return nil, fmt.Errorf("failed to write sheet %s header: %w", name, err)
+	}
+
+	return sheet, nil
+}
+
+// addRow пишет строку и возвращает ее номер
+func (s *reportSheet) addRow(cells []reportCell) (int, error) {
+	s.row++
+
+	values := make([]interface{}, len(cells))
+	for i, cell := range cells {
+		style := s.styles.text
+		if cell.link != "" && s.links < maxSheetHyperlinks {
+			style = s.styles.link
+		}
+		values[i] = excelize.Cell{StyleID: style, Value: cell.value}
+	}
+
+	ref, _ := excelize.CoordinatesToCellName(1, s.row)
+	if err := s.sw.SetRow(ref, values); err != nil {
+		return 0, fmt.Errorf("failed to write sheet %s row: %w", s.name, err)
+	}
+
+	// Ссылки и картинки можно добавлять в лист до Flush: они пишутся в него при завершении
+	for i, cell := range cells {
+		if cell.link == "" || s.links >= maxSheetHyperlinks {
+			continue
+		}
+		ref, _ := excelize.CoordinatesToCellName(i+1, s.row)
+		if err := s.f.SetCellHyperLink(s.name, ref, cell.link, "External"); err != nil {
+			return 0, fmt.Errorf("failed to set hyperlink: %w", err)
+		}
+		s.links++
+	}
+
+	return s.row, nil
+}
+
+// close включает автофильтр по заполненным строкам и завершает запись листа
+func (s *reportSheet) close() error {
+	last, _ := excelize.CoordinatesToCellName(s.columns, s.row)
+	if err := s.f.AutoFilter(s.name, "A1:"+last, nil); err != nil {
+		return fmt.Errorf("failed to set sheet %s auto filter: %w", s.name, err)
+	}
+
+	if err := s.sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush sheet %s: %w", s.name, err)
+	}
+
+	return nil
+}
+
+// blockComponent компонент содержимого блока: текст и первая ссылка фрагмента
+type blockComponent struct {
+	text  string
+	link  string
+	links []string
+}
+
+// blockComponents разбирает содержимое блока на компоненты по путям flattenContent.
+// Возвращает исходные значения и компоненты, где HTML-фрагменты превращены в текст, а ссылки сделаны абсолютными.
+func blockComponents(block *dto.Block, base *url.URL) (map[string]string, map[string]blockComponent, error) {
+	flat, err := flattenContent(block.Content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	components := make(map[string]blockComponent, len(flat))
+	for key, value := range flat {
+		components[key] = parseComponent(value, base)
+	}
+
+	return flat, components, nil
+}
+
+// parseComponent превращает значение компонента в текст и ссылки
+func parseComponent(value string, base *url.URL) blockComponent {
+	value = strings.TrimSpace(value)
+
+	if !strings.Contains(value, "<") {
+		component := blockComponent{text: value}
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			component.link = value
+			component.links = []string{value}
+		}
+		return component
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(value))
+	if err != nil {
+		return blockComponent{text: value}
+	}
+
+	var component blockComponent
+	doc.Find("a[href], img[src]").Each(func(_ int, sel *goquery.Selection) {
+		ref, _ := sel.Attr("href")
+		if goquery.NodeName(sel) == "img" {
+			ref, _ = sel.Attr("src")
+		}
+		if ref = strings.TrimSpace(ref); ref == "" || strings.HasPrefix(ref, "#") || isJavaScriptURL(ref) {
+			return
+		}
+		component.links = append(component.links, absoluteURL(base, ref))
+	})
+	if len(component.links) > 0 {
+		component.link = component.links[0]
+	}
+
+	component.text = selectionText(doc.Selection)
+	if component.text == "" {
+		// Логотип-картинка без текста подписывается альтернативным текстом
+		component.text = strings.TrimSpace(doc.Find("img[alt]").First().AttrOr("alt", ""))
+	}
+
+	return component
+}
+
+// componentTitle делает заголовок колонки из пути компонента: nav_links.0 -> Nav Links 0
+func componentTitle(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '.' || r == '-' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// blockText возвращает видимый текст блока, сокращенный до maxCellText символов
+func blockText(content string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+
+	text := []rune(selectionText(doc.Selection))
+	if len(text) > maxCellText {
+		return string(text[:maxCellText]) + "…"
+	}
+	return string(text)
+}
+
+// selectionText возвращает видимый текст, разделяя текст соседних элементов пробелом:
+// у goquery Text() ссылки меню склеиваются в одно слово
+func selectionText(sel *goquery.Selection) string {
+	var parts []string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			if text := strings.TrimSpace(node.Data); text != "" {
+				parts = append(parts, text)
+			}
+		case node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style" || node.Data == "template"):
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, node := range sel.Nodes {
+		walk(node)
+	}
+
+	return reWhitespace.ReplaceAllString(strings.Join(parts, " "), " ")
+}
+
+// reportContact контакт, найденный в компонентах блоков
+type reportContact struct {
+	kind      string
+	value     string
+	link      string
+	blockType dto.BlockType
+	blockID   string
+}
+
+// reportSocial ссылка на профиль в социальной сети
+type reportSocial struct {
+	network   string
+	url       string
+	blockType dto.BlockType
+	blockID   string
+}
+
+// reportTechnology технология сайта и признаки, по которым она найдена
+type reportTechnology struct {
+	name     string
+	category string
+	evidence []string
+	blocks   int
+}
+
+// technologySignature признаки технологии во фрагментах HTML компонентов
+type technologySignature struct {
+	name     string
+	category string
+	patterns []string
+}
+
+// technologySignatures известные технологии; признаки ищутся в HTML компонентов без учета регистра
+var technologySignatures = []technologySignature{
+	{name: "WordPress", category: "CMS", patterns: []string{"wp-content/", "wp-includes/", "wp-block-"}},
+	{name: "WooCommerce", category: "E-commerce", patterns: []string{"woocommerce"}},
+	{name: "Elementor", category: "Page builder", patterns: []string{"elementor-"}},
+	{name: "Contact Form 7", category: "Forms", patterns: []string{"wpcf7"}},
+	{name: "Tilda", category: "Website builder", patterns: []string{"tildacdn", "t-menu", "t-rec"}},
+	{name: "1C-Bitrix", category: "CMS", patterns: []string{"/bitrix/", "bx-"}},
+	{name: "Bootstrap", category: "UI framework", patterns: []string{"navbar-expand", "col-md-", "col-lg-"}},
+	{name: "Font Awesome", category: "Font", patterns: []string{"fa fa-", "fas fa-", "fab fa-", "font-awesome"}},
+	{name: "Swiper", category: "JavaScript library", patterns: []string{"swiper-"}},
+	{name: "Slick", category: "JavaScript library", patterns: []string{"slick-"}},
+	{name: "reCAPTCHA", category: "Security", patterns: []string{"g-recaptcha"}},
+	{name: "Google Maps", category: "Maps", patterns: []string{"maps.google.", "google.com/maps"}},
+	{name: "Yandex Maps", category: "Maps", patterns: []string{"api-maps.yandex", "yandex.ru/map-widget"}},
+	{name: "YouTube", category: "Video", patterns: []string{"youtube.com/embed", "youtube-nocookie.com"}},
+	{name: "JivoSite", category: "Live chat", patterns: []string{"jivosite", "jivo-"}},
+}
+
+// platformTechnologies технологии, которые следуют из определенной парсером платформы
+var platformTechnologies = map[dto.Platform]technologySignature{
+	dto.PlatformWordPress: {name: "WordPress", category: "CMS"},
+	dto.PlatformTilda:     {name: "Tilda", category: "Website builder"},
+	dto.PlatformBitrix:    {name: "1C-Bitrix", category: "CMS"},
+}
+
+// socialNetworks социальные сети и мессенджеры по домену ссылки
+var socialNetworks = map[string]string{
+	"facebook.com":     "Facebook",
+	"fb.com":           "Facebook",
+	"instagram.com":    "Instagram",
+	"twitter.com":      "X (Twitter)",
+	"x.com":            "X (Twitter)",
+	"linkedin.com":     "LinkedIn",
+	"youtube.com":      "YouTube",
+	"youtu.be":         "YouTube",
+	"vk.com":           "VK",
+	"ok.ru":            "Odnoklassniki",
+	"t.me":             "Telegram",
+	"telegram.me":      "Telegram",
+	"wa.me":            "WhatsApp",
+	"api.whatsapp.com": "WhatsApp",
+	"tiktok.com":       "TikTok",
+	"pinterest.com":    "Pinterest",
+	"github.com":       "GitHub",
+	"dzen.ru":          "Dzen",
+	"rutube.ru":        "Rutube",
+}
+
+// reportFindings контакты, соцсети и технологии, собранные по всем блокам операции без повторов
+type reportFindings struct {
+	contacts     []reportContact
+	socials      []reportSocial
+	technologies map[string]*reportTechnology
+
+	seen map[string]bool
+}
+
+func newReportFindings() *reportFindings {
+	return &reportFindings{
+		technologies: make(map[string]*reportTechnology),
+		seen:         make(map[string]bool),
+	}
+}
+
+// collect добавляет находки из компонентов блока
+func (r *reportFindings) collect(block *dto.Block, raw map[string]string, components map[string]blockComponent) {
+	blockID := block.ID.String()
+
+	keys := make([]string, 0, len(components))
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		component := components[key]
+
+		for _, link := range component.links {
+			r.collectLink(link, block.BlockType, blockID)
+		}
+
+		for _, email := range reEmail.FindAllString(component.text, -1) {
+			r.addContact(reportContact{kind: "email", value: email, link: "mailto:" + email, blockType: block.BlockType, blockID: blockID})
+		}
+		for _, phone := range rePhone.FindAllString(component.text, -1) {
+			if digits := phoneDigits(phone); len(digits) >= 10 && len(digits) <= 15 {
+				r.addContact(reportContact{kind: "phone", value: strings.TrimSpace(phone), link: "tel:+" + digits, blockType: block.BlockType, blockID: blockID})
+			}
+		}
+		if strings.Contains(strings.ToLower(key), "address") && component.text != "" {
+			r.addContact(reportContact{kind: "address", value: component.text, blockType: block.BlockType, blockID: blockID})
+		}
+	}
+
+	// Технологии по платформе и признакам в HTML компонентов; блок учитывается один раз
+	found := make(map[string]string)
+	if platform, ok := platformTechnologies[block.Platform]; ok {
+		found[platform.name] = "platform: " + string(block.Platform)
+	}
+	for _, key := range keys {
+		lower := strings.ToLower(raw[key])
+		for _, signature := range technologySignatures {
+			if _, ok := found[signature.name]; ok {
+				continue
+			}
+			for _, pattern := range signature.patterns {
+				if strings.Contains(lower, pattern) {
+					found[signature.name] = key + ": " + pattern
+					break
+				}
+			}
+		}
+	}
+	for name, evidence := range found {
+		technology, ok := r.technologies[name]
+		if !ok {
+			technology = &reportTechnology{name: name, category: technologyCategory(name)}
+			r.technologies[name] = technology
+		}
+		technology.blocks++
+		if len(technology.evidence) < 3 && !containsString(technology.evidence, evidence) {
+			technology.evidence = append(technology.evidence, evidence)
+		}
+	}
+}
+
+// collectLink разбирает ссылку компонента: tel: и mailto: становятся контактами, ссылки на соцсети профилями
+func (r *reportFindings) collectLink(link string, blockType dto.BlockType, blockID string) {
+	lower := strings.ToLower(link)
+	switch {
+	case strings.HasPrefix(lower, "tel:"):
+		digits := phoneDigits(link[len("tel:"):])
+		if digits != "" {
+			r.addContact(reportContact{kind: "phone", value: strings.TrimSpace(link[len("tel:"):]), link: "tel:+" + digits, blockType: blockType, blockID: blockID})
+		}
+		return
+	case strings.HasPrefix(lower, "mailto:"):
+		email := link[len("mailto:"):]
+		if i := strings.IndexByte(email, '?'); i >= 0 {
+			email = email[:i]
+		}
+		if email != "" {
+			r.addContact(reportContact{kind: "email", value: email, link: "mailto:" + email, blockType: blockType, blockID: blockID})
+		}
+		return
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for domain, network := range socialNetworks {
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		// Ссылки «поделиться» и встроенные плееры не являются профилями сайта
+		if strings.Contains(parsed.Path, "/share") || strings.Contains(parsed.Path, "/embed") || strings.Contains(parsed.Path, "/intent") {
+			return
+		}
+		if r.seen["social:"+link] {
+			return
+		}
+		r.seen["social:"+link] = true
+		r.socials = append(r.socials, reportSocial{network: network, url: link, blockType: blockType, blockID: blockID})
+		return
+	}
+}
+
+// addContact добавляет контакт, если такого еще нет
+func (r *reportFindings) addContact(contact reportContact) {
+	key := contact.kind + ":" + strings.ToLower(contact.value)
+	if contact.kind == "phone" {
+		key = contact.kind + ":" + phoneDigits(contact.value)
+	}
+	if r.seen[key] {
+		return
+	}
+	r.seen[key] = true
+	r.contacts = append(r.contacts, contact)
+}
+
+// sortedTechnologies возвращает технологии по убыванию числа блоков
+func (r *reportFindings) sortedTechnologies() []*reportTechnology {
+	technologies := make([]*reportTechnology, 0, len(r.technologies))
+	for _, technology := range r.technologies {
+		technologies = append(technologies, technology)
+	}
+	sort.Slice(technologies, func(i, j int) bool {
+		if technologies[i].blocks != technologies[j].blocks {
+			return technologies[i].blocks > technologies[j].blocks
+		}
+		return technologies[i].name < technologies[j].name
+	})
+	return technologies
+}
+
+// technologyCategory возвращает категорию технологии по имени
+func technologyCategory(name string) string {
+	for _, signature := range technologySignatures {
+		if signature.name == name {
+			return signature.category
+		}
+	}
+	for _, signature := range platformTechnologies {
+		if signature.name == name {
+			return signature.category
+		}
+	}
+	return ""
+}
+
+// phoneDigits оставляет в номере только цифры; российский номер с 8 приводится к 7
+func phoneDigits(phone string) string {
+	var digits strings.Builder
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits.WriteRune(c)
+		}
+	}
+
+	result := digits.String()
+	if len(result) == 11 && result[0] == '8' {
+		result = "7" + result[1:]
+	}
+	return result
+}
+
+// containsString проверяет, есть ли строка в списке
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
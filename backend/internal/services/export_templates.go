@@ -0,0 +1,373 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+var (
+	// ErrExportTemplateNotFound возвращается, если шаблона экспорта с таким именем нет
+	ErrExportTemplateNotFound = errors.New("export template not found")
+
+	// ErrInvalidExportTemplate возвращается, если шаблон не разбирается или не выполняется на данных операции
+	ErrInvalidExportTemplate = errors.New("invalid export template")
+)
+
+var (
+	// reExportTemplateName имя шаблона: оно же значение format и часть имени файла экспорта
+	reExportTemplateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+	reExportTemplateExtension = regexp.MustCompile(`^[a-z0-9]{1,16}$`)
+)
+
+// exportTemplateDefaults MIME-тип и расширение файла по умолчанию для движков шаблонов
+var exportTemplateDefaults = map[dto.ExportTemplateEngine]struct {
+	contentType string
+	extension   string
+}{
+	dto.ExportTemplateEngineText: {contentType: "text/plain; charset=utf-8", extension: "txt"},
+	dto.ExportTemplateEngineHTML: {contentType: "text/html; charset=utf-8", extension: "html"},
+}
+
+// exportTemplateService реализация ExportTemplateService
+type exportTemplateService struct {
+	logger    *zap.Logger
+	repo      repos.ExportTemplateRepo
+	parser    repos.ParserRepo
+	exporters ExporterRegistry
+}
+
+// NewExportTemplateService создает сервис пользовательских шаблонов экспорта
+func NewExportTemplateService(logger *zap.Logger, repo repos.ExportTemplateRepo, parser repos.ParserRepo, exporters ExporterRegistry) ExportTemplateService {
+	return &exportTemplateService{
+		logger:    logger,
+		repo:      repo,
+		parser:    parser,
+		exporters: exporters,
+	}
+}
+
+// SaveTemplate проверяет и сохраняет шаблон; шаблон с тем же именем заменяется
+func (s *exportTemplateService) SaveTemplate(ctx context.Context, req dto.SaveExportTemplateRequest) (*dto.ExportTemplate, error) {
+	if !reExportTemplateName.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must match %s", ErrInvalidExportTemplate, reExportTemplateName)
+	}
+
+	// Имя встроенного формата занять нельзя: шаблон никогда не был бы выбран
+	exporter, err := s.exporters.Get(ctx, req.Name)
+	switch {
+	case err == nil:
+		if _, ok := exporter.(*templateExporter); !ok {
+			return nil, fmt.Errorf("%w: name %q is reserved by a built-in format", ErrInvalidExportTemplate, req.Name)
+		}
+	case !errors.Is(err, ErrUnsupportedFormat):
+		return nil, err
+	}
+
+	template, err := newExportTemplate(req.Name, req.Engine, req.ContentType, req.Extension, req.Body)
+	if err != nil {
+		return nil, err
+	}
+	template.Description = req.Description
+
+	if err := s.repo.SaveExportTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Export template saved", zap.String("name", template.Name), zap.String("engine", string(template.Engine)))
+	return template, nil
+}
+
+// GetTemplate возвращает шаблон по имени или ErrExportTemplateNotFound
+func (s *exportTemplateService) GetTemplate(ctx context.Context, name string) (*dto.ExportTemplate, error) {
+	template, err := s.repo.GetExportTemplateByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, ErrExportTemplateNotFound
+	}
+	return template, nil
+}
+
+// ListTemplates возвращает все шаблоны
+func (s *exportTemplateService) ListTemplates(ctx context.Context) ([]dto.ExportTemplate, error) {
+	return s.repo.ListExportTemplates(ctx)
+}
+
+// DeleteTemplate удаляет шаблон по имени или возвращает ErrExportTemplateNotFound
+func (s *exportTemplateService) DeleteTemplate(ctx context.Context, name string) error {
+	deleted, err := s.repo.DeleteExportTemplate(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrExportTemplateNotFound
+	}
+	return nil
+}
+
+// PreviewTemplate рендерит шаблон на данных существующей операции: сохраненный по имени
+// или переданный в запросе без сохранения
+func (s *exportTemplateService) PreviewTemplate(ctx context.Context, req dto.PreviewExportTemplateRequest) ([]byte, string, error) {
+	var template *dto.ExportTemplate
+	var err error
+
+	switch {
+	case req.Body != "":
+		template, err = newExportTemplate("preview", req.Engine, "", "", req.Body)
+	case req.Name != "":
+		template, err = s.GetTemplate(ctx, req.Name)
+	default:
+		err = fmt.Errorf("%w: name or body is required", ErrInvalidExportTemplate)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	operation, err := s.parser.GetOperationByID(ctx, req.OperationID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Результат копится в памяти, чтобы ошибку выполнения шаблона можно было вернуть вместо половины файла
+	exporter := &templateExporter{template: *template}
+	var content bytes.Buffer
+	if err := exporter.Export(ctx, &repoExportSource{operation: operation, repo: s.parser}, &content); err != nil {
+		return nil, "", err
+	}
+
+	return content.Bytes(), exporter.ContentType(), nil
+}
+
+// newExportTemplate заполняет значения по умолчанию и проверяет, что шаблон разбирается
+func newExportTemplate(name string, engine dto.ExportTemplateEngine, contentType, extension, body string) (*dto.ExportTemplate, error) {
+	if engine == "" {
+		engine = dto.ExportTemplateEngineText
+	}
+	defaults, ok := exportTemplateDefaults[engine]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown engine %q", ErrInvalidExportTemplate, engine)
+	}
+
+	if contentType == "" {
+		contentType = defaults.contentType
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return nil, fmt.Errorf("%w: invalid content type %q", ErrInvalidExportTemplate, contentType)
+	}
+
+	if extension = strings.TrimPrefix(extension, "."); extension == "" {
+		extension = defaults.extension
+	}
+	if !reExportTemplateExtension.MatchString(extension) {
+		return nil, fmt.Errorf("%w: invalid extension %q", ErrInvalidExportTemplate, extension)
+	}
+
+	if strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: body is required", ErrInvalidExportTemplate)
+	}
+
+	template := &dto.ExportTemplate{
+		Name:        name,
+		Engine:      engine,
+		ContentType: contentType,
+		Extension:   extension,
+		Body:        body,
+	}
+	if _, err := parseExportTemplate(template, nil); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// templateExecutor общий интерфейс шаблонов text/template и html/template
+type templateExecutor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// parseExportTemplate разбирает шаблон движком шаблона с библиотекой функций для операции с адресом base
+func parseExportTemplate(template *dto.ExportTemplate, base *url.URL) (templateExecutor, error) {
+	funcs := exportTemplateFuncs(base)
+
+	var executor templateExecutor
+	var err error
+	switch template.Engine {
+	case dto.ExportTemplateEngineHTML:
+		funcs["rawHTML"] = func(content string) htmltemplate.HTML { return htmltemplate.HTML(content) }
+		executor, err = htmltemplate.New(template.Name).Funcs(funcs).Parse(template.Body)
+	default:
+		funcs["rawHTML"] = func(content string) string { return content }
+		executor, err = texttemplate.New(template.Name).Funcs(funcs).Parse(template.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExportTemplate, err)
+	}
+
+	return executor, nil
+}
+
+// exportTemplateFuncs библиотека функций шаблонов экспорта. Аргумент для конвейера идет последним:
+// {{.Operation.CreatedAt | formatDate "02.01.2006"}}, {{.Blocks | blocksOfType "header"}}, {{.HTML | htmlToText | truncate 200}}
+func exportTemplateFuncs(base *url.URL) map[string]any {
+	// Компоненты блока разбираются один раз, даже если шаблон обходит блоки несколько раз
+	cache := make(map[uuid.UUID]map[string]blockComponent)
+	componentsOf := func(block dto.Block) map[string]blockComponent {
+		if components, ok := cache[block.ID]; ok {
+			return components
+		}
+		_, components, err := blockComponents(&block, base)
+		if err != nil {
+			components = nil
+		}
+		cache[block.ID] = components
+		return components
+	}
+
+	return map[string]any{
+		// component возвращает текст компонента содержимого блока по пути flattenContent, например "nav_links.0"
+		"component": func(path string, block dto.Block) string {
+			return componentsOf(block)[path].text
+		},
+		"componentLink": func(path string, block dto.Block) string {
+			return componentsOf(block)[path].link
+		},
+		"componentLinks": func(path string, block dto.Block) []string {
+			return componentsOf(block)[path].links
+		},
+		// components возвращает тексты всех компонентов блока по путям
+		"components": func(block dto.Block) map[string]string {
+			texts := make(map[string]string)
+			for key, component := range componentsOf(block) {
+				texts[key] = component.text
+			}
+			return texts
+		},
+		"componentTitle": componentTitle,
+		"blocksOfType": func(blockType string, blocks []dto.Block) []dto.Block {
+			var result []dto.Block
+			for _, block := range blocks {
+				if string(block.BlockType) == blockType {
+					result = append(result, block)
+				}
+			}
+			return result
+		},
+		// blocksOfDevice возвращает блоки профиля устройства; в Devices у результата только ID блоков
+		"blocksOfDevice": func(device string, blocks []dto.Block) []dto.Block {
+			var result []dto.Block
+			for _, block := range blocks {
+				if string(block.Device) == device {
+					result = append(result, block)
+				}
+			}
+			return result
+		},
+		"blocksOfCategory": func(category string, blocks []dto.Block) []dto.Block {
+			var result []dto.Block
+			for _, block := range blocks {
+				if block.Classification != nil && string(block.Classification.Category) == category {
+					result = append(result, block)
+				}
+			}
+			return result
+		},
+		"formatDate": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"now": time.Now,
+		"htmlToText": func(content string) string {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+			if err != nil {
+				return ""
+			}
+			return selectionText(doc.Selection)
+		},
+		"truncate": func(length int, text string) string {
+			runes := []rune(text)
+			if length < 0 || len(runes) <= length {
+				return text
+			}
+			return string(runes[:length]) + "…"
+		},
+		"default": func(fallback, value string) string {
+			if strings.TrimSpace(value) == "" {
+				return fallback
+			}
+			return value
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+		"join": func(sep string, values []string) string {
+			return strings.Join(values, sep)
+		},
+		"add": func(a, b int) int { return a + b },
+		"json": func(value any) (string, error) {
+			data, err := json.MarshalIndent(value, "", "  ")
+			return string(data), err
+		},
+	}
+}
+
+// templateExporter экспортер пользовательского шаблона: шаблон выполняется на GetOperationResultResponse
+type templateExporter struct {
+	template dto.ExportTemplate
+}
+
+func (e *templateExporter) Name() string        { return e.template.Name }
+func (e *templateExporter) ContentType() string { return e.template.ContentType }
+func (e *templateExporter) Extension() string   { return e.template.Extension }
+func (e *templateExporter) UserDefined()        {}
+
+// Export загружает блоки операции в GetOperationResultResponse, как в API, и выполняет шаблон.
+// Шаблон разбирается при каждом экспорте: кэш компонентов функций шаблона принадлежит одному выполнению.
+func (e *templateExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	operation := source.Operation()
+	base, _ := url.Parse(operation.URL)
+
+	executor, err := parseExportTemplate(&e.template, base)
+	if err != nil {
+		return err
+	}
+
+	result := &dto.GetOperationResultResponse{Operation: *operation, Blocks: []dto.Block{}}
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		result.Blocks = append(result.Blocks, *block)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	result.Devices = groupBlocksByDevice(result.Blocks)
+
+	if err := executor.Execute(w, result); err != nil {
+		var execErr texttemplate.ExecError
+		var escapeErr *htmltemplate.Error
+		if errors.As(err, &execErr) || errors.As(err, &escapeErr) {
+			return fmt.Errorf("%w: %v", ErrInvalidExportTemplate, err)
+		}
+		return fmt.Errorf("failed to write template export: %w", err)
+	}
+
+	return nil
+}
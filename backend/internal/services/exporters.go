@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/fx"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ErrUnsupportedFormat возвращается, если формат экспорта не зарегистрирован
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// ErrBatchTooLarge возвращается, если под фильтр экспорта попадает больше maxBatchExportOperations операций
+var ErrBatchTooLarge = errors.New("too many operations to export")
+
+// maxBatchExportOperations ограничение числа операций в одном экспорте набора
+const maxBatchExportOperations = 10000
+
+// ErrInvalidSiteExport возвращается, если операции нельзя выгрузить как один сайт
+var ErrInvalidSiteExport = errors.New("invalid site export")
+
+// ExporterParams экспортеры, зарегистрированные в группе fx "exporters", и пользовательские шаблоны экспорта
+type ExporterParams struct {
+	fx.In
+
+	Exporters []Exporter `group:"exporters"`
+	Templates repos.ExportTemplateRepo
+}
+
+// exporterRegistry реализация ExporterRegistry
+type exporterRegistry struct {
+	exporters map[string]Exporter
+	names     []string
+	templates repos.ExportTemplateRepo
+}
+
+// NewExporterRegistry создает реестр форматов экспорта из экспортеров группы fx и шаблонов из базы
+func NewExporterRegistry(params ExporterParams) (ExporterRegistry, error) {
+	registry := &exporterRegistry{
+		exporters: make(map[string]Exporter),
+		templates: params.Templates,
+	}
+
+	for _, exporter := range params.Exporters {
+		name := exporter.Name()
+		if _, ok := registry.exporters[name]; ok {
+			return nil, fmt.Errorf("duplicate exporter: %s", name)
+		}
+		registry.exporters[name] = exporter
+		registry.names = append(registry.names, name)
+	}
+
+	// Порядок в группе fx не определен, список форматов сортируется
+	sort.Strings(registry.names)
+
+	return registry, nil
+}
+
+// Get возвращает встроенный экспортер или экспортер пользовательского шаблона по имени формата
+func (r *exporterRegistry) Get(ctx context.Context, name string) (Exporter, error) {
+	if exporter, ok := r.exporters[name]; ok {
+		return exporter, nil
+	}
+
+	template, err := r.templates.GetExportTemplateByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, name)
+	}
+
+	return &templateExporter{template: *template}, nil
+}
+
+// Names возвращает имена встроенных форматов и пользовательских шаблонов
+func (r *exporterRegistry) Names(ctx context.Context) ([]string, error) {
+	templates, err := r.templates.ListExportTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append([]string(nil), r.names...)
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// exportFilename возвращает имя файла экспорта операции.
+// У пользовательских шаблонов в имени есть имя шаблона: расширение может совпадать со встроенным форматом.
+func exportFilename(operation *dto.Operation, exporter Exporter) string {
+	if _, ok := exporter.(*templateExporter); ok {
+		return fmt.Sprintf("operation_%s_%s.%s", operation.ID.String(), exporter.Name(), exporter.Extension())
+	}
+	return fmt.Sprintf("operation_%s.%s", operation.ID.String(), exporter.Extension())
+}
+
+// operationExport реализация OperationExport
+type operationExport struct {
+	exporter Exporter
+	source   ExportSource
+}
+
+// Filename возвращает имя файла экспорта
+func (e *operationExport) Filename() string {
+	return exportFilename(e.source.Operation(), e.exporter)
+}
+
+// ContentType возвращает MIME-тип файла экспорта
+func (e *operationExport) ContentType() string {
+	return e.exporter.ContentType()
+}
+
+// Write пишет экспорт в w
+func (e *operationExport) Write(ctx context.Context, w io.Writer) error {
+	return e.exporter.Export(ctx, e.source, w)
+}
+
+// siteExport реализация OperationExport для экспорта нескольких страниц сайта
+type siteExport struct {
+	exporter SiteExporter
+	sources  []ExportSource
+}
+
+// Filename возвращает имя файла экспорта по домену сайта
+func (e *siteExport) Filename() string {
+	host := "site"
+	if u, err := url.Parse(e.sources[0].Operation().URL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("site_%s.%s", host, e.exporter.Extension())
+}
+
+// ContentType возвращает MIME-тип файла экспорта
+func (e *siteExport) ContentType() string {
+	return e.exporter.ContentType()
+}
+
+// Write пишет экспорт в w
+func (e *siteExport) Write(ctx context.Context, w io.Writer) error {
+	return e.exporter.ExportSite(ctx, e.sources, w)
+}
+
+// batchExport реализация OperationExport для экспорта набора операций
+type batchExport struct {
+	exporter BatchExporter
+	sources  []ExportSource
+	created  time.Time
+}
+
+// Filename возвращает имя файла экспорта со временем подготовки
+func (e *batchExport) Filename() string {
+	return fmt.Sprintf("operations_%s.%s", e.created.UTC().Format("20060102_150405"), e.exporter.Extension())
+}
+
+// ContentType возвращает MIME-тип файла экспорта
+func (e *batchExport) ContentType() string {
+	return e.exporter.ContentType()
+}
+
+// Write пишет экспорт в w
+func (e *batchExport) Write(ctx context.Context, w io.Writer) error {
+	return e.exporter.ExportBatch(ctx, e.sources, w)
+}
+
+// repoExportSource читает блоки операции из базы построчно при каждом обходе
+type repoExportSource struct {
+	operation *dto.Operation
+	options   dto.ExportOptions
+	repo      repos.ParserRepo
+}
+
+func (s *repoExportSource) Operation() *dto.Operation {
+	return s.operation
+}
+
+func (s *repoExportSource) Options() dto.ExportOptions {
+	return s.options
+}
+
+func (s *repoExportSource) EachBlock(ctx context.Context, fn func(block *dto.Block) error) error {
+	return s.repo.IterateBlocksByOperationID(ctx, s.operation.ID, fn)
+}
+
+// resultExportSource обходит уже загруженные результаты операции
+type resultExportSource struct {
+	result *dto.GetOperationResultResponse
+}
+
+func (s *resultExportSource) Operation() *dto.Operation {
+	return &s.result.Operation
+}
+
+// Options возвращает параметры по умолчанию: архив операции собирается без параметров запроса
+func (s *resultExportSource) Options() dto.ExportOptions {
+	return dto.ExportOptions{}
+}
+
+func (s *resultExportSource) EachBlock(ctx context.Context, fn func(block *dto.Block) error) error {
+	for i := range s.result.Blocks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&s.result.Blocks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonExporter выгружает операцию и блоки так же, как их возвращает API
+type jsonExporter struct{}
+
+// NewJSONExporter создает экспортер в JSON
+func NewJSONExporter() Exporter {
+	return &jsonExporter{}
+}
+
+func (e *jsonExporter) Name() string        { return "json" }
+func (e *jsonExporter) ContentType() string { return "application/json" }
+func (e *jsonExporter) Extension() string   { return "json" }
+
+// Export пишет документ по частям: операцию, затем блоки по одному.
+// Группировка по устройствам, как в API, пишется после блоков ID, собранными за тот же проход.
+func (e *jsonExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	ew := &errWriter{w: w}
+
+	operation, err := json.MarshalIndent(source.Operation(), "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal operation: %w", err)
+	}
+	ew.printf("{\n  \"operation\": %s,\n  \"blocks\": [", operation)
+
+	devices := make(map[dto.DeviceProfile][]uuid.UUID)
+	if err := writeJSONBlocks(ctx, source, ew, devices); err != nil {
+		return err
+	}
+	ew.printf("]")
+
+	if len(devices) > 1 {
+		names := make([]string, 0, len(devices))
+		for device := range devices {
+			names = append(names, string(device))
+		}
+		sort.Strings(names)
+
+		ew.printf(",\n  \"devices\": {")
+		for i, name := range names {
+			if i > 0 {
+				ew.printf(",")
+			}
+			ids, err := json.Marshal(devices[dto.DeviceProfile(name)])
+			if err != nil {
+				return fmt.Errorf("failed to marshal device blocks: %w", err)
+			}
+			ew.printf("\n    %q: %s", name, ids)
+		}
+		ew.printf("\n  }")
+	}
+
+	ew.printf("\n}\n")
+	return ew.err
+}
+
+// writeJSONBlocks пишет блоки источника элементами JSON-массива и собирает в devices их ID по устройствам
+func writeJSONBlocks(ctx context.Context, source ExportSource, ew *errWriter, devices map[dto.DeviceProfile][]uuid.UUID) error {
+	indent := "    "
+
+	count := 0
+	err := source.EachBlock(ctx, func(block *dto.Block) error {
+		devices[block.Device] = append(devices[block.Device], block.ID)
+
+		data, err := json.MarshalIndent(block, indent, "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal block %s: %w", block.ID, err)
+		}
+
+		if count > 0 {
+			ew.printf(",")
+		}
+		ew.printf("\n%s%s", indent, data)
+		count++
+
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		ew.printf("\n%s", indent[2:])
+	}
+	return ew.err
+}
+
+// ndjsonExporter выгружает блоки операции по одному JSON-объекту в строке
+type ndjsonExporter struct{}
+
+// NewNDJSONExporter создает экспортер в NDJSON
+func NewNDJSONExporter() Exporter {
+	return &ndjsonExporter{}
+}
+
+func (e *ndjsonExporter) Name() string        { return "ndjson" }
+func (e *ndjsonExporter) ContentType() string { return "application/x-ndjson" }
+func (e *ndjsonExporter) Extension() string   { return "ndjson" }
+
+// Export пишет каждый блок отдельной строкой; данные операции есть в полях operation_id блоков
+func (e *ndjsonExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return source.EachBlock(ctx, func(block *dto.Block) error {
+		if err := encoder.Encode(block); err != nil {
+			return fmt.Errorf("failed to marshal block %s: %w", block.ID, err)
+		}
+		return nil
+	})
+}
+
+// textExporter формирует текстовый отчет об операции
+type textExporter struct{}
+
+// NewTextExporter создает экспортер в текстовый отчет
+func NewTextExporter() Exporter {
+	return &textExporter{}
+}
+
+func (e *textExporter) Name() string        { return "text" }
+func (e *textExporter) ContentType() string { return "text/plain; charset=utf-8" }
+func (e *textExporter) Extension() string   { return "txt" }
+
+// Export пишет данные операции и список блоков
+func (e *textExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	ew := &errWriter{w: w}
+	operation := source.Operation()
+
+	ew.printf("Operation ID: %s\n", operation.ID.String())
+	ew.printf("URL: %s\n", operation.URL)
+	ew.printf("Status: %s\n", operation.Status)
+	ew.printf("Created At: %s\n", operation.CreatedAt.Format(time.RFC3339))
+	ew.printf("Updated At: %s\n\n", operation.UpdatedAt.Format(time.RFC3339))
+
+	ew.printf("Blocks:\n")
+	err := source.EachBlock(ctx, func(block *dto.Block) error {
+		ew.printf("  ID: %s\n", block.ID.String())
+		ew.printf("  Type: %s\n", block.BlockType)
+		ew.printf("  Platform: %s\n", block.Platform)
+		ew.printf("  Device: %s\n", block.Device)
+		ew.printf("  Created At: %s\n", block.CreatedAt.Format(time.RFC3339))
+		ew.printf("  HTML: %s\n", block.HTML)
+		if block.OriginalHTML != "" {
+			ew.printf("  Original HTML: %s\n", block.OriginalHTML)
+		}
+		ew.printf("\n")
+
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ew.err
+}
+
+// errWriter запоминает первую ошибку записи, чтобы не проверять каждую строку отчета
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Элементы, которые в Gutenberg переносятся как есть в блоке "Произвольный HTML"
+var gutenbergRawElements = map[atom.Atom]bool{
+	atom.Iframe:   true,
+	atom.Video:    true,
+	atom.Audio:    true,
+	atom.Form:     true,
+	atom.Canvas:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Select:   true,
+	atom.Input:    true,
+	atom.Textarea: true,
+}
+
+// Элементы, которые не переносятся: служебные и декоративные
+var gutenbergSkippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Template: true,
+	atom.Noscript: true,
+	atom.Svg:      true,
+	atom.Button:   true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+// Строчные элементы, которые сохраняются в тексте абзацев; остальные строчные элементы заменяются своим текстом
+var gutenbergInlineTags = map[atom.Atom]string{
+	atom.A:      "a",
+	atom.Strong: "strong",
+	atom.B:      "strong",
+	atom.Em:     "em",
+	atom.I:      "em",
+	atom.U:      "u",
+	atom.S:      "s",
+	atom.Del:    "s",
+	atom.Code:   "code",
+	atom.Sub:    "sub",
+	atom.Sup:    "sup",
+	atom.Mark:   "mark",
+	atom.Br:     "br",
+}
+
+// Строчные элементы, внутри которых может оказаться текст абзаца
+var gutenbergPhrasingElements = map[atom.Atom]bool{
+	atom.A: true, atom.Strong: true, atom.B: true, atom.Em: true, atom.I: true, atom.U: true, atom.S: true,
+	atom.Del: true, atom.Ins: true, atom.Code: true, atom.Sub: true, atom.Sup: true, atom.Mark: true, atom.Br: true,
+	atom.Span: true, atom.Small: true, atom.Abbr: true, atom.Time: true, atom.Label: true, atom.Cite: true,
+	atom.Q: true, atom.Font: true, atom.Bdi: true, atom.Bdo: true, atom.Kbd: true, atom.Var: true, atom.Data: true,
+}
+
+// gutenbergConverter переводит HTML блока в разметку блоков редактора WordPress.
+// Текст и строчные элементы между блочными копятся в inline и становятся абзацем на границе блока.
+type gutenbergConverter struct {
+	base   *url.URL
+	out    strings.Builder
+	inline strings.Builder
+}
+
+// gutenbergMarkup возвращает HTML блока страницы разметкой Gutenberg, обернутой в группу
+func gutenbergMarkup(content string, base *url.URL) string {
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return ""
+	}
+
+	converter := &gutenbergConverter{base: base}
+	for _, node := range nodes {
+		converter.node(node)
+	}
+	converter.flush()
+
+	inner := converter.out.String()
+	if inner == "" {
+		return ""
+	}
+	return "<!-- wp:group {\"layout\":{\"type\":\"constrained\"}} -->\n<div class=\"wp-block-group\">" + inner + "</div>\n<!-- /wp:group -->\n\n"
+}
+
+// node переводит узел в блоки или добавляет его в текущий абзац
+func (c *gutenbergConverter) node(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		c.inline.WriteString(html.EscapeString(collapseSpaces(node.Data)))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch {
+	case gutenbergSkippedElements[node.DataAtom]:
+		return
+	case gutenbergRawElements[node.DataAtom]:
+		c.flush()
+		c.raw(node)
+		return
+	}
+
+	// Картинка или ссылка-картинка без текста становятся блоком изображения
+	if img := singleImage(node); img != nil {
+		c.flush()
+		c.image(img, node, nil)
+		return
+	}
+
+	if gutenbergPhrasingElements[node.DataAtom] && !hasBlockDescendant(node) {
+		c.inline.WriteString(c.inlineHTML(node))
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		c.flush()
+		c.heading(node)
+	case atom.P:
+		c.flush()
+		c.paragraph(c.childrenHTML(node))
+	case atom.Ul, atom.Ol:
+		c.flush()
+		c.out.WriteString(c.list(node))
+	case atom.Blockquote:
+		c.flush()
+		c.quote(node)
+	case atom.Table:
+		c.flush()
+		c.table(node)
+	case atom.Hr:
+		c.flush()
+		c.out.WriteString("<!-- wp:separator -->\n<hr class=\"wp-block-separator has-alpha-channel-opacity\"/>\n<!-- /wp:separator -->\n\n")
+	case atom.Pre:
+		c.flush()
+		c.out.WriteString("<!-- wp:preformatted -->\n<pre class=\"wp-block-preformatted\">" + html.EscapeString(nodeText(node)) + "</pre>\n<!-- /wp:preformatted -->\n\n")
+	case atom.Figure:
+		c.flush()
+		if img := findElement(node, atom.Img); img != nil {
+			c.image(img, findElement(node, atom.A), findElement(node, atom.Figcaption))
+		} else {
+			c.children(node)
+		}
+	default:
+		// Контейнеры (div, section, a-карточка и т.п.) разворачиваются, их граница закрывает абзац
+		c.flush()
+		c.children(node)
+		c.flush()
+	}
+}
+
+// children переводит дочерние узлы
+func (c *gutenbergConverter) children(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+// flush закрывает накопленный текст абзацем
+func (c *gutenbergConverter) flush() {
+	content := strings.TrimSpace(c.inline.String())
+	c.inline.Reset()
+	c.paragraph(content)
+}
+
+func (c *gutenbergConverter) paragraph(content string) {
+	content = strings.TrimSpace(content)
+	if content == "" || content == "<br/>" {
+		return
+	}
+	c.out.WriteString("<!-- wp:paragraph -->\n<p>" + content + "</p>\n<!-- /wp:paragraph -->\n\n")
+}
+
+func (c *gutenbergConverter) heading(node *html.Node) {
+	content := strings.TrimSpace(c.childrenHTML(node))
+	if content == "" {
+		return
+	}
+
+	tag := node.Data
+	if tag == "h2" {
+		c.out.WriteString("<!-- wp:heading -->\n")
+	} else {
+		fmt.Fprintf(&c.out, "<!-- wp:heading {\"level\":%s} -->\n", tag[1:])
+	}
+	fmt.Fprintf(&c.out, "<%s class=\"wp-block-heading\">%s</%s>\n<!-- /wp:heading -->\n\n", tag, content, tag)
+}
+
+// list возвращает список с элементами; вложенные списки остаются внутри элементов
+func (c *gutenbergConverter) list(node *html.Node) string {
+	var items strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+
+		var text, nested strings.Builder
+		for part := child.FirstChild; part != nil; part = part.NextSibling {
+			if part.Type == html.ElementNode && (part.DataAtom == atom.Ul || part.DataAtom == atom.Ol) {
+				nested.WriteString(strings.TrimSpace(c.list(part)))
+				continue
+			}
+			text.WriteString(c.inlineHTML(part))
+		}
+
+		content := strings.TrimSpace(text.String())
+		if content == "" && nested.Len() == 0 {
+			continue
+		}
+		items.WriteString("<!-- wp:list-item -->\n<li>" + content + nested.String() + "</li>\n<!-- /wp:list-item -->")
+	}
+	if items.Len() == 0 {
+		return ""
+	}
+
+	if node.DataAtom == atom.Ol {
+		return "<!-- wp:list {\"ordered\":true} -->\n<ol class=\"wp-block-list\">" + items.String() + "</ol>\n<!-- /wp:list -->\n\n"
+	}
+	return "<!-- wp:list -->\n<ul class=\"wp-block-list\">" + items.String() + "</ul>\n<!-- /wp:list -->\n\n"
+}
+
+// quote переводит цитату: ее содержимое становится вложенными блоками
+func (c *gutenbergConverter) quote(node *html.Node) {
+	inner := &gutenbergConverter{base: c.base}
+	inner.children(node)
+	inner.flush()
+	if inner.out.Len() == 0 {
+		return
+	}
+	c.out.WriteString("<!-- wp:quote -->\n<blockquote class=\"wp-block-quote\">" + inner.out.String() + "</blockquote>\n<!-- /wp:quote -->\n\n")
+}
+
+// table пересобирает таблицу из строк и ячеек без атрибутов оформления
+func (c *gutenbergConverter) table(node *html.Node) {
+	var head, body strings.Builder
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead:
+				walk(child, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(child, false)
+			case atom.Tr:
+				var row strings.Builder
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row.WriteString("<" + cell.Data + ">" + strings.TrimSpace(c.childrenHTML(cell)) + "</" + cell.Data + ">")
+					}
+				}
+				if inHead {
+					head.WriteString("<tr>" + row.String() + "</tr>")
+				} else {
+					body.WriteString("<tr>" + row.String() + "</tr>")
+				}
+			}
+		}
+	}
+	walk(node, false)
+
+	if head.Len() == 0 && body.Len() == 0 {
+		return
+	}
+
+	c.out.WriteString("<!-- wp:table -->\n<figure class=\"wp-block-table\"><table>")
+	if head.Len() > 0 {
+		c.out.WriteString("<thead>" + head.String() + "</thead>")
+	}
+	c.out.WriteString("<tbody>" + body.String() + "</tbody></table></figure>\n<!-- /wp:table -->\n\n")
+}
+
+// image пишет блок изображения; link и caption необязательны
+func (c *gutenbergConverter) image(img, link, caption *html.Node) {
+	src := strings.TrimSpace(attrValue(img, "src"))
+	if src == "" || strings.HasPrefix(src, "data:") {
+		return
+	}
+	tag := fmt.Sprintf("<img src=\"%s\" alt=\"%s\"/>", html.EscapeString(absoluteURL(c.base, src)), html.EscapeString(attrValue(img, "alt")))
+
+	href := ""
+	if link != nil && link.DataAtom == atom.A {
+		href = strings.TrimSpace(attrValue(link, "href"))
+		if href == "" || strings.HasPrefix(href, "#") || isJavaScriptURL(href) {
+			href = ""
+		}
+	}
+
+	if href != "" {
+		c.out.WriteString("<!-- wp:image {\"linkDestination\":\"custom\"} -->\n<figure class=\"wp-block-image\">")
+		fmt.Fprintf(&c.out, "<a href=\"%s\">%s</a>", html.EscapeString(absoluteURL(c.base, href)), tag)
+	} else {
+		c.out.WriteString("<!-- wp:image -->\n<figure class=\"wp-block-image\">" + tag)
+	}
+	if caption != nil {
+		if text := strings.TrimSpace(c.childrenHTML(caption)); text != "" {
+			c.out.WriteString("<figcaption class=\"wp-element-caption\">" + text + "</figcaption>")
+		}
+	}
+	c.out.WriteString("</figure>\n<!-- /wp:image -->\n\n")
+}
+
+// raw переносит элемент в блок "Произвольный HTML"
+func (c *gutenbergConverter) raw(node *html.Node) {
+	var b strings.Builder
+	if err := html.Render(&b, node); err != nil {
+		return
+	}
+	c.out.WriteString("<!-- wp:html -->\n" + b.String() + "\n<!-- /wp:html -->\n\n")
+}
+
+// childrenHTML возвращает строчную разметку дочерних узлов
+func (c *gutenbergConverter) childrenHTML(node *html.Node) string {
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(c.inlineHTML(child))
+	}
+	return b.String()
+}
+
+// inlineHTML возвращает строчную разметку узла: разрешенные теги без атрибутов оформления,
+// у ссылок только абсолютный href; остальные элементы заменяются своим содержимым
+func (c *gutenbergConverter) inlineHTML(node *html.Node) string {
+	switch node.Type {
+	case html.TextNode:
+		return html.EscapeString(collapseSpaces(node.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	if gutenbergSkippedElements[node.DataAtom] || gutenbergRawElements[node.DataAtom] || node.DataAtom == atom.Img {
+		return ""
+	}
+
+	inner := c.childrenHTML(node)
+
+	// Блочные элементы внутри строчного контекста отделяются пробелом, чтобы слова не склеивались
+	tag, ok := gutenbergInlineTags[node.DataAtom]
+	switch {
+	case !ok && !gutenbergPhrasingElements[node.DataAtom]:
+		return " " + inner + " "
+	case !ok:
+		return inner
+	case tag == "br":
+		return "<br/>"
+	case tag == "a":
+		href := strings.TrimSpace(attrValue(node, "href"))
+		if href == "" || isJavaScriptURL(href) {
+			return inner
+		}
+		if !strings.HasPrefix(href, "#") {
+			href = absoluteURL(c.base, href)
+		}
+		if attrValue(node, "target") == "_blank" {
+			return fmt.Sprintf("<a href=\"%s\" target=\"_blank\" rel=\"noreferrer noopener\">%s</a>", html.EscapeString(href), inner)
+		}
+		return fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(href), inner)
+	}
+
+	if strings.TrimSpace(inner) == "" {
+		return inner
+	}
+	return "<" + tag + ">" + inner + "</" + tag + ">"
+}
+
+// singleImage возвращает картинку, если узел - это img или строчный элемент с единственной картинкой без текста
+func singleImage(node *html.Node) *html.Node {
+	if node.DataAtom == atom.Img {
+		return node
+	}
+	if !gutenbergPhrasingElements[node.DataAtom] || strings.TrimSpace(nodeText(node)) != "" {
+		return nil
+	}
+
+	var found *html.Node
+	count := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.ElementNode && child.DataAtom == atom.Img {
+				found = child
+				count++
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+
+	if count != 1 {
+		return nil
+	}
+	return found
+}
+
+// hasBlockDescendant проверяет, есть ли внутри строчного элемента блочные элементы, например у ссылки-карточки
+func hasBlockDescendant(node *html.Node) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if !gutenbergPhrasingElements[child.DataAtom] && child.DataAtom != atom.Img && !gutenbergSkippedElements[child.DataAtom] {
+			return true
+		}
+		if hasBlockDescendant(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// findElement возвращает первого потомка с указанным тегом
+func findElement(node *html.Node, tag atom.Atom) *html.Node {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == tag {
+			return child
+		}
+		if found := findElement(child, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeText возвращает текст узла без разметки
+func nodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && gutenbergSkippedElements[child.DataAtom] {
+			continue
+		}
+		b.WriteString(nodeText(child))
+	}
+	return b.String()
+}
+
+// attrValue возвращает значение атрибута узла
+func attrValue(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpaces заменяет последовательности пробельных символов одним пробелом
+func collapseSpaces(text string) string {
+	return reWhitespace.ReplaceAllString(text, " ")
+}
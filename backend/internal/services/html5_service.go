@@ -48,7 +48,8 @@ func (s *html5Service) ParseFooter(html string) (*dto.Block, error) {
 	panic("implement me")
 }
 
-func (s *html5Service) ParseAndClassifyPage(html string, templates []dto.BlockTemplate) ([]*dto.Block, error) {
+// ParseAndClassifyPage делит страницу на блоки и классифицирует их по шаблонам и обученной модели
+func (s *html5Service) ParseAndClassifyPage(html string, templates []dto.BlockTemplate, model *dto.ClassifierModel) ([]*dto.Block, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
 		return nil, err
@@ -59,26 +60,28 @@ func (s *html5Service) ParseAndClassifyPage(html string, templates []dto.BlockTe
 	// Найти header
 	header := doc.Find("header").First()
 	if header.Length() != 0 {
+		headerHTML, _ := goquery.OuterHtml(header)
 		blocks = append(blocks, &dto.Block{
 			BlockType: dto.BlockTypeHeader,
 			Platform:  dto.PlatformHTML5,
 			Content: map[string]string{
 				"template_name": "Шапка",
 			},
-			HTML: "",
+			HTML: headerHTML,
 		})
 	}
 	// Идем сверху вниз по логике документа
 	for sibling := header.Next(); sibling.Length() > 0; sibling = sibling.Next() {
 		node := sibling.Get(0)
 		if node.Data == "footer" {
+			footerHTML, _ := goquery.OuterHtml(sibling)
 			blocks = append(blocks, &dto.Block{
 				BlockType: dto.BlockTypeFooter,
 				Platform:  dto.PlatformHTML5,
 				Content: map[string]string{
 					"template_name": "Футер",
 				},
-				HTML: "",
+				HTML: footerHTML,
 			})
 			break // Достигли footer — останавливаемся
 		}
@@ -98,10 +101,11 @@ func (s *html5Service) ParseAndClassifyPage(html string, templates []dto.BlockTe
 			}
 
 			blocks = append(blocks, &dto.Block{
-				BlockType: blockType,
-				Platform:  dto.PlatformHTML5,
-				Content:   content,
-				HTML:      "",
+				BlockType:      blockType,
+				Platform:       dto.PlatformHTML5,
+				Content:        content,
+				HTML:           htmlContent,
+				Classification: classifyWithModel(htmlContent, matchedTemplate, model),
 			})
 		}
 	}
@@ -109,6 +113,28 @@ func (s *html5Service) ParseAndClassifyPage(html string, templates []dto.BlockTe
 	return blocks, nil
 }
 
+// classifyWithModel определяет категорию блока: шаблон с именем категории имеет приоритет над моделью
+func classifyWithModel(html string, template *dto.BlockTemplate, model *dto.ClassifierModel) *dto.BlockClassification {
+	if template != nil && isKnownCategory(dto.BlockCategory(template.BlockType)) {
+		return &dto.BlockClassification{
+			Category: dto.BlockCategory(template.BlockType),
+			Score:    1,
+			Signals:  []string{"template:" + template.BlockType},
+		}
+	}
+
+	if model == nil {
+		return nil
+	}
+
+	classification := predictNaiveBayes(model, html)
+	if classification != nil && template != nil {
+		classification.Signals = append(classification.Signals, "template:"+template.BlockType)
+	}
+
+	return classification
+}
+
 func matchBlock(html string, templates []dto.BlockTemplate) *dto.BlockTemplate {
 	for _, template := range templates {
 		var tagSequence map[string]interface{}
@@ -172,8 +198,8 @@ func matchBlock(html string, templates []dto.BlockTemplate) *dto.BlockTemplate {
 // Обходит DOM, собирает HTML без скрытых элементов
 func walkVisible(sel *goquery.Selection, buf *bytes.Buffer) {
 
-	sel.Find("div").Each(func(i int, s *goquery.Selection) {
-		// Пропускаем скрытые элементы и их родителей
+	sel.Find("*").Each(func(i int, s *goquery.Selection) {
+		// Пропускаем только сами скрытые элементы, родители остаются
 		if isHidden(s) {
 			s.Remove()
 		}
@@ -182,15 +208,3 @@ func walkVisible(sel *goquery.Selection, buf *bytes.Buffer) {
 	html.Render(buf, sel.Get(0))
 
 }
-
-func isHidden(sel *goquery.Selection) bool {
-	//html, _ := sel.Html()
-	html, _ := sel.Html()
-	if strings.Contains(html, "visibility:hidden") || strings.Contains(html, "visibility: hidden") {
-		return true
-	}
-
-	// Проверка родителей
-	hidden := false
-	return hidden
-}
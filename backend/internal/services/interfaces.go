@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"io"
+	"net/url"
 
 	"scrapper/internal/dto"
 
@@ -23,13 +25,38 @@ type PlatformService interface {
 // ParserService представляет интерфейс для сервиса парсинга
 type ParserService interface {
 	// ParseURL парсит URL и сохраняет результаты в базу данных
-	ParseURL(ctx context.Context, url string) (uuid.UUID, error)
+	ParseURL(ctx context.Context, req dto.ParseURLRequest) (uuid.UUID, error)
+
+	// ParseContent парсит загруженную страницу: HTML, MHTML, HAR или WARC, без обращения к сайту
+	ParseContent(ctx context.Context, req dto.ParseContentRequest) (uuid.UUID, error)
+
+	// ReparseOperation повторно извлекает блоки из сохраненных снимков HTML операции текущей версией парсера
+	// в новую операцию без обращения к сайту
+	ReparseOperation(ctx context.Context, operationID uuid.UUID, req dto.ReparseRequest) (uuid.UUID, error)
 
 	// GetOperationResult получает результаты операции по ID
 	GetOperationResult(ctx context.Context, operationID uuid.UUID) (*dto.GetOperationResultResponse, error)
 
-	// ExportOperation экспортирует результаты операции в файл
-	ExportOperation(ctx context.Context, operationID uuid.UUID, format string) ([]byte, string, error)
+	// GetBlockScreenshot возвращает PNG-скриншот блока операции
+	GetBlockScreenshot(ctx context.Context, operationID, blockID uuid.UUID) ([]byte, error)
+
+	// GetPageScreenshot возвращает PNG-скриншот всей страницы операции
+	GetPageScreenshot(ctx context.Context, operationID uuid.UUID) ([]byte, error)
+
+	// ExportDesignTokens экспортирует токены дизайна страницы операции в JSON или CSS
+	ExportDesignTokens(ctx context.Context, operationID uuid.UUID, format string) ([]byte, string, error)
+
+	// PrepareExport готовит экспорт операции в зарегистрированном формате; файл пишется потоково через OperationExport
+	PrepareExport(ctx context.Context, operationID uuid.UUID, format string, options dto.ExportOptions) (OperationExport, error)
+
+	// PrepareSiteExport готовит экспорт нескольких операций одного сайта в формате, поддерживающем SiteExporter
+	PrepareSiteExport(ctx context.Context, operationIDs []uuid.UUID, format string, options dto.ExportOptions) (OperationExport, error)
+
+	// ListOperations возвращает операции по фильтру, новые первыми
+	ListOperations(ctx context.Context, filter dto.OperationFilter) (*dto.ListOperationsResponse, error)
+
+	// PrepareBatchExport готовит экспорт операций по фильтру в формате, поддерживающем BatchExporter
+	PrepareBatchExport(ctx context.Context, filter dto.OperationFilter, format string) (OperationExport, error)
 
 	// DetectPlatform определяет платформу сайта по HTML
 	DetectPlatform(html string) dto.Platform
@@ -53,19 +80,169 @@ type BitrixService interface {
 // HTML5Service представляет интерфейс для сервиса HTML5
 type HTML5Service interface {
 	PlatformService
-	ParseAndClassifyPage(html string, templates []dto.BlockTemplate) ([]*dto.Block, error)
+	ParseAndClassifyPage(html string, templates []dto.BlockTemplate, model *dto.ClassifierModel) ([]*dto.Block, error)
+}
+
+// BlockClassifier представляет интерфейс для семантической классификации контентных блоков
+type BlockClassifier interface {
+	// SplitSections делит страницу на контентные секции между шапкой и подвалом
+	SplitSections(html string) ([]string, error)
+
+	// Classify определяет семантический тип секции по правилам и признакам
+	Classify(html string, position int) *dto.BlockClassification
+}
+
+// BlockPostProcessor представляет интерфейс обработки HTML блока перед сохранением
+type BlockPostProcessor interface {
+	// Process очищает HTML блока и делает ссылки абсолютными относительно base; при base == nil ссылки не меняются
+	Process(content string, base *url.URL) (string, error)
+}
+
+// ClassifierTrainer представляет интерфейс для обучаемого классификатора блоков
+type ClassifierTrainer interface {
+	// LabelBlock исправляет тип сохраненного блока
+	LabelBlock(ctx context.Context, blockID uuid.UUID, category dto.BlockCategory) (*dto.BlockLabel, error)
+
+	// Train обучает новую версию модели по размеченным блокам и делает ее активной
+	Train(ctx context.Context) (*dto.TrainingRun, error)
+
+	// ListModels возвращает все версии моделей
+	ListModels(ctx context.Context) ([]dto.ClassifierModel, error)
+
+	// ActivateModel откатывает классификатор на указанную версию модели
+	ActivateModel(ctx context.Context, version int) error
+}
+
+// TemplateService представляет интерфейс для генерации шаблонов блоков по примерам
+type TemplateService interface {
+	// GenerateTemplate строит кандидат шаблона по примеру блока и находит подходящие сохраненные блоки
+	GenerateTemplate(ctx context.Context, req dto.GenerateTemplateRequest) (*dto.GenerateTemplateResponse, error)
+
+	// SaveTemplate сохраняет шаблон в block_templates
+	SaveTemplate(ctx context.Context, req dto.SaveTemplateRequest) error
+}
+
+// ExportTemplateService представляет интерфейс сервиса пользовательских шаблонов экспорта
+type ExportTemplateService interface {
+	// SaveTemplate проверяет и сохраняет шаблон; шаблон с тем же именем заменяется
+	SaveTemplate(ctx context.Context, req dto.SaveExportTemplateRequest) (*dto.ExportTemplate, error)
+
+	// GetTemplate возвращает шаблон по имени или ErrExportTemplateNotFound
+	GetTemplate(ctx context.Context, name string) (*dto.ExportTemplate, error)
+
+	// ListTemplates возвращает все шаблоны
+	ListTemplates(ctx context.Context) ([]dto.ExportTemplate, error)
+
+	// DeleteTemplate удаляет шаблон по имени или возвращает ErrExportTemplateNotFound
+	DeleteTemplate(ctx context.Context, name string) error
+
+	// PreviewTemplate рендерит шаблон на данных существующей операции и возвращает результат и его MIME-тип
+	PreviewTemplate(ctx context.Context, req dto.PreviewExportTemplateRequest) ([]byte, string, error)
 }
 
 // DownloaderService представляет интерфейс для сервиса загрузки файлов
 type DownloaderService interface {
-	// DownloadByOperationID загружает файлы по ID операции и сохраняет их в указанный путь
-	DownloadByOperationID(ctx context.Context, operationID uuid.UUID, path string) error
+	// GetAvailableFormats возвращает список форматов экспорта, включая пользовательские шаблоны
+	GetAvailableFormats(ctx context.Context) ([]string, error)
+
+	// PrepareBundle собирает данные операции для ZIP-архива; архив пишется потоково через OperationBundle
+	PrepareBundle(ctx context.Context, operationID uuid.UUID, formats []string) (OperationBundle, error)
+
+	// PrepareStaticSite собирает статический сайт из операций одного домена для ZIP-архива
+	PrepareStaticSite(ctx context.Context, operationIDs []uuid.UUID) (OperationBundle, error)
+
+	// DownloadAssets скачивает файлы, на которые ссылаются сохраненные блоки операции
+	DownloadAssets(ctx context.Context, operationID uuid.UUID) (*dto.AssetManifest, error)
+
+	// GetAssetManifest возвращает файлы операции и их связь с блоками
+	GetAssetManifest(ctx context.Context, operationID uuid.UUID) (*dto.AssetManifest, error)
+
+	// ExportStandaloneBlock собирает автономный HTML блока с применяемыми к нему правилами CSS страницы
+	ExportStandaloneBlock(ctx context.Context, operationID, blockID uuid.UUID, inline bool) ([]byte, string, error)
+}
+
+// Exporter представляет формат экспорта результатов операции
+type Exporter interface {
+	// Name возвращает имя формата в API, например "excel"
+	Name() string
+
+	// ContentType возвращает MIME-тип файла экспорта
+	ContentType() string
 
-	// GetAvailableFormats возвращает список доступных форматов для загрузки
-	GetAvailableFormats() []string
+	// Extension возвращает расширение файла экспорта без точки
+	Extension() string
 
-	// DownloadByOperationIDWithFormat загружает файлы в указанном формате
-	DownloadByOperationIDWithFormat(ctx context.Context, operationID uuid.UUID, format string, path string) error
+	// Export пишет результаты операции в w по мере обхода блоков источника
+	Export(ctx context.Context, source ExportSource, w io.Writer) error
+}
+
+// SiteExporter представляет формат, который выгружает несколько страниц одного сайта в один файл
+type SiteExporter interface {
+	Exporter
+
+	// ExportSite пишет страницы сайта в w; sources идут в порядке, заданном в запросе
+	ExportSite(ctx context.Context, sources []ExportSource, w io.Writer) error
+}
+
+// BatchExporter представляет формат, который выгружает любые операции в один файл для аналитики
+type BatchExporter interface {
+	Exporter
+
+	// ExportBatch пишет операции в w в порядке sources
+	ExportBatch(ctx context.Context, sources []ExportSource, w io.Writer) error
+}
+
+// UserDefinedExporter представляет формат, содержимое файла которого задает пользователь, например шаблон экспорта.
+// Такой файл отдается в песочнице: в нем может оказаться HTML со скриптами
+type UserDefinedExporter interface {
+	Exporter
+
+	// UserDefined отмечает формат как пользовательский
+	UserDefined()
+}
+
+// ExportSource представляет данные операции и параметры экспорта
+type ExportSource interface {
+	// Operation возвращает экспортируемую операцию
+	Operation() *dto.Operation
+
+	// Options возвращает параметры экспорта, заданные в запросе
+	Options() dto.ExportOptions
+
+	// EachBlock передает блоки операции в fn по одному; обход можно повторять,
+	// если формату нужно несколько проходов
+	EachBlock(ctx context.Context, fn func(block *dto.Block) error) error
+}
+
+// ExporterRegistry представляет форматы экспорта: зарегистрированные через fx в группе "exporters"
+// и пользовательские шаблоны из базы
+type ExporterRegistry interface {
+	// Get возвращает экспортер по имени формата или ErrUnsupportedFormat
+	Get(ctx context.Context, name string) (Exporter, error)
+
+	// Names возвращает имена встроенных форматов и пользовательских шаблонов
+	Names(ctx context.Context) ([]string, error)
+}
+
+// OperationExport представляет экспорт операции, готовый к потоковой записи
+type OperationExport interface {
+	// Filename возвращает имя файла экспорта
+	Filename() string
+
+	// ContentType возвращает MIME-тип файла экспорта
+	ContentType() string
+
+	// Write пишет экспорт в w
+	Write(ctx context.Context, w io.Writer) error
+}
+
+// OperationBundle представляет ZIP-архив операции или сайта, готовый к потоковой записи
+type OperationBundle interface {
+	// Filename возвращает имя файла архива
+	Filename() string
+
+	// Write пишет архив в w
+	Write(ctx context.Context, w io.Writer) error
 }
 
 type CrawlerService interface {
@@ -81,3 +258,40 @@ type CrawlerService interface {
 	// SetMaxDepth устанавливает максимальную глубину обхода
 	SetMaxDepth(depth int)
 }
+
+// ArchiveService представляет интерфейс хранения архивов страниц в MHTML и WARC
+type ArchiveService interface {
+	// SaveArchive сохраняет архив страницы, снятый под профилем устройства
+	SaveArchive(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, format dto.ArchiveFormat, data []byte) (*dto.Archive, error)
+
+	// AttachArchives привязывает к операции архивы исходной операции, из которых она была перепарсена
+	AttachArchives(ctx context.Context, sourceID, targetID uuid.UUID) error
+
+	// GetArchives возвращает архивы операции
+	GetArchives(ctx context.Context, operationID uuid.UUID) ([]dto.Archive, error)
+
+	// OpenArchive открывает архив операции нужного формата для профиля устройства
+	OpenArchive(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, format dto.ArchiveFormat) (*dto.Archive, io.ReadCloser, error)
+}
+
+// DiffService представляет интерфейс поблочного сравнения двух операций одного сайта
+type DiffService interface {
+	// DiffOperations сопоставляет блоки операций и возвращает добавленные, удаленные, перемещенные
+	// и измененные блоки или ErrInvalidDiff, если операции относятся к разным сайтам
+	DiffOperations(ctx context.Context, fromID, toID uuid.UUID) (*dto.OperationDiff, error)
+
+	// RenderDiffHTML записывает сравнение в w как HTML-отчет
+	RenderDiffHTML(diff *dto.OperationDiff, w io.Writer) error
+}
+
+// BrandKitService представляет интерфейс сбора фирменного набора сайта
+type BrandKitService interface {
+	// CollectBrandKit находит на странице логотип, иконки, web manifest и og:image, скачивает файлы и сохраняет их в операции
+	CollectBrandKit(ctx context.Context, operationID uuid.UUID, pageURL, html string) (*dto.BrandKit, error)
+
+	// GetBrandKit возвращает сохраненный фирменный набор операции
+	GetBrandKit(ctx context.Context, operationID uuid.UUID) (*dto.BrandKit, error)
+
+	// OpenAsset открывает содержимое файла операции
+	OpenAsset(ctx context.Context, operationID, assetID uuid.UUID) (*dto.Asset, io.ReadCloser, error)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"scrapper/internal/dto"
+)
+
+const (
+	// minBandHeight полосы ниже этой высоты считаются разделителями и пропускаются
+	minBandHeight = 8
+
+	// stackTolerance допустимое перекрытие соседних элементов по вертикали, px
+	stackTolerance = 4
+
+	// tallBandRatio во сколько раз полоса должна быть выше окна, чтобы ее делить дальше
+	tallBandRatio = 1.5
+
+	// fullWidthRatio доля ширины родителя, с которой элемент ряда считается полосой во всю ширину
+	fullWidthRatio = 0.9
+)
+
+// layoutBand визуальная полоса страницы: один элемент или ряд соседних элементов
+type layoutBand struct {
+	sel    *goquery.Selection
+	bounds *dto.BlockBounds
+}
+
+// layoutItem элемент с координатами и порядком в DOM
+type layoutItem struct {
+	sel    *goquery.Selection
+	bounds *dto.BlockBounds
+	index  int
+}
+
+// segmentLayoutBands делит отрендеренную страницу на визуальные полосы во всю ширину в порядке чтения.
+// Полосой становится элемент со своим фоном, элемент с колонками внутри или невысокий элемент;
+// высокие обертки без собственного фона делятся на вложенные полосы.
+func segmentLayoutBands(html string, platform dto.Platform) ([]*dto.Block, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Find("script, style, noscript, template").Remove()
+
+	body := doc.Find("body").First()
+	if _, ok := renderedRect(body); !ok {
+		return nil, fmt.Errorf("page has no layout annotations, render it with chromedp first")
+	}
+
+	viewportHeight := 0
+	if viewport, ok := parseIntList(body.AttrOr(attrViewport, ""), 2); ok {
+		viewportHeight = viewport[1]
+	}
+
+	bands := splitBands(body, body.AttrOr(attrRenderBg, ""), viewportHeight)
+
+	// Порядок чтения: сверху вниз, затем слева направо
+	sort.SliceStable(bands, func(i, j int) bool {
+		if bands[i].bounds.Y == bands[j].bounds.Y {
+			return bands[i].bounds.X < bands[j].bounds.X
+		}
+		return bands[i].bounds.Y < bands[j].bounds.Y
+	})
+
+	blocks := make([]*dto.Block, 0, len(bands))
+	for i, band := range bands {
+		// У полосы из ряда элементов HTML собирается из всех элементов ряда
+		var parts []string
+		band.sel.Each(func(_ int, node *goquery.Selection) {
+			if part, err := goquery.OuterHtml(node); err == nil {
+				parts = append(parts, part)
+			}
+		})
+		if len(parts) == 0 {
+			continue
+		}
+		bandHTML := strings.Join(parts, "\n")
+
+		blocks = append(blocks, &dto.Block{
+			BlockType: bandType(band, i, len(bands)),
+			Platform:  platform,
+			Content: map[string]interface{}{
+				"segmentation": string(dto.SegmentationLayout),
+				"band":         i,
+			},
+			HTML:   bandHTML,
+			Bounds: band.bounds,
+		})
+	}
+
+	return blocks, nil
+}
+
+// splitBands рекурсивно делит элемент на полосы. Потомки группируются в ряды по перекрытию по вертикали:
+// элемент, один занимающий ряд, становится полосой или делится дальше; в ряду из нескольких элементов
+// полосами становятся только элементы во всю ширину родителя, а колонки, карточки и сайдбар
+// с контентом рядом объединяются в одну полосу.
+func splitBands(parent *goquery.Selection, parentBg string, viewportHeight int) []layoutBand {
+	var items []layoutItem
+	for i, child := range laidOutChildren(parent) {
+		bounds, _ := renderedRect(child)
+		if bounds.Height < minBandHeight {
+			continue
+		}
+		items = append(items, layoutItem{sel: child, bounds: bounds, index: i})
+	}
+
+	parentBounds, _ := renderedRect(parent)
+	minWidth := 0.0
+	if parentBounds != nil {
+		minWidth = fullWidthRatio * float64(parentBounds.Width)
+	}
+
+	var bands []layoutBand
+	for _, row := range layoutRows(items) {
+		if len(row) == 1 {
+			bands = append(bands, splitBand(row[0], parentBg, viewportHeight)...)
+			continue
+		}
+
+		var wide []layoutItem
+		for _, item := range row {
+			if float64(item.bounds.Width) >= minWidth {
+				wide = append(wide, item)
+			}
+		}
+
+		// Узкие элементы поверх полосы во всю ширину: плавающие виджеты и декор, отдельными полосами не считаются
+		if len(wide) > 0 {
+			for _, item := range wide {
+				bands = append(bands, splitBand(item, parentBg, viewportHeight)...)
+			}
+			continue
+		}
+
+		// Ряд занимает весь родитель: полосой становится сам родитель, а не склейка его потомков
+		if len(row) == len(items) && parentBounds != nil {
+			return []layoutBand{{sel: parent, bounds: parentBounds}}
+		}
+
+		bands = append(bands, mergeRow(row))
+	}
+
+	return bands
+}
+
+// splitBand делает элемент полосой или делит его на вложенные полосы
+func splitBand(item layoutItem, parentBg string, viewportHeight int) []layoutBand {
+	bg := item.sel.AttrOr(attrRenderBg, "")
+	ownBackground := bg != "" && bg != parentBg
+	children := laidOutChildren(item.sel)
+
+	// Обертка с единственным потомком или высокая обертка из нескольких рядов без своего фона
+	isWrapper := len(children) == 1 ||
+		(len(children) > 1 && hasSeveralRows(children) && viewportHeight > 0 && float64(item.bounds.Height) > tallBandRatio*float64(viewportHeight))
+
+	if !ownBackground && isWrapper && !isLayoutChrome(item.sel) {
+		if nested := splitBands(item.sel, parentBg, viewportHeight); len(nested) > 0 {
+			return nested
+		}
+	}
+
+	return []layoutBand{{sel: item.sel, bounds: item.bounds}}
+}
+
+// layoutRows группирует элементы в ряды сверху вниз: элемент входит в ряд, если перекрывает его по вертикали.
+// Внутри ряда элементы идут в порядке DOM.
+func layoutRows(items []layoutItem) [][]layoutItem {
+	sorted := append([]layoutItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].bounds.Y < sorted[j].bounds.Y })
+
+	var rows [][]layoutItem
+	bottom := 0
+	for _, item := range sorted {
+		if len(rows) > 0 && item.bounds.Y < bottom-stackTolerance {
+			rows[len(rows)-1] = append(rows[len(rows)-1], item)
+		} else {
+			rows = append(rows, []layoutItem{item})
+		}
+		bottom = max(bottom, item.bounds.Y+item.bounds.Height)
+	}
+
+	for _, row := range rows {
+		sort.Slice(row, func(i, j int) bool { return row[i].index < row[j].index })
+	}
+
+	return rows
+}
+
+// mergeRow объединяет элементы ряда в одну полосу с общими границами
+func mergeRow(row []layoutItem) layoutBand {
+	sel := row[0].sel
+	left, top := row[0].bounds.X, row[0].bounds.Y
+	right, bottom := left+row[0].bounds.Width, top+row[0].bounds.Height
+
+	for _, item := range row[1:] {
+		sel = sel.AddSelection(item.sel)
+		left, top = min(left, item.bounds.X), min(top, item.bounds.Y)
+		right = max(right, item.bounds.X+item.bounds.Width)
+		bottom = max(bottom, item.bounds.Y+item.bounds.Height)
+	}
+
+	return layoutBand{
+		sel:    sel,
+		bounds: &dto.BlockBounds{X: left, Y: top, Width: right - left, Height: bottom - top},
+	}
+}
+
+// laidOutChildren возвращает видимые дочерние элементы с известными координатами
+func laidOutChildren(sel *goquery.Selection) []*goquery.Selection {
+	var children []*goquery.Selection
+
+	sel.Children().Each(func(i int, child *goquery.Selection) {
+		bounds, ok := renderedRect(child)
+		if !ok || bounds.Width == 0 || bounds.Height == 0 {
+			return
+		}
+		children = append(children, child)
+	})
+
+	return children
+}
+
+// hasSeveralRows проверяет, что элементы образуют больше одного ряда, а не стоят все в колонках рядом
+func hasSeveralRows(children []*goquery.Selection) bool {
+	items := make([]layoutItem, 0, len(children))
+	for i, child := range children {
+		bounds, _ := renderedRect(child)
+		items = append(items, layoutItem{sel: child, bounds: bounds, index: i})
+	}
+
+	return len(layoutRows(items)) > 1
+}
+
+// bandType определяет, является ли полоса шапкой, подвалом или контентом
+func bandType(band layoutBand, index, total int) dto.BlockType {
+	isHeader := goquery.NodeName(band.sel) == "header" || hasAnyToken(band.sel, "header")
+	isFooter := goquery.NodeName(band.sel) == "footer" || hasAnyToken(band.sel, "footer")
+
+	switch {
+	case index == 0 && (isHeader || band.sel.Find("nav").Length() > 0):
+		return dto.BlockTypeHeader
+	case index == total-1 && (isFooter || strings.Contains(band.sel.Text(), "©")):
+		return dto.BlockTypeFooter
+	case isHeader && index <= 1:
+		return dto.BlockTypeHeader
+	case isFooter && index >= total-2:
+		return dto.BlockTypeFooter
+	}
+
+	return dto.BlockTypeContent
+}
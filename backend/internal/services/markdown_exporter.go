@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"scrapper/internal/dto"
+)
+
+// markdownExporter формирует отчет об операции в Markdown
+type markdownExporter struct{}
+
+// NewMarkdownExporter создает экспортер в Markdown
+func NewMarkdownExporter() Exporter {
+	return &markdownExporter{}
+}
+
+func (e *markdownExporter) Name() string        { return "markdown" }
+func (e *markdownExporter) ContentType() string { return "text/markdown; charset=utf-8" }
+func (e *markdownExporter) Extension() string   { return "md" }
+
+// Export пишет сводку операции и раздел на каждый блок: свойства, компоненты и HTML
+func (e *markdownExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	ew := &errWriter{w: w}
+	op := source.Operation()
+
+	ew.printf("# %s\n\n", markdownEscape(op.URL))
+	ew.printf("| | |\n|---|---|\n")
+	ew.printf("| Operation ID | `%s` |\n", op.ID)
+	ew.printf("| Status | %s |\n", op.Status)
+	ew.printf("| Created At | %s |\n", op.CreatedAt.Format(time.RFC3339))
+	ew.printf("| Updated At | %s |\n", op.UpdatedAt.Format(time.RFC3339))
+
+	index := 0
+	err := source.EachBlock(ctx, func(block *dto.Block) error {
+		index++
+		ew.printf("\n## %d. %s\n\n", index, markdownEscape(string(block.BlockType)))
+
+		ew.printf("- ID: `%s`\n", block.ID)
+		ew.printf("- Platform: %s\n", block.Platform)
+		ew.printf("- Device: %s\n", block.Device)
+		if block.Classification != nil {
+			ew.printf("- Category: %s (%.2f)\n", block.Classification.Category, block.Classification.Score)
+		}
+		if block.Bounds != nil {
+			ew.printf("- Bounds: %d×%d at (%d, %d)\n", block.Bounds.Width, block.Bounds.Height, block.Bounds.X, block.Bounds.Y)
+		}
+
+		components, err := flattenContent(block.Content)
+		if err != nil {
+			return fmt.Errorf("failed to flatten block %s: %w", block.ID, err)
+		}
+		if len(components) > 0 {
+			keys := make([]string, 0, len(components))
+			for key := range components {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			ew.printf("\n### Components\n\n| Path | Value |\n|---|---|\n")
+			for _, key := range keys {
+				ew.printf("| `%s` | %s |\n", key, markdownCell(components[key]))
+			}
+		}
+
+		if block.HTML != "" {
+			fence := markdownFence(block.HTML)
+			ew.printf("\n### HTML\n\n%shtml\n%s\n%s\n", fence, strings.TrimRight(block.HTML, "\n"), fence)
+		}
+
+		return ew.err
+	})
+	if err != nil {
+		return err
+	}
+
+	return ew.err
+}
+
+// markdownEscape экранирует символы разметки Markdown в строке
+func markdownEscape(text string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+		"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+	)
+	return replacer.Replace(text)
+}
+
+// markdownCell готовит значение для ячейки таблицы: одна строка, экранированная разметка
+func markdownCell(text string) string {
+	return strings.Join(strings.Fields(markdownEscape(text)), " ")
+}
+
+// markdownFence возвращает ограничитель блока кода длиннее любой последовательности ` внутри кода
+func markdownFence(code string) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
@@ -12,6 +12,25 @@ var Module = fx.Module("services",
 		NewTildaService,
 		NewBitrixService,
 		NewHTML5Service,
+		NewBlockClassifier,
+		NewClassifierTrainer,
+		NewTemplateService,
+		NewBrandKitService,
+		NewArchiveService,
+		NewDiffService,
+		NewBlockPostProcessor,
+		asExporter(NewExcelExporter),
+		asExporter(NewTextExporter),
+		asExporter(NewJSONExporter),
+		asExporter(NewNDJSONExporter),
+		asExporter(NewCSVExporter),
+		asExporter(NewMarkdownExporter),
+		asExporter(NewPDFExporter),
+		asExporter(NewWXRExporter),
+		asExporter(NewParquetExporter),
+		asExporter(NewSQLiteExporter),
+		NewExporterRegistry,
+		NewExportTemplateService,
 		NewParserService,
 		NewDownloaderService,
 		func(cfg *config.Config) []string {
@@ -20,3 +39,8 @@ var Module = fx.Module("services",
 		NewCrawlerService,
 	),
 )
+
+// asExporter добавляет конструктор экспортера в группу "exporters", из которой собирается ExporterRegistry
+func asExporter(constructor interface{}) interface{} {
+	return fx.Annotate(constructor, fx.ResultTags(`group:"exporters"`))
+}
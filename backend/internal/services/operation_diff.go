@@ -0,0 +1,812 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ErrInvalidDiff возвращается, если операции нельзя сравнить: например, это страницы разных сайтов
+var ErrInvalidDiff = errors.New("invalid operation diff")
+
+const (
+	// minBlockSimilarity наименьшее сходство, при котором контентные блоки считаются одним блоком.
+	// Шапка и подвал сопоставляются по типу при любом сходстве.
+	minBlockSimilarity = 0.5
+
+	// positionWeight доля близости позиций в оценке пары блоков, остальное дает сходство содержимого
+	positionWeight = 0.2
+
+	// textWeight доля сходства текста в сходстве содержимого, остальное дает сходство разметки
+	textWeight = 0.7
+
+	// diffContext число неизмененных строк вокруг изменений в диффе HTML
+	diffContext = 3
+
+	// maxDiffCells предел размера таблицы LCS; для больших блоков дифф сводится к замене целиком
+	maxDiffCells = 1 << 20
+
+	// maxDiffLine длина строки диффа в символах: длинные теги с inline-стилями и SVG обрезаются
+	maxDiffLine = 300
+)
+
+// diffReportTemplate шаблон HTML-отчета о сравнении операций
+//
+//go:embed templates/diff_report.html
+var diffReportTemplate string
+
+var diffReport = template.Must(template.New("diff").Funcs(pdfTemplateFuncs).Parse(diffReportTemplate))
+
+// diffService реализация DiffService
+type diffService struct {
+	logger *zap.Logger
+	repo   repos.ParserRepo
+}
+
+// NewDiffService создает новый экземпляр DiffService
+func NewDiffService(logger *zap.Logger, repo repos.ParserRepo) DiffService {
+	return &diffService{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// diffSide операция одной из сторон сравнения с блоками по профилям устройств
+type diffSide struct {
+	operation *dto.Operation
+	base      *url.URL
+	devices   map[dto.DeviceProfile][]*diffBlock
+}
+
+// diffBlock блок с данными для сопоставления
+type diffBlock struct {
+	block    *dto.Block
+	position int // позиция среди блоков профиля, с 0
+	tokens   []string
+	words    map[string]bool
+	tags     map[string]bool
+}
+
+// DiffOperations сравнивает блоки операции fromID с блоками операции toID
+func (s *diffService) DiffOperations(ctx context.Context, fromID, toID uuid.UUID) (*dto.OperationDiff, error) {
+	from, err := s.loadSide(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.loadSide(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	fromHost, toHost := siteHost(from.base), siteHost(to.base)
+	if fromHost == "" || fromHost != toHost {
+		return nil, fmt.Errorf("%w: operations belong to different sites: %s and %s", ErrInvalidDiff, from.operation.URL, to.operation.URL)
+	}
+
+	diff := &dto.OperationDiff{
+		From:        *from.operation,
+		To:          *to.operation,
+		Blocks:      []dto.BlockDiff{},
+		GeneratedAt: time.Now(),
+	}
+
+	for _, device := range diffDevices(from, to) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		blocks := s.diffDevice(device, from, to)
+		diff.Blocks = append(diff.Blocks, blocks...)
+	}
+
+	for _, block := range diff.Blocks {
+		switch block.Status {
+		case dto.BlockDiffAdded:
+			diff.Summary.Added++
+		case dto.BlockDiffRemoved:
+			diff.Summary.Removed++
+		case dto.BlockDiffChanged:
+			diff.Summary.Changed++
+		case dto.BlockDiffUnchanged:
+			diff.Summary.Unchanged++
+		}
+		if block.Moved {
+			diff.Summary.Moved++
+		}
+	}
+
+	return diff, nil
+}
+
+// RenderDiffHTML записывает сравнение в w как HTML-отчет
+func (s *diffService) RenderDiffHTML(diff *dto.OperationDiff, w io.Writer) error {
+	if err := diffReport.Execute(w, diff); err != nil {
+		return fmt.Errorf("failed to render diff report: %w", err)
+	}
+	return nil
+}
+
+// loadSide загружает операцию с блоками и готовит блоки к сопоставлению
+func (s *diffService) loadSide(ctx context.Context, operationID uuid.UUID) (*diffSide, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.repo.GetBlocksByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blocks: %w", err)
+	}
+
+	side := &diffSide{
+		operation: operation,
+		devices:   make(map[dto.DeviceProfile][]*diffBlock),
+	}
+	side.base, _ = url.Parse(operation.URL)
+
+	for i := range blocks {
+		block := &blocks[i]
+		tokens, words, tags := tokenizeBlock(block.HTML)
+		side.devices[block.Device] = append(side.devices[block.Device], &diffBlock{
+			block:    block,
+			position: len(side.devices[block.Device]),
+			tokens:   tokens,
+			words:    words,
+			tags:     tags,
+		})
+	}
+
+	return side, nil
+}
+
+// siteHost возвращает домен без www для проверки, что операции относятся к одному сайту
+func siteHost(base *url.URL) string {
+	if base == nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(base.Hostname()), "www.")
+}
+
+// diffDevices возвращает профили устройств обеих операций: сначала стандартные, затем остальные по алфавиту
+func diffDevices(from, to *diffSide) []dto.DeviceProfile {
+	present := make(map[string]bool)
+	for device := range from.devices {
+		present[string(device)] = true
+	}
+	for device := range to.devices {
+		present[string(device)] = true
+	}
+
+	var devices []dto.DeviceProfile
+	for _, device := range []dto.DeviceProfile{dto.DeviceDesktop, dto.DeviceTablet, dto.DeviceMobile} {
+		if present[string(device)] {
+			devices = append(devices, device)
+			delete(present, string(device))
+		}
+	}
+	for _, device := range sortedKeys(present) {
+		devices = append(devices, dto.DeviceProfile(device))
+	}
+
+	return devices
+}
+
+// blockPair сопоставленные блоки и их сходство
+type blockPair struct {
+	from, to   *diffBlock
+	similarity float64
+	score      float64
+	moved      bool
+}
+
+// diffDevice сопоставляет блоки одного профиля устройства и возвращает их в порядке новой страницы;
+// удаленные блоки стоят после блока, за которым шли раньше
+func (s *diffService) diffDevice(device dto.DeviceProfile, from, to *diffSide) []dto.BlockDiff {
+	fromBlocks, toBlocks := from.devices[device], to.devices[device]
+	pairs := matchBlocks(fromBlocks, toBlocks)
+	markMoved(pairs)
+
+	matchedFrom := make(map[*diffBlock]*blockPair, len(pairs))
+	matchedTo := make(map[*diffBlock]*blockPair, len(pairs))
+	for i := range pairs {
+		matchedFrom[pairs[i].from] = &pairs[i]
+		matchedTo[pairs[i].to] = &pairs[i]
+	}
+
+	type orderedDiff struct {
+		order float64
+		diff  dto.BlockDiff
+	}
+	var ordered []orderedDiff
+
+	for _, block := range toBlocks {
+		if pair, ok := matchedTo[block]; ok {
+			ordered = append(ordered, orderedDiff{order: float64(block.position), diff: s.diffPair(pair, from.base, to.base)})
+			continue
+		}
+		item := newBlockDiff(dto.BlockDiffAdded, block.block)
+		item.ToBlockID, item.ToPosition = blockRef(block)
+		ordered = append(ordered, orderedDiff{order: float64(block.position), diff: item})
+	}
+
+	after := -1.0
+	for _, block := range fromBlocks {
+		if pair, ok := matchedFrom[block]; ok {
+			after = float64(pair.to.position)
+			continue
+		}
+		item := newBlockDiff(dto.BlockDiffRemoved, block.block)
+		item.FromBlockID, item.FromPosition = blockRef(block)
+		ordered = append(ordered, orderedDiff{order: after + 0.5, diff: item})
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].order < ordered[j].order })
+
+	diffs := make([]dto.BlockDiff, len(ordered))
+	for i, item := range ordered {
+		diffs[i] = item.diff
+	}
+	return diffs
+}
+
+// diffPair сравнивает содержимое сопоставленных блоков
+func (s *diffService) diffPair(pair *blockPair, fromBase, toBase *url.URL) dto.BlockDiff {
+	item := newBlockDiff(dto.BlockDiffUnchanged, pair.to.block)
+	item.Moved = pair.moved
+	item.Similarity = math.Round(pair.similarity*1000) / 1000
+	item.FromBlockID, item.FromPosition = blockRef(pair.from)
+	item.ToBlockID, item.ToPosition = blockRef(pair.to)
+
+	components, err := diffComponents(pair.from.block, pair.to.block, fromBase, toBase)
+	if err != nil {
+		s.logger.Warn("Failed to compare block components",
+			zap.String("from_block_id", pair.from.block.ID.String()),
+			zap.String("to_block_id", pair.to.block.ID.String()),
+			zap.Error(err))
+	}
+	item.Components = components
+
+	if !equalTokens(pair.from.tokens, pair.to.tokens) {
+		item.HTMLDiff = diffLines(pair.from.tokens, pair.to.tokens)
+	}
+	if len(item.Components) > 0 || len(item.HTMLDiff) > 0 {
+		item.Status = dto.BlockDiffChanged
+	}
+
+	return item
+}
+
+// newBlockDiff заполняет общие поля сравнения по блоку
+func newBlockDiff(status dto.BlockDiffStatus, block *dto.Block) dto.BlockDiff {
+	item := dto.BlockDiff{
+		Status:    status,
+		Device:    block.Device,
+		BlockType: block.BlockType,
+		Text:      blockText(block.HTML),
+	}
+	if block.Classification != nil {
+		item.Category = block.Classification.Category
+	}
+	return item
+}
+
+// blockRef возвращает ID блока и его позицию с 1
+func blockRef(block *diffBlock) (*uuid.UUID, *int) {
+	id := block.block.ID
+	position := block.position + 1
+	return &id, &position
+}
+
+// matchBlocks жадно сопоставляет блоки одного типа по убыванию оценки: сходство содержимого
+// с небольшой поправкой на близость позиций, чтобы из одинаковых блоков выбиралась пара на своем месте
+func matchBlocks(fromBlocks, toBlocks []*diffBlock) []blockPair {
+	var candidates []blockPair
+	for _, from := range fromBlocks {
+		for _, to := range toBlocks {
+			if from.block.BlockType != to.block.BlockType {
+				continue
+			}
+
+			similarity := blockSimilarity(from, to)
+			if similarity < minBlockSimilarity && from.block.BlockType == dto.BlockTypeContent {
+				continue
+			}
+
+			closeness := 1 - math.Abs(relativePosition(from.position, len(fromBlocks))-relativePosition(to.position, len(toBlocks)))
+			candidates = append(candidates, blockPair{
+				from:       from,
+				to:         to,
+				similarity: similarity,
+				score:      (1-positionWeight)*similarity + positionWeight*closeness,
+			})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	usedFrom := make(map[*diffBlock]bool)
+	usedTo := make(map[*diffBlock]bool)
+	var pairs []blockPair
+	for _, candidate := range candidates {
+		if usedFrom[candidate.from] || usedTo[candidate.to] {
+			continue
+		}
+		usedFrom[candidate.from] = true
+		usedTo[candidate.to] = true
+		pairs = append(pairs, candidate)
+	}
+
+	return pairs
+}
+
+// relativePosition возвращает положение блока на странице от 0 до 1
+func relativePosition(position, total int) float64 {
+	if total < 2 {
+		return 0
+	}
+	return float64(position) / float64(total-1)
+}
+
+// blockSimilarity сравнивает блоки по словам текста и по набору тегов с классами
+func blockSimilarity(a, b *diffBlock) float64 {
+	if equalTokens(a.tokens, b.tokens) {
+		return 1
+	}
+
+	structure := jaccard(a.tags, b.tags)
+	if len(a.words) == 0 && len(b.words) == 0 {
+		return structure
+	}
+	return textWeight*jaccard(a.words, b.words) + (1-textWeight)*structure
+}
+
+// jaccard возвращает отношение пересечения множеств к их объединению
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	common := 0
+	for key := range a {
+		if b[key] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// markMoved отмечает перемещенные блоки: пары вне наибольшей возрастающей последовательности
+// позиций новой страницы при порядке старой. Так вставка или удаление блока не делает
+// перемещенными все блоки после него.
+func markMoved(pairs []blockPair) {
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].from.position < pairs[j].from.position })
+
+	// tails[k] индекс пары, которой заканчивается лучшая возрастающая последовательность длины k+1
+	tails := make([]int, 0, len(pairs))
+	prev := make([]int, len(pairs))
+	for i := range pairs {
+		position := pairs[i].to.position
+		k := sort.Search(len(tails), func(k int) bool { return pairs[tails[k]].to.position >= position })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	inOrder := make([]bool, len(pairs))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			inOrder[i] = true
+		}
+	}
+	for i := range pairs {
+		pairs[i].moved = !inOrder[i]
+	}
+}
+
+// tokenizeBlock разбивает HTML блока на строки для диффа: по тегу или текстовому узлу на строку.
+// Заодно собирает слова видимого текста и теги с классами для оценки сходства.
+func tokenizeBlock(content string) ([]string, map[string]bool, map[string]bool) {
+	var tokens []string
+	words := make(map[string]bool)
+	tags := make(map[string]bool)
+
+	z := html.NewTokenizer(strings.NewReader(content))
+	rawText := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		switch tt {
+		case html.TextToken:
+			text := strings.Join(strings.Fields(string(z.Text())), " ")
+			if text == "" {
+				continue
+			}
+			tokens = append(tokens, text)
+			if !rawText {
+				for _, word := range strings.Fields(strings.ToLower(text)) {
+					words[word] = true
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			tokens = append(tokens, strings.Join(strings.Fields(string(z.Raw())), " "))
+
+			token := z.Token()
+			if tt == html.EndTagToken {
+				rawText = false
+				continue
+			}
+			rawText = tt == html.StartTagToken && (token.Data == "script" || token.Data == "style")
+
+			tag := token.Data
+			for _, attr := range token.Attr {
+				if attr.Key == "class" {
+					for _, class := range strings.Fields(attr.Val) {
+						tags[tag+"."+class] = true
+					}
+				}
+			}
+			tags[tag] = true
+		}
+	}
+
+	return tokens, words, tags
+}
+
+// equalTokens сравнивает строки разметки двух блоков
+func equalTokens(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffLines строит построчный дифф по наибольшей общей подпоследовательности.
+// Длинные неизмененные участки сворачиваются в строку DiffSkip.
+func diffLines(a, b []string) []dto.DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []dto.DiffLine
+	for _, line := range a[:prefix] {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: line})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: line})
+	}
+
+	return collapseDiff(lines)
+}
+
+// diffMiddle строит дифф участков без общих начала и конца
+func diffMiddle(a, b []string) []dto.DiffLine {
+	lines := make([]dto.DiffLine, 0, len(a)+len(b))
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			lines = append(lines, dto.DiffLine{Op: dto.DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			lines = append(lines, dto.DiffLine{Op: dto.DiffInsert, Text: line})
+		}
+		return lines
+	}
+
+	// lcs[i][j] длина общей подпоследовательности a[i:] и b[j:]
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else {
+				lcs[i*width+j] = max(lcs[(i+1)*width+j], lcs[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, dto.DiffLine{Op: dto.DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			lines = append(lines, dto.DiffLine{Op: dto.DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, dto.DiffLine{Op: dto.DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, dto.DiffLine{Op: dto.DiffInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+// collapseDiff оставляет diffContext неизмененных строк вокруг изменений и обрезает длинные строки
+func collapseDiff(lines []dto.DiffLine) []dto.DiffLine {
+	var result []dto.DiffLine
+	for start := 0; start < len(lines); {
+		if lines[start].Op != dto.DiffEqual {
+			result = append(result, dto.DiffLine{Op: lines[start].Op, Text: truncateDiffLine(lines[start].Text)})
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(lines) && lines[end].Op == dto.DiffEqual {
+			end++
+		}
+
+		keepHead, keepTail := diffContext, diffContext
+		if start == 0 {
+			keepHead = 0
+		}
+		if end == len(lines) {
+			keepTail = 0
+		}
+
+		if end-start > keepHead+keepTail+1 {
+			for _, line := range lines[start : start+keepHead] {
+				result = append(result, dto.DiffLine{Op: line.Op, Text: truncateDiffLine(line.Text)})
+			}
+			skipped := end - start - keepHead - keepTail
+			result = append(result, dto.DiffLine{Op: dto.DiffSkip, Text: strconv.Itoa(skipped) + " unchanged lines"})
+			for _, line := range lines[end-keepTail : end] {
+				result = append(result, dto.DiffLine{Op: line.Op, Text: truncateDiffLine(line.Text)})
+			}
+		} else {
+			for _, line := range lines[start:end] {
+				result = append(result, dto.DiffLine{Op: line.Op, Text: truncateDiffLine(line.Text)})
+			}
+		}
+		start = end
+	}
+
+	return result
+}
+
+// truncateDiffLine обрезает строку диффа до maxDiffLine символов
+func truncateDiffLine(line string) string {
+	runes := []rune(line)
+	if len(runes) <= maxDiffLine {
+		return line
+	}
+	return string(runes[:maxDiffLine]) + "…"
+}
+
+// componentItem пункт списка в содержимом блока, например ссылка меню
+type componentItem struct {
+	key     string
+	index   int
+	display string
+}
+
+// componentGroups раскладывает компоненты блока на одиночные значения и пункты списков.
+// Пункт списка задается путем до первого номера: nav_links.2.title и nav_links.2.url дают пункт nav_links.2.
+func componentGroups(block *dto.Block, base *url.URL) (map[string]string, map[string][]componentItem, error) {
+	_, components, err := blockComponents(block, base)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scalars := make(map[string]string)
+	parts := make(map[string]map[string][]string) // список -> пункт -> значения в порядке путей
+	for _, key := range sortedComponentKeys(components) {
+		display := componentDisplay(components[key])
+		if display == "" {
+			continue
+		}
+
+		segments := strings.Split(key, ".")
+		item := -1
+		for i, segment := range segments {
+			if _, err := strconv.Atoi(segment); err == nil {
+				item = i
+				break
+			}
+		}
+		if item < 0 {
+			scalars[key] = display
+			continue
+		}
+
+		list := strings.Join(segments[:item], ".")
+		itemKey := strings.Join(segments[:item+1], ".")
+		if parts[list] == nil {
+			parts[list] = make(map[string][]string)
+		}
+		parts[list][itemKey] = append(parts[list][itemKey], display)
+	}
+
+	lists := make(map[string][]componentItem, len(parts))
+	for list, items := range parts {
+		for itemKey, values := range items {
+			index, _ := strconv.Atoi(itemKey[strings.LastIndex(itemKey, ".")+1:])
+			lists[list] = append(lists[list], componentItem{key: itemKey, index: index, display: strings.Join(values, " · ")})
+		}
+		sort.Slice(lists[list], func(i, j int) bool { return lists[list][i].index < lists[list][j].index })
+	}
+
+	return scalars, lists, nil
+}
+
+// sortedComponentKeys возвращает пути компонентов с числовыми сегментами по возрастанию номера
+func sortedComponentKeys(components map[string]blockComponent) []string {
+	keys := make([]string, 0, len(components))
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return naturalLess(keys[i], keys[j]) })
+	return keys
+}
+
+// naturalLess сравнивает пути по сегментам, числовые сегменты как числа
+func naturalLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		if aErr == nil && bErr == nil {
+			return an < bn
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
+
+// componentDisplay возвращает значение компонента для показа: текст и ссылку, если она отличается
+func componentDisplay(component blockComponent) string {
+	switch {
+	case component.link == "" || component.link == component.text:
+		return component.text
+	case component.text == "":
+		return component.link
+	default:
+		return component.text + " (" + component.link + ")"
+	}
+}
+
+// diffComponents сравнивает компоненты сопоставленных блоков. Одиночные значения сравниваются по пути,
+// пункты списков по значению: вставка пункта меню дает один добавленный пункт, а не сдвиг всех следующих.
+// Непарные пункты с одним номером показываются как измененные.
+func diffComponents(from, to *dto.Block, fromBase, toBase *url.URL) ([]dto.ComponentChange, error) {
+	fromScalars, fromLists, err := componentGroups(from, fromBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse block %s content: %w", from.ID, err)
+	}
+	toScalars, toLists, err := componentGroups(to, toBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse block %s content: %w", to.ID, err)
+	}
+
+	var changes []dto.ComponentChange
+
+	scalarKeys := make(map[string]bool)
+	for key := range fromScalars {
+		scalarKeys[key] = true
+	}
+	for key := range toScalars {
+		scalarKeys[key] = true
+	}
+	for _, key := range sortedKeys(scalarKeys) {
+		before, hadBefore := fromScalars[key]
+		after, hasAfter := toScalars[key]
+		switch {
+		case !hadBefore:
+			changes = append(changes, dto.ComponentChange{Key: key, Change: dto.ComponentAdded, After: after})
+		case !hasAfter:
+			changes = append(changes, dto.ComponentChange{Key: key, Change: dto.ComponentRemoved, Before: before})
+		case before != after:
+			changes = append(changes, dto.ComponentChange{Key: key, Change: dto.ComponentChanged, Before: before, After: after})
+		}
+	}
+
+	listKeys := make(map[string]bool)
+	for key := range fromLists {
+		listKeys[key] = true
+	}
+	for key := range toLists {
+		listKeys[key] = true
+	}
+	for _, key := range sortedKeys(listKeys) {
+		changes = append(changes, diffComponentList(fromLists[key], toLists[key])...)
+	}
+
+	return changes, nil
+}
+
+// diffComponentList сравнивает пункты списка как мультимножества значений
+func diffComponentList(from, to []componentItem) []dto.ComponentChange {
+	available := make(map[string]int)
+	for _, item := range to {
+		available[item.display]++
+	}
+
+	var removed []componentItem
+	remaining := make(map[string]int)
+	for _, item := range from {
+		if available[item.display] > 0 {
+			available[item.display]--
+			remaining[item.display]++
+			continue
+		}
+		removed = append(removed, item)
+	}
+
+	var added []componentItem
+	for _, item := range to {
+		if remaining[item.display] > 0 {
+			remaining[item.display]--
+			continue
+		}
+		added = append(added, item)
+	}
+
+	addedAt := make(map[int]int, len(added))
+	for i, item := range added {
+		addedAt[item.index] = i
+	}
+	paired := make(map[int]bool)
+
+	var changes []dto.ComponentChange
+	for _, item := range removed {
+		if i, ok := addedAt[item.index]; ok && !paired[i] {
+			paired[i] = true
+			changes = append(changes, dto.ComponentChange{Key: item.key, Change: dto.ComponentChanged, Before: item.display, After: added[i].display})
+			continue
+		}
+		changes = append(changes, dto.ComponentChange{Key: item.key, Change: dto.ComponentRemoved, Before: item.display})
+	}
+	for i, item := range added {
+		if !paired[i] {
+			changes = append(changes, dto.ComponentChange{Key: item.key, Change: dto.ComponentAdded, After: item.display})
+		}
+	}
+
+	return changes
+}
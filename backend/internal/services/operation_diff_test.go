@@ -0,0 +1,305 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"scrapper/internal/dto"
+)
+
+func TestMatchBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		from []string
+		to   []string
+		// want пары позиций from -> to
+		want map[int]int
+	}{
+		{
+			name: "same blocks",
+			from: []string{"<header>Logo</header>", "<p>first block text</p>", "<p>second block text</p>", "<footer>Contacts</footer>"},
+			to:   []string{"<header>Logo</header>", "<p>first block text</p>", "<p>second block text</p>", "<footer>Contacts</footer>"},
+			want: map[int]int{0: 0, 1: 1, 2: 2, 3: 3},
+		},
+		{
+			name: "added block",
+			from: []string{"<p>first block text</p>", "<p>second block text</p>"},
+			to:   []string{"<p>first block text</p>", "<p>brand new promo section</p>", "<p>second block text</p>"},
+			want: map[int]int{0: 0, 1: 2},
+		},
+		{
+			name: "removed block",
+			from: []string{"<p>first block text</p>", "<p>old banner about sale</p>", "<p>second block text</p>"},
+			to:   []string{"<p>first block text</p>", "<p>second block text</p>"},
+			want: map[int]int{0: 0, 2: 1},
+		},
+		{
+			name: "changed block is matched by similarity",
+			from: []string{"<p>our team of twelve engineers builds scrapers</p>"},
+			to:   []string{"<p>our team of fifteen engineers builds scrapers</p>"},
+			want: map[int]int{0: 0},
+		},
+		{
+			name: "unrelated content blocks are not matched",
+			from: []string{"<p>pricing plans for small business</p>"},
+			to:   []string{"<div><span>customer reviews and testimonials</span></div>"},
+			want: map[int]int{},
+		},
+		{
+			name: "header is matched whatever the content",
+			from: []string{"<header><nav>Home About</nav></header>"},
+			to:   []string{"<header><div>Completely new menu</div></header>"},
+			want: map[int]int{0: 0},
+		},
+		{
+			name: "moved block",
+			from: []string{"<p>first block text</p>", "<p>second block text</p>", "<p>third block text</p>"},
+			to:   []string{"<p>third block text</p>", "<p>first block text</p>", "<p>second block text</p>"},
+			want: map[int]int{0: 1, 1: 2, 2: 0},
+		},
+		{
+			name: "identical blocks keep their places",
+			from: []string{"<p>divider</p>", "<p>unique text here</p>", "<p>divider</p>"},
+			to:   []string{"<p>divider</p>", "<p>unique text here</p>", "<p>divider</p>"},
+			want: map[int]int{0: 0, 1: 1, 2: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := matchBlocks(newTestDiffBlocks(tt.from), newTestDiffBlocks(tt.to))
+
+			got := make(map[int]int, len(pairs))
+			for _, pair := range pairs {
+				got[pair.from.position] = pair.to.position
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchBlocks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkMoved(t *testing.T) {
+	tests := []struct {
+		name string
+		// to позиции новой страницы для пар в порядке старой
+		to   []int
+		want []bool
+	}{
+		{name: "no pairs", to: nil, want: []bool{}},
+		{name: "same order", to: []int{0, 1, 2, 3}, want: []bool{false, false, false, false}},
+		{name: "insertion shifts positions", to: []int{0, 2, 3, 4}, want: []bool{false, false, false, false}},
+		{name: "last block moved to the top", to: []int{1, 2, 3, 0}, want: []bool{false, false, false, true}},
+		{name: "first block moved to the bottom", to: []int{3, 0, 1, 2}, want: []bool{true, false, false, false}},
+		{name: "two blocks swapped", to: []int{0, 2, 1, 3}, want: []bool{false, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := make([]blockPair, len(tt.to))
+			for i, to := range tt.to {
+				pairs[i] = blockPair{from: &diffBlock{position: i}, to: &diffBlock{position: to}}
+			}
+			// Порядок входа не важен: пары сортируются по позиции старой страницы
+			sort.Slice(pairs, func(i, j int) bool { return pairs[i].to.position < pairs[j].to.position })
+
+			markMoved(pairs)
+
+			got := make([]bool, len(pairs))
+			for _, pair := range pairs {
+				got[pair.from.position] = pair.moved
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("markMoved() moved = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffMiddle(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []dto.DiffLine
+	}{
+		{
+			name: "empty",
+			want: []dto.DiffLine{},
+		},
+		{
+			name: "only inserts",
+			b:    []string{"<p>", "new", "</p>"},
+			want: []dto.DiffLine{
+				{Op: dto.DiffInsert, Text: "<p>"},
+				{Op: dto.DiffInsert, Text: "new"},
+				{Op: dto.DiffInsert, Text: "</p>"},
+			},
+		},
+		{
+			name: "only deletes",
+			a:    []string{"<br>"},
+			want: []dto.DiffLine{{Op: dto.DiffDelete, Text: "<br>"}},
+		},
+		{
+			name: "changed line between common lines",
+			a:    []string{"<a>", "old", "</a>"},
+			b:    []string{"<a>", "new", "</a>"},
+			want: []dto.DiffLine{
+				{Op: dto.DiffEqual, Text: "<a>"},
+				{Op: dto.DiffDelete, Text: "old"},
+				{Op: dto.DiffInsert, Text: "new"},
+				{Op: dto.DiffEqual, Text: "</a>"},
+			},
+		},
+		{
+			name: "longest common subsequence is kept",
+			a:    []string{"a", "b", "c", "d"},
+			b:    []string{"b", "x", "d", "e"},
+			want: []dto.DiffLine{
+				{Op: dto.DiffDelete, Text: "a"},
+				{Op: dto.DiffEqual, Text: "b"},
+				{Op: dto.DiffDelete, Text: "c"},
+				{Op: dto.DiffInsert, Text: "x"},
+				{Op: dto.DiffEqual, Text: "d"},
+				{Op: dto.DiffInsert, Text: "e"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffMiddle(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffMiddle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffMiddleLargeBlocks(t *testing.T) {
+	// Таблица LCS больше maxDiffCells: блок заменяется целиком, даже если строки совпадают
+	size := 1025
+	a := make([]string, size)
+	b := make([]string, size)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+		b[i] = fmt.Sprintf("line %d", i+1)
+	}
+	if size*size <= maxDiffCells {
+		t.Fatalf("test blocks must exceed maxDiffCells")
+	}
+
+	got := diffMiddle(a, b)
+	if len(got) != 2*size {
+		t.Fatalf("diffMiddle() returned %d lines, want %d", len(got), 2*size)
+	}
+	for i, line := range a {
+		if want := (dto.DiffLine{Op: dto.DiffDelete, Text: line}); got[i] != want {
+			t.Fatalf("diffMiddle()[%d] = %v, want %v", i, got[i], want)
+		}
+	}
+	for i, line := range b {
+		if want := (dto.DiffLine{Op: dto.DiffInsert, Text: line}); got[size+i] != want {
+			t.Fatalf("diffMiddle()[%d] = %v, want %v", size+i, got[size+i], want)
+		}
+	}
+
+	// Чуть меньше предела дифф строится по LCS
+	small := diffMiddle(a[:1000], b[:1000])
+	if small[0] != (dto.DiffLine{Op: dto.DiffDelete, Text: "line 0"}) || small[1] != (dto.DiffLine{Op: dto.DiffEqual, Text: "line 1"}) {
+		t.Errorf("diffMiddle() below maxDiffCells starts with %v, want LCS diff", small[:2])
+	}
+}
+
+func TestDiffComponentList(t *testing.T) {
+	tests := []struct {
+		name string
+		from []string
+		to   []string
+		want []dto.ComponentChange
+	}{
+		{
+			name: "same list",
+			from: []string{"Home", "About"},
+			to:   []string{"Home", "About"},
+			want: nil,
+		},
+		{
+			name: "reordered list has no changes",
+			from: []string{"Home", "About", "Blog"},
+			to:   []string{"Blog", "Home", "About"},
+			want: nil,
+		},
+		{
+			name: "added item",
+			from: []string{"Home", "About"},
+			to:   []string{"Home", "About", "Blog"},
+			want: []dto.ComponentChange{{Key: "menu.2", Change: dto.ComponentAdded, After: "Blog"}},
+		},
+		{
+			name: "removed item",
+			from: []string{"Home", "About", "Blog"},
+			to:   []string{"Home", "Blog"},
+			want: []dto.ComponentChange{{Key: "menu.1", Change: dto.ComponentRemoved, Before: "About"}},
+		},
+		{
+			name: "item changed at the same index",
+			from: []string{"Home", "About us", "Blog"},
+			to:   []string{"Home", "About", "Blog"},
+			want: []dto.ComponentChange{{Key: "menu.1", Change: dto.ComponentChanged, Before: "About us", After: "About"}},
+		},
+		{
+			name: "duplicates are counted",
+			from: []string{"Buy", "Buy"},
+			to:   []string{"Buy"},
+			want: []dto.ComponentChange{{Key: "menu.1", Change: dto.ComponentRemoved, Before: "Buy"}},
+		},
+		{
+			name: "removed and added at different indexes",
+			from: []string{"Home", "Old"},
+			to:   []string{"New", "Home"},
+			want: []dto.ComponentChange{
+				{Key: "menu.1", Change: dto.ComponentRemoved, Before: "Old"},
+				{Key: "menu.0", Change: dto.ComponentAdded, After: "New"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffComponentList(newTestComponentItems(tt.from), newTestComponentItems(tt.to)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffComponentList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestDiffBlocks создает блоки для сопоставления; теги header и footer задают тип блока
+func newTestDiffBlocks(contents []string) []*diffBlock {
+	blocks := make([]*diffBlock, len(contents))
+	for i, content := range contents {
+		block := &dto.Block{BlockType: dto.BlockTypeContent, HTML: content}
+		switch {
+		case strings.HasPrefix(content, "<header>"):
+			block.BlockType = dto.BlockTypeHeader
+		case strings.HasPrefix(content, "<footer>"):
+			block.BlockType = dto.BlockTypeFooter
+		}
+
+		tokens, words, tags := tokenizeBlock(content)
+		blocks[i] = &diffBlock{block: block, position: i, tokens: tokens, words: words, tags: tags}
+	}
+	return blocks
+}
+
+// newTestComponentItems создает пункты списка menu по значениям
+func newTestComponentItems(values []string) []componentItem {
+	items := make([]componentItem, len(values))
+	for i, value := range values {
+		items[i] = componentItem{key: fmt.Sprintf("menu.%d", i), index: i, display: value}
+	}
+	return items
+}
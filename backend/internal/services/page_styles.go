@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/css"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+
+	"scrapper/internal/dto"
+)
+
+// pageStylesKey возвращает ключ таблиц стилей страницы операции для профиля устройства
+func pageStylesKey(operationID uuid.UUID, device dto.DeviceProfile) string {
+	return fmt.Sprintf("styles/%s/%s.css", operationID, device)
+}
+
+// styleCollector собирает таблицы стилей страницы через CSS домен в порядке их подключения
+type styleCollector struct {
+	mu      sync.Mutex
+	headers []*css.StyleSheetHeader
+	removed map[cdp.StyleSheetID]bool
+}
+
+func newStyleCollector() *styleCollector {
+	return &styleCollector{removed: make(map[cdp.StyleSheetID]bool)}
+}
+
+// listen подписывается на события CSS домена вкладки; вызывается до первого chromedp.Run
+func (c *styleCollector) listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		switch ev := ev.(type) {
+		case *css.EventStyleSheetAdded:
+			c.headers = append(c.headers, ev.Header)
+		case *css.EventStyleSheetRemoved:
+			c.removed[ev.StyleSheetID] = true
+		}
+	})
+}
+
+// enable включает DOM и CSS домены, после чего браузер сообщает о каждой таблице стилей
+func (c *styleCollector) enable() chromedp.Action {
+	return chromedp.Tasks{dom.Enable(), css.Enable()}
+}
+
+// capture возвращает действие chromedp, забирающее текст подключенных таблиц стилей.
+// Ссылки в url() делаются абсолютными относительно адреса таблицы,
+// таблицы из link с атрибутом media оборачиваются в @media.
+func (c *styleCollector) capture(styles *string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		c.mu.Lock()
+		var headers []*css.StyleSheetHeader
+		for _, header := range c.headers {
+			if c.removed[header.StyleSheetID] || header.Disabled || header.Origin != css.StyleSheetOriginRegular {
+				continue
+			}
+			headers = append(headers, header)
+		}
+		c.mu.Unlock()
+
+		var buf strings.Builder
+		for _, header := range headers {
+			text, err := css.GetStyleSheetText(header.StyleSheetID).Do(ctx)
+			if err != nil || strings.TrimSpace(text) == "" {
+				continue
+			}
+
+			if base, err := url.Parse(header.SourceURL); err == nil && base.IsAbs() {
+				text = absolutizeCSS(text, base)
+			}
+
+			fmt.Fprintf(&buf, "/* %s */\n", strings.ReplaceAll(header.SourceURL, "*/", "*\\/"))
+			if media := ownerMedia(ctx, header.OwnerNode); media != "" {
+				fmt.Fprintf(&buf, "@media %s {\n%s\n}\n", media, text)
+			} else {
+				buf.WriteString(text + "\n")
+			}
+		}
+
+		*styles = buf.String()
+		return nil
+	})
+}
+
+// ownerMedia возвращает атрибут media элемента, подключившего таблицу стилей
+func ownerMedia(ctx context.Context, owner cdp.BackendNodeID) string {
+	if owner == 0 {
+		return ""
+	}
+
+	node, err := dom.DescribeNode().WithBackendNodeID(owner).Do(ctx)
+	if err != nil {
+		return ""
+	}
+
+	for i := 0; i+1 < len(node.Attributes); i += 2 {
+		if strings.EqualFold(node.Attributes[i], "media") {
+			media := strings.TrimSpace(node.Attributes[i+1])
+			if media == "" || strings.EqualFold(media, "all") {
+				return ""
+			}
+			return media
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+	"go.uber.org/zap"
+)
+
+// parquetExporter выгружает нормализованные таблицы операций в Parquet: по файлу на таблицу в ZIP-архиве.
+// Таблицы пишутся за один проход по блокам во временные файлы, архив собирается после.
+type parquetExporter struct {
+	logger *zap.Logger
+}
+
+// NewParquetExporter создает экспортер в Parquet
+func NewParquetExporter(logger *zap.Logger) Exporter {
+	return &parquetExporter{logger: logger}
+}
+
+func (e *parquetExporter) Name() string        { return "parquet" }
+func (e *parquetExporter) ContentType() string { return "application/zip" }
+func (e *parquetExporter) Extension() string   { return "parquet.zip" }
+
+// Export выгружает одну операцию
+func (e *parquetExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	return e.ExportBatch(ctx, []ExportSource{source}, w)
+}
+
+// parquetTable временный файл таблицы и писатель Parquet в него
+type parquetTable struct {
+	file   *os.File
+	writer *parquet.Writer
+}
+
+// ExportBatch выгружает операции в один архив
+func (e *parquetExporter) ExportBatch(ctx context.Context, sources []ExportSource, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "scrapper-parquet-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			e.logger.Warn("Failed to remove parquet temp dir", zap.String("dir", dir), zap.Error(err))
+		}
+	}()
+
+	tables := make(map[string]*parquetTable, len(analyticsTables))
+	defer func() {
+		for _, table := range tables {
+			table.file.Close()
+		}
+	}()
+
+	for _, table := range analyticsTables {
+		file, err := os.CreateTemp(dir, table.name+"-*.parquet")
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		tables[table.name] = &parquetTable{
+			file:   file,
+			writer: parquet.NewWriter(file, parquet.SchemaOf(table.model), parquet.Compression(&parquet.Zstd)),
+		}
+	}
+
+	err = collectAnalytics(ctx, sources, func(name string, row analyticsRow) error {
+		if err := tables[name].writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write %s row: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, table := range analyticsTables {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		current := tables[table.name]
+		if err := current.writer.Close(); err != nil {
+			return fmt.Errorf("failed to finish %s table: %w", table.name, err)
+		}
+		if _, err := current.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind %s table: %w", table.name, err)
+		}
+
+		// Страницы Parquet уже сжаты zstd
+		if err := writeZipEntry(zw, table.name+".parquet", false, current.file); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+
+	return nil
+}
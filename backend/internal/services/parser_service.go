@@ -1,57 +1,116 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2"
 	"go.uber.org/zap"
 
 	"scrapper/internal/dto"
 	"scrapper/internal/repos"
 )
 
+const (
+	// defaultListOperations размер страницы списка операций по умолчанию
+	defaultListOperations = 50
+
+	// maxListOperations наибольший размер страницы списка операций
+	maxListOperations = 500
+)
+
 // parserService реализация ParserService
 type parserService struct {
 	logger           *zap.Logger
 	repo             repos.ParserRepo
+	classifierRepo   repos.ClassifierRepo
 	wordpressService WordPressService
 	tildaService     TildaService
 	bitrixService    BitrixService
 	html5Service     HTML5Service
+	classifier       BlockClassifier
+	storage          repos.BlobStorage
+	brandKit         BrandKitService
+	archives         ArchiveService
+	snapshots        repos.SnapshotRepo
+	postProcessor    BlockPostProcessor
+	exporters        ExporterRegistry
 }
 
 // NewParserService создает новый экземпляр ParserService
 func NewParserService(
 	logger *zap.Logger,
 	repo repos.ParserRepo,
+	classifierRepo repos.ClassifierRepo,
 	wordpressService WordPressService,
 	tildaService TildaService,
 	bitrixService BitrixService,
 	html5Service HTML5Service,
+	classifier BlockClassifier,
+	storage repos.BlobStorage,
+	brandKit BrandKitService,
+	archives ArchiveService,
+	snapshots repos.SnapshotRepo,
+	postProcessor BlockPostProcessor,
+	exporters ExporterRegistry,
 ) ParserService {
 	return &parserService{
 		logger:           logger,
 		repo:             repo,
+		classifierRepo:   classifierRepo,
 		wordpressService: wordpressService,
 		tildaService:     tildaService,
 		bitrixService:    bitrixService,
 		html5Service:     html5Service,
+		classifier:       classifier,
+		storage:          storage,
+		brandKit:         brandKit,
+		archives:         archives,
+		snapshots:        snapshots,
+		postProcessor:    postProcessor,
+		exporters:        exporters,
 	}
 }
 
 // ParseURL парсит URL и сохраняет результаты в базу данных
-func (s *parserService) ParseURL(ctx context.Context, url string) (uuid.UUID, error) {
+func (s *parserService) ParseURL(ctx context.Context, req dto.ParseURLRequest) (uuid.UUID, error) {
+	// При повторном парсинге из архива URL и профили устройств берутся из исходной операции
+	if req.ArchiveOperationID != nil {
+		if err := s.resolveArchiveRequest(ctx, &req); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	devices, err := resolveDevices(req.Devices)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	// Создаем операцию в БД
-	operationID, err := s.repo.CreateOperation(ctx, url)
+	operationID, err := s.repo.CreateOperation(ctx, req.URL)
 	if err != nil {
 		s.logger.Error("Failed to create operation", zap.Error(err))
 		return uuid.Nil, err
 	}
 
+	// Запоминаем версию парсера, которой получены блоки, и источник страницы
+	origin := dto.OperationOrigin{ParserVersion: ParserVersion, SourceType: dto.SourceTypeURL}
+	if req.ArchiveOperationID != nil {
+		origin.SourceType = dto.SourceTypeWARC
+	}
+	if err := s.repo.UpdateOperationOrigin(ctx, operationID, origin); err != nil {
+		s.logger.Error("Failed to update operation origin", zap.Error(err))
+	}
+
 	// Обновляем статус операции
 	err = s.repo.UpdateOperationStatus(ctx, operationID, dto.StatusProcessing)
 	if err != nil {
@@ -64,124 +123,718 @@ func (s *parserService) ParseURL(ctx context.Context, url string) (uuid.UUID, er
 		// Создаем новый контекст для горутины
 		goCtx := context.Background()
 
-		opts := append(chromedp.DefaultExecAllocatorOptions[:],
-			chromedp.ExecPath("C:/Program Files/Google/Chrome/Application/chrome.exe"), // путь к Chrome
-			chromedp.Flag("headless", true),
-			chromedp.Flag("disable-gpu", true),
-		)
+		// Извлекаем блоки отдельно для каждого профиля устройства
+		rendered := 0
+		for _, device := range devices {
+			var replay *replayArchive
+			if req.ArchiveOperationID != nil {
+				archive, err := s.loadReplay(goCtx, *req.ArchiveOperationID, device.Profile)
+				if err != nil {
+					s.logger.Error("Failed to load page archive", zap.Error(err), zap.String("device", string(device.Profile)))
+					continue
+				}
+				replay = archive
+			}
 
-		allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-		defer cancel()
+			page, err := s.renderPage(req.URL, device, replay)
+			if err != nil {
+				s.logger.Error("Failed to render page with chromedp", zap.Error(err), zap.String("device", string(device.Profile)))
+				continue
+			}
 
-		// Создаем контекст с таймаутом
-		ctx, cancel := chromedp.NewContext(allocCtx)
-		defer cancel()
+			// Фирменный набор операции берется с первого отрендеренного профиля
+			if rendered == 0 {
+				if _, err := s.brandKit.CollectBrandKit(goCtx, operationID, req.URL, page.html); err != nil {
+					s.logger.Error("Failed to collect brand kit", zap.Error(err))
+				}
+			}
 
-		// Устанавливаем общий таймаут на выполнение
-		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
+			s.processPage(goCtx, operationID, device.Profile, page, req, rendered == 0)
+			page.close()
+			s.saveArchives(goCtx, operationID, device.Profile, page)
+			s.saveSnapshot(goCtx, operationID, device.Profile, page)
+			rendered++
+		}
 
-		var html string
+		// Операция, перепарсенная из архива, ссылается на архивы исходной операции
+		if req.ArchiveOperationID != nil && rendered > 0 {
+			if err := s.archives.AttachArchives(goCtx, *req.ArchiveOperationID, operationID); err != nil {
+				s.logger.Error("Failed to attach source archives", zap.Error(err))
+			}
+		}
 
-		// Навигация и извлечение outer HTML
-		err := chromedp.Run(ctx,
-			chromedp.Navigate(url),
-			chromedp.Sleep(2*time.Second), // дать JS отработать
-			chromedp.OuterHTML("html", &html),
-		)
+		status := dto.StatusCompleted
+		if rendered == 0 {
+			status = dto.StatusError
+		}
 
+		// Обновляем статус операции
+		err := s.repo.UpdateOperationStatus(goCtx, operationID, status)
 		if err != nil {
-			s.logger.Error("Failed to render page with chromedp", zap.Error(err))
-			s.repo.UpdateOperationStatus(goCtx, operationID, dto.StatusError)
+			s.logger.Error("Failed to update operation status", zap.Error(err))
 		}
+	}()
 
-		// Определяем платформу сайта
-		platform := s.DetectPlatform(html)
+	return operationID, nil
+}
 
-		// Парсим шапку и подвал в зависимости от платформы
-		var headerBlock, footerBlock *dto.Block
+// ParseContent парсит загруженную страницу: HTML, MHTML, HAR или WARC.
+// Страница открывается в браузере из содержимого файла, к сайту запросы не выполняются.
+func (s *parserService) ParseContent(ctx context.Context, req dto.ParseContentRequest) (uuid.UUID, error) {
+	if req.SourceType == "" {
+		req.SourceType = detectSourceType(req.Filename, req.Content)
+	}
 
-		switch platform {
-		case dto.PlatformWordPress:
-			headerBlock, err = s.wordpressService.ParseHeader(html)
-			if err != nil {
-				s.logger.Error("Failed to parse WordPress header", zap.Error(err))
-			}
+	replay, pageURL, err := newContentReplay(req)
+	if errors.Is(err, ErrInvalidContent) {
+		return uuid.Nil, err
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %v", ErrInvalidContent, err)
+	}
 
-			footerBlock, err = s.wordpressService.ParseFooter(html)
-			if err != nil {
-				s.logger.Error("Failed to parse WordPress footer", zap.Error(err))
-			}
-		case dto.PlatformTilda:
-			headerBlock, err = s.tildaService.ParseHeader(html)
-			if err != nil {
-				s.logger.Error("Failed to parse Tilda header", zap.Error(err))
-			}
+	devices, err := resolveDevices(req.Devices)
+	if err != nil {
+		return uuid.Nil, err
+	}
 
-			footerBlock, err = s.tildaService.ParseFooter(html)
-			if err != nil {
-				s.logger.Error("Failed to parse Tilda footer", zap.Error(err))
-			}
-		case dto.PlatformBitrix:
-			headerBlock, err = s.bitrixService.ParseHeader(html)
-			if err != nil {
-				s.logger.Error("Failed to parse Bitrix header", zap.Error(err))
-			}
+	// Создаем операцию в БД
+	operationID, err := s.repo.CreateOperation(ctx, pageURL)
+	if err != nil {
+		s.logger.Error("Failed to create operation", zap.Error(err))
+		return uuid.Nil, err
+	}
 
-			footerBlock, err = s.bitrixService.ParseFooter(html)
-			if err != nil {
-				s.logger.Error("Failed to parse Bitrix footer", zap.Error(err))
-			}
-		case dto.PlatformHTML5:
-			var blocks []*dto.Block
-			templates, err := s.repo.GetAllTemplates(platform)
+	origin := dto.OperationOrigin{ParserVersion: ParserVersion, SourceType: req.SourceType}
+	if err := s.repo.UpdateOperationOrigin(ctx, operationID, origin); err != nil {
+		s.logger.Error("Failed to update operation origin", zap.Error(err))
+	}
+
+	err = s.repo.UpdateOperationStatus(ctx, operationID, dto.StatusProcessing)
+	if err != nil {
+		s.logger.Error("Failed to update operation status", zap.Error(err))
+		return operationID, err
+	}
+
+	go func() {
+		goCtx := context.Background()
+		parseReq := dto.ParseURLRequest{URL: pageURL, Segmentation: req.Segmentation, Devices: req.Devices}
+
+		rendered := 0
+		for _, device := range devices {
+			page, err := s.renderPage(pageURL, device, replay)
 			if err != nil {
-				s.logger.Error("Failed to load templates:", zap.Error(err))
+				s.logger.Error("Failed to render uploaded page", zap.Error(err), zap.String("device", string(device.Profile)))
+				continue
 			}
 
-			blocks, err = s.html5Service.ParseAndClassifyPage(html, templates)
+			s.processPage(goCtx, operationID, device.Profile, page, parseReq, rendered == 0)
+			page.close()
+			s.saveSnapshot(goCtx, operationID, device.Profile, page)
+			rendered++
+		}
+
+		status := dto.StatusCompleted
+		if rendered == 0 {
+			status = dto.StatusError
+		}
+
+		if err := s.repo.UpdateOperationStatus(goCtx, operationID, status); err != nil {
+			s.logger.Error("Failed to update operation status", zap.Error(err))
+		}
+	}()
+
+	return operationID, nil
+}
+
+// ReparseOperation повторно извлекает блоки из сохраненных снимков HTML операции текущей версией парсера.
+// Результат сохраняется в новую операцию, к сайту запросы не выполняются.
+func (s *parserService) ReparseOperation(ctx context.Context, operationID uuid.UUID, req dto.ReparseRequest) (uuid.UUID, error) {
+	source, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	snapshots, err := s.snapshots.GetSnapshotsByOperationID(ctx, operationID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(snapshots) == 0 {
+		return uuid.Nil, ErrSnapshotNotFound
+	}
+
+	// Создаем операцию для нового набора результатов
+	reparsedID, err := s.repo.CreateOperation(ctx, source.URL)
+	if err != nil {
+		s.logger.Error("Failed to create operation", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	origin := dto.OperationOrigin{ParserVersion: ParserVersion, SourceType: source.SourceType, ReparsedFrom: &operationID}
+	if err := s.repo.UpdateOperationOrigin(ctx, reparsedID, origin); err != nil {
+		s.logger.Error("Failed to update operation origin", zap.Error(err))
+	}
+
+	err = s.repo.UpdateOperationStatus(ctx, reparsedID, dto.StatusProcessing)
+	if err != nil {
+		s.logger.Error("Failed to update operation status", zap.Error(err))
+		return reparsedID, err
+	}
+
+	go func() {
+		goCtx := context.Background()
+		parseReq := dto.ParseURLRequest{URL: source.URL, Segmentation: req.Segmentation}
+
+		processed := 0
+		for _, snapshot := range snapshots {
+			page, err := s.loadSnapshotPage(goCtx, operationID, snapshot)
 			if err != nil {
-				s.logger.Error("Failed to parse HTML5 content", zap.Error(err))
+				s.logger.Error("Failed to load page snapshot", zap.Error(err), zap.String("device", string(snapshot.Device)))
+				continue
 			}
 
-			// Сохраняем найденные блоки в БД
-			for _, block := range blocks {
-				if block != nil {
-					block.OperationID = operationID
+			s.processPage(goCtx, reparsedID, snapshot.Device, page, parseReq, processed == 0)
 
-					err = s.repo.SaveBlock(goCtx, block)
-					if err != nil {
-						s.logger.Error("Failed to save block", zap.Error(err), zap.Any("block", block))
-					}
-				}
+			// Новая операция ссылается на тот же снимок и может быть перепарсена повторно
+			snapshot.OperationID = reparsedID
+			if err := s.snapshots.SaveSnapshot(goCtx, &snapshot); err != nil {
+				s.logger.Error("Failed to save page snapshot", zap.Error(err))
 			}
+			processed++
 		}
 
-		// Сохраняем найденные блоки в БД
-		if headerBlock != nil {
-			headerBlock.OperationID = operationID
-			err = s.repo.SaveBlock(goCtx, headerBlock)
-			if err != nil {
-				s.logger.Error("Failed to save header block", zap.Error(err))
+		if processed > 0 {
+			if err := s.archives.AttachArchives(goCtx, operationID, reparsedID); err != nil {
+				s.logger.Error("Failed to attach source archives", zap.Error(err))
 			}
 		}
 
-		if footerBlock != nil {
-			footerBlock.OperationID = operationID
-			err = s.repo.SaveBlock(goCtx, footerBlock)
-			if err != nil {
-				s.logger.Error("Failed to save footer block", zap.Error(err))
-			}
+		status := dto.StatusCompleted
+		if processed == 0 {
+			status = dto.StatusError
 		}
 
-		// Обновляем статус операции
-		err = s.repo.UpdateOperationStatus(goCtx, operationID, dto.StatusCompleted)
-		if err != nil {
+		if err := s.repo.UpdateOperationStatus(goCtx, reparsedID, status); err != nil {
 			s.logger.Error("Failed to update operation status", zap.Error(err))
 		}
 	}()
 
-	return operationID, nil
+	return reparsedID, nil
+}
+
+// loadSnapshotPage восстанавливает отрендеренную страницу из снимка HTML и скриншота исходной операции
+func (s *parserService) loadSnapshotPage(ctx context.Context, operationID uuid.UUID, snapshot dto.Snapshot) (*renderedPage, error) {
+	html, err := loadSnapshotHTML(ctx, s.storage, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &renderedPage{html: html, url: snapshot.URL}
+
+	// Таблицы стилей переходят в новую операцию вместе со снимком
+	if styles, err := s.readBlob(ctx, pageStylesKey(operationID, snapshot.Device)); err == nil {
+		page.styles = string(styles)
+	}
+
+	// Без скриншота блоки сохраняются без нарезки
+	screenshot, err := s.readBlob(ctx, pageScreenshotKey(operationID, snapshot.Device))
+	if err != nil {
+		s.logger.Warn("Failed to read page screenshot of snapshot", zap.Error(err), zap.String("device", string(snapshot.Device)))
+	} else {
+		page.screenshot = screenshot
+	}
+
+	return page, nil
+}
+
+// renderedPage результат рендеринга страницы в браузере
+type renderedPage struct {
+	html       string
+	url        string
+	styles     string
+	screenshot []byte
+	mhtml      []byte
+	warc       []byte
+
+	// document запрос документа страницы и ответ сервера на него
+	document *networkExchange
+
+	// tab вкладка, в которой страница осталась открытой для скриншотов блоков; nil у снимков
+	tab *liveTab
+}
+
+// close закрывает вкладку браузера страницы, если она еще открыта
+func (p *renderedPage) close() {
+	if p.tab != nil {
+		p.tab.close()
+		p.tab = nil
+	}
+}
+
+// resolveArchiveRequest подставляет в запрос URL исходной операции и профили устройств, для которых есть WARC-архив
+func (s *parserService) resolveArchiveRequest(ctx context.Context, req *dto.ParseURLRequest) error {
+	source, err := s.repo.GetOperationByID(ctx, *req.ArchiveOperationID)
+	if err != nil {
+		return err
+	}
+
+	archives, err := s.archives.GetArchives(ctx, source.ID)
+	if err != nil {
+		return err
+	}
+
+	archived := make(map[dto.DeviceProfile]bool)
+	var archivedDevices []dto.DeviceProfile
+	for _, archive := range archives {
+		if archive.Format == dto.ArchiveFormatWARC && !archived[archive.Device] {
+			archived[archive.Device] = true
+			archivedDevices = append(archivedDevices, archive.Device)
+		}
+	}
+
+	if len(req.Devices) == 0 {
+		req.Devices = archivedDevices
+	}
+	if len(req.Devices) == 0 {
+		return ErrArchiveNotFound
+	}
+	for _, device := range req.Devices {
+		if !archived[device] {
+			return fmt.Errorf("%w: no warc for device %s", ErrArchiveNotFound, device)
+		}
+	}
+
+	req.URL = source.URL
+
+	return nil
+}
+
+// loadReplay читает WARC-архив исходной операции для профиля устройства
+func (s *parserService) loadReplay(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile) (*replayArchive, error) {
+	_, reader, err := s.archives.OpenArchive(ctx, operationID, device, dto.ArchiveFormatWARC)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readWARC(reader)
+}
+
+// newBrowserAllocator запускает headless Chrome с настройками сервиса; им же печатаются отчеты PDF
+func newBrowserAllocator(ctx context.Context) (context.Context, context.CancelFunc) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.ExecPath("C:/Program Files/Google/Chrome/Application/chrome.exe"), // путь к Chrome
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+	)
+
+	return chromedp.NewExecAllocator(ctx, opts...)
+}
+
+// renderPage открывает страницу в headless Chrome с эмуляцией устройства
+// и возвращает HTML с разметкой видимости, полный скриншот и архивы страницы.
+// Если задан replay, страница открывается из сохраненных ответов без обращения к сайту, новые архивы не снимаются.
+// Вкладка остается открытой для скриншотов блоков, вызывающий закрывает ее через page.close.
+func (s *parserService) renderPage(url string, device dto.DeviceSettings, replay *replayArchive) (*renderedPage, error) {
+	allocCtx, cancelAlloc := newBrowserAllocator(context.Background())
+	tabCtx, cancelTab := chromedp.NewContext(allocCtx)
+	closeTab := func() {
+		cancelTab()
+		cancelAlloc()
+	}
+
+	// Браузер запускается первым Run и живет столько же, сколько контекст этого вызова.
+	// Поэтому вкладку создаем без таймаута: она нужна и после загрузки, для скриншотов блоков.
+	if err := chromedp.Run(tabCtx); err != nil {
+		closeTab()
+		return nil, err
+	}
+
+	// Общий таймаут на загрузку и разметку страницы
+	ctx, cancel := context.WithTimeout(tabCtx, 30*time.Second)
+	defer cancel()
+
+	page := &renderedPage{}
+
+	// Эмуляция устройства и источник ответов: архив или живой сайт с записью запросов.
+	// Слушатели привязаны к вкладке: подмена ответов нужна и при скриншотах блоков.
+	tasks := chromedp.Tasks{emulateDevice(device)}
+
+	recorder := newNetworkRecorder()
+	recorder.listen(tabCtx)
+	styles := newStyleCollector()
+	styles.listen(tabCtx)
+	tasks = append(tasks, network.Enable(), styles.enable())
+
+	if replay != nil {
+		replay.serve(tabCtx)
+		tasks = append(tasks, interceptRequests())
+	}
+
+	tasks = append(tasks,
+		chromedp.Navigate(url),
+		chromedp.Sleep(2*time.Second), // дать JS отработать
+	)
+
+	// Архивы снимаются до разметки, чтобы сохранить страницу в исходном виде
+	if replay == nil {
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			if err := captureMHTML(&page.mhtml).Do(ctx); err != nil {
+				s.logger.Warn("Failed to capture MHTML archive", zap.Error(err))
+			}
+			return recorder.captureBodies().Do(ctx)
+		}))
+	}
+
+	// Таблицы стилей страницы для автономного экспорта блоков
+	tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+		if err := styles.capture(&page.styles).Do(ctx); err != nil {
+			s.logger.Warn("Failed to capture page styles", zap.Error(err))
+		}
+		return nil
+	}))
+
+	// Разметка видимости элементов, извлечение outer HTML и скриншот страницы
+	tasks = append(tasks,
+		annotateVisibility(),
+		annotateStyles(),
+		chromedp.Location(&page.url),
+		chromedp.OuterHTML("html", &page.html),
+		chromedp.FullScreenshot(&page.screenshot, screenshotQuality),
+	)
+
+	if err := chromedp.Run(ctx, tasks); err != nil {
+		closeTab()
+		return nil, err
+	}
+
+	page.document = recorder.documentExchange()
+	page.tab = &liveTab{ctx: tabCtx, close: closeTab}
+
+	if replay == nil {
+		warc, err := recorder.warc(url)
+		if err != nil {
+			s.logger.Warn("Failed to build WARC archive", zap.Error(err))
+		}
+		page.warc = warc
+	}
+
+	return page, nil
+}
+
+// extractBlocks определяет платформу и выделяет блоки страницы выбранным способом сегментации
+func (s *parserService) extractBlocks(ctx context.Context, html string, req dto.ParseURLRequest) []*dto.Block {
+	// Определяем платформу сайта
+	platform := s.DetectPlatform(html)
+
+	// Парсерам платформ передаем только видимую разметку
+	if visibleHTML, err := stripHiddenMarkup(html); err != nil {
+		s.logger.Error("Failed to strip hidden markup", zap.Error(err))
+	} else {
+		html = visibleHTML
+	}
+
+	var blocks []*dto.Block
+
+	switch req.Segmentation {
+	case dto.SegmentationLayout:
+		var err error
+		blocks, err = segmentLayoutBands(html, platform)
+		if err != nil {
+			s.logger.Error("Failed to segment page by layout", zap.Error(err))
+		}
+	default:
+		blocks = s.parsePlatformBlocks(ctx, html, platform)
+	}
+
+	// Классифицируем контентные блоки
+	s.classifyContentBlocks(blocks)
+
+	return blocks
+}
+
+// parsePlatformBlocks парсит шапку, подвал и контентные секции парсером платформы
+func (s *parserService) parsePlatformBlocks(ctx context.Context, html string, platform dto.Platform) []*dto.Block {
+	var headerBlock, footerBlock *dto.Block
+	var blocks []*dto.Block
+	var err error
+
+	// Парсим шапку и подвал в зависимости от платформы
+	switch platform {
+	case dto.PlatformWordPress:
+		headerBlock, err = s.wordpressService.ParseHeader(html)
+		if err != nil {
+			s.logger.Error("Failed to parse WordPress header", zap.Error(err))
+		}
+
+		footerBlock, err = s.wordpressService.ParseFooter(html)
+		if err != nil {
+			s.logger.Error("Failed to parse WordPress footer", zap.Error(err))
+		}
+	case dto.PlatformTilda:
+		headerBlock, err = s.tildaService.ParseHeader(html)
+		if err != nil {
+			s.logger.Error("Failed to parse Tilda header", zap.Error(err))
+		}
+
+		footerBlock, err = s.tildaService.ParseFooter(html)
+		if err != nil {
+			s.logger.Error("Failed to parse Tilda footer", zap.Error(err))
+		}
+	case dto.PlatformBitrix:
+		headerBlock, err = s.bitrixService.ParseHeader(html)
+		if err != nil {
+			s.logger.Error("Failed to parse Bitrix header", zap.Error(err))
+		}
+
+		footerBlock, err = s.bitrixService.ParseFooter(html)
+		if err != nil {
+			s.logger.Error("Failed to parse Bitrix footer", zap.Error(err))
+		}
+	case dto.PlatformHTML5:
+		templates, err := s.repo.GetAllTemplates(platform)
+		if err != nil {
+			s.logger.Error("Failed to load templates:", zap.Error(err))
+		}
+
+		model, err := s.classifierRepo.GetActiveModel(ctx)
+		if err != nil {
+			s.logger.Error("Failed to load classifier model", zap.Error(err))
+		}
+
+		blocks, err = s.html5Service.ParseAndClassifyPage(html, templates, model)
+		if err != nil {
+			s.logger.Error("Failed to parse HTML5 content", zap.Error(err))
+		}
+
+		return blocks
+	}
+
+	// Для остальных платформ делим страницу на контентные секции
+	blocks = append(blocks, headerBlock)
+	blocks = append(blocks, s.parseContentSections(html, platform)...)
+	blocks = append(blocks, footerBlock)
+
+	return blocks
+}
+
+// saveDesignTokens собирает токены дизайна всей страницы и сохраняет их в операции
+func (s *parserService) saveDesignTokens(ctx context.Context, operationID uuid.UUID, html string) {
+	tokens := extractDesignTokens(html)
+	if tokens == nil {
+		return
+	}
+
+	if err := s.repo.UpdateOperationDesignTokens(ctx, operationID, tokens); err != nil {
+		s.logger.Error("Failed to save design tokens", zap.Error(err))
+	}
+}
+
+// savePageScreenshot сохраняет полный скриншот страницы.
+// Если primary, скриншот становится скриншотом операции.
+func (s *parserService) savePageScreenshot(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage, primary bool) {
+	if len(page.screenshot) == 0 {
+		return
+	}
+
+	key := pageScreenshotKey(operationID, device)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(page.screenshot)); err != nil {
+		s.logger.Error("Failed to store page screenshot", zap.Error(err))
+		return
+	}
+
+	if primary {
+		if err := s.repo.UpdateOperationScreenshot(ctx, operationID, key); err != nil {
+			s.logger.Error("Failed to save page screenshot key", zap.Error(err))
+		}
+	}
+}
+
+// blockShooter возвращает способ снять скриншоты блоков страницы: в открытой вкладке браузера,
+// а у снимков без браузера вырезкой из сохраненного скриншота страницы. nil, если снять нечем.
+func (s *parserService) blockShooter(page *renderedPage) blockShooter {
+	if page.tab != nil {
+		return page.tab
+	}
+	if len(page.screenshot) == 0 {
+		return nil
+	}
+
+	screenshot, err := newPageScreenshot(page.screenshot, page.html)
+	if err != nil {
+		s.logger.Error("Failed to prepare page screenshot", zap.Error(err))
+		return nil
+	}
+
+	return screenshot
+}
+
+// processPage извлекает блоки страницы, отрендеренной под профилем устройства, и сохраняет их в операции.
+// Для основного профиля также сохраняются токены дизайна и скриншот операции.
+func (s *parserService) processPage(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage, req dto.ParseURLRequest, primary bool) {
+	blocks := s.extractBlocks(ctx, page.html, req)
+	for _, block := range blocks {
+		if block != nil {
+			block.Device = device
+		}
+	}
+
+	if primary {
+		s.saveDesignTokens(ctx, operationID, page.html)
+	}
+
+	s.savePageStyles(ctx, operationID, device, page)
+	s.savePageScreenshot(ctx, operationID, device, page, primary)
+	s.saveBlocks(ctx, operationID, blocks, s.blockShooter(page), pageBaseURL(firstNonEmpty(page.url, req.URL), page.html))
+}
+
+// savePageStyles сохраняет таблицы стилей страницы, из которых собираются автономные блоки
+func (s *parserService) savePageStyles(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage) {
+	if page.styles == "" {
+		return
+	}
+
+	if err := s.storage.Put(ctx, pageStylesKey(operationID, device), strings.NewReader(page.styles)); err != nil {
+		s.logger.Error("Failed to store page styles", zap.Error(err))
+	}
+}
+
+// saveSnapshot сохраняет сжатый отрендеренный HTML и ответ сервера для повторного парсинга без обращения к сайту
+func (s *parserService) saveSnapshot(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage) {
+	snapshot, err := storeSnapshot(ctx, s.storage, operationID, device, page)
+	if err != nil {
+		s.logger.Error("Failed to store page snapshot", zap.Error(err))
+		return
+	}
+
+	if err := s.snapshots.SaveSnapshot(ctx, snapshot); err != nil {
+		s.logger.Error("Failed to save page snapshot", zap.Error(err))
+	}
+}
+
+// saveArchives сохраняет MHTML и WARC архивы страницы, снятые под профилем устройства
+func (s *parserService) saveArchives(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage) {
+	for format, data := range map[dto.ArchiveFormat][]byte{
+		dto.ArchiveFormatMHTML: page.mhtml,
+		dto.ArchiveFormatWARC:  page.warc,
+	} {
+		if len(data) == 0 {
+			continue
+		}
+
+		if _, err := s.archives.SaveArchive(ctx, operationID, device, format, data); err != nil {
+			s.logger.Error("Failed to save page archive", zap.Error(err), zap.String("format", string(format)))
+		}
+	}
+}
+
+// saveBlocks сохраняет найденные блоки операции в БД вместе со скриншотами блоков.
+// HTML блока проходит очистку, исходная разметка сохраняется рядом.
+func (s *parserService) saveBlocks(ctx context.Context, operationID uuid.UUID, blocks []*dto.Block, shooter blockShooter, base *url.URL) {
+	for _, block := range blocks {
+		if block == nil {
+			continue
+		}
+
+		block.OperationID = operationID
+
+		// Корневой элемент снимается по текущим координатам; у полос раскладки границы заданы сегментацией
+		target := blockTarget{bounds: block.Bounds}
+		if block.Bounds == nil {
+			block.Bounds, target.id = blockBounds(block.HTML)
+			target.bounds = block.Bounds
+		}
+		if block.DesignTokens == nil {
+			block.DesignTokens = extractDesignTokens(block.HTML)
+		}
+		block.HTML = stripRenderAttributes(block.HTML)
+		s.postProcessBlock(block, base)
+
+		s.saveBlockScreenshot(ctx, block, target, shooter)
+
+		if err := s.repo.SaveBlock(ctx, block); err != nil {
+			s.logger.Error("Failed to save block", zap.Error(err), zap.String("block_type", string(block.BlockType)))
+		}
+	}
+}
+
+// postProcessBlock очищает HTML блока; если обработка изменила разметку, исходная сохраняется в OriginalHTML
+func (s *parserService) postProcessBlock(block *dto.Block, base *url.URL) {
+	processed, err := s.postProcessor.Process(block.HTML, base)
+	if err != nil {
+		s.logger.Warn("Failed to post-process block", zap.Error(err), zap.String("block_type", string(block.BlockType)))
+		return
+	}
+
+	if processed != block.HTML {
+		block.OriginalHTML = block.HTML
+		block.HTML = processed
+	}
+}
+
+// saveBlockScreenshot снимает блок и сохраняет скриншот в хранилище.
+// Блок, который снять не удалось, сохраняется без ScreenshotKey с предупреждением в логе.
+func (s *parserService) saveBlockScreenshot(ctx context.Context, block *dto.Block, target blockTarget, shooter blockShooter) {
+	fields := []zap.Field{zap.String("block_type", string(block.BlockType)), zap.String("device", string(block.Device))}
+
+	switch {
+	case shooter == nil:
+		s.logger.Warn("Block saved without screenshot: page has no screenshot", fields...)
+		return
+	case target.bounds == nil:
+		s.logger.Warn("Block saved without screenshot: block has no rendered bounds", fields...)
+		return
+	}
+
+	data, err := shooter.shoot(target)
+	if err != nil {
+		s.logger.Warn("Block saved without screenshot", append(fields, zap.Error(err))...)
+		return
+	}
+
+	key := blockScreenshotKey(block.OperationID)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		s.logger.Error("Failed to store block screenshot", zap.Error(err))
+		return
+	}
+
+	block.ScreenshotKey = key
+}
+
+// parseContentSections делит страницу на контентные секции
+func (s *parserService) parseContentSections(html string, platform dto.Platform) []*dto.Block {
+	sections, err := s.classifier.SplitSections(html)
+	if err != nil {
+		s.logger.Error("Failed to split page into sections", zap.Error(err))
+		return nil
+	}
+
+	blocks := make([]*dto.Block, 0, len(sections))
+	for _, section := range sections {
+		blocks = append(blocks, &dto.Block{
+			BlockType: dto.BlockTypeContent,
+			Platform:  platform,
+			Content:   map[string]interface{}{},
+			HTML:      section,
+		})
+	}
+
+	return blocks
+}
+
+// classifyContentBlocks присваивает контентным блокам семантические метки
+func (s *parserService) classifyContentBlocks(blocks []*dto.Block) {
+	position := 0
+	for _, block := range blocks {
+		if block == nil || block.BlockType != dto.BlockTypeContent {
+			continue
+		}
+
+		if block.Classification == nil {
+			block.Classification = s.classifier.Classify(block.HTML, position)
+		}
+		position++
+	}
 }
 
 // GetOperationResult получает результаты операции по ID
@@ -204,96 +857,215 @@ func (s *parserService) GetOperationResult(ctx context.Context, operationID uuid
 	response := &dto.GetOperationResultResponse{
 		Operation: *operation,
 		Blocks:    blocks,
+		Devices:   groupBlocksByDevice(blocks),
 	}
 
 	return response, nil
 }
 
-// ExportOperation экспортирует результаты операции в файл
-func (s *parserService) ExportOperation(ctx context.Context, operationID uuid.UUID, format string) ([]byte, string, error) {
-	// Получаем результаты операции
-	result, err := s.GetOperationResult(ctx, operationID)
+// groupBlocksByDevice группирует ID блоков по профилям устройств, если операция рендерилась под несколькими
+func groupBlocksByDevice(blocks []dto.Block) map[dto.DeviceProfile][]uuid.UUID {
+	devices := make(map[dto.DeviceProfile][]uuid.UUID)
+	for _, block := range blocks {
+		devices[block.Device] = append(devices[block.Device], block.ID)
+	}
+
+	if len(devices) < 2 {
+		return nil
+	}
+
+	return devices
+}
+
+// GetBlockScreenshot возвращает PNG-скриншот блока операции
+func (s *parserService) GetBlockScreenshot(ctx context.Context, operationID, blockID uuid.UUID) ([]byte, error) {
+	block, err := s.repo.GetBlockByID(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.OperationID != operationID || block.ScreenshotKey == "" {
+		return nil, ErrScreenshotNotFound
+	}
+
+	return s.readBlob(ctx, block.ScreenshotKey)
+}
+
+// GetPageScreenshot возвращает PNG-скриншот всей страницы операции
+func (s *parserService) GetPageScreenshot(ctx context.Context, operationID uuid.UUID) ([]byte, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if operation.ScreenshotKey == "" {
+		return nil, ErrScreenshotNotFound
+	}
+
+	return s.readBlob(ctx, operation.ScreenshotKey)
+}
+
+// ExportDesignTokens экспортирует токены дизайна страницы операции в JSON или CSS
+func (s *parserService) ExportDesignTokens(ctx context.Context, operationID uuid.UUID, format string) ([]byte, string, error) {
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	var filename string
-	var content []byte
+	if operation.DesignTokens == nil {
+		return nil, "", ErrDesignTokensNotFound
+	}
 
-	// В зависимости от формата экспортируем результаты
 	switch format {
-	case "excel":
-		// Создаем Excel-файл
-		f := excelize.NewFile()
-
-		// Устанавливаем заголовки для первого листа (Информация об операции)
-		f.SetCellValue("Sheet1", "A1", "ID")
-		f.SetCellValue("Sheet1", "B1", "URL")
-		f.SetCellValue("Sheet1", "C1", "Status")
-		f.SetCellValue("Sheet1", "D1", "Created At")
-		f.SetCellValue("Sheet1", "E1", "Updated At")
-
-		// Заполняем данные операции
-		f.SetCellValue("Sheet1", "A2", result.Operation.ID.String())
-		f.SetCellValue("Sheet1", "B2", result.Operation.URL)
-		f.SetCellValue("Sheet1", "C2", result.Operation.Status)
-		f.SetCellValue("Sheet1", "D2", result.Operation.CreatedAt.Format(time.RFC3339))
-		f.SetCellValue("Sheet1", "E2", result.Operation.UpdatedAt.Format(time.RFC3339))
-
-		// Создаем новый лист для блоков
-		f.NewSheet("Blocks")
-
-		// Устанавливаем заголовки для листа блоков
-		f.SetCellValue("Blocks", "A1", "ID")
-		f.SetCellValue("Blocks", "B1", "Type")
-		f.SetCellValue("Blocks", "C1", "Platform")
-		f.SetCellValue("Blocks", "D1", "Created At")
-		f.SetCellValue("Blocks", "E1", "HTML")
-
-		// Заполняем данные блоков
-		for i, block := range result.Blocks {
-			row := i + 2
-			f.SetCellValue("Blocks", fmt.Sprintf("A%d", row), block.ID.String())
-			f.SetCellValue("Blocks", fmt.Sprintf("B%d", row), block.BlockType)
-			f.SetCellValue("Blocks", fmt.Sprintf("C%d", row), block.Platform)
-			f.SetCellValue("Blocks", fmt.Sprintf("D%d", row), block.CreatedAt.Format(time.RFC3339))
-			f.SetCellValue("Blocks", fmt.Sprintf("E%d", row), block.HTML)
-		}
-
-		// Сохраняем Excel-файл в буфер
-		buffer, err := f.WriteToBuffer()
+	case "json":
+		content, err := json.MarshalIndent(operation.DesignTokens, "", "  ")
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to write Excel file: %w", err)
+			return nil, "", fmt.Errorf("failed to marshal design tokens: %w", err)
 		}
+		return content, fmt.Sprintf("design_tokens_%s.json", operationID.String()), nil
+	case "css":
+		return []byte(designTokensCSS(operation.DesignTokens)), fmt.Sprintf("design_tokens_%s.css", operationID.String()), nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported format: %s", format)
+}
+
+// readBlob читает файл из хранилища целиком
+func (s *parserService) readBlob(ctx context.Context, key string) ([]byte, error) {
+	reader, err := s.storage.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, repos.ErrBlobNotFound) {
+			return nil, ErrScreenshotNotFound
+		}
+		return nil, err
+	}
+	defer reader.Close()
 
-		content = buffer.Bytes()
-		filename = fmt.Sprintf("operation_%s.xlsx", operationID.String())
+	return io.ReadAll(reader)
+}
 
-	case "text":
-		// Формируем текстовый отчет
-		textContent := fmt.Sprintf("Operation ID: %s\n", result.Operation.ID.String())
-		textContent += fmt.Sprintf("URL: %s\n", result.Operation.URL)
-		textContent += fmt.Sprintf("Status: %s\n", result.Operation.Status)
-		textContent += fmt.Sprintf("Created At: %s\n", result.Operation.CreatedAt.Format(time.RFC3339))
-		textContent += fmt.Sprintf("Updated At: %s\n\n", result.Operation.UpdatedAt.Format(time.RFC3339))
+// PrepareExport готовит экспорт операции в зарегистрированном формате.
+// Блоки читаются из базы построчно во время записи, а не загружаются заранее.
+func (s *parserService) PrepareExport(ctx context.Context, operationID uuid.UUID, format string, options dto.ExportOptions) (OperationExport, error) {
+	exporter, err := s.exporters.Get(ctx, format)
+	if err != nil {
+		return nil, err
+	}
 
-		textContent += "Blocks:\n"
-		for _, block := range result.Blocks {
-			textContent += fmt.Sprintf("  ID: %s\n", block.ID.String())
-			textContent += fmt.Sprintf("  Type: %s\n", block.BlockType)
-			textContent += fmt.Sprintf("  Platform: %s\n", block.Platform)
-			textContent += fmt.Sprintf("  Created At: %s\n", block.CreatedAt.Format(time.RFC3339))
-			textContent += fmt.Sprintf("  HTML: %s\n\n", block.HTML)
+	if options.Team != "" && !reTeamName.MatchString(options.Team) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTeam, options.Team)
+	}
+
+	operation, err := s.repo.GetOperationByID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &operationExport{
+		exporter: exporter,
+		source:   &repoExportSource{operation: operation, options: options, repo: s.repo},
+	}, nil
+}
+
+// PrepareSiteExport готовит экспорт нескольких операций одного сайта.
+// Все операции должны быть с одного домена, а формат - поддерживать SiteExporter.
+func (s *parserService) PrepareSiteExport(ctx context.Context, operationIDs []uuid.UUID, format string, options dto.ExportOptions) (OperationExport, error) {
+	exporter, err := s.exporters.Get(ctx, format)
+	if err != nil {
+		return nil, err
+	}
+	siteExporter, ok := exporter.(SiteExporter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not export sites", ErrUnsupportedFormat, format)
+	}
+
+	if options.Team != "" && !reTeamName.MatchString(options.Team) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidTeam, options.Team)
+	}
+
+	if len(operationIDs) == 0 {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalidSiteExport)
+	}
+
+	sources := make([]ExportSource, 0, len(operationIDs))
+	host := ""
+	for _, operationID := range operationIDs {
+		operation, err := s.repo.GetOperationByID(ctx, operationID)
+		if err != nil {
+			return nil, err
 		}
 
-		content = []byte(textContent)
-		filename = fmt.Sprintf("operation_%s.txt", operationID.String())
+		u, err := url.Parse(operation.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid URL of operation %s", ErrInvalidSiteExport, operationID)
+		}
+		if host == "" {
+			host = u.Hostname()
+		} else if !strings.EqualFold(host, u.Hostname()) {
+			return nil, fmt.Errorf("%w: operation %s belongs to %s, not %s", ErrInvalidSiteExport, operationID, u.Hostname(), host)
+		}
 
-	default:
-		return nil, "", fmt.Errorf("unsupported format: %s", format)
+		sources = append(sources, &repoExportSource{operation: operation, options: options, repo: s.repo})
+	}
+
+	return &siteExport{exporter: siteExporter, sources: sources}, nil
+}
+
+// ListOperations возвращает операции по фильтру, новые первыми.
+// Без лимита возвращается defaultListOperations операций, лимит больше maxListOperations уменьшается.
+func (s *parserService) ListOperations(ctx context.Context, filter dto.OperationFilter) (*dto.ListOperationsResponse, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListOperations
+	}
+	filter.Limit = min(filter.Limit, maxListOperations)
+
+	operations, err := s.repo.ListOperations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.ListOperationsResponse{
+		Operations: operations,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}, nil
+}
+
+// PrepareBatchExport готовит экспорт операций по фильтру списка.
+// Без лимита выгружаются все подходящие операции, если их не больше maxBatchExportOperations.
+func (s *parserService) PrepareBatchExport(ctx context.Context, filter dto.OperationFilter, format string) (OperationExport, error) {
+	exporter, err := s.exporters.Get(ctx, format)
+	if err != nil {
+		return nil, err
+	}
+	batchExporter, ok := exporter.(BatchExporter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s does not export operation sets", ErrUnsupportedFormat, format)
+	}
+
+	if filter.Limit > maxBatchExportOperations {
+		return nil, fmt.Errorf("%w: limit is %d", ErrBatchTooLarge, maxBatchExportOperations)
+	}
+	requested := filter.Limit
+	if requested <= 0 {
+		// Лишняя операция показывает, что под фильтр попало больше допустимого
+		filter.Limit = maxBatchExportOperations + 1
+	}
+
+	operations, err := s.repo.ListOperations(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if requested <= 0 && len(operations) > maxBatchExportOperations {
+		return nil, fmt.Errorf("%w: more than %d operations match the filter", ErrBatchTooLarge, maxBatchExportOperations)
+	}
+
+	sources := make([]ExportSource, len(operations))
+	for i := range operations {
+		sources[i] = &repoExportSource{operation: &operations[i], repo: s.repo}
 	}
 
-	return content, filename, nil
+	return &batchExport{exporter: batchExporter, sources: sources, created: time.Now()}, nil
 }
 
 // DetectPlatform определяет платформу сайта по HTML
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"scrapper/internal/dto"
+)
+
+func TestGroupBlocksByDevice(t *testing.T) {
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+
+	tests := []struct {
+		name   string
+		blocks []dto.Block
+		want   map[dto.DeviceProfile][]uuid.UUID
+	}{
+		{
+			name: "no blocks",
+			want: nil,
+		},
+		{
+			name: "single device is not grouped",
+			blocks: []dto.Block{
+				{ID: ids[0], Device: dto.DeviceDesktop},
+				{ID: ids[1], Device: dto.DeviceDesktop},
+			},
+			want: nil,
+		},
+		{
+			name: "blocks without device are not grouped",
+			blocks: []dto.Block{
+				{ID: ids[0]},
+				{ID: ids[1]},
+			},
+			want: nil,
+		},
+		{
+			name: "ids keep page order per device",
+			blocks: []dto.Block{
+				{ID: ids[0], Device: dto.DeviceDesktop},
+				{ID: ids[1], Device: dto.DeviceDesktop},
+				{ID: ids[2], Device: dto.DeviceMobile},
+				{ID: ids[3], Device: dto.DeviceDesktop},
+				{ID: ids[4], Device: dto.DeviceMobile},
+			},
+			want: map[dto.DeviceProfile][]uuid.UUID{
+				dto.DeviceDesktop: {ids[0], ids[1], ids[3]},
+				dto.DeviceMobile:  {ids[2], ids[4]},
+			},
+		},
+		{
+			name: "three devices",
+			blocks: []dto.Block{
+				{ID: ids[0], Device: dto.DeviceDesktop},
+				{ID: ids[1], Device: dto.DeviceTablet},
+				{ID: ids[2], Device: dto.DeviceMobile},
+			},
+			want: map[dto.DeviceProfile][]uuid.UUID{
+				dto.DeviceDesktop: {ids[0]},
+				dto.DeviceTablet:  {ids[1]},
+				dto.DeviceMobile:  {ids[2]},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupBlocksByDevice(tt.blocks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupBlocksByDevice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/cdproto/cdp"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"go.uber.org/zap"
+
+	"scrapper/config"
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ErrInvalidTeam возвращается, если имя команды нельзя использовать как имя шаблона отчета
+var ErrInvalidTeam = errors.New("invalid team name")
+
+// reTeamName допустимое имя команды: оно же имя файла шаблона в каталоге шаблонов
+var reTeamName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// defaultPDFTemplate шаблон отчета для команд без собственного шаблона
+//
+//go:embed templates/pdf_report.html
+var defaultPDFTemplate string
+
+const (
+	// maxPDFBlocks число контентных блоков с миниатюрами в отчете; остальные только считаются
+	maxPDFBlocks = 200
+
+	// pdfReadChunk размер части PDF, читаемой из потока браузера за один запрос
+	pdfReadChunk = 1 << 20
+)
+
+// waitImagesJS ждет загрузки всех картинок документа перед печатью
+const waitImagesJS = `Promise.all(Array.from(document.images, img => img.complete ? null :
+	new Promise(resolve => { img.onload = img.onerror = resolve; })))`
+
+// pdfExporter печатает аудит сайта в PDF через headless Chrome из HTML-шаблона команды
+type pdfExporter struct {
+	logger    *zap.Logger
+	cfg       config.ReportsConfig
+	storage   repos.BlobStorage
+	assets    repos.AssetRepo
+	snapshots repos.SnapshotRepo
+}
+
+// NewPDFExporter создает экспортер в PDF
+func NewPDFExporter(logger *zap.Logger, cfg *config.Config, storage repos.BlobStorage, assets repos.AssetRepo, snapshots repos.SnapshotRepo) Exporter {
+	return &pdfExporter{
+		logger:    logger,
+		cfg:       cfg.Reports,
+		storage:   storage,
+		assets:    assets,
+		snapshots: snapshots,
+	}
+}
+
+func (e *pdfExporter) Name() string        { return "pdf" }
+func (e *pdfExporter) ContentType() string { return "application/pdf" }
+func (e *pdfExporter) Extension() string   { return "pdf" }
+
+// pdfReport данные шаблона отчета
+type pdfReport struct {
+	BrandName   string
+	Team        string
+	GeneratedAt time.Time
+
+	Operation  *dto.Operation
+	Screenshot template.URL
+	Logo       template.URL
+
+	Platforms    []string
+	Technologies []pdfTechnology
+	Layout       []pdfLayoutBlock
+	Blocks       []pdfBlock
+	TotalBlocks  int
+	Contacts     []pdfContact
+	Socials      []pdfSocial
+
+	SEO       []seoFinding
+	SEODevice dto.DeviceProfile
+}
+
+// pdfTechnology технология сайта в отчете
+type pdfTechnology struct {
+	Name     string
+	Category string
+	Evidence string
+}
+
+// pdfLayoutBlock шапка или подвал с разбивкой на компоненты
+type pdfLayoutBlock struct {
+	Type       dto.BlockType
+	Device     dto.DeviceProfile
+	Platform   dto.Platform
+	Components []pdfComponent
+}
+
+// pdfComponent компонент содержимого шапки или подвала
+type pdfComponent struct {
+	Title string
+	Text  string
+	Link  template.URL
+}
+
+// pdfBlock контентный блок с миниатюрой
+type pdfBlock struct {
+	Index     int
+	Device    dto.DeviceProfile
+	Category  string
+	Score     float64
+	Text      string
+	Thumbnail template.URL
+}
+
+// pdfContact контакт в отчете
+type pdfContact struct {
+	Kind      string
+	Value     string
+	Link      template.URL
+	BlockType dto.BlockType
+}
+
+// pdfSocial профиль в социальной сети в отчете
+type pdfSocial struct {
+	Network string
+	URL     template.URL
+}
+
+// pdfTemplateFuncs функции, доступные шаблонам отчета
+var pdfTemplateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format("02.01.2006 15:04") },
+	"percent":    func(score float64) string { return fmt.Sprintf("%.0f%%", score*100) },
+}
+
+// Export собирает данные отчета за один проход по блокам, рендерит шаблон команды
+// и печатает его в PDF, передавая файл в w по частям по мере чтения из браузера.
+func (e *pdfExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	operation := source.Operation()
+	options := source.Options()
+	base, _ := url.Parse(operation.URL)
+
+	tmpl, err := e.loadTemplate(options.Team)
+	if err != nil {
+		return err
+	}
+
+	report := &pdfReport{
+		BrandName:   e.cfg.BrandName,
+		Team:        options.Team,
+		GeneratedAt: time.Now(),
+		Operation:   operation,
+	}
+
+	findings := newReportFindings()
+	platforms := make(map[string]bool)
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		raw, components, err := blockComponents(block, base)
+		if err != nil {
+			return fmt.Errorf("failed to parse block %s components: %w", block.ID, err)
+		}
+		findings.collect(block, raw, components)
+		platforms[string(block.Platform)] = true
+
+		switch block.BlockType {
+		case dto.BlockTypeHeader, dto.BlockTypeFooter:
+			report.Layout = append(report.Layout, pdfLayoutBlock{
+				Type:       block.BlockType,
+				Device:     block.Device,
+				Platform:   block.Platform,
+				Components: layoutComponents(components),
+			})
+		default:
+			report.TotalBlocks++
+			if len(report.Blocks) < maxPDFBlocks {
+				report.Blocks = append(report.Blocks, e.reportBlock(ctx, block, report.TotalBlocks))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report.Platforms = sortedKeys(platforms)
+	for _, technology := range findings.sortedTechnologies() {
+		report.Technologies = append(report.Technologies, pdfTechnology{
+			Name:     technology.name,
+			Category: technology.category,
+			Evidence: strings.Join(technology.evidence, "; "),
+		})
+	}
+	for _, contact := range findings.contacts {
+		report.Contacts = append(report.Contacts, pdfContact{
+			Kind:      contact.kind,
+			Value:     contact.value,
+			Link:      reportURL(contact.link),
+			BlockType: contact.blockType,
+		})
+	}
+	for _, social := range findings.socials {
+		report.Socials = append(report.Socials, pdfSocial{Network: social.network, URL: reportURL(social.url)})
+	}
+
+	if operation.ScreenshotKey != "" {
+		report.Screenshot = e.imageURI(ctx, operation.ScreenshotKey, "image/png")
+	}
+	report.Logo = e.logoURI(ctx, operation)
+	report.SEO, report.SEODevice = e.auditSnapshot(ctx, operation)
+
+	var document bytes.Buffer
+	if err := tmpl.Execute(&document, report); err != nil {
+		return fmt.Errorf("failed to render report template: %w", err)
+	}
+
+	// Колонтитул страниц печатается Chrome отдельно от документа, если шаблон его определяет
+	var footer bytes.Buffer
+	if tmpl.Lookup("footer") != nil {
+		if err := tmpl.ExecuteTemplate(&footer, "footer", report); err != nil {
+			return fmt.Errorf("failed to render report footer: %w", err)
+		}
+	}
+
+	return e.print(ctx, document.String(), footer.String(), w)
+}
+
+// loadTemplate загружает шаблон команды <TemplateDir>/<team>.html.
+// Если у команды нет своего шаблона, используется встроенный шаблон по умолчанию.
+func (e *pdfExporter) loadTemplate(team string) (*template.Template, error) {
+	text := defaultPDFTemplate
+
+	if team != "" {
+		path := filepath.Join(e.cfg.TemplateDir, team+".html")
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			text = string(data)
+		case errors.Is(err, fs.ErrNotExist):
+			e.logger.Warn("Report template not found, using default", zap.String("team", team), zap.String("path", path))
+		default:
+			return nil, fmt.Errorf("failed to read report template %s: %w", path, err)
+		}
+	}
+
+	tmpl, err := template.New("report").Funcs(pdfTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// reportBlock готовит строку контентного блока с миниатюрой скриншота
+func (e *pdfExporter) reportBlock(ctx context.Context, block *dto.Block, index int) pdfBlock {
+	item := pdfBlock{
+		Index:  index,
+		Device: block.Device,
+		Text:   blockText(block.HTML),
+	}
+	if block.Classification != nil {
+		item.Category = string(block.Classification.Category)
+		item.Score = block.Classification.Score
+	}
+	if block.ScreenshotKey != "" {
+		item.Thumbnail = e.imageURI(ctx, block.ScreenshotKey, "image/png")
+	}
+	return item
+}
+
+// layoutComponents возвращает компоненты шапки или подвала в порядке путей содержимого
+func layoutComponents(components map[string]blockComponent) []pdfComponent {
+	keys := make([]string, 0, len(components))
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]pdfComponent, 0, len(keys))
+	for _, key := range keys {
+		component := components[key]
+		if component.text == "" && component.link == "" {
+			continue
+		}
+		result = append(result, pdfComponent{
+			Title: componentTitle(key),
+			Text:  component.text,
+			Link:  reportURL(component.link),
+		})
+	}
+	return result
+}
+
+// reportURL пропускает в шаблон только ссылки http(s), mailto и tel:
+// html/template заменил бы tel: на заглушку, а остальные схемы в отчете не нужны
+func reportURL(link string) template.URL {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "mailto", "tel":
+		return template.URL(link)
+	}
+	return ""
+}
+
+// imageURI возвращает картинку из хранилища как data URI, чтобы документ печатался без обращений к сети
+func (e *pdfExporter) imageURI(ctx context.Context, key, contentType string) template.URL {
+	reader, err := e.storage.Open(ctx, key)
+	if err != nil {
+		e.logger.Warn("Failed to read image for report", zap.Error(err), zap.String("key", key))
+		return ""
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		e.logger.Warn("Failed to read image for report", zap.Error(err), zap.String("key", key))
+		return ""
+	}
+
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// logoURI возвращает логотип из фирменного набора операции, если он скачан
+func (e *pdfExporter) logoURI(ctx context.Context, operation *dto.Operation) template.URL {
+	assets, err := e.assets.GetAssetsByOperationID(ctx, operation.ID)
+	if err != nil {
+		e.logger.Warn("Failed to get assets for report", zap.Error(err), zap.String("operation_id", operation.ID.String()))
+		return ""
+	}
+
+	for _, asset := range assets {
+		if asset.Kind == dto.AssetKindLogo && strings.HasPrefix(asset.ContentType, "image/") {
+			return e.imageURI(ctx, asset.StorageKey, asset.ContentType)
+		}
+	}
+	return ""
+}
+
+// auditSnapshot проверяет SEO по снимку HTML страницы: десктопному, если он есть, иначе первому снятому
+func (e *pdfExporter) auditSnapshot(ctx context.Context, operation *dto.Operation) ([]seoFinding, dto.DeviceProfile) {
+	snapshots, err := e.snapshots.GetSnapshotsByOperationID(ctx, operation.ID)
+	if err != nil {
+		e.logger.Warn("Failed to get snapshots for report", zap.Error(err), zap.String("operation_id", operation.ID.String()))
+		return nil, ""
+	}
+	if len(snapshots) == 0 {
+		return nil, ""
+	}
+
+	snapshot := snapshots[0]
+	for _, candidate := range snapshots {
+		if candidate.Device == dto.DeviceDesktop {
+			snapshot = candidate
+			break
+		}
+	}
+
+	content, err := loadSnapshotHTML(ctx, e.storage, snapshot)
+	if err != nil {
+		e.logger.Warn("Failed to load snapshot for report", zap.Error(err), zap.String("operation_id", operation.ID.String()))
+		return nil, ""
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		e.logger.Warn("Failed to parse snapshot for report", zap.Error(err), zap.String("operation_id", operation.ID.String()))
+		return nil, ""
+	}
+
+	return auditSEO(doc), snapshot.Device
+}
+
+// print открывает документ в headless Chrome и печатает его через Page.printToPDF.
+// PDF читается из потока браузера частями и сразу пишется в w.
+func (e *pdfExporter) print(ctx context.Context, document, footer string, w io.Writer) error {
+	allocCtx, cancel := newBrowserAllocator(ctx)
+	defer cancel()
+
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
+	defer cancel()
+
+	browserCtx, cancel = context.WithTimeout(browserCtx, e.cfg.Timeout)
+	defer cancel()
+
+	err := chromedp.Run(browserCtx,
+		chromedp.Navigate("about:blank"),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			tree, err := page.GetFrameTree().Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get frame tree: %w", err)
+			}
+			return page.SetDocumentContent(tree.Frame.ID, document).Do(ctx)
+		}),
+		chromedp.Evaluate(waitImagesJS, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+			return p.WithAwaitPromise(true)
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			params := page.PrintToPDF().
+				WithPrintBackground(true).
+				WithPreferCSSPageSize(true).
+				WithTransferMode(page.PrintToPDFTransferModeReturnAsStream)
+			if footer != "" {
+				params = params.
+					WithDisplayHeaderFooter(true).
+					WithHeaderTemplate("<span></span>").
+					WithFooterTemplate(footer)
+			}
+
+			_, stream, err := params.Do(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to print PDF: %w", err)
+			}
+			defer cdpio.Close(stream).Do(ctx)
+
+			return copyBrowserStream(ctx, stream, w)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to print report: %w", err)
+	}
+
+	return nil
+}
+
+// copyBrowserStream читает поток браузера через IO.read и пишет его в w.
+// Read из cdproto не возвращает признак base64, поэтому команда выполняется напрямую.
+func copyBrowserStream(ctx context.Context, stream cdpio.StreamHandle, w io.Writer) error {
+	for {
+		var res cdpio.ReadReturns
+		if err := cdp.Execute(ctx, cdpio.CommandRead, cdpio.Read(stream).WithSize(pdfReadChunk), &res); err != nil {
+			return fmt.Errorf("failed to read PDF stream: %w", err)
+		}
+
+		data := []byte(res.Data)
+		if res.Base64encoded {
+			var err error
+			if data, err = base64.StdEncoding.DecodeString(res.Data); err != nil {
+				return fmt.Errorf("failed to decode PDF stream: %w", err)
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+
+		if res.EOF {
+			return nil
+		}
+	}
+}
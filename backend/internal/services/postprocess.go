@@ -0,0 +1,435 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"scrapper/config"
+)
+
+// Форматирование HTML блока после обработки
+const (
+	blockFormatMinify = "minify"
+	blockFormatPretty = "pretty"
+)
+
+var (
+	// reWhitespace находит последовательности пробельных символов
+	reWhitespace = regexp.MustCompile(`\s+`)
+
+	// reBaseHref находит адрес из тега <base href>
+	reBaseHref = regexp.MustCompile(`(?i)<base\s[^>]*href\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// linkAttributes атрибуты, содержащие одну ссылку
+var linkAttributes = []string{"href", "src", "data-src", "data-lazy-src", "data-original", "poster", "action", "formaction", "background"}
+
+// srcsetAttributes атрибуты со списком кандидатов изображения
+var srcsetAttributes = []string{"srcset", "data-srcset"}
+
+// blockLevelElements элементы, вокруг которых пробелы не влияют на отображение
+var blockLevelElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "details": true,
+	"dialog": true, "dd": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "thead": true, "tbody": true, "tfoot": true,
+	"tr": true, "td": true, "th": true, "caption": true, "colgroup": true, "ul": true, "summary": true,
+	"link": true, "meta": true, "style": true, "script": true, "noscript": true, "template": true,
+}
+
+// preformattedElements элементы, в которых пробелы сохраняются как есть
+var preformattedElements = map[string]bool{"pre": true, "textarea": true, "script": true, "style": true}
+
+// postProcessStep шаг обработки разметки блока
+type postProcessStep func(doc *goquery.Document, base *url.URL)
+
+// blockPostProcessor реализация BlockPostProcessor
+type blockPostProcessor struct {
+	logger   *zap.Logger
+	steps    []postProcessStep
+	format   string
+	trackers []string
+}
+
+// NewBlockPostProcessor создает новый экземпляр BlockPostProcessor.
+// Набор шагов задается конфигурацией: очистка, абсолютные ссылки и форматирование.
+func NewBlockPostProcessor(logger *zap.Logger, cfg *config.Config) BlockPostProcessor {
+	p := &blockPostProcessor{
+		logger: logger,
+		format: cfg.Blocks.Format,
+	}
+
+	for _, domain := range cfg.Blocks.TrackingDomains {
+		p.trackers = append(p.trackers, strings.ToLower(strings.TrimPrefix(domain, ".")))
+	}
+
+	if cfg.Blocks.Sanitize {
+		p.steps = append(p.steps, p.sanitize)
+	}
+	if cfg.Blocks.Absolutize {
+		p.steps = append(p.steps, absolutizeLinks)
+	}
+
+	switch p.format {
+	case "", blockFormatPretty:
+	case blockFormatMinify:
+		p.steps = append(p.steps, minifyMarkup)
+	default:
+		logger.Warn("Unknown block format, formatting is disabled", zap.String("format", p.format))
+		p.format = ""
+	}
+
+	return p
+}
+
+// Process очищает HTML блока и делает ссылки абсолютными относительно base; при base == nil ссылки не меняются
+func (p *blockPostProcessor) Process(content string, base *url.URL) (string, error) {
+	if len(p.steps) == 0 && p.format == "" {
+		return content, nil
+	}
+
+	// Блок разбирается как фрагмент body: ведущие link и style остаются на месте
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse block html: %w", err)
+	}
+
+	root := &html.Node{Type: html.DocumentNode}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+
+	doc := goquery.NewDocumentFromNode(root)
+	for _, step := range p.steps {
+		step(doc, base)
+	}
+
+	var buf strings.Builder
+	for node := root.FirstChild; node != nil; node = node.NextSibling {
+		if p.format == blockFormatPretty {
+			err = renderPretty(&buf, node, 0)
+		} else {
+			err = html.Render(&buf, node)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to render block html: %w", err)
+		}
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// pageBaseURL возвращает адрес, относительно которого разрешаются ссылки страницы, с учетом <base href>.
+// У загруженного HTML без базового URL адреса нет, и ссылки остаются относительными.
+func pageBaseURL(pageURL, page string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil || base.Host == "" || pageURL == uploadBaseURL {
+		return nil
+	}
+
+	if match := reBaseHref.FindStringSubmatch(page); match != nil {
+		if href, err := url.Parse(html.UnescapeString(firstNonEmpty(match[1:]...))); err == nil {
+			base = base.ResolveReference(href)
+		}
+	}
+
+	return base
+}
+
+// sanitize удаляет скрипты, обработчики событий, javascript: ссылки, счетчики и фреймы трекеров
+func (p *blockPostProcessor) sanitize(doc *goquery.Document, base *url.URL) {
+	removeElements(doc.Find("script, noscript"))
+
+	doc.Find("iframe, img, embed, object, link").Each(func(i int, sel *goquery.Selection) {
+		ref := firstNonEmpty(sel.AttrOr("src", ""), sel.AttrOr("href", ""), sel.AttrOr("data", ""))
+		if p.isTracker(ref) {
+			removeElements(sel)
+		}
+	})
+
+	// Пиксели счетчиков размером 1x1 и меньше
+	doc.Find("img").Each(func(i int, sel *goquery.Selection) {
+		width, height := sel.AttrOr("width", ""), sel.AttrOr("height", "")
+		if (width == "0" || width == "1") && (height == "0" || height == "1") {
+			removeElements(sel)
+		}
+	})
+
+	doc.Find("*").Each(func(i int, sel *goquery.Selection) {
+		for _, node := range sel.Nodes {
+			attrs := node.Attr[:0]
+			for _, attr := range node.Attr {
+				name := strings.ToLower(attr.Key)
+				if strings.HasPrefix(name, "on") {
+					continue
+				}
+				if isLinkAttribute(name) && isJavaScriptURL(attr.Val) {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			node.Attr = attrs
+		}
+	})
+}
+
+// removeElements удаляет элементы вместе с отступом перед ними, чтобы не оставлять пустых строк
+func removeElements(sel *goquery.Selection) {
+	for _, node := range sel.Nodes {
+		if node.Parent == nil {
+			continue
+		}
+		if prev := node.PrevSibling; prev != nil && prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "" {
+			node.Parent.RemoveChild(prev)
+		}
+		node.Parent.RemoveChild(node)
+	}
+}
+
+// isTracker проверяет, ведет ли ссылка на домен счетчика или рекламной сети
+func (p *blockPostProcessor) isTracker(ref string) bool {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range p.trackers {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// absolutizeLinks заменяет относительные ссылки в атрибутах, srcset и CSS абсолютными
+func absolutizeLinks(doc *goquery.Document, base *url.URL) {
+	if base == nil {
+		return
+	}
+
+	for _, attr := range linkAttributes {
+		doc.Find("[" + attr + "]").Each(func(i int, sel *goquery.Selection) {
+			sel.SetAttr(attr, absoluteURL(base, sel.AttrOr(attr, "")))
+		})
+	}
+
+	for _, attr := range srcsetAttributes {
+		doc.Find("[" + attr + "]").Each(func(i int, sel *goquery.Selection) {
+			candidates := strings.Split(sel.AttrOr(attr, ""), ",")
+			for j, candidate := range candidates {
+				fields := strings.Fields(candidate)
+				if len(fields) == 0 {
+					continue
+				}
+				fields[0] = absoluteURL(base, fields[0])
+				candidates[j] = strings.Join(fields, " ")
+			}
+			sel.SetAttr(attr, strings.Join(candidates, ", "))
+		})
+	}
+
+	doc.Find("[style]").Each(func(i int, sel *goquery.Selection) {
+		sel.SetAttr("style", absolutizeCSS(sel.AttrOr("style", ""), base))
+	})
+
+	// Текст style меняется в узле напрямую: SetText экранирует кавычки
+	doc.Find("style").Each(func(i int, sel *goquery.Selection) {
+		for _, node := range sel.Nodes {
+			if child := node.FirstChild; child != nil && child.Type == html.TextNode {
+				child.Data = absolutizeCSS(child.Data, base)
+			}
+		}
+	})
+}
+
+// absolutizeCSS заменяет относительные ссылки в url() и @import абсолютными
+func absolutizeCSS(css string, base *url.URL) string {
+	css = reCSSURL.ReplaceAllStringFunc(css, func(match string) string {
+		groups := reCSSURL.FindStringSubmatch(match)
+		ref := firstNonEmpty(groups[1:]...)
+		if resolved := absoluteURL(base, ref); resolved != ref {
+			return fmt.Sprintf("url('%s')", resolved)
+		}
+		return match
+	})
+
+	return reCSSImport.ReplaceAllStringFunc(css, func(match string) string {
+		groups := reCSSImport.FindStringSubmatch(match)
+		ref := firstNonEmpty(groups[1:]...)
+		if resolved := absoluteURL(base, ref); resolved != ref {
+			return fmt.Sprintf("@import '%s'", resolved)
+		}
+		return match
+	})
+}
+
+// absoluteURL возвращает абсолютную ссылку; якоря, data:, mailto: и прочие ссылки со схемой не меняются
+func absoluteURL(base *url.URL, ref string) string {
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return ref
+	}
+
+	parsed, err := url.Parse(trimmed)
+	if err != nil || parsed.IsAbs() {
+		return ref
+	}
+
+	return base.ResolveReference(parsed).String()
+}
+
+// isLinkAttribute проверяет, содержит ли атрибут ссылку
+func isLinkAttribute(name string) bool {
+	for _, attr := range linkAttributes {
+		if name == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// isJavaScriptURL проверяет, является ли ссылка javascript: кодом
+func isJavaScriptURL(ref string) bool {
+	ref = strings.ToLower(reWhitespace.ReplaceAllString(ref, ""))
+	return strings.HasPrefix(ref, "javascript:")
+}
+
+// minifyMarkup удаляет комментарии и лишние пробелы, не меняя отображения блока
+func minifyMarkup(doc *goquery.Document, base *url.URL) {
+	var walk func(node *html.Node, preformatted bool)
+	walk = func(node *html.Node, preformatted bool) {
+		// Комментарии удаляются, соседние текстовые узлы склеиваются
+		for child := node.FirstChild; child != nil; {
+			next := child.NextSibling
+			switch {
+			case child.Type == html.CommentNode:
+				node.RemoveChild(child)
+			case child.Type == html.TextNode && child.PrevSibling != nil && child.PrevSibling.Type == html.TextNode:
+				child.PrevSibling.Data += child.Data
+				node.RemoveChild(child)
+			}
+			child = next
+		}
+
+		for child := node.FirstChild; child != nil; {
+			next := child.NextSibling
+
+			switch child.Type {
+			case html.TextNode:
+				if node.Type == html.ElementNode && node.Data == "style" {
+					child.Data = strings.Join(strings.Fields(child.Data), " ")
+				} else if !preformatted {
+					child.Data = reWhitespace.ReplaceAllString(child.Data, " ")
+					if child.Data == " " && isBlockBoundary(child.PrevSibling, node) && isBlockBoundary(next, node) {
+						node.RemoveChild(child)
+					}
+				}
+			case html.ElementNode:
+				walk(child, preformatted || preformattedElements[child.Data])
+			}
+
+			child = next
+		}
+	}
+
+	for _, node := range doc.Nodes {
+		walk(node, false)
+	}
+}
+
+// isBlockBoundary проверяет, является ли соседний узел границей блока: блочным элементом или краем блочного родителя
+func isBlockBoundary(sibling, parent *html.Node) bool {
+	if sibling == nil {
+		return parent.Type == html.DocumentNode || blockLevelElements[parent.Data]
+	}
+	return sibling.Type == html.ElementNode && blockLevelElements[sibling.Data]
+}
+
+// renderPretty выводит узел с отступами; элементы со строчным содержимым выводятся одной строкой
+func renderPretty(buf *strings.Builder, node *html.Node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	switch node.Type {
+	case html.TextNode:
+		text := strings.TrimSpace(reWhitespace.ReplaceAllString(node.Data, " "))
+		if text == "" {
+			return nil
+		}
+		buf.WriteString(indent)
+		if err := html.Render(buf, &html.Node{Type: html.TextNode, Data: text}); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		return nil
+
+	case html.ElementNode:
+		if !hasBlockChildrenOnly(node) {
+			buf.WriteString(indent)
+			if err := html.Render(buf, node); err != nil {
+				return err
+			}
+			buf.WriteString("\n")
+			return nil
+		}
+
+		// Открывающий тег берется из рендера элемента без детей
+		var tag strings.Builder
+		shallow := &html.Node{Type: node.Type, Data: node.Data, DataAtom: node.DataAtom, Namespace: node.Namespace, Attr: node.Attr}
+		if err := html.Render(&tag, shallow); err != nil {
+			return err
+		}
+		closing := "</" + node.Data + ">"
+		opening := strings.TrimSuffix(tag.String(), closing)
+
+		buf.WriteString(indent + opening + "\n")
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if err := renderPretty(buf, child, depth+1); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(indent + closing + "\n")
+		return nil
+
+	default:
+		buf.WriteString(indent)
+		if err := html.Render(buf, node); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+}
+
+// hasBlockChildrenOnly проверяет, что у элемента есть дети и все они блочные: перенос строк между ними не виден.
+// Предформатированные элементы всегда выводятся как есть.
+func hasBlockChildrenOnly(node *html.Node) bool {
+	if preformattedElements[node.Data] || node.FirstChild == nil {
+		return false
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case html.TextNode:
+			if strings.TrimSpace(child.Data) != "" {
+				return false
+			}
+		case html.ElementNode:
+			if !blockLevelElements[child.Data] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
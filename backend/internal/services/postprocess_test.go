@@ -0,0 +1,251 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+
+	"scrapper/config"
+)
+
+func TestBlockPostProcessorProcess(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post/")
+
+	tests := []struct {
+		name   string
+		blocks config.BlockPostProcessConfig
+		html   string
+		base   *url.URL
+		want   string
+	}{
+		{
+			name:   "event handlers are removed",
+			blocks: config.BlockPostProcessConfig{Sanitize: true},
+			html:   `<div onclick="steal()" OnMouseOver="x()" class="a"><button onfocus="y()">Go</button></div>`,
+			want:   `<div class="a"><button>Go</button></div>`,
+		},
+		{
+			name:   "scripts and noscript are removed",
+			blocks: config.BlockPostProcessConfig{Sanitize: true},
+			html:   `<div><script>alert(1)</script><noscript><img src="/p.gif"></noscript><p>Text</p></div>`,
+			want:   `<div><p>Text</p></div>`,
+		},
+		{
+			name:   "javascript urls are removed",
+			blocks: config.BlockPostProcessConfig{Sanitize: true},
+			html:   `<a href="javascript:alert(1)">a</a><a href=" JavaScript :void(0)">b</a><form action="javascript:x()"></form><a href="/ok">c</a>`,
+			want:   `<a>a</a><a>b</a><form></form><a href="/ok">c</a>`,
+		},
+		{
+			name:   "tracker frames and pixels are removed",
+			blocks: config.BlockPostProcessConfig{Sanitize: true, TrackingDomains: []string{".doubleclick.net", "mc.yandex.ru"}},
+			html: `<div><iframe src="https://ad.doubleclick.net/x"></iframe><img src="https://mc.yandex.ru/watch/1">` +
+				`<img src="/spacer.gif" width="1" height="1"><img src="/logo.png" width="1" height="20">` +
+				`<iframe src="https://www.youtube.com/embed/1"></iframe></div>`,
+			want: `<div><img src="/logo.png" width="1" height="20"/><iframe src="https://www.youtube.com/embed/1"></iframe></div>`,
+		},
+		{
+			name:   "relative links become absolute",
+			blocks: config.BlockPostProcessConfig{Absolutize: true},
+			html:   `<a href="../about">a</a><a href="#top">b</a><a href="mailto:a@b.c">c</a><img src="/img/a.png" data-src="b.png">`,
+			base:   base,
+			want: `<a href="https://example.com/blog/about">a</a><a href="#top">b</a><a href="mailto:a@b.c">c</a>` +
+				`<img src="https://example.com/img/a.png" data-src="https://example.com/blog/post/b.png"/>`,
+		},
+		{
+			name:   "relative srcset candidates become absolute",
+			blocks: config.BlockPostProcessConfig{Absolutize: true},
+			html:   `<img srcset="a.png 1x,/b.png 2x, https://cdn.example.com/c.png 3x">`,
+			base:   base,
+			want:   `<img srcset="https://example.com/blog/post/a.png 1x, https://example.com/b.png 2x, https://cdn.example.com/c.png 3x"/>`,
+		},
+		{
+			name:   "relative css urls become absolute",
+			blocks: config.BlockPostProcessConfig{Absolutize: true},
+			html:   `<style>.a { background: url("bg.png") } @import "/theme.css";</style><div style="background-image: url(/x.png)"></div>`,
+			base:   base,
+			want: `<style>.a { background: url('https://example.com/blog/post/bg.png') } @import 'https://example.com/theme.css';</style>` +
+				`<div style="background-image: url(&#39;https://example.com/x.png&#39;)"></div>`,
+		},
+		{
+			name:   "links stay relative without base",
+			blocks: config.BlockPostProcessConfig{Absolutize: true},
+			html:   `<a href="/about">a</a>`,
+			want:   `<a href="/about">a</a>`,
+		},
+		{
+			name:   "minify keeps whitespace in pre and textarea",
+			blocks: config.BlockPostProcessConfig{Format: blockFormatMinify},
+			html:   "<div>\n  <!-- note -->\n  <p>Some   text\n here</p>\n  <pre>  a\n    b</pre>\n  <p><textarea>x\n\n  y</textarea></p>\n</div>",
+			want:   "<div><p>Some text here</p><pre>  a\n    b</pre><p><textarea>x\n\n  y</textarea></p></div>",
+		},
+		{
+			name:   "minify keeps spaces between inline elements",
+			blocks: config.BlockPostProcessConfig{Format: blockFormatMinify},
+			html:   "<p><b>a</b>\n   <i>b</i></p>",
+			want:   "<p><b>a</b> <i>b</i></p>",
+		},
+		{
+			name:   "no steps returns the input",
+			blocks: config.BlockPostProcessConfig{},
+			html:   `<div onclick="x()">  a  </div>`,
+			want:   `<div onclick="x()">  a  </div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewBlockPostProcessor(zap.NewNop(), &config.Config{Blocks: tt.blocks})
+
+			got, err := processor.Process(tt.html, tt.base)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Process() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		pageURL string
+		page    string
+		want    string
+	}{
+		{
+			name:    "page url",
+			pageURL: "https://example.com/a/b",
+			page:    "<html><head></head></html>",
+			want:    "https://example.com/a/b",
+		},
+		{
+			name:    "relative base href",
+			pageURL: "https://example.com/a/b",
+			page:    `<html><head><BASE target="_self" href="/static/"></head></html>`,
+			want:    "https://example.com/static/",
+		},
+		{
+			name:    "absolute base href with entities",
+			pageURL: "https://example.com/a/b",
+			page:    `<head><base href='https://cdn.example.com/x/?a=1&amp;b=2'></head>`,
+			want:    "https://cdn.example.com/x/?a=1&b=2",
+		},
+		{
+			name:    "uploaded page without base url",
+			pageURL: uploadBaseURL,
+			page:    `<base href="https://example.com/">`,
+			want:    "",
+		},
+		{
+			name:    "url without host",
+			pageURL: "/relative",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if base := pageBaseURL(tt.pageURL, tt.page); base != nil {
+				got = base.String()
+			}
+			if got != tt.want {
+				t.Errorf("pageBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post/")
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "image.png", want: "https://example.com/blog/post/image.png"},
+		{ref: "/image.png", want: "https://example.com/image.png"},
+		{ref: "../image.png", want: "https://example.com/blog/image.png"},
+		{ref: "//cdn.example.com/a.js", want: "https://cdn.example.com/a.js"},
+		{ref: "?page=2", want: "https://example.com/blog/post/?page=2"},
+		{ref: " image.png ", want: "https://example.com/blog/post/image.png"},
+		{ref: "#section", want: "#section"},
+		{ref: "", want: ""},
+		{ref: "https://other.com/x", want: "https://other.com/x"},
+		{ref: "data:image/png;base64,AAAA", want: "data:image/png;base64,AAAA"},
+		{ref: "mailto:a@example.com", want: "mailto:a@example.com"},
+		{ref: "tel:+100", want: "tel:+100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := absoluteURL(base, tt.ref); got != tt.want {
+				t.Errorf("absoluteURL(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifyMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "comments and indentation between blocks are removed",
+			html: "<ul>\n  <!-- items -->\n  <li>a</li>\n  <li>b</li>\n</ul>",
+			want: "<ul><li>a</li><li>b</li></ul>",
+		},
+		{
+			name: "whitespace in pre is preserved",
+			html: "<pre>  line 1\n\n  line 2\n</pre>",
+			want: "<pre>  line 1\n\n  line 2\n</pre>",
+		},
+		{
+			name: "whitespace in nested pre elements is preserved",
+			html: "<pre><code>  a\n  b</code></pre>",
+			want: "<pre><code>  a\n  b</code></pre>",
+		},
+		{
+			name: "whitespace in textarea is preserved, spaces around it stay",
+			html: "<form>\n  <textarea>  keep\n  me  </textarea>\n</form>",
+			want: "<form> <textarea>  keep\n  me  </textarea> </form>",
+		},
+		{
+			name: "style text is collapsed",
+			html: "<div><style>\n  .a {\n    color: red;\n  }\n</style></div>",
+			want: "<div><style>.a { color: red; }</style></div>",
+		},
+		{
+			name: "inline spaces are collapsed to one",
+			html: "<p>a  \n b<span>  c  </span>d</p>",
+			want: "<p>a b<span> c </span>d</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("failed to parse markup: %v", err)
+			}
+
+			minifyMarkup(doc, nil)
+
+			got, err := doc.Find("body").Html()
+			if err != nil {
+				t.Fatalf("failed to render markup: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("minifyMarkup() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+
+	"scrapper/internal/dto"
+)
+
+// ErrScreenshotNotFound возвращается, если для блока или операции нет скриншота
+var ErrScreenshotNotFound = errors.New("screenshot not found")
+
+const (
+	// screenshotQuality качество 100 заставляет chromedp снимать PNG без потерь
+	screenshotQuality = 100
+
+	// blockScreenshotTimeout время на скриншот одного блока в открытой вкладке
+	blockScreenshotTimeout = 10 * time.Second
+)
+
+// liveRectJS возвращает текущие координаты элемента с номером рендера в координатах документа
+const liveRectJS = `(() => {
+	const el = document.querySelector('[` + attrRenderID + `="' + %s + '"]');
+	if (!el) return [];
+	const rect = el.getBoundingClientRect();
+	return [rect.left + window.scrollX, rect.top + window.scrollY, rect.width, rect.height];
+})()`
+
+// blockTarget область блока на странице: номер корневого элемента в DOM и координаты при рендере
+type blockTarget struct {
+	id     string
+	bounds *dto.BlockBounds
+}
+
+// blockShooter снимает скриншот области блока
+type blockShooter interface {
+	shoot(target blockTarget) ([]byte, error)
+}
+
+// liveTab вкладка браузера с отрендеренной страницей, открытая до сохранения блоков.
+// Блоки снимаются в ней по отдельности, а не вырезаются из полного скриншота.
+type liveTab struct {
+	ctx   context.Context
+	close func()
+}
+
+// shoot снимает элемент блока по его текущим координатам; если элемента нет в DOM,
+// снимается область по координатам рендера
+func (t *liveTab) shoot(target blockTarget) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(t.ctx, blockScreenshotTimeout)
+	defer cancel()
+
+	x, y := float64(target.bounds.X), float64(target.bounds.Y)
+	width, height := float64(target.bounds.Width), float64(target.bounds.Height)
+
+	if target.id != "" {
+		var rect []float64
+		if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(liveRectJS, strconv.Quote(target.id)), &rect)); err != nil {
+			return nil, fmt.Errorf("failed to locate block element: %w", err)
+		}
+		if len(rect) == 4 && rect[2] > 0 && rect[3] > 0 {
+			x, y, width, height = rect[0], rect[1], rect[2], rect[3]
+		}
+	}
+
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("block has empty bounds")
+	}
+
+	var data []byte
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		data, err = page.CaptureScreenshot().
+			WithFormat(page.CaptureScreenshotFormatPng).
+			WithClip(&page.Viewport{X: x, Y: y, Width: width, Height: height, Scale: 1}).
+			WithCaptureBeyondViewport(true).
+			Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture block screenshot: %w", err)
+	}
+
+	return data, nil
+}
+
+// pageScreenshotKey возвращает ключ полного скриншота страницы операции для профиля устройства
+func pageScreenshotKey(operationID uuid.UUID, device dto.DeviceProfile) string {
+	return fmt.Sprintf("screenshots/%s/page-%s.png", operationID, device)
+}
+
+// blockScreenshotKey возвращает ключ скриншота блока
+func blockScreenshotKey(operationID uuid.UUID) string {
+	return fmt.Sprintf("screenshots/%s/%s.png", operationID, uuid.New())
+}
+
+// pageScreenshot полный скриншот страницы, из которого вырезаются блоки
+type pageScreenshot struct {
+	img   image.Image
+	scale float64
+}
+
+// newPageScreenshot декодирует скриншот и вычисляет масштаб относительно CSS-пикселей страницы
+func newPageScreenshot(data []byte, html string) (*pageScreenshot, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page screenshot: %w", err)
+	}
+
+	scale := 1.0
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err == nil {
+		if body, ok := renderedRect(doc.Find("body").First()); ok && body.Width > 0 {
+			scale = float64(img.Bounds().Dx()) / float64(body.Width)
+		}
+	}
+
+	return &pageScreenshot{img: img, scale: scale}, nil
+}
+
+// shoot вырезает блок из сохраненного скриншота; используется при перепарсинге снимков без браузера
+func (p *pageScreenshot) shoot(target blockTarget) ([]byte, error) {
+	return p.crop(target.bounds)
+}
+
+// crop вырезает область блока из полного скриншота и кодирует ее в PNG
+func (p *pageScreenshot) crop(bounds *dto.BlockBounds) ([]byte, error) {
+	rect := image.Rect(
+		int(float64(bounds.X)*p.scale),
+		int(float64(bounds.Y)*p.scale),
+		int(float64(bounds.X+bounds.Width)*p.scale),
+		int(float64(bounds.Y+bounds.Height)*p.scale),
+	).Intersect(p.img.Bounds())
+
+	if rect.Empty() {
+		return nil, fmt.Errorf("block is outside of the page screenshot")
+	}
+
+	cropped := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), p.img, rect.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, cropped); err != nil {
+		return nil, fmt.Errorf("failed to encode block screenshot: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Статусы проверок SEO
+const (
+	seoStatusOK      = "ok"
+	seoStatusWarning = "warning"
+	seoStatusError   = "error"
+)
+
+// Рекомендуемые длины заголовка и описания страницы в символах
+const (
+	seoTitleMinLength       = 10
+	seoTitleMaxLength       = 60
+	seoDescriptionMinLength = 50
+	seoDescriptionMaxLength = 160
+)
+
+// seoFinding результат одной проверки SEO страницы
+type seoFinding struct {
+	Check  string
+	Status string
+	Detail string
+}
+
+// auditSEO проверяет мета-теги и разметку страницы, влияющие на поисковую выдачу
+func auditSEO(doc *goquery.Document) []seoFinding {
+	var findings []seoFinding
+	add := func(check, status, detail string) {
+		findings = append(findings, seoFinding{Check: check, Status: status, Detail: detail})
+	}
+
+	title := strings.TrimSpace(doc.Find("head title").First().Text())
+	switch length := utf8.RuneCountInString(title); {
+	case title == "":
+		add("Title", seoStatusError, "Title tag is missing or empty")
+	case length < seoTitleMinLength || length > seoTitleMaxLength:
+		add("Title", seoStatusWarning, fmt.Sprintf("%q: %d characters, recommended %d-%d", title, length, seoTitleMinLength, seoTitleMaxLength))
+	default:
+		add("Title", seoStatusOK, title)
+	}
+
+	description := strings.TrimSpace(doc.Find(`meta[name="description" i]`).First().AttrOr("content", ""))
+	switch length := utf8.RuneCountInString(description); {
+	case description == "":
+		add("Meta description", seoStatusError, "Meta description is missing")
+	case length < seoDescriptionMinLength || length > seoDescriptionMaxLength:
+		add("Meta description", seoStatusWarning, fmt.Sprintf("%d characters, recommended %d-%d", length, seoDescriptionMinLength, seoDescriptionMaxLength))
+	default:
+		add("Meta description", seoStatusOK, description)
+	}
+
+	switch h1 := doc.Find("h1"); h1.Length() {
+	case 0:
+		add("H1", seoStatusError, "No h1 heading")
+	case 1:
+		add("H1", seoStatusOK, selectionText(h1))
+	default:
+		add("H1", seoStatusWarning, fmt.Sprintf("%d h1 headings, one is recommended", h1.Length()))
+	}
+
+	if lang := strings.TrimSpace(doc.Find("html").AttrOr("lang", "")); lang != "" {
+		add("Language", seoStatusOK, lang)
+	} else {
+		add("Language", seoStatusWarning, "The html element has no lang attribute")
+	}
+
+	if canonical := strings.TrimSpace(doc.Find(`link[rel="canonical" i]`).First().AttrOr("href", "")); canonical != "" {
+		add("Canonical", seoStatusOK, canonical)
+	} else {
+		add("Canonical", seoStatusWarning, "No canonical URL")
+	}
+
+	if viewport := doc.Find(`meta[name="viewport" i]`); viewport.Length() > 0 {
+		add("Viewport", seoStatusOK, viewport.First().AttrOr("content", ""))
+	} else {
+		add("Viewport", seoStatusError, "No viewport meta tag, the page is not mobile-friendly")
+	}
+
+	robots := strings.ToLower(doc.Find(`meta[name="robots" i]`).First().AttrOr("content", ""))
+	if strings.Contains(robots, "noindex") {
+		add("Robots", seoStatusError, "Indexing is disabled: "+robots)
+	} else {
+		add("Robots", seoStatusOK, "Indexing is allowed")
+	}
+
+	var missingOG []string
+	for _, property := range []string{"og:title", "og:description", "og:image"} {
+		if doc.Find(fmt.Sprintf(`meta[property=%q]`, property)).Length() == 0 {
+			missingOG = append(missingOG, property)
+		}
+	}
+	if len(missingOG) == 0 {
+		add("Open Graph", seoStatusOK, "og:title, og:description and og:image are set")
+	} else {
+		add("Open Graph", seoStatusWarning, "Missing: "+strings.Join(missingOG, ", "))
+	}
+
+	images := doc.Find("img")
+	// Пустой alt допустим у декоративных изображений, ошибкой считается только отсутствие атрибута
+	withoutAlt := images.FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		_, ok := sel.Attr("alt")
+		return !ok
+	}).Length()
+	switch {
+	case images.Length() == 0:
+		add("Image alt", seoStatusOK, "No images")
+	case withoutAlt == 0:
+		add("Image alt", seoStatusOK, fmt.Sprintf("All %d images have alt text", images.Length()))
+	default:
+		add("Image alt", seoStatusWarning, fmt.Sprintf("%d of %d images have no alt attribute", withoutAlt, images.Length()))
+	}
+
+	return findings
+}
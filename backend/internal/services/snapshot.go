@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// ParserVersion версия правил определения платформы и извлечения блоков.
+// Увеличивается при изменениях парсеров, чтобы отличать результаты повторного парсинга снимков.
+const ParserVersion = "2026.10.1"
+
+// ErrSnapshotNotFound возвращается, если у операции нет сохраненных снимков HTML
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// snapshotKey возвращает ключ сжатого HTML страницы операции для профиля устройства
+func snapshotKey(operationID uuid.UUID, device dto.DeviceProfile) string {
+	return fmt.Sprintf("snapshots/%s/%s.html.gz", operationID, device)
+}
+
+// storeSnapshot сжимает отрендеренный HTML, сохраняет его в хранилище и возвращает запись снимка
+// с ответом сервера на запрос документа
+func storeSnapshot(ctx context.Context, storage repos.BlobStorage, operationID uuid.UUID, device dto.DeviceProfile, page *renderedPage) (*dto.Snapshot, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := io.WriteString(writer, page.html); err != nil {
+		return nil, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	key := snapshotKey(operationID, device)
+	size := int64(compressed.Len())
+	if err := storage.Put(ctx, key, &compressed); err != nil {
+		return nil, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(page.html))
+	snapshot := &dto.Snapshot{
+		OperationID:    operationID,
+		Device:         device,
+		StorageKey:     key,
+		Size:           int64(len(page.html)),
+		CompressedSize: size,
+		SHA256:         hex.EncodeToString(sum[:]),
+		Headers:        map[string]string{},
+		CapturedAt:     time.Now().UTC(),
+	}
+
+	if document := page.document; document != nil {
+		snapshot.URL = document.request.URL
+		snapshot.StatusCode = int(document.response.Status)
+		snapshot.ContentType = document.response.MimeType
+		for name, value := range document.response.Headers {
+			snapshot.Headers[name] = fmt.Sprint(value)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// loadSnapshotHTML читает и распаковывает HTML снимка из хранилища
+func loadSnapshotHTML(ctx context.Context, storage repos.BlobStorage, snapshot dto.Snapshot) (string, error) {
+	reader, err := storage.Open(ctx, snapshot.StorageKey)
+	if err != nil {
+		if errors.Is(err, repos.ErrBlobNotFound) {
+			return "", ErrSnapshotNotFound
+		}
+		return "", err
+	}
+	defer reader.Close()
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	defer gz.Close()
+
+	html, err := io.ReadAll(gz)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+
+	return string(html), nil
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/chromedp/cdproto/fetch"
+
+	"scrapper/internal/dto"
+)
+
+// ErrInvalidContent возвращается, если загруженную страницу не удалось разобрать
+var ErrInvalidContent = errors.New("invalid page content")
+
+// maxDecompressedSize максимальный размер загруженного файла после распаковки gzip
+const maxDecompressedSize = 256 << 20
+
+// uploadBaseURL адрес, по которому открывается загруженный HTML без базового URL
+const uploadBaseURL = "https://upload.invalid/"
+
+// sourceExtensions типы источников по расширению файла
+var sourceExtensions = map[string]dto.SourceType{
+	".html":  dto.SourceTypeHTML,
+	".htm":   dto.SourceTypeHTML,
+	".mhtml": dto.SourceTypeMHTML,
+	".mht":   dto.SourceTypeMHTML,
+	".har":   dto.SourceTypeHAR,
+	".warc":  dto.SourceTypeWARC,
+}
+
+// detectSourceType определяет тип загруженного файла по расширению, иначе по содержимому
+func detectSourceType(filename string, content []byte) dto.SourceType {
+	name := strings.TrimSuffix(strings.ToLower(filename), ".gz")
+	if sourceType, ok := sourceExtensions[path.Ext(name)]; ok {
+		return sourceType
+	}
+
+	head := bytes.TrimSpace(content[:min(len(content), 512)])
+	switch {
+	case bytes.HasPrefix(head, []byte("WARC/")):
+		return dto.SourceTypeWARC
+	case bytes.HasPrefix(head, []byte("{")) && bytes.Contains(head, []byte(`"log"`)):
+		return dto.SourceTypeHAR
+	case bytes.Contains(head, []byte("multipart/related")) || bytes.HasPrefix(head, []byte("From: <Saved by")):
+		return dto.SourceTypeMHTML
+	}
+
+	return dto.SourceTypeHTML
+}
+
+// newContentReplay превращает загруженную страницу в набор ответов, из которых она откроется в браузере.
+// Возвращает также адрес страницы: из самого архива, иначе базовый URL.
+func newContentReplay(req dto.ParseContentRequest) (*replayArchive, string, error) {
+	content, err := gunzipContent(req.Content)
+	if err != nil {
+		return nil, "", err
+	}
+
+	base := ""
+	if req.BaseURL != "" {
+		parsed, err := url.Parse(req.BaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, "", fmt.Errorf("base url must be an absolute http(s) url")
+		}
+		parsed.Fragment = ""
+		base = parsed.String()
+	}
+
+	var archive *replayArchive
+	switch req.SourceType {
+	case dto.SourceTypeHTML:
+		if base == "" {
+			base = uploadBaseURL
+		}
+		archive = newReplayArchive()
+		archive.add(base, &archivedResponse{
+			status:     http.StatusOK,
+			statusText: "OK",
+			headers:    []*fetch.HeaderEntry{{Name: "Content-Type", Value: "text/html; charset=utf-8"}},
+			body:       content,
+		})
+	case dto.SourceTypeMHTML:
+		archive, err = readMHTML(content)
+	case dto.SourceTypeHAR:
+		archive, err = readHAR(content)
+	case dto.SourceTypeWARC:
+		archive, err = readWARC(bytes.NewReader(content))
+	default:
+		return nil, "", fmt.Errorf("unsupported source type: %s", req.SourceType)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	pageURL := archive.document
+	if pageURL == "" {
+		pageURL = base
+	}
+	if pageURL == "" {
+		return nil, "", fmt.Errorf("%s has no html page, base url is required", req.SourceType)
+	}
+
+	return archive, pageURL, nil
+}
+
+// gunzipContent распаковывает файл, сжатый gzip (например, .warc.gz)
+func gunzipContent(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != 0x1f || content[1] != 0x8b {
+		return content, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+	defer reader.Close()
+
+	// Небольшой архив может распаковаться в гигабайты, поэтому читается не больше лимита
+	data, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress content: %w", err)
+	}
+	if len(data) > maxDecompressedSize {
+		return nil, fmt.Errorf("%w: decompressed content exceeds %d bytes", ErrInvalidContent, maxDecompressedSize)
+	}
+
+	return data, nil
+}
+
+// readMHTML читает части MHTML по Content-Location; главной считается страница из Snapshot-Content-Location
+func readMHTML(content []byte) (*replayArchive, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid mhtml: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("invalid mhtml: not a multipart document")
+	}
+
+	archive := newReplayArchive()
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mhtml: %w", err)
+		}
+
+		// quoted-printable multipart.Reader декодирует сам, base64 — нет
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mhtml part: %w", err)
+		}
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			data, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid mhtml part: %w", err)
+			}
+		}
+
+		archive.add(part.Header.Get("Content-Location"), &archivedResponse{
+			status:     http.StatusOK,
+			statusText: "OK",
+			headers:    []*fetch.HeaderEntry{{Name: "Content-Type", Value: part.Header.Get("Content-Type")}},
+			body:       data,
+		})
+	}
+
+	if location := msg.Header.Get("Snapshot-Content-Location"); location != "" {
+		if _, ok := archive.responses[location]; ok {
+			archive.document = location
+		}
+	}
+
+	if len(archive.responses) == 0 {
+		return nil, fmt.Errorf("mhtml has no parts")
+	}
+
+	return archive, nil
+}
+
+// harFile поля HAR, нужные для воспроизведения страницы
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status     int    `json:"status"`
+				StatusText string `json:"statusText"`
+				Headers    []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// readHAR читает ответы на GET-запросы из HAR; запросы без ответа пропускаются
+func readHAR(content []byte) (*replayArchive, error) {
+	var har harFile
+	if err := json.Unmarshal(content, &har); err != nil {
+		return nil, fmt.Errorf("invalid har: %w", err)
+	}
+
+	archive := newReplayArchive()
+	for _, entry := range har.Log.Entries {
+		if entry.Request.Method != http.MethodGet || entry.Response.Status == 0 {
+			continue
+		}
+
+		body := []byte(entry.Response.Content.Text)
+		if entry.Response.Content.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Response.Content.Text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid har content for %s: %w", entry.Request.URL, err)
+			}
+			body = decoded
+		}
+
+		response := &archivedResponse{
+			status:     entry.Response.Status,
+			statusText: entry.Response.StatusText,
+			body:       body,
+		}
+
+		hasContentType := false
+		for _, header := range entry.Response.Headers {
+			name := strings.ToLower(header.Name)
+			if warcSkipHeaders[name] || strings.HasPrefix(name, ":") {
+				continue
+			}
+			hasContentType = hasContentType || name == "content-type"
+			response.headers = append(response.headers, &fetch.HeaderEntry{Name: header.Name, Value: header.Value})
+		}
+		if !hasContentType && entry.Response.Content.MimeType != "" {
+			response.headers = append(response.headers, &fetch.HeaderEntry{Name: "Content-Type", Value: entry.Response.Content.MimeType})
+		}
+
+		if parsed, err := url.Parse(entry.Request.URL); err == nil {
+			parsed.Fragment = ""
+			archive.add(parsed.String(), response)
+		}
+	}
+
+	if len(archive.responses) == 0 {
+		return nil, fmt.Errorf("har has no responses")
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"scrapper/internal/dto"
+)
+
+func TestDetectSourceType(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     dto.SourceType
+	}{
+		{name: "html extension", filename: "page.HTM", content: "WARC/1.1", want: dto.SourceTypeHTML},
+		{name: "gzipped warc extension", filename: "crawl.warc.gz", want: dto.SourceTypeWARC},
+		{name: "mhtml extension", filename: "saved.mht", want: dto.SourceTypeMHTML},
+		{name: "warc content", content: "\r\nWARC/1.0\r\nWARC-Type: warcinfo", want: dto.SourceTypeWARC},
+		{name: "har content", content: `{ "log": { "entries": [] } }`, want: dto.SourceTypeHAR},
+		{name: "mhtml content", content: "From: <Saved by Blink>\r\nSubject: page", want: dto.SourceTypeMHTML},
+		{name: "mhtml content type", content: "MIME-Version: 1.0\r\nContent-Type: multipart/related;", want: dto.SourceTypeMHTML},
+		{name: "anything else is html", filename: "page.txt", content: "<p>x</p>", want: dto.SourceTypeHTML},
+		{name: "empty", want: dto.SourceTypeHTML},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectSourceType(tt.filename, []byte(tt.content)); got != tt.want {
+				t.Errorf("detectSourceType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMHTML(t *testing.T) {
+	mhtml := func(snapshot string, parts ...string) string {
+		return "From: <Saved by Blink>\r\nSnapshot-Content-Location: " + snapshot + "\r\n" +
+			"MIME-Version: 1.0\r\nContent-Type: multipart/related; type=\"text/html\"; boundary=\"b\"\r\n\r\n" +
+			strings.Join(parts, "") + "--b--\r\n"
+	}
+	part := func(location, contentType, encoding, body string) string {
+		return "--b\r\nContent-Type: " + contentType + "\r\nContent-Transfer-Encoding: " + encoding + "\r\n" +
+			"Content-Location: " + location + "\r\n\r\n" + body + "\r\n"
+	}
+
+	tests := []struct {
+		name     string
+		mhtml    string
+		want     map[string]string
+		document string
+		wantErr  string
+	}{
+		{
+			name: "quoted-printable and base64 parts",
+			mhtml: mhtml("https://example.com/",
+				part("https://example.com/", "text/html", "quoted-printable", "<p class=3D\"a\">caf=C3=A9</p>"),
+				part("https://example.com/logo.png", "image/png", "base64", "iVBO\r\nRw=="),
+			),
+			want: map[string]string{
+				"https://example.com/":         `<p class="a">café</p>`,
+				"https://example.com/logo.png": "\x89PNG",
+			},
+			document: "https://example.com/",
+		},
+		{
+			name: "snapshot location outside the parts falls back to the first page",
+			mhtml: mhtml("https://example.com/missing",
+				part("https://example.com/a.css", "text/css", "binary", "body{}"),
+				part("https://example.com/page", "text/html", "binary", "<p>x</p>"),
+			),
+			want: map[string]string{
+				"https://example.com/a.css": "body{}",
+				"https://example.com/page":  "<p>x</p>",
+			},
+			document: "https://example.com/page",
+		},
+		{
+			name:    "not a message",
+			mhtml:   "<html></html>",
+			wantErr: "invalid mhtml",
+		},
+		{
+			name:    "not multipart",
+			mhtml:   "Content-Type: text/html\r\n\r\n<p>x</p>",
+			wantErr: "not a multipart document",
+		},
+		{
+			name:    "broken base64 part",
+			mhtml:   mhtml("https://example.com/", part("https://example.com/", "text/html", "base64", "!!!")),
+			wantErr: "invalid mhtml part",
+		},
+		{
+			name:    "no parts",
+			mhtml:   mhtml("https://example.com/"),
+			wantErr: "mhtml has no parts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := readMHTML([]byte(tt.mhtml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readMHTML() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readMHTML() error = %v", err)
+			}
+
+			assertReplayBodies(t, archive, tt.want)
+			if archive.document != tt.document {
+				t.Errorf("readMHTML() document = %q, want %q", archive.document, tt.document)
+			}
+		})
+	}
+}
+
+func TestReadHAR(t *testing.T) {
+	tests := []struct {
+		name     string
+		har      string
+		want     map[string]string
+		document string
+		wantErr  string
+	}{
+		{
+			name: "get responses with text and base64 content",
+			har: `{"log": {"entries": [
+				{"request": {"method": "GET", "url": "https://example.com/#top"},
+				 "response": {"status": 200, "statusText": "OK", "headers": [{"name": "Content-Type", "value": "text/html"}],
+				              "content": {"mimeType": "text/html", "text": "<p>page</p>"}}},
+				{"request": {"method": "GET", "url": "https://example.com/a.png"},
+				 "response": {"status": 200, "content": {"mimeType": "image/png", "text": "iVBORw==", "encoding": "base64"}}},
+				{"request": {"method": "POST", "url": "https://example.com/api"},
+				 "response": {"status": 200, "content": {"text": "{}"}}},
+				{"request": {"method": "GET", "url": "https://example.com/pending"},
+				 "response": {"status": 0}}
+			]}}`,
+			want: map[string]string{
+				"https://example.com/":      "<p>page</p>",
+				"https://example.com/a.png": "\x89PNG",
+			},
+			document: "https://example.com/",
+		},
+		{
+			name:    "not json",
+			har:     "<html>",
+			wantErr: "invalid har",
+		},
+		{
+			name:    "broken base64 content",
+			har:     `{"log": {"entries": [{"request": {"method": "GET", "url": "https://example.com/"}, "response": {"status": 200, "content": {"text": "!!!", "encoding": "base64"}}}]}}`,
+			wantErr: "invalid har content for https://example.com/",
+		},
+		{
+			name:    "no entries",
+			har:     `{"log": {"entries": []}}`,
+			wantErr: "har has no responses",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := readHAR([]byte(tt.har))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readHAR() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readHAR() error = %v", err)
+			}
+
+			assertReplayBodies(t, archive, tt.want)
+			if archive.document != tt.document {
+				t.Errorf("readHAR() document = %q, want %q", archive.document, tt.document)
+			}
+		})
+	}
+}
+
+func TestNewContentReplay(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte("<p>zipped</p>"))
+	gz.Close()
+
+	tests := []struct {
+		name    string
+		req     dto.ParseContentRequest
+		wantURL string
+		wantErr string
+	}{
+		{
+			name:    "html without base url opens at the upload address",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeHTML, Content: []byte("<p>x</p>")},
+			wantURL: uploadBaseURL,
+		},
+		{
+			name:    "html with base url",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeHTML, Content: []byte("<p>x</p>"), BaseURL: "https://example.com/a#frag"},
+			wantURL: "https://example.com/a",
+		},
+		{
+			name:    "gzipped html",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeHTML, Content: gzipped.Bytes()},
+			wantURL: uploadBaseURL,
+		},
+		{
+			name:    "relative base url",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeHTML, Content: []byte("<p>x</p>"), BaseURL: "/page"},
+			wantErr: "base url must be an absolute http(s) url",
+		},
+		{
+			name:    "archive without html page needs base url",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeHAR, Content: []byte(`{"log": {"entries": [{"request": {"method": "GET", "url": "https://example.com/a.css"}, "response": {"status": 200, "content": {"mimeType": "text/css", "text": "body{}"}}}]}}`)},
+			wantErr: "har has no html page",
+		},
+		{
+			name:    "unsupported source type",
+			req:     dto.ParseContentRequest{SourceType: dto.SourceTypeURL, Content: []byte("x")},
+			wantErr: "unsupported source type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, pageURL, err := newContentReplay(tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newContentReplay() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newContentReplay() error = %v", err)
+			}
+			if pageURL != tt.wantURL {
+				t.Errorf("newContentReplay() url = %q, want %q", pageURL, tt.wantURL)
+			}
+			if archive.responses[pageURL] == nil {
+				t.Errorf("newContentReplay() has no response for the page url")
+			}
+		})
+	}
+}
+
+// assertReplayBodies сравнивает тела ответов архива с ожидаемыми по URL
+func assertReplayBodies(t *testing.T, archive *replayArchive, want map[string]string) {
+	t.Helper()
+
+	if len(archive.responses) != len(want) {
+		t.Errorf("archive has %d responses, want %d", len(archive.responses), len(want))
+	}
+	for target, body := range want {
+		response, ok := archive.responses[target]
+		if !ok {
+			t.Errorf("archive has no response for %s", target)
+			continue
+		}
+		if string(response.body) != body {
+			t.Errorf("response %s body = %q, want %q", target, response.body, body)
+		}
+	}
+}
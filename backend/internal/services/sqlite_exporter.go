@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteExporter выгружает нормализованные таблицы операций в базу SQLite.
+// База собирается во временном файле одной транзакцией и затем копируется в ответ.
+type sqliteExporter struct {
+	logger *zap.Logger
+}
+
+// NewSQLiteExporter создает экспортер в SQLite
+func NewSQLiteExporter(logger *zap.Logger) Exporter {
+	return &sqliteExporter{logger: logger}
+}
+
+func (e *sqliteExporter) Name() string        { return "sqlite" }
+func (e *sqliteExporter) ContentType() string { return "application/vnd.sqlite3" }
+func (e *sqliteExporter) Extension() string   { return "sqlite" }
+
+// Export выгружает одну операцию
+func (e *sqliteExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	return e.ExportBatch(ctx, []ExportSource{source}, w)
+}
+
+// ExportBatch выгружает операции в одну базу
+func (e *sqliteExporter) ExportBatch(ctx context.Context, sources []ExportSource, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "scrapper-sqlite-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			e.logger.Warn("Failed to remove sqlite temp dir", zap.String("dir", dir), zap.Error(err))
+		}
+	}()
+
+	path := filepath.Join(dir, "export.sqlite")
+	if err := e.build(ctx, path, sources); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite export: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to write sqlite export: %w", err)
+	}
+
+	return nil
+}
+
+// build создает таблицы, загружает строки операций и строит индексы
+func (e *sqliteExporter) build(ctx context.Context, path string, sources []ExportSource) error {
+	// Время пишется в формате, который понимают функции даты SQLite и DuckDB
+	db, err := sql.Open("sqlite", "file:"+path+"?_time_format=sqlite")
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	defer db.Close()
+
+	// PRAGMA действуют на соединение, поэтому вся загрузка идет через одно
+	db.SetMaxOpenConns(1)
+
+	// Файл временный: журнал и синхронизация с диском не нужны
+	for _, pragma := range []string{"PRAGMA journal_mode = OFF", "PRAGMA synchronous = OFF"} {
+		if _, err := db.ExecContext(ctx, pragma); err != nil {
+			return fmt.Errorf("failed to configure sqlite database: %w", err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin sqlite transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := make(map[string]*sql.Stmt, len(analyticsTables))
+	for _, table := range analyticsTables {
+		if _, err := tx.ExecContext(ctx, table.schema); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", table.name, err)
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(table.model.values())), ", ")
+		stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", table.name, placeholders))
+		if err != nil {
+			return fmt.Errorf("failed to prepare %s insert: %w", table.name, err)
+		}
+		defer stmt.Close()
+		statements[table.name] = stmt
+	}
+
+	err = collectAnalytics(ctx, sources, func(name string, row analyticsRow) error {
+		if _, err := statements[name].ExecContext(ctx, row.values()...); err != nil {
+			return fmt.Errorf("failed to insert %s row: %w", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, index := range analyticsIndexes {
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sqlite transaction: %w", err)
+	}
+
+	return nil
+}
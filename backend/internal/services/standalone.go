@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// standalonePage таблицы стилей и разметка отрендеренной страницы, из которых собираются автономные блоки
+type standalonePage struct {
+	rules []cssRule
+	base  *url.URL
+
+	// doc снимок страницы; nil, если снимка нет и правила проверяются только по разметке блока
+	doc *html.Node
+}
+
+// ExportStandaloneBlock собирает автономный HTML блока: только применяемые к нему правила CSS страницы,
+// классы с префиксом блока и, если inline, картинки и шрифты в data: URI
+func (s *downloaderService) ExportStandaloneBlock(ctx context.Context, operationID, blockID uuid.UUID, inline bool) ([]byte, string, error) {
+	result, err := s.parserService.GetOperationResult(ctx, operationID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var block *dto.Block
+	for i := range result.Blocks {
+		if result.Blocks[i].ID == blockID {
+			block = &result.Blocks[i]
+			break
+		}
+	}
+	if block == nil {
+		return nil, "", ErrBlockNotFound
+	}
+
+	base, err := url.Parse(result.Operation.URL)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid operation url: %w", err)
+	}
+
+	page, err := s.loadStandalonePage(ctx, operationID, block.Device, base)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var inliner func(ref string) string
+	if inline {
+		inliner, err = s.assetInliner(ctx, operationID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	content, err := page.render(*block, inliner)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return []byte(content), fmt.Sprintf("block_%s.html", blockID), nil
+}
+
+// loadStandalonePage читает таблицы стилей и снимок страницы операции для профиля устройства.
+// Операции без сохраненных стилей или снимка собираются из того, что есть.
+func (s *downloaderService) loadStandalonePage(ctx context.Context, operationID uuid.UUID, device dto.DeviceProfile, base *url.URL) (*standalonePage, error) {
+	page := &standalonePage{base: base}
+
+	reader, err := s.storage.Open(ctx, pageStylesKey(operationID, device))
+	switch {
+	case err == nil:
+		styles, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page styles: %w", err)
+		}
+		page.rules = parseCSSRules(string(styles))
+	case !errors.Is(err, repos.ErrBlobNotFound):
+		return nil, err
+	}
+
+	snapshots, err := s.snapshots.GetSnapshotsByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Device != device {
+			continue
+		}
+
+		content, err := loadSnapshotHTML(ctx, s.storage, snapshot)
+		if err != nil {
+			s.logger.Warn("Failed to load page snapshot for standalone block", zap.Error(err))
+			break
+		}
+
+		doc, err := html.Parse(strings.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse page snapshot: %w", err)
+		}
+		page.doc = doc
+		break
+	}
+
+	return page, nil
+}
+
+// render собирает автономную HTML-страницу блока.
+// Блок помещается в пустые копии своих предков на странице, чтобы сохранить правила с контекстом вроде ".pricing .card".
+func (p *standalonePage) render(block dto.Block, inline func(ref string) string) (string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(block.HTML), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse block html: %w", err)
+	}
+
+	var root *html.Node
+	for _, node := range nodes {
+		if node.Type == html.ElementNode {
+			root = node
+			break
+		}
+	}
+
+	// Элементы, по которым отбираются правила: блок на странице с предками или сам блок
+	var scope, ancestors []*html.Node
+	if located := p.locate(root, block.Bounds); located != nil {
+		for node := located.Parent; node != nil && node.Type == html.ElementNode; node = node.Parent {
+			ancestors = append([]*html.Node{node}, ancestors...)
+		}
+		scope = append(scope, ancestors...)
+		scope = appendElements(scope, located)
+	} else {
+		doc := &html.Node{Type: html.DocumentNode}
+		htmlNode := &html.Node{Type: html.ElementNode, Data: "html", DataAtom: atom.Html}
+		bodyNode := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+		doc.AppendChild(htmlNode)
+		htmlNode.AppendChild(bodyNode)
+		for _, node := range nodes {
+			bodyNode.AppendChild(node)
+		}
+		ancestors = []*html.Node{htmlNode, bodyNode}
+		scope = appendElements(nil, htmlNode)
+	}
+
+	prefix := fmt.Sprintf("b%s-", strings.ReplaceAll(block.ID.String(), "-", "")[:8])
+	styles := newStyleFilter(scope, prefix).filter(p.rules)
+
+	doc, container, err := standaloneSkeleton(block.BlockType, ancestors)
+	if err != nil {
+		return "", err
+	}
+	for _, node := range nodes {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
+		}
+		container.AppendChild(node)
+	}
+
+	// Классы разметки получают тот же префикс, что и селекторы
+	for _, node := range appendElements(nil, doc) {
+		for i, attr := range node.Attr {
+			if attr.Key != "class" {
+				continue
+			}
+			classes := strings.Fields(attr.Val)
+			for j, class := range classes {
+				classes[j] = prefix + class
+			}
+			node.Attr[i].Val = strings.Join(classes, " ")
+		}
+	}
+
+	selection := goquery.NewDocumentFromNode(doc)
+	absolutizeLinks(selection, p.base)
+	if inline != nil {
+		inlineAssets(selection, inline)
+		styles = inlineCSSAssets(styles, inline)
+	}
+
+	styleNode := selection.Find("head > style").Get(0)
+	styleNode.AppendChild(&html.Node{Type: html.TextNode, Data: "\n" + styles})
+
+	var buf strings.Builder
+	if err := html.Render(&buf, doc); err != nil {
+		return "", fmt.Errorf("failed to render standalone block: %w", err)
+	}
+	buf.WriteString("\n")
+
+	return buf.String(), nil
+}
+
+// locate находит корневой элемент блока в снимке страницы по тегу, id, классам и координатам
+func (p *standalonePage) locate(root *html.Node, bounds *dto.BlockBounds) *html.Node {
+	if p.doc == nil || root == nil {
+		return nil
+	}
+
+	id, class := nodeAttr(root, "id"), strings.Join(strings.Fields(nodeAttr(root, "class")), " ")
+
+	var candidates []*html.Node
+	for _, node := range appendElements(nil, p.doc) {
+		if node.Data == root.Data && nodeAttr(node, "id") == id && strings.Join(strings.Fields(nodeAttr(node, "class")), " ") == class {
+			candidates = append(candidates, node)
+		}
+	}
+
+	if len(candidates) <= 1 || bounds == nil {
+		if len(candidates) == 0 {
+			return nil
+		}
+		return candidates[0]
+	}
+
+	for _, node := range candidates {
+		values, ok := parseIntList(nodeAttr(node, attrRenderRect), 4)
+		if ok && values[0] == bounds.X && values[1] == bounds.Y && values[2] == bounds.Width && values[3] == bounds.Height {
+			return node
+		}
+	}
+
+	return candidates[0]
+}
+
+// standaloneSkeleton создает документ с пустыми копиями предков блока: только тег, id и классы.
+// Возвращает документ и элемент, в который кладется блок.
+func standaloneSkeleton(blockType dto.BlockType, ancestors []*html.Node) (*html.Node, *html.Node, error) {
+	doc, err := html.Parse(strings.NewReader(fmt.Sprintf(
+		"<!DOCTYPE html><html><head><meta charset=\"utf-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1\"><title>%s</title><style></style></head><body></body></html>",
+		html.EscapeString(string(blockType)),
+	)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create standalone document: %w", err)
+	}
+
+	htmlNode := doc.LastChild
+	container := htmlNode.LastChild
+
+	for _, ancestor := range ancestors {
+		var target *html.Node
+		switch ancestor.Data {
+		case "html":
+			target = htmlNode
+		case "body":
+			target = container
+		default:
+			target = &html.Node{Type: html.ElementNode, Data: ancestor.Data, DataAtom: ancestor.DataAtom, Namespace: ancestor.Namespace}
+			container.AppendChild(target)
+			container = target
+		}
+
+		for _, key := range []string{"id", "class", "lang", "dir"} {
+			if value := nodeAttr(ancestor, key); value != "" {
+				target.Attr = append(target.Attr, html.Attribute{Key: key, Val: value})
+			}
+		}
+	}
+
+	return doc, container, nil
+}
+
+// inlineAssets заменяет ссылки на картинки и фоны в разметке на data: URI
+func inlineAssets(doc *goquery.Document, inline func(ref string) string) {
+	doc.Find("img[src], video[poster], input[type=image][src]").Each(func(i int, sel *goquery.Selection) {
+		for _, attr := range []string{"src", "poster"} {
+			if value, ok := sel.Attr(attr); ok {
+				sel.SetAttr(attr, inline(value))
+			}
+		}
+	})
+
+	doc.Find("[srcset]").Each(func(i int, sel *goquery.Selection) {
+		candidates := strings.Split(sel.AttrOr("srcset", ""), ",")
+		for j, candidate := range candidates {
+			fields := strings.Fields(candidate)
+			if len(fields) == 0 {
+				continue
+			}
+			fields[0] = inline(fields[0])
+			candidates[j] = strings.Join(fields, " ")
+		}
+		sel.SetAttr("srcset", strings.Join(candidates, ", "))
+	})
+
+	doc.Find("[style]").Each(func(i int, sel *goquery.Selection) {
+		sel.SetAttr("style", inlineCSSAssets(sel.AttrOr("style", ""), inline))
+	})
+
+	// Текст style меняется в узле напрямую: SetText экранирует кавычки
+	doc.Find("body style").Each(func(i int, sel *goquery.Selection) {
+		for _, node := range sel.Nodes {
+			if child := node.FirstChild; child != nil && child.Type == html.TextNode {
+				child.Data = inlineCSSAssets(child.Data, inline)
+			}
+		}
+	})
+}
+
+// inlineCSSAssets заменяет ссылки url() на шрифты и картинки на data: URI
+func inlineCSSAssets(css string, inline func(ref string) string) string {
+	return reCSSURL.ReplaceAllStringFunc(css, func(match string) string {
+		groups := reCSSURL.FindStringSubmatch(match)
+		ref := firstNonEmpty(groups[1:]...)
+		if inlined := inline(ref); inlined != ref {
+			return fmt.Sprintf("url('%s')", inlined)
+		}
+		return match
+	})
+}
+
+// assetInliner возвращает функцию, превращающую абсолютную ссылку на картинку или шрифт в data: URI.
+// Файлы берутся из скачанных файлов операции, остальные скачиваются; ссылки, которые не удалось встроить, не меняются.
+func (s *downloaderService) assetInliner(ctx context.Context, operationID uuid.UUID) (func(ref string) string, error) {
+	assets, err := s.assets.GetAssetsByOperationID(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]dto.Asset, len(assets))
+	for _, asset := range assets {
+		stored[asset.URL] = asset
+	}
+
+	inlined := make(map[string]string)
+	return func(ref string) string {
+		if !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") {
+			return ref
+		}
+		if value, ok := inlined[ref]; ok {
+			return value
+		}
+
+		inlined[ref] = ref
+		data, contentType, err := s.readInlineAsset(ctx, stored, ref)
+		if err != nil {
+			s.logger.Warn("Failed to inline asset", zap.Error(err), zap.String("url", ref))
+			return ref
+		}
+
+		if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "font/") || strings.Contains(contentType, "font") {
+			inlined[ref] = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+		return inlined[ref]
+	}, nil
+}
+
+// readInlineAsset читает файл из хранилища операции или скачивает его
+func (s *downloaderService) readInlineAsset(ctx context.Context, stored map[string]dto.Asset, ref string) ([]byte, string, error) {
+	if asset, ok := stored[ref]; ok {
+		reader, err := s.storage.Open(ctx, asset.StorageKey)
+		if err == nil {
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to read asset: %w", err)
+			}
+			return data, asset.ContentType, nil
+		}
+	}
+
+	fetched, err := fetchAsset(ctx, s.client, ref, s.cfg.MaxAssetSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return fetched.data, fetched.contentType, nil
+}
+
+// appendElements добавляет в список элемент и всех его потомков-элементов в порядке документа
+func appendElements(list []*html.Node, node *html.Node) []*html.Node {
+	if node.Type == html.ElementNode {
+		list = append(list, node)
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		list = appendElements(list, child)
+	}
+	return list
+}
+
+// nodeAttr возвращает значение атрибута узла
+func nodeAttr(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
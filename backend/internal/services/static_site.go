@@ -0,0 +1,455 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// staticSite реализация OperationBundle: статический сайт из страниц операций одного домена.
+// Каждая страница лежит в <путь>/index.html и открывается из файловой системы без сервера.
+type staticSite struct {
+	service *downloaderService
+	host    string
+	pages   []*staticPage
+	home    *staticPage
+
+	// byPath страница по пути URL, по нему ссылки навигации переписываются на локальные файлы
+	byPath map[string]*staticPage
+
+	// header и footer общие шапка и подвал сайта с главной страницы
+	header *staticPartial
+	footer *staticPartial
+
+	// assets файлы всех операций без повторов по URL, assetFiles путь файла в архиве по исходному URL
+	assets     []dto.Asset
+	assetFiles map[string]string
+
+	// missing файлы, которых не оказалось в хранилище при записи архива
+	missing []string
+}
+
+// staticPartial общая часть страниц; ссылки в ней разрешаются относительно страницы, с которой она взята
+type staticPartial struct {
+	html string
+	base *url.URL
+}
+
+// staticPage страница статического сайта, собранная из блоков одной операции
+type staticPage struct {
+	operation dto.Operation
+	base      *url.URL
+	device    dto.DeviceProfile
+	file      string
+	title     string
+	blocks    []string
+
+	// stylesheets скачанные таблицы стилей операции, подключаются, если стили страницы не сохранены
+	stylesheets []string
+}
+
+// PrepareStaticSite собирает статический сайт из операций одного домена; архив пишется потоково через OperationBundle.
+// Шапка и подвал берутся с главной страницы, контентные блоки каждой страницы идут в исходном порядке.
+func (s *downloaderService) PrepareStaticSite(ctx context.Context, operationIDs []uuid.UUID) (OperationBundle, error) {
+	if len(operationIDs) == 0 {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalidSiteExport)
+	}
+
+	site := &staticSite{
+		service:    s,
+		byPath:     make(map[string]*staticPage),
+		assetFiles: make(map[string]string),
+	}
+
+	files := make(map[string]*staticPage)
+	headers := make(map[*staticPage]*staticPartial)
+	footers := make(map[*staticPage]*staticPartial)
+
+	for _, operationID := range operationIDs {
+		result, err := s.parserService.GetOperationResult(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+
+		base, err := url.Parse(result.Operation.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid URL of operation %s", ErrInvalidSiteExport, operationID)
+		}
+		if site.host == "" {
+			site.host = base.Hostname()
+		} else if !strings.EqualFold(site.host, base.Hostname()) {
+			return nil, fmt.Errorf("%w: operation %s belongs to %s, not %s", ErrInvalidSiteExport, operationID, base.Hostname(), site.host)
+		}
+
+		page := &staticPage{
+			operation: result.Operation,
+			base:      base,
+			device:    staticSiteDevice(result.Blocks),
+			file:      staticPageFile(base),
+		}
+		if other, ok := files[page.file]; ok {
+			return nil, fmt.Errorf("%w: operations %s and %s both map to %s", ErrInvalidSiteExport, other.operation.ID, operationID, page.file)
+		}
+		files[page.file] = page
+
+		for _, block := range result.Blocks {
+			if block.Device != page.device {
+				continue
+			}
+			switch block.BlockType {
+			case dto.BlockTypeHeader:
+				if headers[page] == nil {
+					headers[page] = &staticPartial{html: block.HTML, base: base}
+				}
+			case dto.BlockTypeFooter:
+				if footers[page] == nil {
+					footers[page] = &staticPartial{html: block.HTML, base: base}
+				}
+			default:
+				page.blocks = append(page.blocks, block.HTML)
+				if page.title == "" {
+					page.title = firstHeading(block.HTML)
+				}
+			}
+		}
+		if page.title == "" {
+			page.title = pageTitleFromURL(base)
+		}
+
+		assets, err := s.siteAssets(ctx, operationID)
+		if err != nil {
+			return nil, err
+		}
+		for _, asset := range assets {
+			if _, ok := site.assetFiles[asset.URL]; ok {
+				continue
+			}
+			site.assetFiles[asset.URL] = "assets/" + path.Base(asset.StorageKey)
+			site.assets = append(site.assets, asset)
+			if asset.Kind == dto.AssetKindStylesheet {
+				page.stylesheets = append(page.stylesheets, asset.URL)
+			}
+		}
+
+		site.byPath[sitePagePath(base)] = page
+		site.pages = append(site.pages, page)
+	}
+
+	site.home = site.pages[0]
+	for _, page := range site.pages {
+		if len(sitePagePath(page.base)) < len(sitePagePath(site.home.base)) {
+			site.home = page
+		}
+	}
+
+	// Общие шапка и подвал: с главной страницы, а если их там нет - с первой страницы, где они есть
+	site.header, site.footer = headers[site.home], footers[site.home]
+	for _, page := range site.pages {
+		if site.header == nil {
+			site.header = headers[page]
+		}
+		if site.footer == nil {
+			site.footer = footers[page]
+		}
+	}
+
+	return site, nil
+}
+
+// siteAssets возвращает скачанные файлы операции. Если файлы блоков еще не скачивались, они скачиваются здесь:
+// без них страницы сайта ссылались бы на исходный сайт
+func (s *downloaderService) siteAssets(ctx context.Context, operationID uuid.UUID) ([]dto.Asset, error) {
+	links, err := s.assets.GetBlockAssetLinks(ctx, operationID)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) > 0 {
+		return s.assets.GetAssetsByOperationID(ctx, operationID)
+	}
+
+	manifest, err := s.DownloadAssets(ctx, operationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download assets of operation %s: %w", operationID, err)
+	}
+
+	return manifest.Assets, nil
+}
+
+// staticSiteDevice выбирает профиль устройства страницы: десктоп, если он есть, иначе первый встреченный
+func staticSiteDevice(blocks []dto.Block) dto.DeviceProfile {
+	for _, block := range blocks {
+		if block.Device == dto.DeviceDesktop {
+			return dto.DeviceDesktop
+		}
+	}
+	if len(blocks) > 0 {
+		return blocks[0].Device
+	}
+	return dto.DeviceDesktop
+}
+
+// staticPageFile возвращает путь страницы в архиве: index.html для главной и <путь>/index.html для остальных,
+// расширение вроде .html или .php отбрасывается
+func staticPageFile(u *url.URL) string {
+	var segments []string
+	for _, segment := range strings.Split(sitePagePath(u), "/") {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+		segment = strings.TrimSuffix(segment, path.Ext(segment))
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, `\:*?"<>|`) {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return "index.html"
+	}
+	return path.Join(append(segments, "index.html")...)
+}
+
+// Filename возвращает имя файла архива
+func (s *staticSite) Filename() string {
+	return fmt.Sprintf("site_%s.zip", s.host)
+}
+
+// Write пишет архив в w: страницы, их таблицы стилей, партиалы шапки и подвала, скачанные файлы
+// и manifest.json со списком файлов, которых не оказалось в хранилище
+func (s *staticSite) Write(ctx context.Context, w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	for _, page := range s.pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.writePage(ctx, zw, page); err != nil {
+			return err
+		}
+	}
+
+	// Партиалы лежат отдельно для правки; ссылки в них указаны относительно корня сайта
+	partials := []struct {
+		file    string
+		partial *staticPartial
+	}{
+		{"partials/header.html", s.header},
+		{"partials/footer.html", s.footer},
+	}
+	for _, entry := range partials {
+		if entry.partial == nil {
+			continue
+		}
+		content := s.localize(entry.partial.html, entry.partial.base, "") + "\n"
+		if err := writeZipEntry(zw, entry.file, true, strings.NewReader(content)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.writeAssets(ctx, zw); err != nil {
+		return err
+	}
+
+	if err := s.writeManifest(zw); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish zip: %w", err)
+	}
+
+	return nil
+}
+
+// writePage пишет страницу и ее таблицу стилей. Страницы открываются из file://, где партиалы нельзя
+// подгрузить скриптом, поэтому шапка и подвал подставляются в каждую страницу при сборке.
+func (s *staticSite) writePage(ctx context.Context, zw *zip.Writer, page *staticPage) error {
+	prefix := strings.Repeat("../", strings.Count(page.file, "/"))
+
+	var head strings.Builder
+	styles, err := s.pageStyles(ctx, page)
+	if err != nil {
+		return err
+	}
+	if styles != "" {
+		file := "css/" + strings.TrimSuffix(strings.ReplaceAll(page.file, "/", "_"), ".html") + ".css"
+		if err := writeZipEntry(zw, file, true, strings.NewReader(styles)); err != nil {
+			return err
+		}
+		fmt.Fprintf(&head, "<link rel=\"stylesheet\" href=\"%s\">\n", html.EscapeString(prefix+file))
+	} else {
+		for _, stylesheet := range page.stylesheets {
+			fmt.Fprintf(&head, "<link rel=\"stylesheet\" href=\"%s\">\n", html.EscapeString(prefix+s.assetFiles[stylesheet]))
+		}
+	}
+
+	var body strings.Builder
+	if s.header != nil {
+		body.WriteString(s.localize(s.header.html, s.header.base, prefix) + "\n")
+	}
+	for _, block := range page.blocks {
+		body.WriteString(s.localize(block, page.base, prefix) + "\n")
+	}
+	if s.footer != nil {
+		body.WriteString(s.localize(s.footer.html, s.footer.base, prefix) + "\n")
+	}
+
+	content := fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n<title>%s</title>\n%s</head>\n<body>\n%s</body>\n</html>\n",
+		html.EscapeString(page.title), head.String(), body.String())
+
+	return writeZipEntry(zw, page.file, true, strings.NewReader(content))
+}
+
+// pageStyles возвращает сохраненные таблицы стилей страницы со ссылками url() на файлы архива.
+// Таблица лежит в css/, поэтому ссылки на файлы начинаются с ../
+func (s *staticSite) pageStyles(ctx context.Context, page *staticPage) (string, error) {
+	reader, err := s.service.storage.Open(ctx, pageStylesKey(page.operation.ID, page.device))
+	if err != nil {
+		if errors.Is(err, repos.ErrBlobNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer reader.Close()
+
+	styles, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read page styles: %w", err)
+	}
+
+	return reCSSURL.ReplaceAllStringFunc(string(styles), func(match string) string {
+		groups := reCSSURL.FindStringSubmatch(match)
+		if file, ok := s.assetFiles[firstNonEmpty(groups[1:]...)]; ok {
+			return fmt.Sprintf("url('../%s')", file)
+		}
+		return match
+	}), nil
+}
+
+// writeAssets копирует скачанные файлы из хранилища; одинаковые файлы пишутся один раз
+func (s *staticSite) writeAssets(ctx context.Context, zw *zip.Writer) error {
+	written := make(map[string]bool)
+
+	for _, asset := range s.assets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		file := s.assetFiles[asset.URL]
+		if written[file] {
+			continue
+		}
+		written[file] = true
+
+		reader, err := s.service.storage.Open(ctx, asset.StorageKey)
+		if err != nil {
+			// Ответ уже начат: архив дописывается без файла, а его путь попадает в манифест
+			if errors.Is(err, repos.ErrBlobNotFound) {
+				s.service.logger.Warn("Static site asset is missing in storage",
+					zap.String("host", s.host), zap.String("file", file), zap.Error(err))
+				s.missing = append(s.missing, file)
+				continue
+			}
+			return fmt.Errorf("failed to open asset %s: %w", asset.ID, err)
+		}
+
+		err = writeZipEntry(zw, file, isCompressible(asset.ContentType), reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeManifest пишет manifest.json со страницами сайта и пропущенными файлами
+func (s *staticSite) writeManifest(zw *zip.Writer) error {
+	manifest := dto.StaticSiteManifest{
+		Host:        s.host,
+		Pages:       make([]string, 0, len(s.pages)),
+		Missing:     s.missing,
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, page := range s.pages {
+		manifest.Pages = append(manifest.Pages, page.file)
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal static site manifest: %w", err)
+	}
+
+	return writeZipEntry(zw, "manifest.json", true, bytes.NewReader(content))
+}
+
+// localize переписывает ссылки блока на скачанные файлы и выгружаемые страницы локальными путями с префиксом prefix
+func (s *staticSite) localize(content string, base *url.URL, prefix string) string {
+	content = s.rewriteNavigation(content, base, prefix)
+	return rewriteAssetLinks(content, base, func(absURL string) (string, bool) {
+		file, ok := s.assetFiles[absURL]
+		return prefix + file, ok
+	})
+}
+
+// rewriteNavigation заменяет ссылки на выгружаемые страницы сайта путями к их index.html,
+// остальные ссылки на сайт делает абсолютными
+func (s *staticSite) rewriteNavigation(content string, base *url.URL, prefix string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+
+	doc.Find("a[href], area[href]").Each(func(i int, sel *goquery.Selection) {
+		href := strings.TrimSpace(sel.AttrOr("href", ""))
+		if href == "" || strings.HasPrefix(href, "#") || isJavaScriptURL(href) {
+			return
+		}
+
+		target, err := base.Parse(href)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || !strings.EqualFold(target.Hostname(), s.host) {
+			return
+		}
+
+		// Страницы, которых нет в архиве, остаются ссылками на исходный сайт
+		page, ok := s.byPath[sitePagePath(target)]
+		if !ok {
+			sel.SetAttr("href", target.String())
+			return
+		}
+
+		local := prefix + page.file
+		if target.Fragment != "" {
+			local += "#" + target.Fragment
+		}
+		sel.SetAttr("href", local)
+	})
+
+	head, err := doc.Find("head").Html()
+	if err != nil {
+		return content
+	}
+
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return content
+	}
+
+	return head + body
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+const (
+	// templateScanLimit количество последних блоков, на которых проверяется кандидат шаблона
+	templateScanLimit = 1000
+
+	// templateMatchesLimit количество совпавших блоков в ответе
+	templateMatchesLimit = 50
+
+	maxSkeletonSteps = 6
+	maxClassSteps    = 5
+	maxFieldSteps    = 4
+)
+
+// ErrInvalidTemplate возвращается при сохранении шаблона без типа блока или шагов
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// ErrTemplateNotDerived возвращается, если по примеру блока нельзя построить шаблон
+var ErrTemplateNotDerived = errors.New("template cannot be derived from example block")
+
+// skeletonTags структурные теги, из которых строится скелет шаблона
+var skeletonTags = map[string]bool{
+	"section": true, "article": true, "nav": true, "form": true, "input": true, "textarea": true,
+	"select": true, "button": true, "ul": true, "ol": true, "table": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "details": true, "blockquote": true, "figure": true, "picture": true,
+	"video": true, "iframe": true, "img": true, "time": true,
+}
+
+// reUnstableClass отсеивает классы с цифрами и служебными символами (хэши, утилиты сеток)
+var reUnstableClass = regexp.MustCompile(`[0-9:/\[\]]`)
+
+// genericClasses классы разметки, которые встречаются почти в каждом блоке
+var genericClasses = map[string]bool{
+	"container": true, "wrapper": true, "wrap": true, "row": true, "col": true, "inner": true,
+	"clearfix": true, "active": true, "item": true, "flex": true, "grid": true, "hidden": true,
+}
+
+// templateService реализация TemplateService
+type templateService struct {
+	logger *zap.Logger
+	repo   repos.ParserRepo
+}
+
+// NewTemplateService создает новый экземпляр TemplateService
+func NewTemplateService(logger *zap.Logger, repo repos.ParserRepo) TemplateService {
+	return &templateService{
+		logger: logger,
+		repo:   repo,
+	}
+}
+
+// GenerateTemplate строит кандидат шаблона по примеру блока и находит подходящие сохраненные блоки
+func (s *templateService) GenerateTemplate(ctx context.Context, req dto.GenerateTemplateRequest) (*dto.GenerateTemplateResponse, error) {
+	html := req.HTML
+	platform := req.Platform
+
+	if req.BlockID != nil {
+		block, err := s.repo.GetBlockByID(ctx, *req.BlockID)
+		if err != nil {
+			if errors.Is(err, repos.ErrBlockNotFound) {
+				return nil, ErrBlockNotFound
+			}
+			return nil, err
+		}
+		html = block.HTML
+		if platform == "" {
+			platform = block.Platform
+		}
+	}
+
+	if strings.TrimSpace(html) == "" {
+		return nil, fmt.Errorf("%w: example block has no HTML", ErrTemplateNotDerived)
+	}
+
+	if platform == "" {
+		platform = dto.PlatformHTML5
+	}
+
+	steps, features, err := deriveTemplateSteps(html)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateNotDerived, err)
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: no stable structural features found in example block", ErrTemplateNotDerived)
+	}
+
+	template := make(map[string]interface{}, len(steps))
+	for i, step := range steps {
+		template[fmt.Sprintf("step%d", i+1)] = step
+	}
+
+	response := &dto.GenerateTemplateResponse{
+		BlockType: req.BlockType,
+		Platform:  platform,
+		Template:  template,
+		Features:  features,
+		Matches:   []dto.TemplateMatch{},
+	}
+
+	if err := s.previewMatches(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// previewMatches проверяет кандидат шаблона на последних сохраненных блоках платформы
+func (s *templateService) previewMatches(ctx context.Context, response *dto.GenerateTemplateResponse) error {
+	tags, err := json.Marshal(response.Template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	candidate := []dto.BlockTemplate{{BlockType: response.BlockType, HTMLTags: tags}}
+
+	blocks, err := s.repo.GetRecentBlocksByPlatform(ctx, response.Platform, templateScanLimit)
+	if err != nil {
+		s.logger.Error("Failed to load blocks for template preview", zap.Error(err))
+		return err
+	}
+
+	response.Scanned = len(blocks)
+	for _, block := range blocks {
+		if matchBlock(block.HTML, candidate) == nil {
+			continue
+		}
+
+		response.MatchCount++
+		if len(response.Matches) < templateMatchesLimit {
+			response.Matches = append(response.Matches, dto.TemplateMatch{
+				BlockID:        block.ID,
+				OperationID:    block.OperationID,
+				BlockType:      block.BlockType,
+				Classification: block.Classification,
+			})
+		}
+	}
+
+	return nil
+}
+
+// SaveTemplate сохраняет шаблон в block_templates
+func (s *templateService) SaveTemplate(ctx context.Context, req dto.SaveTemplateRequest) error {
+	if req.BlockType == "" {
+		return fmt.Errorf("%w: block type is required", ErrInvalidTemplate)
+	}
+
+	if len(req.Template) == 0 {
+		return fmt.Errorf("%w: template is empty", ErrInvalidTemplate)
+	}
+
+	if req.Platform == "" {
+		req.Platform = dto.PlatformHTML5
+	}
+
+	// Новый шаблон по умолчанию проверяется после уже существующих
+	if _, ok := req.Template["priority"]; !ok {
+		existing, err := s.repo.GetAllTemplates(req.Platform)
+		if err != nil {
+			return err
+		}
+		req.Template["priority"] = len(existing) + 1
+	}
+
+	tags, err := json.Marshal(req.Template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	return s.repo.SaveTemplate(ctx, req.Platform, dto.BlockTemplate{
+		BlockType: req.BlockType,
+		HTMLTags:  tags,
+	})
+}
+
+// deriveTemplateSteps выделяет устойчивые признаки блока: скелет тегов, повторяющиеся классы и поля форм
+func deriveTemplateSteps(html string) ([]string, map[string][]string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var skeleton, fields []string
+	seenTags := make(map[string]bool)
+	seenFields := make(map[string]bool)
+	classCounts := make(map[string]int)
+	var classOrder []string
+
+	doc.Find("body *").Each(func(i int, sel *goquery.Selection) {
+		tag := goquery.NodeName(sel)
+
+		if skeletonTags[tag] && !seenTags[tag] && len(skeleton) < maxSkeletonSteps {
+			seenTags[tag] = true
+			skeleton = append(skeleton, "<"+tag)
+		}
+
+		if class, ok := sel.Attr("class"); ok {
+			for _, name := range strings.Fields(class) {
+				if classCounts[name] == 0 {
+					classOrder = append(classOrder, name)
+				}
+				classCounts[name]++
+			}
+		}
+
+		if tag == "input" || tag == "select" || tag == "textarea" {
+			for _, field := range formFieldSteps(sel) {
+				if !seenFields[field] && len(fields) < maxFieldSteps {
+					seenFields[field] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+	})
+
+	// Повторяющиеся классы: встречаются минимум дважды и не похожи на утилиты
+	var classes []string
+	for _, name := range classOrder {
+		if classCounts[name] >= 2 && len(name) >= 4 && !genericClasses[name] && !reUnstableClass.MatchString(name) {
+			classes = append(classes, name)
+		}
+	}
+	sort.SliceStable(classes, func(i, j int) bool { return classCounts[classes[i]] > classCounts[classes[j]] })
+	if len(classes) > maxClassSteps {
+		classes = classes[:maxClassSteps]
+	}
+
+	var steps []string
+	steps = append(steps, skeleton...)
+	steps = append(steps, classes...)
+	steps = append(steps, fields...)
+
+	features := map[string][]string{
+		"skeleton":    nonNil(skeleton),
+		"classes":     nonNil(classes),
+		"form_fields": nonNil(fields),
+	}
+
+	return steps, features, nil
+}
+
+// formFieldSteps возвращает признаки поля формы: тип и имя
+func formFieldSteps(sel *goquery.Selection) []string {
+	var steps []string
+
+	if inputType, ok := sel.Attr("type"); ok {
+		switch inputType {
+		case "text", "hidden", "submit", "button":
+		default:
+			steps = append(steps, fmt.Sprintf(`type="%s"`, inputType))
+		}
+	}
+
+	if name, ok := sel.Attr("name"); ok && name != "" && !reUnstableClass.MatchString(name) {
+		steps = append(steps, fmt.Sprintf(`name="%s"`, name))
+	}
+
+	return steps
+}
+
+// nonNil заменяет nil на пустой срез, чтобы в JSON был [] вместо null
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/chromedp/chromedp"
+
+	"scrapper/internal/dto"
+)
+
+// Атрибуты, которыми браузер размечает DOM перед извлечением HTML
+const (
+	attrRenderHidden = "data-scr-hidden"
+	attrRenderRect   = "data-scr-rect"
+	attrRenderZ      = "data-scr-z"
+	attrRenderBg     = "data-scr-bg"
+	attrViewport     = "data-scr-viewport"
+
+	// attrRenderID номер видимого элемента, по которому блок находится в живом DOM для скриншота
+	attrRenderID = "data-scr-id"
+)
+
+// reRenderAttributes находит служебные атрибуты разметки рендера
+var reRenderAttributes = regexp.MustCompile(`\s+data-scr-(?:hidden|rect|z|bg|viewport|style|id)(?:="[^"]*")?`)
+
+// annotateVisibilityJS вычисляет в браузере видимость, координаты, фон и z-index элементов.
+// Элемент помечается скрытым, только если не видны ни он сам, ни его потомки,
+// поэтому видимые дети скрытых родителей не теряются.
+const annotateVisibilityJS = `(() => {
+	const body = document.body;
+	if (!body) {
+		return 0;
+	}
+
+	const pageWidth = Math.max(document.documentElement.scrollWidth, window.innerWidth);
+	const pageHeight = Math.max(document.documentElement.scrollHeight, window.innerHeight);
+	const elements = Array.from(body.querySelectorAll('*'));
+	const visible = new Map();
+
+	const background = (style) => {
+		const color = style.backgroundColor;
+		const hasColor = color && color !== 'transparent' && color !== 'rgba(0, 0, 0, 0)';
+		const hasImage = style.backgroundImage && style.backgroundImage !== 'none';
+		if (!hasColor && !hasImage) return '';
+		return (hasColor ? color : '') + (hasImage ? '|image' : '');
+	};
+
+	const selfVisible = (el, style, rect) => {
+		if (el.getClientRects().length === 0) return false;
+		if (style.visibility === 'hidden' || style.visibility === 'collapse') return false;
+		if (parseFloat(style.opacity) === 0) return false;
+		if (rect.width === 0 || rect.height === 0) return false;
+		if (style.clip === 'rect(0px, 0px, 0px, 0px)' || style.clipPath === 'inset(50%)') return false;
+
+		const left = rect.left + window.scrollX;
+		const top = rect.top + window.scrollY;
+		if (left + rect.width <= 0 || top + rect.height <= 0 || left >= pageWidth || top >= pageHeight) return false;
+
+		return true;
+	};
+
+	// Обходим элементы снизу вверх, чтобы знать видимость потомков
+	for (let i = elements.length - 1; i >= 0; i--) {
+		const el = elements[i];
+		const style = window.getComputedStyle(el);
+		const rect = el.getBoundingClientRect();
+
+		let isVisible = selfVisible(el, style, rect);
+		if (!isVisible) {
+			for (const child of el.children) {
+				if (visible.get(child)) {
+					isVisible = true;
+					break;
+				}
+			}
+		}
+		visible.set(el, isVisible);
+
+		el.removeAttribute('` + attrRenderHidden + `');
+		el.removeAttribute('` + attrRenderRect + `');
+		el.removeAttribute('` + attrRenderZ + `');
+		el.removeAttribute('` + attrRenderBg + `');
+		el.removeAttribute('` + attrRenderID + `');
+
+		if (!isVisible) {
+			el.setAttribute('` + attrRenderHidden + `', '1');
+			continue;
+		}
+
+		el.setAttribute('` + attrRenderRect + `', [
+			Math.round(rect.left + window.scrollX),
+			Math.round(rect.top + window.scrollY),
+			Math.round(rect.width),
+			Math.round(rect.height),
+		].join(','));
+		el.setAttribute('` + attrRenderID + `', String(i));
+
+		if (style.zIndex !== 'auto') {
+			el.setAttribute('` + attrRenderZ + `', style.zIndex);
+		}
+
+		const bg = background(style);
+		if (bg) {
+			el.setAttribute('` + attrRenderBg + `', bg);
+		}
+	}
+
+	// Размеры страницы и окна нужны для сегментации по раскладке
+	const bodyRect = body.getBoundingClientRect();
+	body.setAttribute('` + attrRenderRect + `', [0, 0, Math.round(Math.max(bodyRect.width, pageWidth)), Math.round(pageHeight)].join(','));
+	body.setAttribute('` + attrViewport + `', [window.innerWidth, window.innerHeight].join(','));
+	const bodyBg = background(window.getComputedStyle(body));
+	if (bodyBg) {
+		body.setAttribute('` + attrRenderBg + `', bodyBg);
+	}
+
+	return elements.length;
+})()`
+
+// annotateVisibility возвращает действие chromedp, размечающее DOM вычисленной видимостью
+func annotateVisibility() chromedp.Action {
+	var annotated int
+	return chromedp.Evaluate(annotateVisibilityJS, &annotated)
+}
+
+// stripHiddenMarkup удаляет из страницы невидимые элементы.
+// Если страница не размечена браузером, используются инлайновые стили и атрибут hidden.
+func stripHiddenMarkup(html string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return "", err
+	}
+
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		if isHidden(s) {
+			s.Remove()
+		}
+	})
+
+	return doc.Html()
+}
+
+// stripRenderAttributes удаляет служебные атрибуты рендера из HTML блока
+func stripRenderAttributes(html string) string {
+	return reRenderAttributes.ReplaceAllString(html, "")
+}
+
+// isHidden проверяет, скрыт ли сам элемент: по разметке браузера или по инлайновым признакам
+func isHidden(sel *goquery.Selection) bool {
+	if _, ok := sel.Attr(attrRenderHidden); ok {
+		return true
+	}
+
+	if _, ok := sel.Attr("hidden"); ok {
+		return true
+	}
+
+	style := strings.ReplaceAll(strings.ToLower(sel.AttrOr("style", "")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// renderedRect возвращает координаты элемента на отрендеренной странице
+func renderedRect(sel *goquery.Selection) (*dto.BlockBounds, bool) {
+	values, ok := parseIntList(sel.AttrOr(attrRenderRect, ""), 4)
+	if !ok {
+		return nil, false
+	}
+
+	return &dto.BlockBounds{
+		X:      values[0],
+		Y:      values[1],
+		Width:  values[2],
+		Height: values[3],
+	}, true
+}
+
+// blockBounds возвращает координаты и номер корневого элемента HTML блока в отрендеренном DOM, nil если координаты неизвестны
+func blockBounds(html string) (*dto.BlockBounds, string) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, ""
+	}
+
+	root := doc.Find("body").Children().First()
+	if root.Length() == 0 {
+		return nil, ""
+	}
+
+	bounds, ok := renderedRect(root)
+	if !ok {
+		return nil, ""
+	}
+
+	return bounds, root.AttrOr(attrRenderID, "")
+}
+
+// parseIntList разбирает список целых чисел через запятую заданной длины
+func parseIntList(value string, n int) ([]int, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != n {
+		return nil, false
+	}
+
+	values := make([]int, n)
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+
+	return values, true
+}
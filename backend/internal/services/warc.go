@@ -0,0 +1,524 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/google/uuid"
+)
+
+// warcVersion версия формата WARC, в которой пишутся архивы
+const warcVersion = "WARC/1.1"
+
+// warcSkipHeaders заголовки ответа, которые не сохраняются: браузер отдает тело уже распакованным
+var warcSkipHeaders = map[string]bool{
+	"content-encoding":  true,
+	"transfer-encoding": true,
+	"content-length":    true,
+}
+
+// networkExchange запрос страницы и ответ на него
+type networkExchange struct {
+	requestID network.RequestID
+	request   *network.Request
+	response  *network.Response
+	date      time.Time
+	body      []byte
+	finished  bool
+}
+
+// networkRecorder собирает запросы и ответы страницы по событиям сети браузера
+type networkRecorder struct {
+	mu        sync.Mutex
+	exchanges []*networkExchange
+	pending   map[network.RequestID]*networkExchange
+
+	// document ID запроса документа страницы: первый запрос типа Document
+	document network.RequestID
+}
+
+// newNetworkRecorder создает пустой журнал запросов
+func newNetworkRecorder() *networkRecorder {
+	return &networkRecorder{pending: make(map[network.RequestID]*networkExchange)}
+}
+
+// listen подписывается на события сети вкладки; вызывается до первого chromedp.Run
+func (r *networkRecorder) listen(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		switch ev := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			// При редиректе запрос с тем же ID продолжается, ответ-редирект закрывает предыдущий обмен
+			if prev, ok := r.pending[ev.RequestID]; ok && ev.RedirectResponse != nil {
+				prev.response = ev.RedirectResponse
+				prev.finished = true
+			}
+
+			if strings.HasPrefix(ev.Request.URL, "data:") {
+				delete(r.pending, ev.RequestID)
+				return
+			}
+
+			if r.document == "" && ev.Type == network.ResourceTypeDocument {
+				r.document = ev.RequestID
+			}
+
+			exchange := &networkExchange{requestID: ev.RequestID, request: ev.Request, date: time.Now().UTC()}
+			if ev.WallTime != nil {
+				exchange.date = ev.WallTime.Time().UTC()
+			}
+			r.exchanges = append(r.exchanges, exchange)
+			r.pending[ev.RequestID] = exchange
+		case *network.EventResponseReceived:
+			if exchange, ok := r.pending[ev.RequestID]; ok {
+				exchange.response = ev.Response
+			}
+		case *network.EventLoadingFinished:
+			if exchange, ok := r.pending[ev.RequestID]; ok {
+				exchange.finished = true
+			}
+		}
+	})
+}
+
+// captureBodies возвращает действие chromedp, забирающее у браузера тела завершенных ответов
+func (r *networkRecorder) captureBodies() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		r.mu.Lock()
+		var finished []*networkExchange
+		for _, exchange := range r.exchanges {
+			if exchange.finished && exchange.response != nil && !isRedirect(exchange.response.Status) {
+				finished = append(finished, exchange)
+			}
+		}
+		r.mu.Unlock()
+
+		// Тела нет у ответов без содержимого и у ресурсов, выгруженных из памяти, такие ответы пишутся без тела
+		for _, exchange := range finished {
+			body, err := network.GetResponseBody(exchange.requestID).Do(ctx)
+			if err != nil {
+				continue
+			}
+
+			r.mu.Lock()
+			exchange.body = body
+			r.mu.Unlock()
+		}
+
+		return nil
+	})
+}
+
+// documentExchange возвращает итоговый обмен запроса документа страницы после всех редиректов
+func (r *networkRecorder) documentExchange() *networkExchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.exchanges) - 1; i >= 0; i-- {
+		exchange := r.exchanges[i]
+		if exchange.requestID == r.document && exchange.response != nil {
+			return exchange
+		}
+	}
+
+	return nil
+}
+
+// warc записывает собранные запросы и ответы в WARC
+func (r *networkRecorder) warc(pageURL string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	info := fmt.Sprintf("software: scrapper\r\nformat: WARC File Format 1.1\r\nisPartOf: %s\r\n", pageURL)
+	err := writeWARCRecord(&buf, []warcHeader{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", warcDate(time.Now())},
+		{"Content-Type", "application/warc-fields"},
+	}, []byte(info))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, exchange := range r.exchanges {
+		if exchange.response == nil || !exchange.finished {
+			continue
+		}
+
+		responseID := warcRecordID()
+		date := warcDate(exchange.date)
+
+		err := writeWARCRecord(&buf, []warcHeader{
+			{"WARC-Type", "response"},
+			{"WARC-Record-ID", responseID},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", exchange.request.URL},
+			{"WARC-IP-Address", exchange.response.RemoteIPAddress},
+			{"Content-Type", "application/http;msgtype=response"},
+		}, httpResponseBlock(exchange.response, exchange.body))
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeWARCRecord(&buf, []warcHeader{
+			{"WARC-Type", "request"},
+			{"WARC-Record-ID", warcRecordID()},
+			{"WARC-Date", date},
+			{"WARC-Target-URI", exchange.request.URL},
+			{"WARC-Concurrent-To", responseID},
+			{"Content-Type", "application/http;msgtype=request"},
+		}, httpRequestBlock(exchange.request))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// warcHeader поле заголовка записи WARC
+type warcHeader struct {
+	name  string
+	value string
+}
+
+// writeWARCRecord пишет запись WARC: заголовки, блок и два перевода строки.
+// Пустые поля пропускаются, Content-Length и WARC-Block-Digest вычисляются по блоку.
+func writeWARCRecord(w io.Writer, headers []warcHeader, block []byte) error {
+	digest := sha1.Sum(block)
+
+	var b strings.Builder
+	b.WriteString(warcVersion + "\r\n")
+	for _, header := range headers {
+		if header.value != "" {
+			fmt.Fprintf(&b, "%s: %s\r\n", header.name, header.value)
+		}
+	}
+	fmt.Fprintf(&b, "WARC-Block-Digest: sha1:%s\r\n", base32.StdEncoding.EncodeToString(digest[:]))
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(block))
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write warc record: %w", err)
+	}
+	if _, err := w.Write(block); err != nil {
+		return fmt.Errorf("failed to write warc record: %w", err)
+	}
+	if _, err := io.WriteString(w, "\r\n\r\n"); err != nil {
+		return fmt.Errorf("failed to write warc record: %w", err)
+	}
+
+	return nil
+}
+
+// warcRecordID возвращает новый идентификатор записи WARC
+func warcRecordID() string {
+	return fmt.Sprintf("<urn:uuid:%s>", uuid.New())
+}
+
+// warcDate форматирует время для WARC-Date
+func warcDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// isRedirect проверяет, что статус ответа означает редирект
+func isRedirect(status int64) bool {
+	return status >= 300 && status < 400
+}
+
+// httpResponseBlock собирает ответ в виде HTTP/1.1 сообщения; протокол h2/h3 приводится к HTTP/1.1
+func httpResponseBlock(response *network.Response, body []byte) []byte {
+	var b bytes.Buffer
+
+	statusText := response.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(int(response.Status))
+	}
+	fmt.Fprintf(&b, "HTTP/1.1 %d %s\r\n", response.Status, statusText)
+
+	writeHTTPHeaders(&b, response.Headers)
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(body))
+	b.Write(body)
+
+	return b.Bytes()
+}
+
+// httpRequestBlock собирает запрос в виде HTTP/1.1 сообщения
+func httpRequestBlock(request *network.Request) []byte {
+	var b bytes.Buffer
+
+	target := request.URL
+	host := ""
+	if parsed, err := url.Parse(request.URL); err == nil {
+		target = parsed.RequestURI()
+		host = parsed.Host
+	}
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", request.Method, target)
+
+	if _, ok := headerValue(request.Headers, "Host"); !ok && host != "" {
+		fmt.Fprintf(&b, "Host: %s\r\n", host)
+	}
+	writeHTTPHeaders(&b, request.Headers)
+	b.WriteString("\r\n")
+
+	for _, entry := range request.PostDataEntries {
+		if data, err := base64.StdEncoding.DecodeString(entry.Bytes); err == nil {
+			b.Write(data)
+		}
+	}
+
+	return b.Bytes()
+}
+
+// writeHTTPHeaders пишет заголовки в порядке имен; псевдозаголовки HTTP/2 пропускаются,
+// значения из нескольких строк разбиваются на отдельные заголовки
+func writeHTTPHeaders(b *bytes.Buffer, headers network.Headers) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if strings.HasPrefix(name, ":") || warcSkipHeaders[strings.ToLower(name)] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range strings.Split(fmt.Sprint(headers[name]), "\n") {
+			fmt.Fprintf(b, "%s: %s\r\n", name, value)
+		}
+	}
+}
+
+// headerValue ищет заголовок без учета регистра имени
+func headerValue(headers network.Headers, name string) (string, bool) {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return fmt.Sprint(value), true
+		}
+	}
+	return "", false
+}
+
+// archivedResponse ответ, сохраненный в архиве страницы
+type archivedResponse struct {
+	status     int
+	statusText string
+	headers    []*fetch.HeaderEntry
+	body       []byte
+}
+
+// isDocument проверяет, что ответ — успешно загруженная HTML-страница
+func (r *archivedResponse) isDocument() bool {
+	if r.status < 200 || r.status >= 300 {
+		return false
+	}
+
+	for _, header := range r.headers {
+		if strings.EqualFold(header.Name, "Content-Type") {
+			return strings.HasPrefix(strings.ToLower(header.Value), "text/html")
+		}
+	}
+
+	return false
+}
+
+// replayArchive ответы архива по URL, из которых страница открывается без обращения к сайту
+type replayArchive struct {
+	responses map[string]*archivedResponse
+
+	// document URL первой HTML-страницы архива
+	document string
+}
+
+// newReplayArchive создает пустой набор ответов
+func newReplayArchive() *replayArchive {
+	return &replayArchive{responses: make(map[string]*archivedResponse)}
+}
+
+// add добавляет ответ по URL; при повторах URL остается первый ответ
+func (a *replayArchive) add(target string, response *archivedResponse) {
+	if _, ok := a.responses[target]; ok || target == "" {
+		return
+	}
+
+	a.responses[target] = response
+	if a.document == "" && response.isDocument() {
+		a.document = target
+	}
+}
+
+// readWARC читает записи response из WARC; при повторах URL остается первый ответ
+func readWARC(r io.Reader) (*replayArchive, error) {
+	reader := bufio.NewReader(r)
+	archive := newReplayArchive()
+
+	for {
+		headers, err := readWARCHeaders(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 || length > maxDecompressedSize {
+			return nil, fmt.Errorf("invalid warc record length: %q", headers.Get("Content-Length"))
+		}
+
+		// Длина записи берется из заголовка, поэтому буфер растет по мере чтения,
+		// а не выделяется заранее: короткий файл с большой длиной обрывается на EOF
+		var block bytes.Buffer
+		if _, err := io.CopyN(&block, reader, length); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to read warc record: %w", err)
+		}
+
+		if headers.Get("WARC-Type") != "response" {
+			continue
+		}
+
+		target := headers.Get("WARC-Target-URI")
+		if _, ok := archive.responses[target]; ok || target == "" {
+			continue
+		}
+
+		response, err := parseArchivedResponse(block.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("invalid warc response for %s: %w", target, err)
+		}
+		archive.add(target, response)
+	}
+
+	if len(archive.responses) == 0 {
+		return nil, fmt.Errorf("warc archive has no responses")
+	}
+
+	return archive, nil
+}
+
+// readWARCHeaders пропускает пустые строки между записями и читает заголовки очередной записи
+func readWARCHeaders(reader *bufio.Reader) (http.Header, error) {
+	var version string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && strings.TrimSpace(line) == "" {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read warc record: %w", err)
+		}
+		if version = strings.TrimSpace(line); version != "" {
+			break
+		}
+	}
+
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid warc record version: %q", version)
+	}
+
+	headers := make(http.Header)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read warc headers: %w", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return headers, nil
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid warc header: %q", line)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+}
+
+// parseArchivedResponse разбирает HTTP-ответ из блока записи WARC
+func parseArchivedResponse(block []byte) (*archivedResponse, error) {
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	archived := &archivedResponse{
+		status:     response.StatusCode,
+		statusText: strings.TrimSpace(strings.TrimPrefix(response.Status, strconv.Itoa(response.StatusCode))),
+		body:       body,
+	}
+
+	for name, values := range response.Header {
+		for _, value := range values {
+			archived.headers = append(archived.headers, &fetch.HeaderEntry{Name: name, Value: value})
+		}
+	}
+
+	return archived, nil
+}
+
+// serve перехватывает все запросы вкладки и отвечает на них из архива.
+// Запросы, которых нет в архиве, завершаются ошибкой сети; вызывается до первого chromedp.Run.
+func (a *replayArchive) serve(ctx context.Context) {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+
+		// Команды из обработчика событий отправляются в отдельной горутине, иначе chromedp блокируется
+		go func() {
+			execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+
+			target := paused.Request.URL
+			response, ok := a.responses[target]
+			if !ok {
+				_ = fetch.FailRequest(paused.RequestID, network.ErrorReasonInternetDisconnected).Do(execCtx)
+				return
+			}
+
+			fulfill := fetch.FulfillRequest(paused.RequestID, int64(response.status)).
+				WithResponseHeaders(response.headers).
+				WithBody(base64.StdEncoding.EncodeToString(response.body))
+			if response.statusText != "" {
+				fulfill = fulfill.WithResponsePhrase(response.statusText)
+			}
+			_ = fulfill.Do(execCtx)
+		}()
+	})
+}
+
+// interceptRequests возвращает действие chromedp, включающее перехват всех запросов вкладки
+func interceptRequests() chromedp.Action {
+	return fetch.Enable().WithPatterns([]*fetch.RequestPattern{{URLPattern: "*"}})
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadWARC(t *testing.T) {
+	page := "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n<html>page</html>"
+	style := "HTTP/1.1 200 OK\r\nContent-Type: text/css\r\n\r\nbody{}"
+	moved := "HTTP/1.1 301 Moved Permanently\r\nLocation: https://example.com/\r\n\r\n"
+
+	tests := []struct {
+		name    string
+		warc    string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "responses by target uri",
+			warc: warcTestRecord("warcinfo", "", "software: test\r\n") +
+				warcTestRecord("request", "https://example.com/", "GET / HTTP/1.1\r\n\r\n") +
+				warcTestRecord("response", "https://example.com/", page) +
+				warcTestRecord("response", "https://example.com/a.css", style),
+			want: map[string]string{
+				"https://example.com/":      "<html>page</html>",
+				"https://example.com/a.css": "body{}",
+			},
+		},
+		{
+			name: "first response wins",
+			warc: warcTestRecord("response", "https://example.com/", page) +
+				warcTestRecord("response", "https://example.com/", strings.Replace(page, "page", "other", 1)),
+			want: map[string]string{"https://example.com/": "<html>page</html>"},
+		},
+		{
+			name: "redirect without body",
+			warc: warcTestRecord("response", "http://example.com/", moved),
+			want: map[string]string{"http://example.com/": ""},
+		},
+		{
+			name: "lf line endings and blank lines between records",
+			warc: "\n\nWARC/1.0\nWARC-Type: response\nWARC-Target-URI: https://example.com/\n" +
+				"Content-Length: " + strconv.Itoa(len(page)) + "\n\n" + page + "\n\n\n",
+			want: map[string]string{"https://example.com/": "<html>page</html>"},
+		},
+		{
+			name:    "empty input",
+			warc:    "",
+			wantErr: "no responses",
+		},
+		{
+			name:    "no response records",
+			warc:    warcTestRecord("request", "https://example.com/", "GET / HTTP/1.1\r\n\r\n"),
+			wantErr: "no responses",
+		},
+		{
+			name:    "not a warc file",
+			warc:    "<html></html>\r\n",
+			wantErr: "invalid warc record version",
+		},
+		{
+			name:    "missing content length",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\n\r\n",
+			wantErr: "invalid warc record length",
+		},
+		{
+			name:    "negative content length",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: -1\r\n\r\n",
+			wantErr: "invalid warc record length",
+		},
+		{
+			name:    "truncated block",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 100\r\n\r\nHTTP/1.1 200 OK\r\n",
+			wantErr: "failed to read warc record",
+		},
+		{
+			name:    "length above the size limit",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: " + strconv.Itoa(maxDecompressedSize+1) + "\r\n\r\nHTTP/1.1 200 OK\r\n",
+			wantErr: "invalid warc record length",
+		},
+		{
+			name:    "length far beyond the input",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: " + strconv.Itoa(maxDecompressedSize) + "\r\n\r\nHTTP/1.1 200 OK\r\n",
+			wantErr: "unexpected EOF",
+		},
+		{
+			name:    "length overflowing int64",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 99999999999999999999\r\n\r\n",
+			wantErr: "invalid warc record length",
+		},
+		{
+			name:    "header without colon",
+			warc:    "WARC/1.1\r\nWARC-Type response\r\n\r\n",
+			wantErr: "invalid warc header",
+		},
+		{
+			name:    "truncated headers",
+			warc:    "WARC/1.1\r\nWARC-Type: response\r\n",
+			wantErr: "failed to read warc headers",
+		},
+		{
+			name:    "response block is not http",
+			warc:    warcTestRecord("response", "https://example.com/", "garbage"),
+			wantErr: "invalid warc response for https://example.com/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := readWARC(strings.NewReader(tt.warc))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readWARC() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readWARC() error = %v", err)
+			}
+
+			if len(archive.responses) != len(tt.want) {
+				t.Errorf("readWARC() read %d responses, want %d", len(archive.responses), len(tt.want))
+			}
+			for target, body := range tt.want {
+				response, ok := archive.responses[target]
+				if !ok {
+					t.Errorf("readWARC() has no response for %s", target)
+					continue
+				}
+				if string(response.body) != body {
+					t.Errorf("response %s body = %q, want %q", target, response.body, body)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteWARCRecord(t *testing.T) {
+	block := []byte("HTTP/1.1 404 Not Found\r\nContent-Type: text/plain\r\nX-Test: a\r\n\r\nmissing")
+
+	var buf bytes.Buffer
+	headers := []warcHeader{
+		{name: "WARC-Type", value: "response"},
+		{name: "WARC-Target-URI", value: "https://example.com/missing"},
+		{name: "WARC-Concurrent-To", value: ""},
+	}
+	if err := writeWARCRecord(&buf, headers, block); err != nil {
+		t.Fatalf("writeWARCRecord() error = %v", err)
+	}
+
+	record := buf.String()
+	if !strings.HasPrefix(record, warcVersion+"\r\n") {
+		t.Errorf("record does not start with %s: %q", warcVersion, record)
+	}
+	if strings.Contains(record, "WARC-Concurrent-To") {
+		t.Errorf("record has empty header: %q", record)
+	}
+	if !strings.Contains(record, "WARC-Block-Digest: sha1:") {
+		t.Errorf("record has no block digest: %q", record)
+	}
+
+	// Записанная запись читается обратно
+	archive, err := readWARC(&buf)
+	if err != nil {
+		t.Fatalf("readWARC() error = %v", err)
+	}
+	response := archive.responses["https://example.com/missing"]
+	if response == nil {
+		t.Fatalf("readWARC() has no written response")
+	}
+	if response.status != 404 || response.statusText != "Not Found" || string(response.body) != "missing" {
+		t.Errorf("response = %d %q %q, want 404 \"Not Found\" \"missing\"", response.status, response.statusText, response.body)
+	}
+
+	found := false
+	for _, header := range response.headers {
+		if header.Name == "X-Test" && header.Value == "a" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("response headers = %v, want X-Test: a", response.headers)
+	}
+}
+
+// warcTestRecord собирает запись WARC с заданным типом, адресом и блоком
+func warcTestRecord(recordType, target, block string) string {
+	var b strings.Builder
+	b.WriteString("WARC/1.1\r\nWARC-Type: " + recordType + "\r\n")
+	if target != "" {
+		b.WriteString("WARC-Target-URI: " + target + "\r\n")
+	}
+	b.WriteString("Content-Length: " + strconv.Itoa(len(block)) + "\r\n\r\n")
+	b.WriteString(block)
+	b.WriteString("\r\n\r\n")
+	return b.String()
+}
@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
+
 	"scrapper/internal/dto"
 )
 
@@ -79,9 +81,12 @@ func (s *wordPressService) ParseHeader(html string) (*dto.Block, error) {
 		logoMatch = reLogo.FindStringSubmatch(headerHtml)
 	}
 
-	var logoHtml string
+	// В шапке хранится ссылка на изображение логотипа, а не разметка ссылки вокруг него
+	var logoSrc string
 	if len(logoMatch) >= 1 {
-		logoHtml = logoMatch[0]
+		if logoDoc, err := goquery.NewDocumentFromReader(strings.NewReader(logoMatch[0])); err == nil {
+			logoSrc = logoSource(logoDoc.Find("body"))
+		}
 	}
 
 	// Парсим навигационное меню
@@ -119,7 +124,7 @@ func (s *wordPressService) ParseHeader(html string) (*dto.Block, error) {
 
 	// Создаем структуру для хранения содержимого шапки
 	content := map[string]interface{}{
-		"logo":     logoHtml,
+		"logo":     logoSrc,
 		"menu":     menuHtml,
 		"contacts": contactsHtml,
 		"search":   searchHtml,
@@ -0,0 +1,525 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"go.uber.org/zap"
+
+	"scrapper/internal/dto"
+	"scrapper/internal/repos"
+)
+
+// wxrMenuSlug меню, в которое импортируется навигация шапки
+const wxrMenuSlug = "header-menu"
+
+// wxrAttachmentKinds файлы, которые импортер WordPress загружает в медиабиблиотеку
+var wxrAttachmentKinds = map[dto.AssetKind]bool{
+	dto.AssetKindLogo:       true,
+	dto.AssetKindImage:      true,
+	dto.AssetKindBackground: true,
+	dto.AssetKindOGImage:    true,
+	dto.AssetKindPoster:     true,
+}
+
+// wxrExporter выгружает страницы в WordPress eXtended RSS для импорта через WordPress Importer:
+// каждая операция становится страницей из контентных блоков в разметке Gutenberg,
+// навигация шапки главной страницы - меню, скачанные картинки - вложениями
+type wxrExporter struct {
+	logger *zap.Logger
+	assets repos.AssetRepo
+}
+
+// NewWXRExporter создает экспортер в WXR
+func NewWXRExporter(logger *zap.Logger, assets repos.AssetRepo) Exporter {
+	return &wxrExporter{
+		logger: logger,
+		assets: assets,
+	}
+}
+
+func (e *wxrExporter) Name() string        { return "wxr" }
+func (e *wxrExporter) ContentType() string { return "application/xml; charset=utf-8" }
+func (e *wxrExporter) Extension() string   { return "xml" }
+
+// Export выгружает одну операцию как сайт из одной страницы
+func (e *wxrExporter) Export(ctx context.Context, source ExportSource, w io.Writer) error {
+	return e.ExportSite(ctx, []ExportSource{source}, w)
+}
+
+// wxrPage страница сайта, собранная из блоков операции
+type wxrPage struct {
+	id       int
+	parent   int
+	url      *url.URL
+	title    string
+	slug     string
+	content  string
+	header   string
+	date     time.Time
+	language string
+}
+
+// wxrMenuItem пункт меню шапки; object - ID страницы, если пункт ведет на выгружаемую страницу
+type wxrMenuItem struct {
+	id       int
+	title    string
+	url      string
+	object   int
+	children []*wxrMenuItem
+}
+
+// wxrAttachment файл операции для медиабиблиотеки
+type wxrAttachment struct {
+	id     int
+	parent int
+	url    string
+	title  string
+	date   time.Time
+}
+
+// ExportSite выгружает операции страницами одного сайта.
+// Вложенность страниц строится по путям URL, меню берется из шапки главной страницы.
+func (e *wxrExporter) ExportSite(ctx context.Context, sources []ExportSource, w io.Writer) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("no pages to export")
+	}
+
+	pages := make([]*wxrPage, 0, len(sources))
+	for i, source := range sources {
+		page, err := e.buildPage(ctx, source)
+		if err != nil {
+			return err
+		}
+		page.id = i + 1
+		pages = append(pages, page)
+	}
+
+	byPath := make(map[string]*wxrPage, len(pages))
+	for _, page := range pages {
+		byPath[sitePagePath(page.url)] = page
+	}
+	home := pages[0]
+	for _, page := range pages {
+		if len(sitePagePath(page.url)) < len(sitePagePath(home.url)) {
+			home = page
+		}
+	}
+	for _, page := range pages {
+		page.parent = wxrParentID(page, home, byPath)
+	}
+
+	nextID := len(pages) + 1
+
+	// Вложения: картинки операций без повторов по URL, привязанные к первой странице, где встретились
+	var attachments []*wxrAttachment
+	seen := make(map[string]bool)
+	for i, source := range sources {
+		operation := source.Operation()
+		assets, err := e.assets.GetAssetsByOperationID(ctx, operation.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get assets of operation %s: %w", operation.ID, err)
+		}
+		for _, asset := range assets {
+			if !wxrAttachmentKinds[asset.Kind] || !strings.HasPrefix(asset.ContentType, "image/") || asset.ContentType == "image/svg+xml" || seen[asset.URL] {
+				continue
+			}
+			seen[asset.URL] = true
+			attachments = append(attachments, &wxrAttachment{
+				id:     nextID,
+				parent: pages[i].id,
+				url:    asset.URL,
+				title:  wxrAttachmentTitle(asset.URL),
+				date:   operation.CreatedAt,
+			})
+			nextID++
+		}
+	}
+
+	menu := wxrMenu(home.header, home.url, byPath)
+	var assignIDs func(items []*wxrMenuItem)
+	assignIDs = func(items []*wxrMenuItem) {
+		for _, item := range items {
+			item.id = nextID
+			nextID++
+			assignIDs(item.children)
+		}
+	}
+	assignIDs(menu)
+
+	return writeWXR(w, home, pages, attachments, menu)
+}
+
+// buildPage собирает страницу из контентных блоков операции. Если операция рендерилась под несколькими
+// устройствами, используется десктопная версия, иначе первая встреченная.
+func (e *wxrExporter) buildPage(ctx context.Context, source ExportSource) (*wxrPage, error) {
+	operation := source.Operation()
+	base, err := url.Parse(operation.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operation URL %q: %w", operation.URL, err)
+	}
+
+	type deviceContent struct {
+		content strings.Builder
+		title   string
+		header  string
+	}
+	devices := make(map[dto.DeviceProfile]*deviceContent)
+	var order []dto.DeviceProfile
+
+	err = source.EachBlock(ctx, func(block *dto.Block) error {
+		device, ok := devices[block.Device]
+		if !ok {
+			device = &deviceContent{}
+			devices[block.Device] = device
+			order = append(order, block.Device)
+		}
+
+		switch block.BlockType {
+		case dto.BlockTypeHeader:
+			if device.header == "" {
+				device.header = block.HTML
+			}
+		case dto.BlockTypeFooter:
+		default:
+			device.content.WriteString(gutenbergMarkup(block.HTML, base))
+			if device.title == "" {
+				device.title = firstHeading(block.HTML)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &wxrPage{
+		url:  base,
+		slug: wxrSlug(base),
+		date: operation.CreatedAt,
+	}
+	if len(order) > 0 {
+		device := devices[order[0]]
+		if desktop, ok := devices[dto.DeviceDesktop]; ok {
+			device = desktop
+		}
+		page.content = device.content.String()
+		page.title = device.title
+		page.header = device.header
+	}
+	if page.title == "" {
+		page.title = pageTitleFromURL(base)
+	}
+
+	return page, nil
+}
+
+// firstHeading возвращает текст первого h1 блока
+func firstHeading(content string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	return selectionText(doc.Find("h1").First())
+}
+
+// sitePagePath возвращает путь страницы без завершающего слеша: ключ страницы сайта для вложенности и ссылок
+func sitePagePath(u *url.URL) string {
+	return strings.TrimSuffix(u.EscapedPath(), "/")
+}
+
+// wxrParentID возвращает ID ближайшей выгружаемой страницы выше по пути; главная страница - корень
+func wxrParentID(page, home *wxrPage, byPath map[string]*wxrPage) int {
+	if page == home {
+		return 0
+	}
+	for dir := sitePagePath(page.url); dir != "" && dir != "/"; {
+		dir = path.Dir(dir)
+		if dir == "/" || dir == "." {
+			break
+		}
+		if parent, ok := byPath[dir]; ok && parent != page {
+			return parent.id
+		}
+	}
+	return 0
+}
+
+// wxrSlug возвращает ярлык страницы: последний сегмент пути без расширения
+func wxrSlug(u *url.URL) string {
+	segment := path.Base(sitePagePath(u))
+	if segment == "." || segment == "/" || segment == "" {
+		return "home"
+	}
+	segment = strings.TrimSuffix(segment, path.Ext(segment))
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		segment = unescaped
+	}
+	return strings.ToLower(segment)
+}
+
+// pageTitleFromURL возвращает заголовок страницы без h1: ярлык пути или домен для главной
+func pageTitleFromURL(u *url.URL) string {
+	slug := wxrSlug(u)
+	if slug == "home" {
+		return u.Hostname()
+	}
+	title := []rune(strings.NewReplacer("-", " ", "_", " ").Replace(slug))
+	if len(title) == 0 {
+		return u.Hostname()
+	}
+	title[0] = unicode.ToUpper(title[0])
+	return string(title)
+}
+
+// wxrAttachmentTitle возвращает заголовок вложения по имени файла
+func wxrAttachmentTitle(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	name := path.Base(u.Path)
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// wxrMenu строит дерево меню из навигации шапки: самого насыщенного ссылками nav или списка.
+// Пункты, ведущие на выгружаемые страницы, ссылаются на них, остальные остаются произвольными ссылками.
+func wxrMenu(header string, base *url.URL, byPath map[string]*wxrPage) []*wxrMenuItem {
+	if header == "" {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(header))
+	if err != nil {
+		return nil
+	}
+
+	var root *goquery.Selection
+	best := 0
+	candidates := doc.Find("nav")
+	if candidates.Length() == 0 {
+		candidates = doc.Find("ul")
+	}
+	candidates.Each(func(_ int, sel *goquery.Selection) {
+		if links := sel.Find("a[href]").Length(); links > best {
+			root, best = sel, links
+		}
+	})
+	if root == nil {
+		return nil
+	}
+
+	newItem := func(link *goquery.Selection) *wxrMenuItem {
+		href := strings.TrimSpace(link.AttrOr("href", ""))
+		title := selectionText(link)
+		if href == "" || isJavaScriptURL(href) || title == "" {
+			return nil
+		}
+		item := &wxrMenuItem{title: title, url: absoluteURL(base, href)}
+		if target, err := url.Parse(item.url); err == nil && target.Host == base.Host {
+			if page, ok := byPath[sitePagePath(target)]; ok {
+				item.object = page.id
+			}
+		}
+		return item
+	}
+
+	var parseList func(list *goquery.Selection) []*wxrMenuItem
+	parseList = func(list *goquery.Selection) []*wxrMenuItem {
+		var items []*wxrMenuItem
+		list.ChildrenFiltered("li").Each(func(_ int, li *goquery.Selection) {
+			// Ссылка пункта - первая ссылка вне вложенного списка
+			link := li.Find("a[href]").FilterFunction(func(_ int, a *goquery.Selection) bool {
+				return a.ParentsUntilSelection(li).Filter("ul, ol").Length() == 0
+			}).First()
+			item := newItem(link)
+			if item == nil {
+				return
+			}
+			if nested := li.Find("ul, ol").First(); nested.Length() > 0 {
+				item.children = parseList(nested)
+			}
+			items = append(items, item)
+		})
+		return items
+	}
+
+	list := root
+	if goquery.NodeName(root) != "ul" && goquery.NodeName(root) != "ol" {
+		list = root.Find("ul, ol").First()
+	}
+	if list.Length() > 0 {
+		if items := parseList(list); len(items) > 0 {
+			return items
+		}
+	}
+
+	// Навигация без списков: плоское меню из ссылок
+	var items []*wxrMenuItem
+	root.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+		if item := newItem(link); item != nil {
+			items = append(items, item)
+		}
+	})
+	return items
+}
+
+// writeWXR пишет документ WXR 1.2: страницы, затем вложения и пункты меню, чтобы импортер
+// уже знал страницы, на которые они ссылаются
+func writeWXR(w io.Writer, home *wxrPage, pages []*wxrPage, attachments []*wxrAttachment, menu []*wxrMenuItem) error {
+	ew := &errWriter{w: w}
+	site := (&url.URL{Scheme: home.url.Scheme, Host: home.url.Host}).String()
+
+	ew.printf("<?xml version=\"1.0\" encoding=\"UTF-8\" ?>\n")
+	ew.printf("<rss version=\"2.0\"\n\txmlns:excerpt=\"http://wordpress.org/export/1.2/excerpt/\"\n\txmlns:content=\"http://purl.org/rss/1.0/modules/content/\"\n\txmlns:wfw=\"http://wellformedweb.org/CommentAPI/\"\n\txmlns:dc=\"http://purl.org/dc/elements/1.1/\"\n\txmlns:wp=\"http://wordpress.org/export/1.2/\"\n>\n")
+	ew.printf("<channel>\n")
+	ew.printf("\t<title>%s</title>\n", wxrCDATA(home.title))
+	ew.printf("\t<link>%s</link>\n", html.EscapeString(site))
+	ew.printf("\t<description></description>\n")
+	ew.printf("\t<pubDate>%s</pubDate>\n", time.Now().UTC().Format(time.RFC1123Z))
+	ew.printf("\t<wp:wxr_version>1.2</wp:wxr_version>\n")
+	ew.printf("\t<wp:base_site_url>%s</wp:base_site_url>\n", html.EscapeString(site))
+	ew.printf("\t<wp:base_blog_url>%s</wp:base_blog_url>\n", html.EscapeString(site))
+	if len(menu) > 0 {
+		ew.printf("\t<wp:term><wp:term_id>1</wp:term_id><wp:term_taxonomy>nav_menu</wp:term_taxonomy><wp:term_slug>%s</wp:term_slug><wp:term_name>%s</wp:term_name></wp:term>\n", wxrMenuSlug, wxrCDATA("Header menu"))
+	}
+
+	for _, page := range pages {
+		writeWXRItem(ew, wxrItem{
+			id:       page.id,
+			parent:   page.parent,
+			title:    page.title,
+			link:     page.url.String(),
+			slug:     page.slug,
+			date:     page.date,
+			postType: "page",
+			status:   "publish",
+			content:  page.content,
+			meta:     [][2]string{{"_scrapper_source_url", page.url.String()}},
+		})
+	}
+
+	for _, attachment := range attachments {
+		writeWXRItem(ew, wxrItem{
+			id:         attachment.id,
+			parent:     attachment.parent,
+			title:      attachment.title,
+			link:       attachment.url,
+			slug:       strings.ToLower(attachment.title),
+			date:       attachment.date,
+			postType:   "attachment",
+			status:     "inherit",
+			attachment: attachment.url,
+		})
+	}
+
+	var writeMenu func(items []*wxrMenuItem, parent int)
+	writeMenu = func(items []*wxrMenuItem, parent int) {
+		for order, item := range items {
+			meta := [][2]string{
+				{"_menu_item_menu_item_parent", fmt.Sprint(parent)},
+				{"_menu_item_target", ""},
+				{"_menu_item_classes", ""},
+				{"_menu_item_xfn", ""},
+			}
+			if item.object > 0 {
+				meta = append(meta,
+					[2]string{"_menu_item_type", "post_type"},
+					[2]string{"_menu_item_object", "page"},
+					[2]string{"_menu_item_object_id", fmt.Sprint(item.object)},
+					[2]string{"_menu_item_url", ""},
+				)
+			} else {
+				meta = append(meta,
+					[2]string{"_menu_item_type", "custom"},
+					[2]string{"_menu_item_object", "custom"},
+					[2]string{"_menu_item_object_id", fmt.Sprint(item.id)},
+					[2]string{"_menu_item_url", item.url},
+				)
+			}
+
+			writeWXRItem(ew, wxrItem{
+				id:        item.id,
+				title:     item.title,
+				link:      item.url,
+				slug:      fmt.Sprintf("menu-item-%d", item.id),
+				date:      home.date,
+				postType:  "nav_menu_item",
+				status:    "publish",
+				menuOrder: order + 1,
+				menu:      true,
+				meta:      meta,
+			})
+			writeMenu(item.children, item.id)
+		}
+	}
+	writeMenu(menu, 0)
+
+	ew.printf("</channel>\n</rss>\n")
+	return ew.err
+}
+
+// wxrItem запись item документа WXR
+type wxrItem struct {
+	id         int
+	parent     int
+	title      string
+	link       string
+	slug       string
+	date       time.Time
+	postType   string
+	status     string
+	content    string
+	attachment string
+	menuOrder  int
+	menu       bool
+	meta       [][2]string
+}
+
+func writeWXRItem(ew *errWriter, item wxrItem) {
+	date := item.date.UTC()
+
+	ew.printf("\t<item>\n")
+	ew.printf("\t\t<title>%s</title>\n", wxrCDATA(item.title))
+	ew.printf("\t\t<link>%s</link>\n", html.EscapeString(item.link))
+	ew.printf("\t\t<pubDate>%s</pubDate>\n", date.Format(time.RFC1123Z))
+	ew.printf("\t\t<dc:creator>%s</dc:creator>\n", wxrCDATA("admin"))
+	ew.printf("\t\t<guid isPermaLink=\"false\">%s</guid>\n", html.EscapeString(item.link))
+	ew.printf("\t\t<description></description>\n")
+	ew.printf("\t\t<content:encoded>%s</content:encoded>\n", wxrCDATA(item.content))
+	ew.printf("\t\t<excerpt:encoded>%s</excerpt:encoded>\n", wxrCDATA(""))
+	ew.printf("\t\t<wp:post_id>%d</wp:post_id>\n", item.id)
+	ew.printf("\t\t<wp:post_date>%s</wp:post_date>\n", wxrCDATA(date.Format(time.DateTime)))
+	ew.printf("\t\t<wp:post_date_gmt>%s</wp:post_date_gmt>\n", wxrCDATA(date.Format(time.DateTime)))
+	ew.printf("\t\t<wp:comment_status>%s</wp:comment_status>\n", wxrCDATA("closed"))
+	ew.printf("\t\t<wp:ping_status>%s</wp:ping_status>\n", wxrCDATA("closed"))
+	ew.printf("\t\t<wp:post_name>%s</wp:post_name>\n", wxrCDATA(item.slug))
+	ew.printf("\t\t<wp:status>%s</wp:status>\n", wxrCDATA(item.status))
+	ew.printf("\t\t<wp:post_parent>%d</wp:post_parent>\n", item.parent)
+	ew.printf("\t\t<wp:menu_order>%d</wp:menu_order>\n", item.menuOrder)
+	ew.printf("\t\t<wp:post_type>%s</wp:post_type>\n", wxrCDATA(item.postType))
+	ew.printf("\t\t<wp:post_password>%s</wp:post_password>\n", wxrCDATA(""))
+	ew.printf("\t\t<wp:is_sticky>0</wp:is_sticky>\n")
+	if item.attachment != "" {
+		ew.printf("\t\t<wp:attachment_url>%s</wp:attachment_url>\n", wxrCDATA(item.attachment))
+	}
+	if item.menu {
+		ew.printf("\t\t<category domain=\"nav_menu\" nicename=\"%s\">%s</category>\n", wxrMenuSlug, wxrCDATA("Header menu"))
+	}
+	for _, meta := range item.meta {
+		ew.printf("\t\t<wp:postmeta>\n\t\t\t<wp:meta_key>%s</wp:meta_key>\n\t\t\t<wp:meta_value>%s</wp:meta_value>\n\t\t</wp:postmeta>\n", wxrCDATA(meta[0]), wxrCDATA(meta[1]))
+	}
+	ew.printf("\t</item>\n")
+}
+
+// wxrCDATA оборачивает текст в CDATA, разбивая "]]>" внутри текста
+func wxrCDATA(text string) string {
+	return "<![CDATA[" + strings.ReplaceAll(text, "]]>", "]]]]><![CDATA[>") + "]]>"
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWXRCDATA(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: "<![CDATA[]]>"},
+		{text: "<p>a & b</p>", want: "<![CDATA[<p>a & b</p>]]>"},
+		{text: "x]]>y", want: "<![CDATA[x]]]]><![CDATA[>y]]>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := wxrCDATA(tt.text); got != tt.want {
+				t.Errorf("wxrCDATA(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWXRSlug(t *testing.T) {
+	tests := []struct {
+		rawURL    string
+		wantSlug  string
+		wantTitle string
+	}{
+		{rawURL: "https://example.com/", wantSlug: "home", wantTitle: "example.com"},
+		{rawURL: "https://example.com", wantSlug: "home", wantTitle: "example.com"},
+		{rawURL: "https://example.com/about-us/", wantSlug: "about-us", wantTitle: "About us"},
+		{rawURL: "https://example.com/catalog/Big_Sale.html", wantSlug: "big_sale", wantTitle: "Big sale"},
+		{rawURL: "https://example.com/%D0%BE-%D0%BD%D0%B0%D1%81", wantSlug: "о-нас", wantTitle: "О нас"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawURL, func(t *testing.T) {
+			u, _ := url.Parse(tt.rawURL)
+			if got := wxrSlug(u); got != tt.wantSlug {
+				t.Errorf("wxrSlug() = %q, want %q", got, tt.wantSlug)
+			}
+			if got := pageTitleFromURL(u); got != tt.wantTitle {
+				t.Errorf("pageTitleFromURL() = %q, want %q", got, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestWXRParentID(t *testing.T) {
+	page := func(id int, rawURL string) *wxrPage {
+		u, _ := url.Parse(rawURL)
+		return &wxrPage{id: id, url: u}
+	}
+	home := page(1, "https://example.com/")
+	catalog := page(2, "https://example.com/catalog/")
+	item := page(3, "https://example.com/catalog/shoes/red")
+	about := page(4, "https://example.com/about")
+
+	byPath := make(map[string]*wxrPage)
+	for _, p := range []*wxrPage{home, catalog, item, about} {
+		byPath[sitePagePath(p.url)] = p
+	}
+
+	tests := []struct {
+		name string
+		page *wxrPage
+		want int
+	}{
+		{name: "home is the root", page: home, want: 0},
+		{name: "top level page", page: about, want: 0},
+		{name: "direct child", page: catalog, want: 0},
+		{name: "nearest exported ancestor", page: item, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wxrParentID(tt.page, home, byPath); got != tt.want {
+				t.Errorf("wxrParentID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWXRMenu(t *testing.T) {
+	base, _ := url.Parse("https://example.com/")
+	about, _ := url.Parse("https://example.com/about")
+	byPath := map[string]*wxrPage{"/about": {id: 7, url: about}}
+
+	type item struct {
+		Title    string
+		URL      string
+		Object   int
+		Children []item
+	}
+	var flatten func(items []*wxrMenuItem) []item
+	flatten = func(items []*wxrMenuItem) []item {
+		var result []item
+		for _, i := range items {
+			result = append(result, item{Title: i.title, URL: i.url, Object: i.object, Children: flatten(i.children)})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		want   []item
+	}{
+		{
+			name:   "no header",
+			header: "",
+			want:   nil,
+		},
+		{
+			name: "nested list in the richest nav",
+			header: `<header><nav><a href="/">Logo</a></nav><nav><ul>` +
+				`<li><a href="/about/">About</a></li>` +
+				`<li><a href="/services">Services</a><ul><li><a href="https://other.com/x">Partner</a></li></ul></li>` +
+				`<li><a href="javascript:void(0)">Skip</a></li>` +
+				`</ul></nav></header>`,
+			want: []item{
+				{Title: "About", URL: "https://example.com/about/", Object: 7},
+				{Title: "Services", URL: "https://example.com/services", Children: []item{
+					{Title: "Partner", URL: "https://other.com/x"},
+				}},
+			},
+		},
+		{
+			name:   "flat links without lists",
+			header: `<nav><a href="/about">About</a> <a href="#contacts">Contacts</a> <a href="/empty"> </a></nav>`,
+			want: []item{
+				{Title: "About", URL: "https://example.com/about", Object: 7},
+				{Title: "Contacts", URL: "#contacts"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flatten(wxrMenu(tt.header, base, byPath)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wxrMenu() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteWXR(t *testing.T) {
+	date := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	homeURL, _ := url.Parse("https://example.com/")
+	aboutURL, _ := url.Parse("https://example.com/about?x=1&y=2")
+
+	home := &wxrPage{id: 1, url: homeURL, title: "Home", slug: "home", content: "<!-- wp:paragraph --><p>Hi</p><!-- /wp:paragraph -->", date: date}
+	about := &wxrPage{id: 2, url: aboutURL, title: "About ]]> us", slug: "about", content: "<p>x]]>y</p>", date: date}
+	attachments := []*wxrAttachment{{id: 3, parent: 1, url: "https://example.com/logo.png", title: "Logo", date: date}}
+	menu := []*wxrMenuItem{
+		{id: 4, title: "About", url: "https://example.com/about", object: 2, children: []*wxrMenuItem{
+			{id: 5, title: "Blog", url: "https://blog.example.com/"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeWXR(&buf, home, []*wxrPage{home, about}, attachments, menu); err != nil {
+		t.Fatalf("writeWXR() error = %v", err)
+	}
+
+	// Документ должен разбираться как XML, а CDATA возвращать исходный текст
+	var doc struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+			Items []struct {
+				Title    string `xml:"title"`
+				Link     string `xml:"link"`
+				Content  string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+				ID       int    `xml:"http://wordpress.org/export/1.2/ post_id"`
+				Parent   int    `xml:"http://wordpress.org/export/1.2/ post_parent"`
+				Order    int    `xml:"http://wordpress.org/export/1.2/ menu_order"`
+				PostType string `xml:"http://wordpress.org/export/1.2/ post_type"`
+				Meta     []struct {
+					Key   string `xml:"http://wordpress.org/export/1.2/ meta_key"`
+					Value string `xml:"http://wordpress.org/export/1.2/ meta_value"`
+				} `xml:"http://wordpress.org/export/1.2/ postmeta"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("writeWXR() produced invalid XML: %v\n%s", err, buf.String())
+	}
+
+	if doc.Channel.Title != "Home" || doc.Channel.Link != "https://example.com" {
+		t.Errorf("channel = %q %q, want Home https://example.com", doc.Channel.Title, doc.Channel.Link)
+	}
+
+	type row struct {
+		id, parent, order int
+		postType, title   string
+	}
+	var got []row
+	for _, item := range doc.Channel.Items {
+		got = append(got, row{item.ID, item.Parent, item.Order, item.PostType, item.Title})
+	}
+	want := []row{
+		{1, 0, 0, "page", "Home"},
+		{2, 0, 0, "page", "About ]]> us"},
+		{3, 1, 0, "attachment", "Logo"},
+		{4, 0, 1, "nav_menu_item", "About"},
+		{5, 0, 1, "nav_menu_item", "Blog"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("items = %+v, want %+v", got, want)
+	}
+
+	items := doc.Channel.Items
+	if items[1].Content != "<p>x]]>y</p>" || items[1].Link != "https://example.com/about?x=1&y=2" {
+		t.Errorf("about page = %q %q, want original content and link", items[1].Content, items[1].Link)
+	}
+
+	meta := func(index int) map[string]string {
+		result := make(map[string]string)
+		for _, m := range items[index].Meta {
+			result[m.Key] = m.Value
+		}
+		return result
+	}
+	if m := meta(3); m["_menu_item_type"] != "post_type" || m["_menu_item_object_id"] != "2" || m["_menu_item_menu_item_parent"] != "0" {
+		t.Errorf("page menu item meta = %v", m)
+	}
+	if m := meta(4); m["_menu_item_type"] != "custom" || m["_menu_item_url"] != "https://blog.example.com/" || m["_menu_item_menu_item_parent"] != "4" {
+		t.Errorf("custom menu item meta = %v", m)
+	}
+
+	if !strings.Contains(buf.String(), "<wp:term_taxonomy>nav_menu</wp:term_taxonomy>") {
+		t.Errorf("writeWXR() has no nav_menu term")
+	}
+}
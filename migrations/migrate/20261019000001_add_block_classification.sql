@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Разрешаем сохранять контентные блоки
+ALTER TABLE blocks DROP CONSTRAINT IF EXISTS blocks_block_type_check;
+ALTER TABLE blocks ADD CONSTRAINT blocks_block_type_check CHECK (block_type IN ('header', 'footer', 'content'));
+
+-- Семантическая метка блока: категория, оценка и сработавшие признаки
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS classification JSONB;
+
+-- Индекс для поиска блоков по категории
+CREATE INDEX IF NOT EXISTS idx_blocks_classification_category ON blocks((classification ->> 'category'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_blocks_classification_category;
+ALTER TABLE blocks DROP COLUMN IF EXISTS classification;
+
+-- Возвращаем исходное ограничение: контентные блоки в прежней схеме не хранятся
+DELETE FROM blocks WHERE block_type = 'content';
+ALTER TABLE blocks DROP CONSTRAINT IF EXISTS blocks_block_type_check;
+ALTER TABLE blocks ADD CONSTRAINT blocks_block_type_check CHECK (block_type IN ('header', 'footer'));
+-- +goose StatementEnd
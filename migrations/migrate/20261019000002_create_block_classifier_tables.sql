@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ручная разметка типов блоков для обучения классификатора
+CREATE TABLE IF NOT EXISTS block_labels (
+                                            block_id UUID PRIMARY KEY REFERENCES blocks(id) ON DELETE CASCADE,
+                                            category VARCHAR(32) NOT NULL,
+                                            created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Версии обученных моделей классификатора
+CREATE TABLE IF NOT EXISTS classifier_models (
+                                                 id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                 version SERIAL NOT NULL UNIQUE,
+                                                 algorithm VARCHAR(32) NOT NULL,
+                                                 samples INT NOT NULL DEFAULT 0,
+                                                 accuracy DOUBLE PRECISION NOT NULL DEFAULT 0,
+                                                 active BOOLEAN NOT NULL DEFAULT FALSE,
+                                                 parameters JSONB NOT NULL,
+                                                 created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Активной может быть только одна версия модели
+CREATE UNIQUE INDEX IF NOT EXISTS idx_classifier_models_active ON classifier_models(active) WHERE active;
+
+-- Запуски обучения классификатора
+CREATE TABLE IF NOT EXISTS classifier_training_runs (
+                                                        id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                        model_id UUID REFERENCES classifier_models(id) ON DELETE SET NULL,
+                                                        status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'completed', 'error')),
+                                                        samples INT NOT NULL DEFAULT 0,
+                                                        accuracy DOUBLE PRECISION NOT NULL DEFAULT 0,
+                                                        error TEXT NOT NULL DEFAULT '',
+                                                        started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                        finished_at TIMESTAMP WITH TIME ZONE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS classifier_training_runs CASCADE;
+DROP TABLE IF EXISTS classifier_models CASCADE;
+DROP TABLE IF EXISTS block_labels CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Шаблоны блоков: последовательности HTML-признаков для каждой платформы
+CREATE TABLE IF NOT EXISTS block_templates (
+                                               block_type VARCHAR(64) PRIMARY KEY,
+                                               wordpress JSONB,
+                                               tilda JSONB,
+                                               bitrix JSONB,
+                                               html5 JSONB,
+                                               created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Таблица могла быть создана вручную без первичного ключа
+CREATE UNIQUE INDEX IF NOT EXISTS idx_block_templates_block_type ON block_templates(block_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS block_templates CASCADE;
+-- +goose StatementEnd
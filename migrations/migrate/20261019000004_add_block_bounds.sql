@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Координаты блока на отрендеренной странице
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS bounds JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE blocks DROP COLUMN IF EXISTS bounds;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Ключи скриншотов в хранилище файлов
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS screenshot_key TEXT;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS screenshot_key TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE blocks DROP COLUMN IF EXISTS screenshot_key;
+ALTER TABLE operations DROP COLUMN IF EXISTS screenshot_key;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Профиль устройства, под которым был отрендерен блок
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS device VARCHAR(20) NOT NULL DEFAULT 'desktop';
+CREATE INDEX IF NOT EXISTS idx_blocks_operation_device ON blocks (operation_id, device);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_blocks_operation_device;
+ALTER TABLE blocks DROP COLUMN IF EXISTS device;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Токены дизайна (цвета, типографика, отступы) страницы и отдельных блоков
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS design_tokens JSONB;
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS design_tokens JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE blocks DROP COLUMN IF EXISTS design_tokens;
+ALTER TABLE operations DROP COLUMN IF EXISTS design_tokens;
+-- +goose StatementEnd
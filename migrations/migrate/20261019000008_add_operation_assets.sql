@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Файлы, скачанные для операции: логотип, иконки, изображения
+CREATE TABLE IF NOT EXISTS operation_assets (
+                                                id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                operation_id UUID NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+                                                kind VARCHAR(32) NOT NULL,
+                                                url TEXT NOT NULL,
+                                                storage_key TEXT NOT NULL,
+                                                content_type VARCHAR(100) NOT NULL DEFAULT '',
+                                                size BIGINT NOT NULL DEFAULT 0,
+                                                width INT NOT NULL DEFAULT 0,
+                                                height INT NOT NULL DEFAULT 0,
+                                                sha256 CHAR(64) NOT NULL,
+                                                created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_operation_assets_operation ON operation_assets(operation_id, kind);
+CREATE INDEX IF NOT EXISTS idx_operation_assets_sha256 ON operation_assets(sha256);
+
+-- Фирменные параметры сайта: название, theme-color, цвет фона из манифеста
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS brand JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE operations DROP COLUMN IF EXISTS brand;
+DROP TABLE IF EXISTS operation_assets CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Манифест операции: какой блок ссылается на какой файл и по какому исходному URL
+CREATE TABLE IF NOT EXISTS block_assets (
+                                            block_id UUID NOT NULL REFERENCES blocks(id) ON DELETE CASCADE,
+                                            asset_id UUID NOT NULL REFERENCES operation_assets(id) ON DELETE CASCADE,
+                                            url TEXT NOT NULL,
+                                            PRIMARY KEY (block_id, asset_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_block_assets_asset ON block_assets(asset_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS block_assets CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Архивы страницы в MHTML и WARC, снятые при рендеринге под каждым профилем устройства.
+-- Операция, перепарсенная из архива, ссылается на те же файлы хранилища, captured_at сохраняется от исходной.
+CREATE TABLE IF NOT EXISTS operation_archives (
+                                                  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                  operation_id UUID NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+                                                  device VARCHAR(16) NOT NULL DEFAULT 'desktop',
+                                                  format VARCHAR(16) NOT NULL,
+                                                  storage_key TEXT NOT NULL,
+                                                  size BIGINT NOT NULL DEFAULT 0,
+                                                  sha256 CHAR(64) NOT NULL,
+                                                  captured_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                                                  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                  UNIQUE (operation_id, device, format)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS operation_archives CASCADE;
+-- +goose StatementEnd
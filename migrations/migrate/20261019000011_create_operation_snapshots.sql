@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Отрендеренный HTML страницы (gzip в хранилище) и ответ сервера на запрос документа для каждого профиля устройства.
+-- Операция, перепарсенная из снимков, ссылается на те же файлы хранилища.
+CREATE TABLE IF NOT EXISTS operation_snapshots (
+                                                   id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                   operation_id UUID NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+                                                   device VARCHAR(16) NOT NULL DEFAULT 'desktop',
+                                                   storage_key TEXT NOT NULL,
+                                                   size BIGINT NOT NULL DEFAULT 0,
+                                                   compressed_size BIGINT NOT NULL DEFAULT 0,
+                                                   sha256 CHAR(64) NOT NULL,
+                                                   url TEXT NOT NULL DEFAULT '',
+                                                   status_code INT NOT NULL DEFAULT 0,
+                                                   content_type VARCHAR(100) NOT NULL DEFAULT '',
+                                                   headers JSONB,
+                                                   captured_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+                                                   created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                   UNIQUE (operation_id, device)
+);
+
+-- Версия парсера, которой получены блоки, и операция, из снимков которой они перепарсены
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS parser_version VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS reparsed_from UUID REFERENCES operations(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE operations DROP COLUMN IF EXISTS reparsed_from;
+ALTER TABLE operations DROP COLUMN IF EXISTS parser_version;
+DROP TABLE IF EXISTS operation_snapshots CASCADE;
+-- +goose StatementEnd
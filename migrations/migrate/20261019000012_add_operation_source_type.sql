@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Источник страницы операции: живой URL, загруженный HTML, MHTML, HAR или WARC
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS source_type VARCHAR(16) NOT NULL DEFAULT 'url';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE operations DROP COLUMN IF EXISTS source_type;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Исходный HTML блока до очистки, абсолютизации ссылок и форматирования
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS original_html TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE blocks DROP COLUMN IF EXISTS original_html;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Пользовательские шаблоны экспорта (text/template или html/template); имя шаблона - значение format в экспорте операции
+CREATE TABLE IF NOT EXISTS export_templates (
+                                                id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+                                                name VARCHAR(64) NOT NULL UNIQUE,
+                                                engine VARCHAR(8) NOT NULL DEFAULT 'text',
+                                                content_type VARCHAR(100) NOT NULL,
+                                                extension VARCHAR(16) NOT NULL,
+                                                description TEXT NOT NULL DEFAULT '',
+                                                body TEXT NOT NULL,
+                                                created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+                                                updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS export_templates CASCADE;
+-- +goose StatementEnd